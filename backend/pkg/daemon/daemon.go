@@ -18,13 +18,18 @@ type Settings struct {
 	Host     string
 	CertFile string
 	KeyFile  string
+
+	// WriteTimeout is how long a response can take to be written. Endpoints that
+	// stream, such as server-sent events, lift it for their own responses.
+	WriteTimeout time.Duration
 }
 
 func (s Settings) Defaults() Settings {
 	return Settings{
-		Host:     "localhost",
-		CertFile: "/run/secrets/cert.pem",
-		KeyFile:  "/run/secrets/key.pem",
+		Host:         "localhost",
+		CertFile:     "/run/secrets/cert.pem",
+		KeyFile:      "/run/secrets/key.pem",
+		WriteTimeout: time.Minute,
 	}
 }
 
@@ -82,13 +87,19 @@ func (d *Daemon) serveHTTPS(ctx context.Context) error {
 		return fmt.Errorf("could not load TLS config: %v", err)
 	}
 
+	// Shutting down waits for the requests in flight, so streams are told to end
+	requests, stopRequests := context.WithCancel(context.Background())
+	defer stopRequests()
+
 	sv := http.Server{
 		Addr:         net.JoinHostPort(d.settings.Host, "443"),
 		Handler:      d.multiplexer(),
 		ReadTimeout:  time.Minute,
-		WriteTimeout: time.Minute,
+		WriteTimeout: d.settings.WriteTimeout,
 		TLSConfig:    tlsConfig,
+		BaseContext:  func(net.Listener) context.Context { return requests },
 	}
+	sv.RegisterOnShutdown(stopRequests)
 
 	context.AfterFunc(ctx, func() {
 		_ = sv.Shutdown(context.Background())
@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/jsondb"
@@ -23,6 +24,11 @@ type DB interface {
 	DeleteSession(id string) error
 	PurgeSessions() error
 
+	LookupCalendarFeed(token string) (dbtypes.CalendarFeed, error)
+	LookupUserCalendarFeed(user string) (dbtypes.CalendarFeed, error)
+	SetCalendarFeed(f dbtypes.CalendarFeed) error
+	DeleteCalendarFeed(user string) error
+
 	Products() ([]product.Product, error)
 	LookupProduct(ID product.ID) (product.Product, error)
 	SetProduct(p product.Product) (product.ID, error)
@@ -31,23 +37,79 @@ type DB interface {
 	Recipes(asUser string) ([]recipe.Recipe, error)
 	LookupRecipe(asUser string, id recipe.ID) (recipe.Recipe, error)
 	SetRecipe(r recipe.Recipe) (recipe.ID, error)
+	// SetRecipes saves several recipes in a single transaction, and returns their IDs in the same order.
+	SetRecipes(rs []recipe.Recipe) ([]recipe.ID, error)
+	// ForkRecipes saves new copies of recipes in a single transaction, and returns their IDs in the same order.
+	// Every copy must have ForkedFrom set. Sub-recipes pointing to a recipe copied earlier in the same call are
+	// pointed to its copy instead.
+	ForkRecipes(copies []recipe.Recipe) ([]recipe.ID, error)
 	DeleteRecipe(asUser string, id recipe.ID) error
 
+	RecipeRevisions(asUser string, id recipe.ID) ([]dbtypes.RecipeRevision, error)
+	LookupRecipeRevision(asUser string, id recipe.ID, revision int) (dbtypes.RecipeRevision, error)
+
+	SharedRecipes(asUser string) ([]recipe.Recipe, error)
+	LookupSharedRecipe(asUser string, id recipe.ID) (recipe.Recipe, error)
+	LookupRecipeSharing(owner string, id recipe.ID) (dbtypes.RecipeSharing, error)
+	SetRecipeSharing(s dbtypes.RecipeSharing) error
+
 	Menus(user string) ([]dbtypes.Menu, error)
 	LookupMenu(user, name string) (dbtypes.Menu, error)
 	SetMenu(m dbtypes.Menu) error
+	// CreateMenu stores a new menu. It returns fs.ErrExist if the user already has a menu with the same name.
+	CreateMenu(m dbtypes.Menu) error
 	DeleteMenu(user, name string) error
+	RenameMenu(user, oldName, newName string) error
 
 	Pantries(user string) ([]dbtypes.Pantry, error)
 	LookupPantry(user, name string) (dbtypes.Pantry, error)
 	SetPantry(p dbtypes.Pantry) error
+	// UpdatePantry applies the operations to an existing pantry atomically, and returns the pantry after them.
+	UpdatePantry(user, name string, ops []dbtypes.PantryOp) (dbtypes.Pantry, error)
 	DeletePantry(user, name string) error
 
+	// PantryStaples returns the minimum-stock rules of a pantry, which are empty until they are set.
+	PantryStaples(user, pantry string) (dbtypes.PantryStaples, error)
+	SetPantryStaples(s dbtypes.PantryStaples) error
+
+	PantryGroups(user string) ([]dbtypes.PantryGroup, error)
+	LookupPantryGroup(user, name string) (dbtypes.PantryGroup, error)
+	SetPantryGroup(g dbtypes.PantryGroup) error
+	DeletePantryGroup(user, name string) error
+
+	// PantryLedger returns the changes made to a pantry, oldest first.
+	PantryLedger(user, name string) ([]dbtypes.PantryChange, error)
+	// RollbackPantry brings a pantry back to the snapshot of an entry of its ledger, recorded as a new entry.
+	// It returns fs.ErrExist if meals were cooked from the pantry after the entry and are still recorded as
+	// cooked, because uncooking them would put their stock back a second time.
+	RollbackPantry(user, name string, entry int) error
+
 	ShoppingLists(user string) ([]dbtypes.ShoppingList, error)
 	LookupShoppingList(user, menu, pantry string) (dbtypes.ShoppingList, error)
 	SetShoppingList(m dbtypes.ShoppingList) error
+	// UpdateShoppingList applies the operations to a shopping list atomically, creating it if needed,
+	// and returns the list after them.
+	UpdateShoppingList(user, menu, pantry string, ops []dbtypes.ShoppingListOp) (dbtypes.ShoppingList, error)
 	DeleteShoppingList(user, menu, pantry string) error
 
+	// CookedMeals returns the meals of a menu that have been cooked, with the stock they used.
+	CookedMeals(user, menu string) ([]dbtypes.CookedMeal, error)
+	// CookMeals takes what the meals of a menu need out of a pantry and records the stock each of them used,
+	// in a single transaction. Lots expired when the meals are cooked are not used. It returns fs.ErrExist if
+	// any of the meals was already cooked.
+	CookMeals(user, menu, pantry string, cooked time.Time, meals []dbtypes.MealToCook) ([]dbtypes.CookedMeal, error)
+	// UncookMeals puts the stock used by the given meals of a menu back into their pantries and deletes their
+	// records, in a single transaction. Meals that were not cooked are skipped, and it returns fs.ErrNotExist
+	// if none of them was.
+	UncookMeals(user, menu string, meals []dbtypes.MealRef) error
+
+	// Purchases returns the purchases made from the shopping list of a menu and a pantry, oldest first.
+	Purchases(user, menu, pantry string) ([]dbtypes.Purchase, error)
+	// AddPurchase records a purchase, adds the lots bought to its pantry and unmarks the products bought in its
+	// shopping list, in a single transaction. With updatePrices, the prices paid become the prices of the products.
+	// It returns the shopping list after the purchase.
+	AddPurchase(p dbtypes.Purchase, lots []dbtypes.PantryLot, updatePrices bool) (dbtypes.ShoppingList, error)
+
 	Close() error
 }
 
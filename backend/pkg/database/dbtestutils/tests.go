@@ -1,8 +1,10 @@
 package dbtestutils
 
 import (
+	"fmt"
 	"io/fs"
 	"slices"
+	"sync"
 	"testing"
 	"time"
 
@@ -11,6 +13,7 @@ import (
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers/blank"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -163,6 +166,63 @@ func SessionsTest(t *testing.T, openDB func() database.DB) {
 	require.NoError(t, err, "Should find non-expired Session after purging the database")
 }
 
+func CalendarFeedsTest(t *testing.T, openDB func() database.DB) {
+	t.Helper()
+
+	db := openDB()
+	defer db.Close()
+
+	const user = "test-user-123"
+	const anotherUser = "another-user-456"
+
+	require.NoError(t, db.SetUser(user))
+	require.NoError(t, db.SetUser(anotherUser))
+
+	_, err := db.LookupCalendarFeed("AAAAAAAAAAAAAAAAAAAAA")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = db.LookupUserCalendarFeed(user)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	feed := dbtypes.CalendarFeed{User: user, Token: "token123"}
+	require.NoError(t, db.SetCalendarFeed(feed), "Could not set calendar feed")
+
+	f, err := db.LookupCalendarFeed(feed.Token)
+	require.NoError(t, err, "Could not find calendar feed just created")
+	require.Equal(t, feed, f, "Calendar feed does not match the one just created")
+
+	f, err = db.LookupUserCalendarFeed(user)
+	require.NoError(t, err, "Could not find calendar feed of the user")
+	require.Equal(t, feed, f, "Calendar feed does not match the one just created")
+
+	err = db.SetCalendarFeed(dbtypes.CalendarFeed{User: anotherUser, Token: feed.Token})
+	require.ErrorIs(t, err, fs.ErrExist, "Should not reuse the token of another user")
+
+	// Setting a new token revokes the old one
+	newFeed := dbtypes.CalendarFeed{User: user, Token: "token456"}
+	require.NoError(t, db.SetCalendarFeed(newFeed), "Could not replace calendar feed")
+
+	_, err = db.LookupCalendarFeed(feed.Token)
+	require.ErrorIs(t, err, fs.ErrNotExist, "Should not find the revoked token")
+
+	t.Log("Closing DB and reopening")
+	require.NoError(t, db.Close())
+
+	db = openDB()
+	defer db.Close()
+
+	f, err = db.LookupCalendarFeed(newFeed.Token)
+	require.NoError(t, err, "Could not find calendar feed after reopening DB")
+	require.Equal(t, newFeed, f, "Calendar feed does not match the one after reopening DB")
+
+	require.NoError(t, db.DeleteCalendarFeed(user))
+
+	_, err = db.LookupCalendarFeed(newFeed.Token)
+	require.ErrorIs(t, err, fs.ErrNotExist, "Should not find calendar feed after deleting it")
+
+	require.NoError(t, db.Close())
+}
+
 func ProductsTest(t *testing.T, openDB func() database.DB) {
 	t.Helper()
 
@@ -180,10 +240,12 @@ func ProductsTest(t *testing.T, openDB func() database.DB) {
 	}
 
 	product2 := product.Product{
-		Provider:  blank.Provider{},
-		Name:      "Product #2",
-		Price:     0.64,
-		BatchSize: 99,
+		Provider:     blank.Provider{},
+		Name:         "Product #2",
+		Price:        0.64,
+		PriceUpdated: time.Date(2024, time.March, 25, 10, 30, 0, 0, time.UTC),
+		BatchSize:    99,
+		Unit:         "kg",
 	}
 
 	id, err := db.SetProduct(product1)
@@ -215,6 +277,17 @@ func ProductsTest(t *testing.T, openDB func() database.DB) {
 	require.NoError(t, err, "Could not find empty Product just created")
 	require.Equal(t, product2, p, "Empty menu does not match the one just created")
 
+	// Saving a product without a price update time keeps the stored one
+	noTime := product2
+	noTime.PriceUpdated = time.Time{}
+
+	_, err = db.SetProduct(noTime)
+	require.NoError(t, err, "Could not override Product")
+
+	p, err = db.LookupProduct(product2.ID)
+	require.NoError(t, err, "Could not find Product just overridden")
+	require.Equal(t, product2, p, "Price update time should be kept when none is given")
+
 	t.Log("Closing DB and reopening")
 	require.NoError(t, db.Close())
 
@@ -369,12 +442,74 @@ func RecipesTest(t *testing.T, openDB func() database.DB) {
 	require.NoError(t, err, "Could not find empty Recipe after reopening DB")
 	require.Equal(t, recipe2, r, "Empty menu does not match the one after reopening DB")
 
+	recipe3 := recipe.Recipe{
+		User: user,
+		Name: "Ice",
+		Ingredients: []recipe.Ingredient{
+			{ProductID: oxygen.ID, Amount: 0.5},
+		},
+		SubRecipes: []recipe.SubRecipe{
+			{RecipeID: recipe1.ID, Amount: 3.0},
+		},
+	}
+
+	recipe3.ID, err = db.SetRecipe(recipe3)
+	require.NoError(t, err, "Could not set Recipe with sub-recipes")
+
+	r, err = db.LookupRecipe(user, recipe3.ID)
+	require.NoError(t, err, "Could not find Recipe with sub-recipes")
+	require.Equal(t, recipe3, r, "Recipe with sub-recipes does not match the one just created")
+
+	// Several recipes can be saved at once
+	recipe2.Ingredients = []recipe.Ingredient{{ProductID: hydrogen.ID, Amount: 4.0}}
+	recipe3.Ingredients = []recipe.Ingredient{{ProductID: hydrogen.ID, Amount: 1.0}}
+
+	ids, err := db.SetRecipes([]recipe.Recipe{recipe2, recipe3})
+	require.NoError(t, err, "Could not set Recipes")
+	require.Equal(t, []recipe.ID{recipe2.ID, recipe3.ID}, ids, "IDs should be the same after overriding")
+
+	for _, want := range []recipe.Recipe{recipe2, recipe3} {
+		r, err = db.LookupRecipe(user, want.ID)
+		require.NoError(t, err, "Could not find Recipe just overridden")
+		require.Equal(t, want, r, "Recipe does not match the one just overridden")
+	}
+
+	// Forks are saved with their sub-recipes pointing to the copies
+	require.NoError(t, db.SetUser(anotherUser))
+
+	waterCopy := recipe.Recipe{User: anotherUser, Name: recipe1.Name, Ingredients: recipe1.Ingredients, ForkedFrom: recipe1.ID}
+	iceCopy := recipe.Recipe{User: anotherUser, Name: recipe3.Name, Ingredients: recipe3.Ingredients, SubRecipes: recipe3.SubRecipes, ForkedFrom: recipe3.ID}
+
+	ids, err = db.ForkRecipes([]recipe.Recipe{waterCopy, iceCopy})
+	require.NoError(t, err, "Could not fork Recipes")
+	require.Len(t, ids, 2)
+	require.NotContains(t, ids, recipe1.ID, "Copies should get new IDs")
+	require.NotContains(t, ids, recipe3.ID, "Copies should get new IDs")
+
+	waterCopy.ID = ids[0]
+	iceCopy.ID = ids[1]
+	iceCopy.SubRecipes = []recipe.SubRecipe{{RecipeID: waterCopy.ID, Amount: 3.0}}
+
+	recipes, err = db.Recipes(anotherUser)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []recipe.Recipe{waterCopy, iceCopy}, recipes, "Forks do not match the ones just created")
+
+	r, err = db.LookupRecipe(user, recipe3.ID)
+	require.NoError(t, err)
+	require.Equal(t, recipe3, r, "Forking should not change the original Recipe")
+
 	err = db.DeleteRecipe(user, recipe1.ID)
 	require.NoError(t, err)
 
 	_, err = db.LookupRecipe(user, recipe1.ID)
 	require.ErrorIs(t, err, fs.ErrNotExist)
 
+	// Deleting a recipe removes it from the recipes that use it
+	recipe3.SubRecipes = nil
+	r, err = db.LookupRecipe(user, recipe3.ID)
+	require.NoError(t, err, "Could not find Recipe whose sub-recipe was deleted")
+	require.Equal(t, recipe3, r, "Recipe whose sub-recipe was deleted should have no sub-recipes")
+
 	err = db.DeleteRecipe(anotherUser, recipe2.ID)
 	require.NoError(t, err)
 	_, err = db.LookupRecipe(user, recipe2.ID)
@@ -383,6 +518,290 @@ func RecipesTest(t *testing.T, openDB func() database.DB) {
 	require.NoError(t, db.Close())
 }
 
+// DeleteUserTest checks that deleting a user leaves the data of the other users untouched.
+func DeleteUserTest(t *testing.T, openDB func() database.DB) {
+	t.Helper()
+
+	db := openDB()
+	defer db.Close()
+
+	const deleted = "deleted-user-123"
+	const kept = "kept-user-456"
+
+	recipes := make(map[string]recipe.ID)
+	for _, u := range []string{deleted, kept} {
+		require.NoError(t, db.SetUser(u))
+
+		id, err := db.SetRecipe(recipe.Recipe{User: u, Name: "Recipe"})
+		require.NoError(t, err)
+		recipes[u] = id
+
+		require.NoError(t, db.SetPantry(dbtypes.Pantry{User: u, Name: "Pantry"}))
+		require.NoError(t, db.SetMenu(dbtypes.Menu{User: u, Name: "Menu"}))
+		require.NoError(t, db.SetSession(dbtypes.Session{ID: "session-" + u, User: u, NotAfter: time.Now().Add(time.Hour)}))
+	}
+
+	require.NoError(t, db.DeleteUser(deleted))
+
+	t.Log("Closing DB and reopening")
+	require.NoError(t, db.Close())
+
+	db = openDB()
+	defer db.Close()
+
+	_, err := db.LookupRecipe(deleted, recipes[deleted])
+	require.ErrorIs(t, err, fs.ErrNotExist, "Recipe of the deleted user should not exist")
+
+	_, err = db.LookupRecipe(kept, recipes[kept])
+	require.NoError(t, err, "Recipe of another user should not be deleted")
+
+	_, err = db.LookupPantry(kept, "Pantry")
+	require.NoError(t, err, "Pantry of another user should not be deleted")
+
+	_, err = db.LookupMenu(kept, "Menu")
+	require.NoError(t, err, "Menu of another user should not be deleted")
+
+	_, err = db.LookupSession("session-" + kept)
+	require.NoError(t, err, "Session of another user should not be deleted")
+
+	_, err = db.LookupSession("session-" + deleted)
+	require.ErrorIs(t, err, fs.ErrNotExist, "Session of the deleted user should not exist")
+}
+
+func RecipeSharingTest(t *testing.T, openDB func() database.DB) {
+	t.Helper()
+
+	db := openDB()
+	defer db.Close()
+
+	const owner = "test-user-123"
+	const friend = "friend-user-456"
+	const stranger = "stranger-user-789"
+
+	for _, u := range []string{owner, friend, stranger} {
+		require.NoError(t, db.SetUser(u))
+	}
+
+	salt := product.Product{
+		Provider:  blank.Provider{},
+		Name:      "Salt",
+		ID:        11,
+		BatchSize: 1,
+	}
+
+	_, err := db.SetProduct(salt)
+	require.NoError(t, err)
+
+	brine := recipe.Recipe{
+		User:        owner,
+		Name:        "Brine",
+		Ingredients: []recipe.Ingredient{{ProductID: salt.ID, Amount: 0.1}},
+	}
+
+	brine.ID, err = db.SetRecipe(brine)
+	require.NoError(t, err)
+
+	// Not shared yet
+	sh, err := db.LookupRecipeSharing(owner, brine.ID)
+	require.NoError(t, err, "Could not lookup sharing of a recipe that is not shared")
+	require.Equal(t, dbtypes.RecipeSharing{Recipe: brine.ID, Owner: owner}, sh)
+
+	_, err = db.LookupRecipeSharing(friend, brine.ID)
+	require.ErrorIs(t, err, fs.ErrNotExist, "Only the owner can lookup the sharing of a recipe")
+
+	_, err = db.LookupSharedRecipe(friend, brine.ID)
+	require.ErrorIs(t, err, fs.ErrNotExist, "Recipe should not be visible before sharing")
+
+	r, err := db.LookupSharedRecipe(owner, brine.ID)
+	require.NoError(t, err, "Owner should always see their recipe")
+	require.Equal(t, brine, r)
+
+	err = db.SetRecipeSharing(dbtypes.RecipeSharing{Recipe: brine.ID, Owner: friend, Public: true})
+	require.Error(t, err, "Only the owner can share a recipe")
+
+	// Share with a friend
+	sh = dbtypes.RecipeSharing{Recipe: brine.ID, Owner: owner, Users: []string{friend}}
+	require.NoError(t, db.SetRecipeSharing(sh))
+
+	got, err := db.LookupRecipeSharing(owner, brine.ID)
+	require.NoError(t, err)
+	require.Equal(t, sh, got)
+
+	r, err = db.LookupSharedRecipe(friend, brine.ID)
+	require.NoError(t, err, "Recipe should be visible to the user it is shared with")
+	require.Equal(t, brine, r)
+
+	_, err = db.LookupSharedRecipe(stranger, brine.ID)
+	require.ErrorIs(t, err, fs.ErrNotExist, "Recipe should not be visible to other users")
+
+	recs, err := db.SharedRecipes(friend)
+	require.NoError(t, err)
+	require.Equal(t, []recipe.Recipe{brine}, recs)
+
+	recs, err = db.SharedRecipes(owner)
+	require.NoError(t, err)
+	require.Empty(t, recs, "Own recipes are not listed as shared")
+
+	// Deleting a user stops sharing with them
+	require.NoError(t, db.DeleteUser(friend))
+
+	got, err = db.LookupRecipeSharing(owner, brine.ID)
+	require.NoError(t, err)
+	require.Equal(t, dbtypes.RecipeSharing{Recipe: brine.ID, Owner: owner}, got, "Deleted users should not be left in the sharing of a recipe")
+
+	// Publish it
+	sh = dbtypes.RecipeSharing{Recipe: brine.ID, Owner: owner, Public: true}
+	require.NoError(t, db.SetRecipeSharing(sh))
+
+	recs, err = db.SharedRecipes(stranger)
+	require.NoError(t, err)
+	require.Equal(t, []recipe.Recipe{brine}, recs, "Public recipes are visible to everyone")
+
+	// Fork it
+	fork := brine
+	fork.ID = 0
+	fork.User = stranger
+	fork.ForkedFrom = brine.ID
+
+	fork.ID, err = db.SetRecipe(fork)
+	require.NoError(t, err)
+
+	t.Log("Closing DB and reopening")
+	require.NoError(t, db.Close())
+
+	db = openDB()
+	defer db.Close()
+
+	got, err = db.LookupRecipeSharing(owner, brine.ID)
+	require.NoError(t, err)
+	require.Equal(t, sh, got, "Sharing should persist after reopening")
+
+	r, err = db.LookupRecipe(stranger, fork.ID)
+	require.NoError(t, err)
+	require.Equal(t, fork, r, "Fork should keep a link to the original")
+
+	recs, err = db.Recipes(stranger)
+	require.NoError(t, err)
+	require.Equal(t, []recipe.Recipe{fork}, recs)
+
+	// Deleting the original keeps the fork
+	require.NoError(t, db.DeleteRecipe(owner, brine.ID))
+
+	recs, err = db.SharedRecipes(stranger)
+	require.NoError(t, err)
+	require.Empty(t, recs, "Deleted recipes should not be shared")
+
+	r, err = db.LookupRecipe(stranger, fork.ID)
+	require.NoError(t, err)
+	require.Equal(t, fork, r, "Fork should outlive the original")
+
+	require.NoError(t, db.Close())
+}
+
+func RecipeRevisionsTest(t *testing.T, openDB func() database.DB) {
+	t.Helper()
+
+	db := openDB()
+	defer db.Close()
+
+	const user = "test-user-123"
+	const anotherUser = "another-user-456"
+
+	require.NoError(t, db.SetUser(user))
+	require.NoError(t, db.SetUser(anotherUser))
+
+	flour := product.Product{
+		Provider:  blank.Provider{},
+		Name:      "Flour",
+		ID:        21,
+		BatchSize: 1,
+	}
+
+	water := product.Product{
+		Provider:  blank.Provider{},
+		Name:      "Water",
+		ID:        22,
+		BatchSize: 1,
+	}
+
+	for _, p := range []product.Product{flour, water} {
+		_, err := db.SetProduct(p)
+		require.NoError(t, err)
+	}
+
+	dough := recipe.Recipe{
+		User:        user,
+		Name:        "Dough",
+		Ingredients: []recipe.Ingredient{{ProductID: flour.ID, Amount: 0.5}},
+	}
+
+	var err error
+	dough.ID, err = db.SetRecipe(dough)
+	require.NoError(t, err)
+
+	first := dough
+	first.Ingredients = slices.Clone(dough.Ingredients)
+
+	dough.Name = "Bread dough"
+	dough.Ingredients = []recipe.Ingredient{
+		{ProductID: flour.ID, Amount: 0.4},
+		{ProductID: water.ID, Amount: 0.3},
+	}
+
+	_, err = db.SetRecipe(dough)
+	require.NoError(t, err)
+
+	check := func() {
+		t.Helper()
+
+		revs, err := db.RecipeRevisions(user, dough.ID)
+		require.NoError(t, err)
+		require.Len(t, revs, 2, "Every save should create a revision")
+
+		require.Equal(t, 1, revs[0].Revision)
+		require.Equal(t, 2, revs[1].Revision)
+		require.Equal(t, user, revs[0].Author)
+		require.Equal(t, first, revs[0].Recipe, "First revision should not be modified by later saves")
+		require.Equal(t, dough, revs[1].Recipe)
+		require.False(t, revs[1].Timestamp.Before(revs[0].Timestamp), "Revisions should be in chronological order")
+		require.WithinDuration(t, time.Now(), revs[1].Timestamp, time.Minute)
+
+		rev, err := db.LookupRecipeRevision(user, dough.ID, 1)
+		require.NoError(t, err)
+		require.Equal(t, revs[0], rev)
+
+		_, err = db.LookupRecipeRevision(user, dough.ID, 3)
+		require.ErrorIs(t, err, fs.ErrNotExist)
+
+		_, err = db.RecipeRevisions(anotherUser, dough.ID)
+		require.ErrorIs(t, err, fs.ErrNotExist, "Should not find revisions from another user")
+
+		_, err = db.LookupRecipeRevision(anotherUser, dough.ID, 1)
+		require.ErrorIs(t, err, fs.ErrNotExist, "Should not find revisions from another user")
+
+		r, err := db.LookupRecipe(user, dough.ID)
+		require.NoError(t, err)
+		require.Equal(t, dough, r, "The recipe should be the latest revision")
+	}
+
+	check()
+
+	t.Log("Closing DB and reopening")
+	require.NoError(t, db.Close())
+
+	db = openDB()
+	defer db.Close()
+
+	check()
+
+	require.NoError(t, db.DeleteRecipe(user, dough.ID))
+
+	_, err = db.RecipeRevisions(user, dough.ID)
+	require.ErrorIs(t, err, fs.ErrNotExist, "Revisions should be deleted with the recipe")
+
+	require.NoError(t, db.Close())
+}
+
 func MenuTest(t *testing.T, openDB func() database.DB) {
 	t.Helper()
 
@@ -449,6 +868,47 @@ func MenuTest(t *testing.T, openDB func() database.DB) {
 	require.NoError(t, err, "Could not find Menu just overridden")
 	require.Equal(t, myMenu, m, "Menu does not match the one just overridden")
 
+	// Test dates
+	startDate, err := dbtypes.ParseDate("2024-03-25")
+	require.NoError(t, err)
+	myMenu.StartDate = &startDate
+
+	require.NoError(t, db.SetMenu(myMenu), "Could not set Menu date")
+	m, err = db.LookupMenu(user, myMenu.Name)
+	require.NoError(t, err, "Could not find Menu just dated")
+	require.Equal(t, myMenu, m, "Menu does not match the one just dated")
+
+	// Test attendance
+	attendees := 3
+	myMenu.Household = 4
+	myMenu.Days[0].Meals[0].Attendees = &attendees
+
+	require.NoError(t, db.SetMenu(myMenu), "Could not set Menu attendance")
+	m, err = db.LookupMenu(user, myMenu.Name)
+	require.NoError(t, err, "Could not find Menu just given attendance")
+	require.Equal(t, myMenu, m, "Menu does not match the one just given attendance")
+
+	// Test leftovers
+	myMenu.Days = append(myMenu.Days, dbtypes.Day{
+		Name: "Terça-Feira",
+		Meals: []dbtypes.Meal{
+			{
+				Name:   "Almoço",
+				Dishes: []dbtypes.Dish{{ID: recipeID, Amount: 4}},
+			},
+			{
+				Name:   "Jantar",
+				Dishes: []dbtypes.Dish{{ID: recipeID, Amount: 2, LeftoverFrom: &dbtypes.MealRef{Day: 1, Meal: 0}}},
+			},
+		},
+	})
+	require.NoError(t, myMenu.Validate())
+
+	require.NoError(t, db.SetMenu(myMenu), "Could not set Menu leftovers")
+	m, err = db.LookupMenu(user, myMenu.Name)
+	require.NoError(t, err, "Could not find Menu just given leftovers")
+	require.Equal(t, myMenu, m, "Menu does not match the one just given leftovers")
+
 	emptyMenu := dbtypes.Menu{
 		User: user,
 		Name: "Empty Menu",
@@ -482,6 +942,22 @@ func MenuTest(t *testing.T, openDB func() database.DB) {
 
 	require.ElementsMatch(t, []dbtypes.Menu{myMenu, emptyMenu}, menus, "Menus do not match the ones after reopening DB")
 
+	// Creating a menu never overwrites an existing one
+	clone := myMenu
+	clone.Name = "Cloned Menu"
+
+	require.NoError(t, db.CreateMenu(clone), "Could not create Menu")
+	m, err = db.LookupMenu(user, clone.Name)
+	require.NoError(t, err, "Could not find Menu just created")
+	require.Equal(t, clone, m, "Menu does not match the one just created")
+
+	err = db.CreateMenu(dbtypes.Menu{User: user, Name: emptyMenu.Name, Household: 3})
+	require.ErrorIs(t, err, fs.ErrExist, "Creating a Menu with a taken name should fail")
+
+	m, err = db.LookupMenu(user, emptyMenu.Name)
+	require.NoError(t, err, "Could not find Menu after failing to create it again")
+	require.Equal(t, emptyMenu, m, "Menu should not change after failing to create it again")
+
 	err = db.DeleteMenu(user, myMenu.Name)
 	require.NoError(t, err)
 
@@ -563,6 +1039,27 @@ func PantriesTest(t *testing.T, openDB func() database.DB) {
 	require.NoError(t, err, "Could not find Pantry just overridden")
 	require.Equal(t, pantry1, p, "Pantry does not match the one just overridden")
 
+	// Test lots
+	pantry1.Lots = []dbtypes.PantryLot{
+		{
+			ProductID:  hydrogen.ID,
+			Amount:     3,
+			Purchased:  &dbtypes.Date{Year: 2024, Month: time.March, Day: 20},
+			BestBefore: &dbtypes.Date{Year: 2024, Month: time.April, Day: 5},
+		},
+		{
+			ProductID:  hydrogen.ID,
+			Amount:     1,
+			BestBefore: &dbtypes.Date{Year: 2024, Month: time.March, Day: 30},
+		},
+	}
+	require.NoError(t, pantry1.Validate())
+
+	require.NoError(t, db.SetPantry(pantry1), "Could not set Pantry lots")
+	p, err = db.LookupPantry(user, pantry1.Name)
+	require.NoError(t, err, "Could not find Pantry just given lots")
+	require.Equal(t, pantry1, p, "Pantry does not match the one just given lots")
+
 	require.NoError(t, db.SetPantry(pantry2), "Could not set Pantry")
 	p, err = db.LookupPantry(user, pantry2.Name)
 	require.NoError(t, err, "Could not find empty Pantry just created")
@@ -647,8 +1144,9 @@ func ShoppingListsTest(t *testing.T, openDB func() database.DB) {
 	}
 
 	menu2 := dbtypes.Menu{
-		User: user,
-		Name: "Menu #99",
+		User:      user,
+		Name:      "Menu #99",
+		StartDate: &dbtypes.Date{Year: 2024, Month: time.April, Day: 1},
 	}
 
 	err = db.SetMenu(menu1)
@@ -755,5 +1253,716 @@ func ShoppingListsTest(t *testing.T, openDB func() database.DB) {
 	_, err = db.LookupShoppingList(user, list1.Menu, list1.Pantry)
 	require.ErrorIs(t, err, fs.ErrNotExist)
 
+	// Renaming a menu keeps its shopping lists
+	require.NoError(t, db.RenameMenu(user, menu2.Name, "Menu #2"), "Could not rename Menu")
+
+	_, err = db.LookupMenu(user, menu2.Name)
+	require.ErrorIs(t, err, fs.ErrNotExist, "Should not find Menu under its old name")
+
+	_, err = db.LookupShoppingList(user, menu2.Name, pantry.Name)
+	require.ErrorIs(t, err, fs.ErrNotExist, "Should not find ShoppingList under the old Menu name")
+
+	menu2.Name = "Menu #2"
+	list2.Menu = menu2.Name
+
+	m, err := db.LookupMenu(user, menu2.Name)
+	require.NoError(t, err, "Could not find Menu under its new name")
+	require.Equal(t, menu2, m, "Menu does not match the one just renamed")
+
+	p, err = db.LookupShoppingList(user, list2.Menu, list2.Pantry)
+	require.NoError(t, err, "Could not find ShoppingList under the new Menu name")
+	require.Equal(t, list2, p, "ShoppingList does not match the one of the renamed Menu")
+
+	err = db.RenameMenu(user, menu2.Name, menu1.Name)
+	require.ErrorIs(t, err, fs.ErrExist, "Should not rename Menu to an existing name")
+
+	err = db.RenameMenu(user, "FAKE MENU", "Menu #3")
+	require.ErrorIs(t, err, fs.ErrNotExist, "Should not rename a Menu that does not exist")
+
+	// Deleting a menu deletes its shopping lists
+	require.NoError(t, db.DeleteMenu(user, menu2.Name))
+
+	_, err = db.LookupShoppingList(user, list2.Menu, list2.Pantry)
+	require.ErrorIs(t, err, fs.ErrNotExist, "Should not find ShoppingList of a deleted Menu")
+
 	require.NoError(t, db.Close())
 }
+
+func CookedMealsTest(t *testing.T, openDB func() database.DB) {
+	t.Helper()
+
+	db := openDB()
+	defer db.Close()
+
+	const user = "test-user-123"
+
+	require.NoError(t, db.SetUser(user))
+
+	id, err := db.SetProduct(product.Product{
+		Provider:  blank.Provider{},
+		Name:      "Hydrogen",
+		BatchSize: 1,
+	})
+	require.NoError(t, err)
+
+	menu := dbtypes.Menu{
+		User: user,
+		Name: "Menu #1",
+		Days: []dbtypes.Day{{
+			Name:  "Monday",
+			Meals: []dbtypes.Meal{{Name: "Lunch"}, {Name: "Dinner"}},
+		}},
+	}
+	require.NoError(t, db.SetMenu(menu))
+
+	pantry := dbtypes.Pantry{
+		User:     user,
+		Name:     "Pantry #1",
+		Contents: []recipe.Ingredient{{ProductID: id, Amount: 5}},
+		Lots: []dbtypes.PantryLot{
+			{ProductID: id, Amount: 2, BestBefore: &dbtypes.Date{Year: 2024, Month: time.April, Day: 3}},
+		},
+	}
+
+	cooked, err := db.CookedMeals(user, menu.Name)
+	require.NoError(t, err)
+	require.Empty(t, cooked)
+
+	when := time.Date(2024, time.April, 1, 12, 0, 0, 0, time.UTC)
+	dinner := []dbtypes.MealToCook{{Meal: dbtypes.MealRef{Day: 0, Meal: 1}, Need: []recipe.Ingredient{{ProductID: id, Amount: 3}}}}
+
+	_, err = db.CookMeals(user, menu.Name, pantry.Name, when, dinner)
+	require.ErrorIs(t, err, fs.ErrNotExist, "Should not cook from a pantry that does not exist")
+
+	require.NoError(t, db.SetPantry(pantry))
+
+	meal := dbtypes.CookedMeal{
+		User:   user,
+		Menu:   menu.Name,
+		Meal:   dbtypes.MealRef{Day: 0, Meal: 1},
+		Pantry: pantry.Name,
+		Cooked: when,
+		Used:   []dbtypes.PantryLot{{ProductID: id, Amount: 2, BestBefore: &dbtypes.Date{Year: 2024, Month: time.April, Day: 3}}, {ProductID: id, Amount: 1}},
+	}
+
+	records, err := db.CookMeals(user, menu.Name, pantry.Name, when, dinner)
+	require.NoError(t, err)
+	require.Equal(t, []dbtypes.CookedMeal{meal}, records, "Cooked meal should use the lots that expire first")
+
+	got, err := db.LookupPantry(user, pantry.Name)
+	require.NoError(t, err)
+	require.Equal(t, []recipe.Ingredient{{ProductID: id, Amount: 2}}, got.Contents, "Pantry was not updated with the meal")
+	require.Empty(t, got.Lots, "Pantry lots were not updated with the meal")
+
+	_, err = db.CookMeals(user, menu.Name, pantry.Name, when, dinner)
+	require.ErrorIs(t, err, fs.ErrExist, "Should not cook the same meal twice")
+
+	got, err = db.LookupPantry(user, pantry.Name)
+	require.NoError(t, err)
+	require.Equal(t, []recipe.Ingredient{{ProductID: id, Amount: 2}}, got.Contents, "Cooking a meal twice should not take its stock twice")
+
+	cooked, err = db.CookedMeals(user, menu.Name)
+	require.NoError(t, err)
+	require.Equal(t, []dbtypes.CookedMeal{meal}, cooked, "Cooked meal does not match the one just created")
+
+	// Saving the menu again must not forget what was cooked
+	require.NoError(t, db.SetMenu(menu))
+
+	t.Log("Closing DB and reopening")
+	require.NoError(t, db.Close())
+
+	db = openDB()
+	defer db.Close()
+
+	cooked, err = db.CookedMeals(user, menu.Name)
+	require.NoError(t, err)
+	require.Equal(t, []dbtypes.CookedMeal{meal}, cooked, "Cooked meal does not match after reopening")
+
+	require.NoError(t, db.RenameMenu(user, menu.Name, "Menu #2"))
+	menu.Name = "Menu #2"
+	meal.Menu = menu.Name
+
+	cooked, err = db.CookedMeals(user, menu.Name)
+	require.NoError(t, err)
+	require.Equal(t, []dbtypes.CookedMeal{meal}, cooked, "Cooked meal did not follow the renamed menu")
+
+	// Changes made to the pantry after cooking must be kept when the stock is put back
+	_, err = db.UpdatePantry(user, pantry.Name, []dbtypes.PantryOp{{Op: dbtypes.PantryOpAdd, ProductID: id, Amount: 1}})
+	require.NoError(t, err)
+
+	err = db.UncookMeals(user, menu.Name, []dbtypes.MealRef{{Day: 0, Meal: 0}})
+	require.ErrorIs(t, err, fs.ErrNotExist, "Should not uncook a meal that was not cooked")
+
+	require.NoError(t, db.UncookMeals(user, menu.Name, []dbtypes.MealRef{{Day: 0, Meal: 0}, meal.Meal}))
+
+	got, err = db.LookupPantry(user, pantry.Name)
+	require.NoError(t, err)
+	require.Equal(t, []recipe.Ingredient{{ProductID: id, Amount: 6}}, got.Contents, "Pantry was not restored")
+	require.Len(t, got.Lots, 1, "Pantry lots were not restored")
+	require.InDelta(t, 2, got.Lots[0].Amount, 1e-6, "Pantry lots were not restored")
+
+	cooked, err = db.CookedMeals(user, menu.Name)
+	require.NoError(t, err)
+	require.Empty(t, cooked, "Cooked meal was not deleted")
+
+	err = db.UncookMeals(user, menu.Name, []dbtypes.MealRef{meal.Meal})
+	require.ErrorIs(t, err, fs.ErrNotExist, "Should not put the stock of a meal back twice")
+
+	// Cooked meals go away with their pantry
+	_, err = db.CookMeals(user, menu.Name, pantry.Name, when, dinner)
+	require.NoError(t, err)
+	require.NoError(t, db.DeletePantry(user, pantry.Name))
+
+	cooked, err = db.CookedMeals(user, menu.Name)
+	require.NoError(t, err)
+	require.Empty(t, cooked, "Cooked meal was not deleted with its pantry")
+}
+
+func PurchasesTest(t *testing.T, openDB func() database.DB) {
+	t.Helper()
+
+	db := openDB()
+	defer db.Close()
+
+	const user = "test-user-123"
+
+	require.NoError(t, db.SetUser(user))
+
+	id, err := db.SetProduct(product.Product{
+		Provider:  blank.Provider{},
+		Name:      "Hydrogen",
+		BatchSize: 4,
+	})
+	require.NoError(t, err)
+
+	menu := dbtypes.Menu{User: user, Name: "Menu #1"}
+	require.NoError(t, db.SetMenu(menu))
+
+	stocked := dbtypes.PantryLot{ProductID: id, Amount: 4, Purchased: &dbtypes.Date{Year: 2024, Month: time.March, Day: 1}}
+	pantry := dbtypes.Pantry{
+		User:     user,
+		Name:     "Pantry #1",
+		Contents: []recipe.Ingredient{{ProductID: id, Amount: 4}},
+		Lots:     []dbtypes.PantryLot{stocked},
+	}
+
+	got, err := db.Purchases(user, menu.Name, pantry.Name)
+	require.NoError(t, err)
+	require.Empty(t, got)
+
+	purchase := dbtypes.Purchase{
+		User:   user,
+		Menu:   menu.Name,
+		Pantry: pantry.Name,
+		Date:   time.Date(2024, time.April, 1, 12, 0, 0, 0, time.UTC),
+		Items:  []dbtypes.PurchaseItem{{ProductID: id, Packs: 2, Price: 1.5}},
+	}
+
+	bought := dbtypes.PantryLot{ProductID: id, Amount: 8, Purchased: &dbtypes.Date{Year: 2024, Month: time.April, Day: 1}}
+
+	_, err = db.AddPurchase(purchase, []dbtypes.PantryLot{bought}, false)
+	require.ErrorIs(t, err, fs.ErrNotExist, "Should not restock a pantry that does not exist")
+
+	require.NoError(t, db.SetPantry(pantry))
+	require.NoError(t, db.SetPantryStaples(dbtypes.PantryStaples{User: user, Pantry: pantry.Name, Staples: []dbtypes.Staple{{ProductID: id, Minimum: 2}}}))
+	require.NoError(t, db.SetShoppingList(dbtypes.ShoppingList{User: user, Menu: menu.Name, Pantry: pantry.Name, Contents: []product.ID{id}}))
+
+	list, err := db.AddPurchase(purchase, []dbtypes.PantryLot{bought}, false)
+	require.NoError(t, err)
+	require.Empty(t, list.Contents, "The product bought should no longer be marked done")
+
+	p, err := db.LookupPantry(user, pantry.Name)
+	require.NoError(t, err)
+	require.Equal(t, []recipe.Ingredient{{ProductID: id, Amount: 12}}, p.Contents, "Pantry was not restocked")
+	require.ElementsMatch(t, []dbtypes.PantryLot{stocked, bought}, p.Lots, "Pantry lots were not restocked")
+
+	_, err = db.LookupShoppingList(user, menu.Name, pantry.Name)
+	require.ErrorIs(t, err, fs.ErrNotExist, "Shopping list was not cleared")
+
+	prod, err := db.LookupProduct(id)
+	require.NoError(t, err)
+	require.Zero(t, prod.Price, "Prices should not be updated unless requested")
+
+	// The second purchase updates the prices, which must not affect anything that references the product
+	second := purchase
+	second.Date = purchase.Date.Add(24 * time.Hour)
+	second.Items = []dbtypes.PurchaseItem{{ProductID: id, Packs: 1, Price: 2}}
+	_, err = db.AddPurchase(second, []dbtypes.PantryLot{{ProductID: id, Amount: 4, Purchased: &dbtypes.Date{Year: 2024, Month: time.April, Day: 2}}}, true)
+	require.NoError(t, err)
+
+	prod, err = db.LookupProduct(id)
+	require.NoError(t, err)
+	require.InDelta(t, 2, prod.Price, 1e-6, "Price was not updated")
+	require.Equal(t, second.Date, prod.PriceUpdated.UTC(), "Price update date was not stored")
+
+	p, err = db.LookupPantry(user, pantry.Name)
+	require.NoError(t, err)
+	require.Equal(t, []recipe.Ingredient{{ProductID: id, Amount: 16}}, p.Contents, "Pantry contents were lost when updating prices")
+	require.Len(t, p.Lots, 3, "Pantry lots were lost when updating prices")
+
+	staples, err := db.PantryStaples(user, pantry.Name)
+	require.NoError(t, err)
+	require.Len(t, staples.Staples, 1, "Staples were lost when updating prices")
+
+	got, err = db.Purchases(user, menu.Name, pantry.Name)
+	require.NoError(t, err)
+	require.Equal(t, []dbtypes.Purchase{purchase, second}, got, "Purchases were lost when updating prices")
+
+	t.Log("Closing DB and reopening")
+	require.NoError(t, db.Close())
+
+	db = openDB()
+	defer db.Close()
+
+	got, err = db.Purchases(user, menu.Name, pantry.Name)
+	require.NoError(t, err)
+	require.Equal(t, []dbtypes.Purchase{purchase, second}, got, "Purchases do not match the ones just created")
+	require.InDelta(t, 3, got[0].Spent(), 1e-6)
+
+	got, err = db.Purchases(user, menu.Name, "Pantry #2")
+	require.NoError(t, err)
+	require.Empty(t, got, "Should not find purchases for another pantry")
+
+	require.NoError(t, db.RenameMenu(user, menu.Name, "Menu #2"))
+
+	got, err = db.Purchases(user, "Menu #2", pantry.Name)
+	require.NoError(t, err)
+	require.Len(t, got, 2, "Purchases did not follow the renamed menu")
+}
+
+func PantryLedgerTest(t *testing.T, openDB func() database.DB) {
+	t.Helper()
+
+	db := openDB()
+	defer db.Close()
+
+	const user = "test-user-123"
+
+	require.NoError(t, db.SetUser(user))
+
+	flour, err := db.SetProduct(product.Product{
+		Provider:  blank.Provider{},
+		Name:      "Flour",
+		BatchSize: 1,
+	})
+	require.NoError(t, err)
+
+	water, err := db.SetProduct(product.Product{
+		Provider:  blank.Provider{},
+		Name:      "Water",
+		BatchSize: 1,
+	})
+	require.NoError(t, err)
+
+	_, err = db.PantryLedger(user, "Pantry #1")
+	require.ErrorIs(t, err, fs.ErrNotExist, "Should not find the ledger of a pantry that does not exist")
+
+	pantry := dbtypes.Pantry{
+		User:     user,
+		Name:     "Pantry #1",
+		Contents: []recipe.Ingredient{{ProductID: flour, Amount: 2}},
+	}
+	require.NoError(t, db.SetPantry(pantry))
+	require.NoError(t, db.SetPantry(pantry), "Saving an unchanged pantry should not fail")
+
+	pantry.Contents = []recipe.Ingredient{{ProductID: flour, Amount: 1.5}, {ProductID: water, Amount: 3}}
+	require.NoError(t, db.SetPantry(pantry))
+
+	menu := dbtypes.Menu{User: user, Name: "Menu #1"}
+	require.NoError(t, db.SetMenu(menu))
+
+	pantry.Contents = []recipe.Ingredient{{ProductID: flour, Amount: 1}, {ProductID: water, Amount: 3}}
+	_, err = db.CookMeals(user, menu.Name, pantry.Name, time.Date(2024, time.April, 1, 12, 0, 0, 0, time.UTC), []dbtypes.MealToCook{{
+		Need: []recipe.Ingredient{{ProductID: flour, Amount: 0.5}},
+	}})
+	require.NoError(t, err)
+
+	check := func(t *testing.T, want []dbtypes.PantryReason) []dbtypes.PantryChange {
+		t.Helper()
+
+		ledger, err := db.PantryLedger(user, pantry.Name)
+		require.NoError(t, err)
+		require.Len(t, ledger, len(want), "Every change, and only changes, should create an entry")
+
+		for i, c := range ledger {
+			require.Equal(t, i+1, c.Entry, "Entries should be numbered in order")
+			require.Equal(t, want[i], c.Reason, "Entry %d has the wrong reason", c.Entry)
+			require.Equal(t, user, c.Author)
+			require.WithinDuration(t, time.Now(), c.Timestamp, time.Minute)
+		}
+
+		return ledger
+	}
+
+	ledger := check(t, []dbtypes.PantryReason{dbtypes.PantryManual, dbtypes.PantryManual, dbtypes.PantryCooked})
+	require.Equal(t, []recipe.Ingredient{{ProductID: flour, Amount: 2}}, ledger[0].Deltas)
+	require.ElementsMatch(t, []recipe.Ingredient{{ProductID: flour, Amount: -0.5}, {ProductID: water, Amount: 3}}, ledger[1].Deltas)
+	require.Equal(t, []recipe.Ingredient{{ProductID: flour, Amount: -0.5}}, ledger[2].Deltas)
+	require.ElementsMatch(t, []recipe.Ingredient{{ProductID: flour, Amount: 2}}, ledger[0].Snapshot.Contents)
+
+	t.Log("Closing DB and reopening")
+	require.NoError(t, db.Close())
+
+	db = openDB()
+	defer db.Close()
+
+	check(t, []dbtypes.PantryReason{dbtypes.PantryManual, dbtypes.PantryManual, dbtypes.PantryCooked})
+
+	err = db.RollbackPantry(user, pantry.Name, 4)
+	require.ErrorIs(t, err, fs.ErrNotExist, "Should not roll back to an entry that does not exist")
+
+	// Rolling back past a cooked meal would put its stock back twice once it is uncooked
+	err = db.RollbackPantry(user, pantry.Name, 1)
+	require.ErrorIs(t, err, fs.ErrExist, "Should not roll back past a meal that is still cooked")
+
+	p, err := db.LookupPantry(user, pantry.Name)
+	require.NoError(t, err)
+	require.ElementsMatch(t, pantry.Contents, p.Contents, "Pantry should not change after a refused rollback")
+
+	require.NoError(t, db.UncookMeals(user, menu.Name, []dbtypes.MealRef{{}}))
+	require.NoError(t, db.RollbackPantry(user, pantry.Name, 1))
+
+	p, err = db.LookupPantry(user, pantry.Name)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []recipe.Ingredient{{ProductID: flour, Amount: 2}}, p.Contents, "Pantry was not rolled back")
+
+	ledger = check(t, []dbtypes.PantryReason{
+		dbtypes.PantryManual, dbtypes.PantryManual, dbtypes.PantryCooked, dbtypes.PantryUncooked, dbtypes.PantryRollback,
+	})
+	require.ElementsMatch(t, []recipe.Ingredient{{ProductID: flour, Amount: 0.5}, {ProductID: water, Amount: -3}}, ledger[4].Deltas)
+
+	require.NoError(t, db.DeletePantry(user, pantry.Name))
+	require.NoError(t, db.SetPantry(dbtypes.Pantry{User: user, Name: pantry.Name}))
+
+	ledger, err = db.PantryLedger(user, pantry.Name)
+	require.NoError(t, err)
+	require.Empty(t, ledger, "The ledger should be deleted with the pantry")
+}
+
+func PantryUpdatesTest(t *testing.T, openDB func() database.DB) {
+	t.Helper()
+
+	db := openDB()
+	defer db.Close()
+
+	const user = "test-user-123"
+
+	require.NoError(t, db.SetUser(user))
+
+	apples, err := db.SetProduct(product.Product{
+		Provider:  blank.Provider{},
+		Name:      "Apples",
+		BatchSize: 6,
+	})
+	require.NoError(t, err)
+
+	pears, err := db.SetProduct(product.Product{
+		Provider:  blank.Provider{},
+		Name:      "Pears",
+		BatchSize: 4,
+	})
+	require.NoError(t, err)
+
+	_, err = db.UpdatePantry(user, "Pantry #1", []dbtypes.PantryOp{{ProductID: apples, Op: dbtypes.PantryOpAdd, Amount: 1}})
+	require.ErrorIs(t, err, fs.ErrNotExist, "Should not update a pantry that does not exist")
+
+	oldLot := dbtypes.PantryLot{ProductID: apples, Amount: 2, BestBefore: &dbtypes.Date{Year: 2024, Month: time.April, Day: 3}}
+	newLot := dbtypes.PantryLot{ProductID: apples, Amount: 3, BestBefore: &dbtypes.Date{Year: 2024, Month: time.April, Day: 10}}
+
+	require.NoError(t, db.SetPantry(dbtypes.Pantry{
+		User:     user,
+		Name:     "Pantry #1",
+		Contents: []recipe.Ingredient{{ProductID: apples, Amount: 6}},
+		Lots:     []dbtypes.PantryLot{oldLot, newLot},
+	}))
+
+	_, err = db.UpdatePantry(user, "Pantry #1", []dbtypes.PantryOp{{ProductID: apples, Op: "multiply", Amount: 2}})
+	require.Error(t, err, "Should not apply unknown operations")
+
+	p, err := db.UpdatePantry(user, "Pantry #1", []dbtypes.PantryOp{
+		{ProductID: apples, Op: dbtypes.PantryOpSubtract, Amount: 3},
+		{ProductID: pears, Op: dbtypes.PantryOpSet, Amount: 4},
+	})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []recipe.Ingredient{{ProductID: apples, Amount: 3}, {ProductID: pears, Amount: 4}}, p.Contents)
+
+	newLot.Amount = 2
+	require.Equal(t, []dbtypes.PantryLot{newLot}, p.Lots, "Subtracted stock should come from the lot that expires first")
+
+	got, err := db.LookupPantry(user, "Pantry #1")
+	require.NoError(t, err)
+	require.ElementsMatch(t, p.Contents, got.Contents, "Returned pantry does not match the stored one")
+
+	const workers = 8
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := db.UpdatePantry(user, "Pantry #1", []dbtypes.PantryOp{{ProductID: pears, Op: dbtypes.PantryOpAdd, Amount: 1}})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	t.Log("Closing DB and reopening")
+	require.NoError(t, db.Close())
+
+	db = openDB()
+	defer db.Close()
+
+	got, err = db.LookupPantry(user, "Pantry #1")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []recipe.Ingredient{{ProductID: apples, Amount: 3}, {ProductID: pears, Amount: 4 + workers}}, got.Contents,
+		"Concurrent updates should not overwrite each other")
+
+	p, err = db.UpdatePantry(user, "Pantry #1", []dbtypes.PantryOp{{ProductID: apples, Op: dbtypes.PantryOpSet, Amount: 0}})
+	require.NoError(t, err)
+	require.Equal(t, []recipe.Ingredient{{ProductID: pears, Amount: 4 + workers}}, p.Contents, "Setting a product to zero should remove it")
+	require.Empty(t, p.Lots, "Setting a product to zero should remove its lots")
+}
+
+func PantryStaplesTest(t *testing.T, openDB func() database.DB) {
+	t.Helper()
+
+	db := openDB()
+	defer db.Close()
+
+	const user = "test-user-123"
+
+	require.NoError(t, db.SetUser(user))
+
+	oil, err := db.SetProduct(product.Product{
+		Provider:  blank.Provider{},
+		Name:      "Olive oil",
+		BatchSize: 1,
+	})
+	require.NoError(t, err)
+
+	salt, err := db.SetProduct(product.Product{
+		Provider:  blank.Provider{},
+		Name:      "Salt",
+		BatchSize: 1,
+	})
+	require.NoError(t, err)
+
+	staples := dbtypes.PantryStaples{
+		User:    user,
+		Pantry:  "Pantry #1",
+		Staples: []dbtypes.Staple{{ProductID: oil, Minimum: 1}, {ProductID: salt, Minimum: 0.5}},
+	}
+
+	_, err = db.PantryStaples(user, staples.Pantry)
+	require.ErrorIs(t, err, fs.ErrNotExist, "Should not find the staples of a pantry that does not exist")
+
+	err = db.SetPantryStaples(staples)
+	require.ErrorIs(t, err, fs.ErrNotExist, "Should not set the staples of a pantry that does not exist")
+
+	require.NoError(t, db.SetPantry(dbtypes.Pantry{User: user, Name: staples.Pantry}))
+
+	got, err := db.PantryStaples(user, staples.Pantry)
+	require.NoError(t, err)
+	require.Empty(t, got.Staples, "A new pantry should have no staples")
+
+	invalid := staples
+	invalid.Staples = []dbtypes.Staple{{ProductID: oil, Minimum: 1}, {ProductID: oil, Minimum: 2}}
+	require.Error(t, db.SetPantryStaples(invalid), "Should not set the same product twice")
+
+	require.NoError(t, db.SetPantryStaples(staples))
+
+	t.Log("Closing DB and reopening")
+	require.NoError(t, db.Close())
+
+	db = openDB()
+	defer db.Close()
+
+	got, err = db.PantryStaples(user, staples.Pantry)
+	require.NoError(t, err)
+	require.Equal(t, staples.User, got.User)
+	require.Equal(t, staples.Pantry, got.Pantry)
+	require.ElementsMatch(t, staples.Staples, got.Staples, "Staples do not match the ones just set")
+
+	staples.Staples = staples.Staples[:1]
+	require.NoError(t, db.SetPantryStaples(staples))
+
+	got, err = db.PantryStaples(user, staples.Pantry)
+	require.NoError(t, err)
+	require.Equal(t, staples.Staples, got.Staples, "Setting staples should replace the old ones")
+
+	require.NoError(t, db.DeletePantry(user, staples.Pantry))
+	require.NoError(t, db.SetPantry(dbtypes.Pantry{User: user, Name: staples.Pantry}))
+
+	got, err = db.PantryStaples(user, staples.Pantry)
+	require.NoError(t, err)
+	require.Empty(t, got.Staples, "Staples should be deleted with the pantry")
+}
+
+func PantryGroupsTest(t *testing.T, openDB func() database.DB) {
+	t.Helper()
+
+	db := openDB()
+	defer db.Close()
+
+	const user = "test-user-123"
+
+	require.NoError(t, db.SetUser(user))
+
+	group := dbtypes.PantryGroup{
+		User:     user,
+		Name:     "Home",
+		Pantries: []string{"Kitchen", "Freezer", "Cellar"},
+	}
+
+	_, err := db.LookupPantryGroup(user, group.Name)
+	require.ErrorIs(t, err, fs.ErrNotExist, "Should not find a group that does not exist")
+
+	err = db.SetPantryGroup(group)
+	require.ErrorIs(t, err, fs.ErrNotExist, "Should not set a group with pantries that do not exist")
+
+	for _, p := range group.Pantries {
+		require.NoError(t, db.SetPantry(dbtypes.Pantry{User: user, Name: p}))
+	}
+
+	invalid := group
+	invalid.Pantries = []string{"Kitchen", "Kitchen"}
+	require.Error(t, db.SetPantryGroup(invalid), "Should not set the same pantry twice")
+
+	require.NoError(t, db.SetPantryGroup(group))
+	require.NoError(t, db.SetPantryGroup(dbtypes.PantryGroup{User: user, Name: "Cold", Pantries: []string{"Freezer"}}))
+
+	t.Log("Closing DB and reopening")
+	require.NoError(t, db.Close())
+
+	db = openDB()
+	defer db.Close()
+
+	got, err := db.LookupPantryGroup(user, group.Name)
+	require.NoError(t, err)
+	require.Equal(t, group, got, "Group does not match the one just set")
+
+	groups, err := db.PantryGroups(user)
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+
+	group.Pantries = []string{"Cellar", "Kitchen"}
+	require.NoError(t, db.SetPantryGroup(group))
+
+	got, err = db.LookupPantryGroup(user, group.Name)
+	require.NoError(t, err)
+	require.Equal(t, group.Pantries, got.Pantries, "Setting a group should replace its pantries in order")
+
+	require.NoError(t, db.DeletePantry(user, "Cellar"))
+
+	got, err = db.LookupPantryGroup(user, group.Name)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Kitchen"}, got.Pantries, "Deleting a pantry should remove it from its groups")
+
+	require.NoError(t, db.DeletePantryGroup(user, group.Name))
+
+	_, err = db.LookupPantryGroup(user, group.Name)
+	require.ErrorIs(t, err, fs.ErrNotExist, "Group should have been deleted")
+
+	_, err = db.LookupPantry(user, "Kitchen")
+	require.NoError(t, err, "Deleting a group should not delete its pantries")
+
+	groups, err = db.PantryGroups(user)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+
+	require.NoError(t, db.DeletePantry(user, "Freezer"))
+
+	_, err = db.LookupPantryGroup(user, "Cold")
+	require.ErrorIs(t, err, fs.ErrNotExist, "Deleting the last pantry of a group should delete the group")
+
+	groups, err = db.PantryGroups(user)
+	require.NoError(t, err)
+	require.Empty(t, groups, "Empty groups should not be listed")
+}
+
+func ShoppingListUpdatesTest(t *testing.T, openDB func() database.DB) {
+	t.Helper()
+
+	db := openDB()
+	defer db.Close()
+
+	const user = "test-user-123"
+	const menu = "Menu #1"
+	const pantry = "Pantry #1"
+
+	require.NoError(t, db.SetUser(user))
+	require.NoError(t, db.SetMenu(dbtypes.Menu{User: user, Name: menu}))
+	require.NoError(t, db.SetPantry(dbtypes.Pantry{User: user, Name: pantry}))
+
+	const workers = 8
+
+	ids := make([]product.ID, 0, workers)
+	for i := range workers {
+		id, err := db.SetProduct(product.Product{
+			Provider:  blank.Provider{},
+			Name:      fmt.Sprintf("Product #%d", i),
+			BatchSize: 1,
+		})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	l, err := db.UpdateShoppingList(user, menu, pantry, []dbtypes.ShoppingListOp{{ProductID: ids[0], Done: false}})
+	require.NoError(t, err)
+	require.Empty(t, l.Contents, "Unmarking a product of a list that does not exist should leave it empty")
+
+	_, err = db.LookupShoppingList(user, menu, pantry)
+	require.ErrorIs(t, err, fs.ErrNotExist, "Empty shopping lists should not be stored")
+
+	l, err = db.UpdateShoppingList(user, menu, pantry, []dbtypes.ShoppingListOp{
+		{ProductID: ids[1], Done: true},
+		{ProductID: ids[0], Done: true},
+		{ProductID: ids[1], Done: true},
+	})
+	require.NoError(t, err)
+
+	// Product IDs are random, and the contents are sorted
+	want := []product.ID{ids[0], ids[1]}
+	slices.Sort(want)
+	require.Equal(t, want, l.Contents, "Marking a product twice should list it once")
+
+	var wg sync.WaitGroup
+	for _, id := range ids[2:] {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := db.UpdateShoppingList(user, menu, pantry, []dbtypes.ShoppingListOp{{ProductID: id, Done: true}})
+			assert.NoError(t, err)
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := db.UpdateShoppingList(user, menu, pantry, []dbtypes.ShoppingListOp{{ProductID: ids[0], Done: false}})
+		assert.NoError(t, err)
+	}()
+	wg.Wait()
+
+	t.Log("Closing DB and reopening")
+	require.NoError(t, db.Close())
+
+	db = openDB()
+	defer db.Close()
+
+	got, err := db.LookupShoppingList(user, menu, pantry)
+	require.NoError(t, err)
+	require.ElementsMatch(t, ids[1:], got.Contents, "Concurrent updates should not overwrite each other")
+
+	ops := make([]dbtypes.ShoppingListOp, 0, len(ids))
+	for _, id := range ids {
+		ops = append(ops, dbtypes.ShoppingListOp{ProductID: id, Done: false})
+	}
+
+	l, err = db.UpdateShoppingList(user, menu, pantry, ops)
+	require.NoError(t, err)
+	require.Empty(t, l.Contents)
+
+	_, err = db.LookupShoppingList(user, menu, pantry)
+	require.ErrorIs(t, err, fs.ErrNotExist, "Shopping lists should be removed once empty")
+}
@@ -0,0 +1,80 @@
+package dbtypes
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DateLayout is the format of dates, such as 2024-03-25.
+const DateLayout = time.DateOnly
+
+// Date is a calendar day, without time of day nor time zone.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// NewDate returns the date of the given time, in its own location.
+func NewDate(t time.Time) Date {
+	y, m, d := t.Date()
+	return Date{Year: y, Month: m, Day: d}
+}
+
+// ParseDate parses a date in the format 2024-03-25.
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return Date{}, fmt.Errorf("invalid date %q: expected the format YYYY-MM-DD", s)
+	}
+	return NewDate(t), nil
+}
+
+// Time returns midnight of the date in UTC.
+func (d Date) Time() time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, time.UTC)
+}
+
+func (d Date) String() string {
+	return d.Time().Format(DateLayout)
+}
+
+// AddDays returns the date n days after this one. Negative values go back in time.
+func (d Date) AddDays(n int) Date {
+	return NewDate(d.Time().AddDate(0, 0, n))
+}
+
+// Sub returns the number of days from other to d.
+func (d Date) Sub(other Date) int {
+	return int(d.Time().Sub(other.Time()).Hours() / 24)
+}
+
+// Weekday returns the day of the week of the date.
+func (d Date) Weekday() time.Weekday {
+	return d.Time().Weekday()
+}
+
+// Compare returns -1 if d is before other, 1 if it is after, and 0 if they are the same day.
+func (d Date) Compare(other Date) int {
+	return d.Time().Compare(other.Time())
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Date) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	parsed, err := ParseDate(s)
+	if err != nil {
+		return err
+	}
+
+	*d = parsed
+	return nil
+}
@@ -1,6 +1,10 @@
 package dbtypes
 
 import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
@@ -15,16 +19,66 @@ type Session struct {
 	NotAfter     time.Time
 }
 
+// CalendarFeed is a secret token that gives read-only access to the menus of a user
+// as a calendar subscription. Every user has at most one.
+type CalendarFeed struct {
+	Token string `json:"token"`
+	User  string `json:"user"`
+}
+
+// RecipeSharing represents who, other than its owner, can see and fork a recipe.
+type RecipeSharing struct {
+	Recipe recipe.ID `json:"recipe_id"`
+	Owner  string    `json:"owner"`
+	Public bool      `json:"public"`
+	Users  []string  `json:"users,omitempty"`
+}
+
+// RecipeRevision is a snapshot of a recipe, taken every time it is saved.
+type RecipeRevision struct {
+	Revision  int           `json:"revision"`
+	Author    string        `json:"author"`
+	Timestamp time.Time     `json:"timestamp"`
+	Recipe    recipe.Recipe `json:"recipe"`
+}
+
 // Dish represents a single dish that is part of a meal.
+// The amount is per person when the menu or the meal specify how many people eat.
 type Dish struct {
 	ID     recipe.ID `json:"recipe_id"`
 	Amount float32   `json:"amount"`
+
+	// LeftoverFrom is set when the dish is not cooked, because it is eaten from a batch of
+	// the same recipe cooked in an earlier meal. The ingredients of leftovers are not counted.
+	LeftoverFrom *MealRef `json:"leftover_from,omitempty"`
+}
+
+// Cooked returns true if the dish is cooked for its meal, rather than eaten as leftovers.
+func (d Dish) Cooked() bool {
+	return d.LeftoverFrom == nil
+}
+
+// MealRef points to a meal of a menu by the position of its day and its position in the day.
+type MealRef struct {
+	Day  int `json:"day"`
+	Meal int `json:"meal"`
+}
+
+// Before returns true if the meal referenced by r happens before the one referenced by other.
+func (r MealRef) Before(other MealRef) bool {
+	if r.Day != other.Day {
+		return r.Day < other.Day
+	}
+	return r.Meal < other.Meal
 }
 
 // Meal represents a meal that is part of a day.
 type Meal struct {
 	Name   string `json:"name"`
 	Dishes []Dish `json:"dishes,omitempty"`
+
+	// Attendees is the number of people eating this meal. Nil means the household size of the menu.
+	Attendees *int `json:"attendees,omitempty"`
 }
 
 // Day represents a day of the week.
@@ -33,17 +87,480 @@ type Day struct {
 	Meals []Meal `json:"meals,omitempty"`
 }
 
-// Menu represents a menu for a week.
+// Menu represents a menu for a week, or any other number of days.
+//
+// Menus with a start date are planned for the days starting on it: the first day of
+// the menu is the start date, the second one is the day after, and so on. Menus without
+// a start date are templates, which can be copied to plan a specific week.
 type Menu struct {
-	User string `json:"user"`
-	Name string `json:"name"`
-	Days []Day  `json:"days,omitempty"`
+	User      string `json:"user"`
+	Name      string `json:"name"`
+	StartDate *Date  `json:"start_date,omitempty"`
+	Days      []Day  `json:"days,omitempty"`
+
+	// Household is the number of people eating each meal, unless the meal says otherwise.
+	// Zero means the amounts of the dishes are not scaled.
+	Household int `json:"household,omitempty"`
+}
+
+// Attendees returns the number of people eating a meal of the menu.
+// It returns 1 if neither the meal nor the menu specify it, so that amounts are not scaled.
+func (m Menu) Attendees(meal Meal) int {
+	if meal.Attendees != nil {
+		return *meal.Attendees
+	}
+
+	if m.Household > 0 {
+		return m.Household
+	}
+
+	return 1
+}
+
+// Servings returns the amount of a dish in a meal of the menu, scaled by the attendance.
+func (m Menu) Servings(meal Meal, dish Dish) float32 {
+	return dish.Amount * float32(m.Attendees(meal))
+}
+
+// Meal returns the meal referenced by r, or false if the menu does not have it.
+func (m Menu) Meal(r MealRef) (Meal, bool) {
+	if r.Day < 0 || r.Day >= len(m.Days) {
+		return Meal{}, false
+	}
+
+	meals := m.Days[r.Day].Meals
+	if r.Meal < 0 || r.Meal >= len(meals) {
+		return Meal{}, false
+	}
+
+	return meals[r.Meal], true
+}
+
+// Validate checks that no meal has a negative number of people, and that every leftover dish comes
+// from an earlier meal where the same recipe is cooked.
+func (m Menu) Validate() error {
+	if m.Household < 0 {
+		return fmt.Errorf("household cannot be negative: %d", m.Household)
+	}
+
+	for i, day := range m.Days {
+		for j, meal := range day.Meals {
+			here := MealRef{Day: i, Meal: j}
+
+			if meal.Attendees != nil && *meal.Attendees < 0 {
+				return fmt.Errorf("%s: %s: attendees cannot be negative: %d", day.Name, meal.Name, *meal.Attendees)
+			}
+
+			for _, dish := range meal.Dishes {
+				if dish.Cooked() {
+					continue
+				}
+
+				from := *dish.LeftoverFrom
+				if !from.Before(here) {
+					return fmt.Errorf("%s: %s: recipe %d cannot be a leftover from a later meal", day.Name, meal.Name, dish.ID)
+				}
+
+				source, ok := m.Meal(from)
+				if !ok {
+					return fmt.Errorf("%s: %s: recipe %d is a leftover from a meal that does not exist", day.Name, meal.Name, dish.ID)
+				}
+
+				if !slices.ContainsFunc(source.Dishes, func(d Dish) bool { return d.ID == dish.ID && d.Cooked() }) {
+					return fmt.Errorf("%s: %s: recipe %d is a leftover from a meal where it is not cooked", day.Name, meal.Name, dish.ID)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Dates returns the date of every day in the menu. It returns nil for templates.
+func (m Menu) Dates() []Date {
+	if m.StartDate == nil {
+		return nil
+	}
+
+	out := make([]Date, len(m.Days))
+	for i := range m.Days {
+		out[i] = m.StartDate.AddDays(i)
+	}
+
+	return out
 }
 
 type Pantry struct {
 	User     string              `json:"user"`
 	Name     string              `json:"name"`
 	Contents []recipe.Ingredient `json:"contents"`
+
+	// Lots split the contents by purchase, so that the oldest can be used first.
+	// The lots of a product add up to at most its amount in the contents: the rest has no known dates.
+	Lots []PantryLot `json:"lots,omitempty"`
+}
+
+// PantryLot is part of the stock of a product in a pantry, bought at the same time.
+type PantryLot struct {
+	ProductID  product.ID `json:"product_id"`
+	Amount     float32    `json:"amount"`
+	Purchased  *Date      `json:"purchased,omitempty"`
+	BestBefore *Date      `json:"best_before,omitempty"`
+}
+
+// Expired returns true if the lot is past its best-before date on the given date.
+func (l PantryLot) Expired(on Date) bool {
+	return l.BestBefore != nil && l.BestBefore.Compare(on) < 0
+}
+
+// Validate checks that every lot has a positive amount, and that the lots of every product fit in the contents.
+func (p Pantry) Validate() error {
+	have := make(map[product.ID]float32, len(p.Contents))
+	for _, i := range p.Contents {
+		have[i.ProductID] += i.Amount
+	}
+
+	lots := make(map[product.ID]float32)
+	for _, l := range p.Lots {
+		if l.Amount <= 0 {
+			return fmt.Errorf("lot of product %d has an amount of %g", l.ProductID, l.Amount)
+		}
+
+		if l.Purchased != nil && l.BestBefore != nil && l.BestBefore.Compare(*l.Purchased) < 0 {
+			return fmt.Errorf("lot of product %d is best before %s, but was purchased on %s", l.ProductID, l.BestBefore, l.Purchased)
+		}
+
+		lots[l.ProductID] += l.Amount
+	}
+
+	for id, amount := range lots {
+		if amount > have[id] {
+			return fmt.Errorf("lots of product %d add up to %g, but the pantry only has %g", id, amount, have[id])
+		}
+	}
+
+	return nil
+}
+
+// Stock returns the lots of the pantry, plus one undated lot per product for the amount not covered by lots.
+//
+// The output is sorted by product, and then in the order the lots should be used: the ones with the
+// soonest best-before date first, then the ones purchased earliest, and the undated ones last.
+func (p Pantry) Stock() []PantryLot {
+	undated := make(map[product.ID]float32, len(p.Contents))
+	for _, i := range p.Contents {
+		undated[i.ProductID] += i.Amount
+	}
+
+	out := make([]PantryLot, 0, len(p.Lots)+len(p.Contents))
+	for _, l := range p.Lots {
+		undated[l.ProductID] -= l.Amount
+		out = append(out, l)
+	}
+
+	for id, amount := range undated {
+		if amount > 0 {
+			out = append(out, PantryLot{ProductID: id, Amount: amount})
+		}
+	}
+
+	slices.SortStableFunc(out, func(a, b PantryLot) int {
+		if c := cmp.Compare(a.ProductID, b.ProductID); c != 0 {
+			return c
+		}
+		if c := compareDates(a.BestBefore, b.BestBefore); c != 0 {
+			return c
+		}
+		return compareDates(a.Purchased, b.Purchased)
+	})
+
+	return out
+}
+
+// Consume takes the needed ingredients out of the pantry stock, using the lots in the order they are given.
+// Lots that have expired on the given date are not used. A nil date uses every lot.
+//
+// It returns the ingredients that are still missing, in the same order as need, and the parts of
+// the lots that were used, in the order they were used.
+func Consume(need []recipe.Ingredient, stock []PantryLot, on *Date) (missing []recipe.Ingredient, used []PantryLot) {
+	lots := make(map[product.ID][]PantryLot)
+	for _, l := range stock {
+		if on != nil && l.Expired(*on) {
+			continue
+		}
+		lots[l.ProductID] = append(lots[l.ProductID], l)
+	}
+
+	missing = make([]recipe.Ingredient, 0, len(need))
+	for _, n := range need {
+		for i := range lots[n.ProductID] {
+			if n.Amount <= 0 {
+				break
+			}
+
+			l := &lots[n.ProductID][i]
+			if l.Amount <= 0 {
+				continue
+			}
+
+			take := min(l.Amount, n.Amount)
+			l.Amount -= take
+			n.Amount -= take
+
+			u := *l
+			u.Amount = take
+			used = append(used, u)
+		}
+
+		n.Amount = max(n.Amount, 0)
+		missing = append(missing, n)
+	}
+
+	return missing, used
+}
+
+// Take removes the given lots from the pantry, such as the ones used by Consume.
+// Dated lots are taken from the lots with the same dates, and undated ones only from the contents.
+func (p *Pantry) Take(used []PantryLot) {
+	for _, u := range used {
+		if i := slices.IndexFunc(p.Contents, func(c recipe.Ingredient) bool { return c.ProductID == u.ProductID }); i != -1 {
+			p.Contents[i].Amount = max(p.Contents[i].Amount-u.Amount, 0)
+		}
+
+		left := u.Amount
+		for i := range p.Lots {
+			if left <= 0 {
+				break
+			}
+
+			l := &p.Lots[i]
+			if !l.sameLot(u) {
+				continue
+			}
+
+			take := min(l.Amount, left)
+			l.Amount -= take
+			left -= take
+		}
+	}
+
+	p.Contents = slices.DeleteFunc(p.Contents, func(c recipe.Ingredient) bool { return c.Amount <= 0 })
+	p.Lots = slices.DeleteFunc(p.Lots, func(l PantryLot) bool { return l.Amount <= 0 })
+}
+
+// Add puts lots into the pantry, such as new purchases or lots that were taken out. It is the inverse of Take.
+// Dated lots are merged with the lots with the same dates, and undated ones only added to the contents.
+func (p *Pantry) Add(lots []PantryLot) {
+	for _, l := range lots {
+		if i := slices.IndexFunc(p.Contents, func(c recipe.Ingredient) bool { return c.ProductID == l.ProductID }); i != -1 {
+			p.Contents[i].Amount += l.Amount
+		} else {
+			p.Contents = append(p.Contents, recipe.Ingredient{ProductID: l.ProductID, Amount: l.Amount})
+		}
+
+		if l.Purchased == nil && l.BestBefore == nil {
+			continue
+		}
+
+		if i := slices.IndexFunc(p.Lots, l.sameLot); i != -1 {
+			p.Lots[i].Amount += l.Amount
+		} else {
+			p.Lots = append(p.Lots, l)
+		}
+	}
+}
+
+// PantryGroup is a named set of pantries, such as a kitchen and a freezer, whose stock is used together.
+type PantryGroup struct {
+	User     string   `json:"user"`
+	Name     string   `json:"name"`
+	Pantries []string `json:"pantries"`
+}
+
+// Validate checks that the group has at least one pantry, and no pantry twice.
+func (g PantryGroup) Validate() error {
+	if len(g.Pantries) == 0 {
+		return errors.New("group has no pantries")
+	}
+
+	seen := make(map[string]bool, len(g.Pantries))
+	for _, p := range g.Pantries {
+		if p == "" {
+			return errors.New("pantry name cannot be empty")
+		} else if seen[p] {
+			return fmt.Errorf("pantry %q is in the group more than once", p)
+		}
+		seen[p] = true
+	}
+
+	return nil
+}
+
+// CombinedStock returns the stock of several pantries as if they were a single one,
+// in the same order as Stock.
+func CombinedStock(pantries []Pantry) []PantryLot {
+	var all Pantry
+	for _, p := range pantries {
+		all.Contents = append(all.Contents, p.Contents...)
+		all.Lots = append(all.Lots, p.Lots...)
+	}
+
+	return all.Stock()
+}
+
+// PantryCover is how much of a product is taken from a pantry.
+type PantryCover struct {
+	Pantry string  `json:"pantry"`
+	Amount float32 `json:"amount"`
+}
+
+// CoveredBy splits the lots used from the CombinedStock of the pantries by the pantry they come from.
+// Lots with the same dates in several pantries are taken from the first pantry that has them.
+func CoveredBy(pantries []Pantry, used []PantryLot) map[product.ID][]PantryCover {
+	stock := make([][]PantryLot, len(pantries))
+	for i, p := range pantries {
+		stock[i] = p.Stock()
+	}
+
+	out := make(map[product.ID][]PantryCover)
+	for _, u := range used {
+		left := u.Amount
+		for i := range stock {
+			var taken float32
+			for j := range stock[i] {
+				if left <= 0 {
+					break
+				}
+
+				l := &stock[i][j]
+				if !l.sameLot(u) {
+					continue
+				}
+
+				take := min(l.Amount, left)
+				l.Amount -= take
+				left -= take
+				taken += take
+			}
+
+			if taken <= 0 {
+				continue
+			}
+
+			covers := out[u.ProductID]
+			if k := slices.IndexFunc(covers, func(c PantryCover) bool { return c.Pantry == pantries[i].Name }); k != -1 {
+				covers[k].Amount += taken
+			} else {
+				out[u.ProductID] = append(covers, PantryCover{Pantry: pantries[i].Name, Amount: taken})
+			}
+		}
+	}
+
+	return out
+}
+
+// PantryOpKind is how a PantryOp changes the amount of a product.
+type PantryOpKind string
+
+const (
+	PantryOpAdd      PantryOpKind = "add"
+	PantryOpSubtract PantryOpKind = "subtract"
+	PantryOpSet      PantryOpKind = "set"
+)
+
+// PantryOp changes the amount of one product in a pantry, without knowing the rest of its contents.
+type PantryOp struct {
+	ProductID product.ID   `json:"product_id"`
+	Op        PantryOpKind `json:"op"`
+	Amount    float32      `json:"amount"`
+}
+
+func (o PantryOp) Validate() error {
+	switch o.Op {
+	case PantryOpAdd, PantryOpSubtract, PantryOpSet:
+	default:
+		return fmt.Errorf("product %d has unknown operation %q", o.ProductID, o.Op)
+	}
+
+	if o.Amount < 0 {
+		return fmt.Errorf("product %d has a negative amount %g", o.ProductID, o.Amount)
+	}
+
+	return nil
+}
+
+// Apply runs the operations on the pantry in order. Added stock has no dates, and subtracted
+// stock is taken from the lots in the order they should be used. Subtracting more than there
+// is empties the product.
+func (p *Pantry) Apply(ops []PantryOp) error {
+	for _, o := range ops {
+		if err := o.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, o := range ops {
+		var have float32
+		for _, c := range p.Contents {
+			if c.ProductID == o.ProductID {
+				have += c.Amount
+			}
+		}
+
+		delta := o.Amount
+		switch o.Op {
+		case PantryOpSubtract:
+			delta = -o.Amount
+		case PantryOpSet:
+			delta = o.Amount - have
+		}
+
+		if delta > 0 {
+			p.Add([]PantryLot{{ProductID: o.ProductID, Amount: delta}})
+		} else if delta < 0 {
+			p.subtract(o.ProductID, -delta)
+		}
+	}
+
+	return nil
+}
+
+// subtract takes an amount of a product out of the pantry, from the lots in the order they should be used.
+func (p *Pantry) subtract(id product.ID, amount float32) {
+	used := make([]PantryLot, 0)
+	for _, l := range p.Stock() {
+		if amount <= 0 {
+			break
+		} else if l.ProductID != id {
+			continue
+		}
+
+		l.Amount = min(l.Amount, amount)
+		amount -= l.Amount
+		used = append(used, l)
+	}
+
+	p.Take(used)
+}
+
+// sameLot returns true if both lots are of the same product and have the same dates.
+func (l PantryLot) sameLot(other PantryLot) bool {
+	return l.ProductID == other.ProductID &&
+		compareDates(l.Purchased, other.Purchased) == 0 &&
+		compareDates(l.BestBefore, other.BestBefore) == 0
+}
+
+// compareDates compares two optional dates, with missing dates coming last.
+func compareDates(a, b *Date) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return a.Compare(*b)
 }
 
 type ShoppingList struct {
@@ -52,3 +569,166 @@ type ShoppingList struct {
 	Pantry   string       `json:"pantry"`
 	Contents []product.ID `json:"contents"`
 }
+
+// ShoppingListOp marks a single product of a shopping list as done or not done,
+// without knowing the state of the rest of the list.
+type ShoppingListOp struct {
+	ProductID product.ID `json:"product_id"`
+	Done      bool       `json:"done"`
+}
+
+// Apply runs the operations on the shopping list in order. The contents end up sorted.
+func (l *ShoppingList) Apply(ops []ShoppingListOp) {
+	slices.Sort(l.Contents)
+	for _, o := range ops {
+		i, found := slices.BinarySearch(l.Contents, o.ProductID)
+		if o.Done && !found {
+			l.Contents = slices.Insert(l.Contents, i, o.ProductID)
+		} else if !o.Done && found {
+			l.Contents = slices.Delete(l.Contents, i, i+1)
+		}
+	}
+}
+
+// Staple is a product that must always be in stock in a pantry, whether a menu needs it or not.
+type Staple struct {
+	ProductID product.ID `json:"product_id"`
+
+	// Minimum is the amount below which the product has to be restocked.
+	Minimum float32 `json:"minimum"`
+}
+
+// PantryStaples are the minimum-stock rules of a pantry.
+type PantryStaples struct {
+	User    string   `json:"user"`
+	Pantry  string   `json:"pantry"`
+	Staples []Staple `json:"staples"`
+}
+
+// Validate checks that every staple has a positive minimum, and that no product appears twice.
+func (s PantryStaples) Validate() error {
+	seen := make(map[product.ID]bool, len(s.Staples))
+	for _, st := range s.Staples {
+		if st.Minimum <= 0 {
+			return fmt.Errorf("product %d has a minimum stock of %g", st.ProductID, st.Minimum)
+		} else if seen[st.ProductID] {
+			return fmt.Errorf("product %d has more than one minimum stock", st.ProductID)
+		}
+		seen[st.ProductID] = true
+	}
+
+	return nil
+}
+
+// PantryReason is why the contents of a pantry changed.
+type PantryReason string
+
+const (
+	// PantryManual is a change made by editing the pantry.
+	PantryManual PantryReason = "manual"
+
+	// PantryCooked is stock taken out to cook a meal.
+	PantryCooked PantryReason = "cooked"
+
+	// PantryUncooked is stock put back when a meal is marked as not cooked.
+	PantryUncooked PantryReason = "uncooked"
+
+	// PantryPurchased is stock added when checking out a shopping list.
+	PantryPurchased PantryReason = "purchased"
+
+	// PantryRollback is a change that brings the pantry back to an earlier entry of its ledger.
+	PantryRollback PantryReason = "rollback"
+)
+
+// PantryChange is an entry in the ledger of a pantry. Every time a pantry is stored, an entry is
+// added with how much of each product changed and a snapshot of the pantry after the change.
+type PantryChange struct {
+	Entry     int                 `json:"entry"`
+	Author    string              `json:"author"`
+	Timestamp time.Time           `json:"timestamp"`
+	Reason    PantryReason        `json:"reason"`
+	Deltas    []recipe.Ingredient `json:"deltas"`
+	Snapshot  Pantry              `json:"snapshot"`
+}
+
+// PantryDeltas returns how much the amount of each product changed from one pantry to another,
+// sorted by product. Products whose amount did not change are left out.
+func PantryDeltas(from, to Pantry) []recipe.Ingredient {
+	delta := make(map[product.ID]float32)
+	for _, i := range from.Contents {
+		delta[i.ProductID] -= i.Amount
+	}
+	for _, i := range to.Contents {
+		delta[i.ProductID] += i.Amount
+	}
+
+	out := make([]recipe.Ingredient, 0, len(delta))
+	for id, d := range delta {
+		if d != 0 {
+			out = append(out, recipe.Ingredient{ProductID: id, Amount: d})
+		}
+	}
+
+	slices.SortFunc(out, func(a, b recipe.Ingredient) int { return cmp.Compare(a.ProductID, b.ProductID) })
+	return out
+}
+
+// PantryUnchanged returns true if both pantries have the same contents and lots.
+func PantryUnchanged(from, to Pantry) bool {
+	if len(PantryDeltas(from, to)) != 0 {
+		return false
+	}
+
+	return slices.EqualFunc(from.Lots, to.Lots, func(a, b PantryLot) bool {
+		return a.sameLot(b) && a.Amount == b.Amount
+	})
+}
+
+// CookedAfter returns true if meals were cooked in any entry of the ledger after the given one.
+func CookedAfter(ledger []PantryChange, entry int) bool {
+	return slices.ContainsFunc(ledger, func(c PantryChange) bool {
+		return c.Entry > entry && c.Reason == PantryCooked
+	})
+}
+
+// CookedMeal records the stock taken out of a pantry when a meal of a menu was cooked,
+// so that it can be put back if the meal is marked as not cooked.
+type CookedMeal struct {
+	User   string      `json:"user"`
+	Menu   string      `json:"menu"`
+	Meal   MealRef     `json:"meal"`
+	Pantry string      `json:"pantry"`
+	Cooked time.Time   `json:"cooked"`
+	Used   []PantryLot `json:"used"`
+}
+
+// MealToCook is a meal of a menu about to be cooked, with the ingredients it needs.
+type MealToCook struct {
+	Meal MealRef
+	Need []recipe.Ingredient
+}
+
+// Purchase records what was bought when checking out a shopping list, and what was paid for it.
+type Purchase struct {
+	User   string         `json:"user"`
+	Menu   string         `json:"menu"`
+	Pantry string         `json:"pantry"`
+	Date   time.Time      `json:"date"`
+	Items  []PurchaseItem `json:"items"`
+}
+
+// PurchaseItem is a product bought in a purchase, with the price paid for each pack.
+type PurchaseItem struct {
+	ProductID product.ID `json:"product_id"`
+	Packs     int        `json:"packs"`
+	Price     float32    `json:"price"`
+}
+
+// Spent returns the total paid for the purchase.
+func (p Purchase) Spent() float32 {
+	var total float32
+	for _, i := range p.Items {
+		total += float32(i.Packs) * i.Price
+	}
+	return total
+}
@@ -23,19 +23,35 @@ import (
 type JSON struct {
 	users         []string
 	sessions      []dbtypes.Session
+	calendarFeeds []dbtypes.CalendarFeed
 	products      []product.Product
 	recipes       []recipe.Recipe
+	recipeSharing []dbtypes.RecipeSharing
+	revisions     []dbtypes.RecipeRevision
 	menus         []dbtypes.Menu
 	pantries      []dbtypes.Pantry
+	pantryLedger  []dbtypes.PantryChange
+	pantryStaples []dbtypes.PantryStaples
+	pantryGroups  []dbtypes.PantryGroup
 	shoppingLists []dbtypes.ShoppingList
+	cookedMeals   []dbtypes.CookedMeal
+	purchases     []dbtypes.Purchase
 
 	usersPath         string
 	sesionsPath       string
+	calendarFeedsPath string
 	productsPath      string
 	recipesPath       string
+	recipeSharingPath string
+	revisionsPath     string
 	menusPath         string
 	pantriesPath      string
+	pantryLedgerPath  string
+	pantryStaplesPath string
+	pantryGroupsPath  string
 	shoppingListsPath string
+	cookedMealsPath   string
+	purchasesPath     string
 
 	log logger.Logger
 	mu  sync.RWMutex
@@ -44,11 +60,19 @@ type JSON struct {
 type Settings struct {
 	Users         string
 	Sessions      string
+	CalendarFeeds string
 	Products      string
 	Recipes       string
+	RecipeSharing string
+	Revisions     string
 	Menus         string
 	Pantries      string
+	PantryLedger  string
+	PantryStaples string
+	PantryGroups  string
 	ShoppingLists string
+	CookedMeals   string
+	Purchases     string
 }
 
 func DefaultSettings() Settings {
@@ -59,11 +83,19 @@ func DefaultSettingsPath(root string) Settings {
 	return Settings{
 		Users:         filepath.Join(root, "users.json"),
 		Sessions:      filepath.Join(root, "sessions.json"),
+		CalendarFeeds: filepath.Join(root, "calendarFeeds.json"),
 		Products:      filepath.Join(root, "products.json"),
 		Recipes:       filepath.Join(root, "recipes.json"),
+		RecipeSharing: filepath.Join(root, "recipeSharing.json"),
+		Revisions:     filepath.Join(root, "revisions.json"),
 		Menus:         filepath.Join(root, "menus.json"),
 		Pantries:      filepath.Join(root, "pantries.json"),
+		PantryLedger:  filepath.Join(root, "pantryLedger.json"),
+		PantryStaples: filepath.Join(root, "pantryStaples.json"),
+		PantryGroups:  filepath.Join(root, "pantryGroups.json"),
 		ShoppingLists: filepath.Join(root, "shoppingLists.json"),
+		CookedMeals:   filepath.Join(root, "cookedMeals.json"),
+		Purchases:     filepath.Join(root, "purchases.json"),
 	}
 }
 
@@ -72,11 +104,19 @@ func New(ctx context.Context, log logger.Logger, s Settings) (*JSON, error) {
 		log:               log,
 		usersPath:         s.Users,
 		sesionsPath:       s.Sessions,
+		calendarFeedsPath: s.CalendarFeeds,
 		productsPath:      s.Products,
 		recipesPath:       s.Recipes,
+		recipeSharingPath: s.RecipeSharing,
+		revisionsPath:     s.Revisions,
 		menusPath:         s.Menus,
 		pantriesPath:      s.Pantries,
+		pantryLedgerPath:  s.PantryLedger,
+		pantryStaplesPath: s.PantryStaples,
+		pantryGroupsPath:  s.PantryGroups,
 		shoppingListsPath: s.ShoppingLists,
+		cookedMealsPath:   s.CookedMeals,
+		purchasesPath:     s.Purchases,
 	}
 
 	log = log.WithField("database", "json")
@@ -85,11 +125,19 @@ func New(ctx context.Context, log logger.Logger, s Settings) (*JSON, error) {
 	return db, errors.Join(
 		load(db.usersPath, &db.users),
 		load(db.sesionsPath, &db.sessions),
+		load(db.calendarFeedsPath, &db.calendarFeeds),
 		load(db.productsPath, &db.products),
 		load(db.recipesPath, &db.recipes),
+		load(db.recipeSharingPath, &db.recipeSharing),
+		load(db.revisionsPath, &db.revisions),
 		load(db.menusPath, &db.menus),
 		load(db.pantriesPath, &db.pantries),
+		load(db.pantryLedgerPath, &db.pantryLedger),
+		load(db.pantryStaplesPath, &db.pantryStaples),
+		load(db.pantryGroupsPath, &db.pantryGroups),
 		load(db.shoppingListsPath, &db.shoppingLists),
+		load(db.cookedMealsPath, &db.cookedMeals),
+		load(db.purchasesPath, &db.purchases),
 	)
 }
 
@@ -146,10 +194,27 @@ func (db *JSON) DeleteUser(id string) error {
 	}
 
 	db.sessions = removeIf(db.sessions, func(s dbtypes.Session) bool { return s.User == id })
+	db.calendarFeeds = slices.DeleteFunc(db.calendarFeeds, func(f dbtypes.CalendarFeed) bool { return f.User == id })
 	db.recipes = removeIf(db.recipes, func(r recipe.Recipe) bool { return r.User == id })
+	db.recipeSharing = removeIf(db.recipeSharing, func(s dbtypes.RecipeSharing) bool { return s.Owner == id })
+	for i := range db.recipeSharing {
+		users := slices.DeleteFunc(slices.Clone(db.recipeSharing[i].Users), func(u string) bool { return u == id })
+		if len(users) == 0 {
+			users = nil
+		}
+		db.recipeSharing[i].Users = users
+	}
+	// Recipes that are no longer shared with anyone are not stored, as in SetRecipeSharing
+	db.recipeSharing = removeIf(db.recipeSharing, func(s dbtypes.RecipeSharing) bool { return !s.Public && len(s.Users) == 0 })
+	db.revisions = removeIf(db.revisions, func(r dbtypes.RecipeRevision) bool { return r.Recipe.User == id })
 	db.menus = removeIf(db.menus, func(m dbtypes.Menu) bool { return m.User == id })
 	db.pantries = removeIf(db.pantries, func(p dbtypes.Pantry) bool { return p.User == id })
+	db.pantryLedger = removeIf(db.pantryLedger, func(c dbtypes.PantryChange) bool { return c.Snapshot.User == id })
+	db.pantryStaples = removeIf(db.pantryStaples, func(s dbtypes.PantryStaples) bool { return s.User == id })
+	db.pantryGroups = removeIf(db.pantryGroups, func(g dbtypes.PantryGroup) bool { return g.User == id })
 	db.shoppingLists = removeIf(db.shoppingLists, func(s dbtypes.ShoppingList) bool { return s.User == id })
+	db.cookedMeals = removeIf(db.cookedMeals, func(c dbtypes.CookedMeal) bool { return c.User == id })
+	db.purchases = removeIf(db.purchases, func(p dbtypes.Purchase) bool { return p.User == id })
 
 	if err := db.save(); err != nil {
 		return err
@@ -223,6 +288,65 @@ func (db *JSON) PurgeSessions() error {
 	return nil
 }
 
+func (db *JSON) LookupCalendarFeed(token string) (dbtypes.CalendarFeed, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	i := slices.IndexFunc(db.calendarFeeds, func(f dbtypes.CalendarFeed) bool { return f.Token == token })
+	if token == "" || i == -1 {
+		return dbtypes.CalendarFeed{}, fs.ErrNotExist
+	}
+
+	return db.calendarFeeds[i], nil
+}
+
+func (db *JSON) LookupUserCalendarFeed(user string) (dbtypes.CalendarFeed, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	i := slices.IndexFunc(db.calendarFeeds, func(f dbtypes.CalendarFeed) bool { return f.User == user })
+	if i == -1 {
+		return dbtypes.CalendarFeed{}, fs.ErrNotExist
+	}
+
+	return db.calendarFeeds[i], nil
+}
+
+func (db *JSON) SetCalendarFeed(f dbtypes.CalendarFeed) error {
+	if f.User == "" {
+		return errors.New("user cannot be empty")
+	} else if f.Token == "" {
+		return errors.New("token cannot be empty")
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if slices.ContainsFunc(db.calendarFeeds, func(x dbtypes.CalendarFeed) bool { return x.Token == f.Token && x.User != f.User }) {
+		return fs.ErrExist
+	}
+
+	db.calendarFeeds = slices.DeleteFunc(db.calendarFeeds, func(x dbtypes.CalendarFeed) bool { return x.User == f.User })
+	db.calendarFeeds = append(db.calendarFeeds, f)
+
+	if err := db.save(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (db *JSON) DeleteCalendarFeed(user string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.calendarFeeds = slices.DeleteFunc(db.calendarFeeds, func(f dbtypes.CalendarFeed) bool { return f.User == user })
+
+	if err := db.save(); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (db *JSON) Products() ([]product.Product, error) {
 	db.mu.RLock()
 	defer db.mu.RUnlock()
@@ -262,6 +386,10 @@ func (db *JSON) SetProduct(p product.Product) (product.ID, error) {
 	if i == -1 {
 		db.products = append(db.products, p)
 	} else {
+		// A zero time means it is not known by the caller, so the stored time is kept
+		if p.PriceUpdated.IsZero() {
+			p.PriceUpdated = db.products[i].PriceUpdated
+		}
 		db.products[i] = p
 	}
 
@@ -326,6 +454,96 @@ func (db *JSON) SetRecipe(r recipe.Recipe) (recipe.ID, error) {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
+	id, err := db.setRecipe(r)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := db.save(); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (db *JSON) SetRecipes(rs []recipe.Recipe) ([]recipe.ID, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	// Permissions are checked beforehand so that no recipe is saved if any of them fails
+	for _, r := range rs {
+		if err := db.checkRecipeOwner(r); err != nil {
+			return nil, err
+		}
+	}
+
+	ids := make([]recipe.ID, 0, len(rs))
+	for _, r := range rs {
+		id, err := db.setRecipe(r)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err := db.save(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (db *JSON) ForkRecipes(copies []recipe.Recipe) ([]recipe.ID, error) {
+	for _, r := range copies {
+		if r.ForkedFrom == 0 {
+			return nil, fmt.Errorf("recipe %q is not a fork", r.Name)
+		}
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	// forked maps the IDs of the original recipes to their copies
+	forked := make(map[recipe.ID]recipe.ID, len(copies))
+
+	ids := make([]recipe.ID, 0, len(copies))
+	for _, r := range copies {
+		r.ID = 0
+		r.SubRecipes = slices.Clone(r.SubRecipes)
+		for i, sub := range r.SubRecipes {
+			if id, ok := forked[sub.RecipeID]; ok {
+				r.SubRecipes[i].RecipeID = id
+			}
+		}
+
+		// New recipes always belong to their user, so this cannot fail halfway
+		id, err := db.setRecipe(r)
+		if err != nil {
+			return nil, err
+		}
+
+		forked[r.ForkedFrom] = id
+		ids = append(ids, id)
+	}
+
+	if err := db.save(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// checkRecipeOwner returns an error if the recipe exists and belongs to another user.
+func (db *JSON) checkRecipeOwner(r recipe.Recipe) error {
+	i := slices.IndexFunc(db.recipes, func(entry recipe.Recipe) bool { return entry.ID == r.ID })
+	if i != -1 && db.recipes[i].User != r.User {
+		return fmt.Errorf("permission denied: recipe %d bolongs to another user", r.ID)
+	}
+	return nil
+}
+
+// setRecipe stores the recipe and a revision of it. The caller must hold the lock and save the DB.
+func (db *JSON) setRecipe(r recipe.Recipe) (recipe.ID, error) {
 	for r.ID == 0 {
 		newID := recipe.NewRandomID()
 		idx := slices.IndexFunc(db.recipes, func(entry recipe.Recipe) bool { return entry.ID == newID })
@@ -334,22 +552,81 @@ func (db *JSON) SetRecipe(r recipe.Recipe) (recipe.ID, error) {
 		}
 	}
 
+	if err := db.checkRecipeOwner(r); err != nil {
+		return 0, err
+	}
+
 	i := slices.IndexFunc(db.recipes, func(entry recipe.Recipe) bool { return entry.ID == r.ID })
 	if i == -1 {
 		db.recipes = append(db.recipes, r)
-	} else if db.recipes[i].User != r.User {
-		return 0, fmt.Errorf("permission denied: recipe %d bolongs to another user", r.ID)
 	} else {
 		db.recipes[i] = r
 	}
 
-	if err := db.save(); err != nil {
-		return 0, err
-	}
+	db.appendRevision(r)
 
 	return r.ID, nil
 }
 
+// appendRevision stores a snapshot of the recipe as its latest revision.
+func (db *JSON) appendRevision(r recipe.Recipe) {
+	last := 0
+	for _, rev := range db.revisions {
+		if rev.Recipe.ID == r.ID {
+			last = max(last, rev.Revision)
+		}
+	}
+
+	// The snapshot must not share memory with the live recipe
+	r.Ingredients = slices.Clone(r.Ingredients)
+	r.SubRecipes = slices.Clone(r.SubRecipes)
+
+	db.revisions = append(db.revisions, dbtypes.RecipeRevision{
+		Revision:  last + 1,
+		Author:    r.User,
+		Timestamp: time.Now().UTC().Truncate(time.Second),
+		Recipe:    r,
+	})
+}
+
+func (db *JSON) RecipeRevisions(asUser string, id recipe.ID) ([]dbtypes.RecipeRevision, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if !slices.ContainsFunc(db.recipes, func(r recipe.Recipe) bool { return r.ID == id && r.User == asUser }) {
+		return nil, fs.ErrNotExist
+	}
+
+	out := make([]dbtypes.RecipeRevision, 0)
+	for _, rev := range db.revisions {
+		if rev.Recipe.ID == id {
+			out = append(out, rev)
+		}
+	}
+
+	slices.SortFunc(out, func(a, b dbtypes.RecipeRevision) int { return cmp.Compare(a.Revision, b.Revision) })
+	return out, nil
+}
+
+func (db *JSON) LookupRecipeRevision(asUser string, id recipe.ID, revision int) (dbtypes.RecipeRevision, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if !slices.ContainsFunc(db.recipes, func(r recipe.Recipe) bool { return r.ID == id && r.User == asUser }) {
+		return dbtypes.RecipeRevision{}, fs.ErrNotExist
+	}
+
+	i := slices.IndexFunc(db.revisions, func(r dbtypes.RecipeRevision) bool {
+		return r.Recipe.ID == id && r.Revision == revision
+	})
+
+	if i == -1 {
+		return dbtypes.RecipeRevision{}, fs.ErrNotExist
+	}
+
+	return db.revisions[i], nil
+}
+
 func (db *JSON) DeleteRecipe(asUser string, id recipe.ID) error {
 	db.mu.Lock()
 	defer db.mu.Unlock()
@@ -363,6 +640,107 @@ func (db *JSON) DeleteRecipe(asUser string, id recipe.ID) error {
 	}
 
 	db.recipes = append(db.recipes[:i], db.recipes[i+1:]...)
+	db.recipeSharing = slices.DeleteFunc(db.recipeSharing, func(s dbtypes.RecipeSharing) bool { return s.Recipe == id })
+	db.revisions = slices.DeleteFunc(db.revisions, func(r dbtypes.RecipeRevision) bool { return r.Recipe.ID == id })
+
+	// Remove the recipe from any other recipe that uses it as a sub-recipe
+	for j := range db.recipes {
+		if db.recipes[j].User != asUser {
+			continue
+		}
+		db.recipes[j].SubRecipes = slices.DeleteFunc(db.recipes[j].SubRecipes, func(sub recipe.SubRecipe) bool {
+			return sub.RecipeID == id
+		})
+		if len(db.recipes[j].SubRecipes) == 0 {
+			db.recipes[j].SubRecipes = nil
+		}
+	}
+
+	if err := db.save(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (db *JSON) SharedRecipes(asUser string) ([]recipe.Recipe, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	out := make([]recipe.Recipe, 0)
+	for _, s := range db.recipeSharing {
+		if s.Owner == asUser || !sharedWith(s, asUser) {
+			continue
+		}
+
+		i := slices.IndexFunc(db.recipes, func(r recipe.Recipe) bool { return r.ID == s.Recipe })
+		if i == -1 {
+			continue
+		}
+
+		out = append(out, db.recipes[i])
+	}
+
+	return out, nil
+}
+
+func (db *JSON) LookupSharedRecipe(asUser string, id recipe.ID) (recipe.Recipe, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	i := slices.IndexFunc(db.recipes, func(r recipe.Recipe) bool { return r.ID == id })
+	if i == -1 {
+		return recipe.Recipe{}, fs.ErrNotExist
+	}
+
+	if db.recipes[i].User == asUser {
+		return db.recipes[i], nil
+	}
+
+	j := slices.IndexFunc(db.recipeSharing, func(s dbtypes.RecipeSharing) bool { return s.Recipe == id })
+	if j == -1 || !sharedWith(db.recipeSharing[j], asUser) {
+		return recipe.Recipe{}, fs.ErrNotExist
+	}
+
+	return db.recipes[i], nil
+}
+
+func (db *JSON) LookupRecipeSharing(owner string, id recipe.ID) (dbtypes.RecipeSharing, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if !slices.ContainsFunc(db.recipes, func(r recipe.Recipe) bool { return r.ID == id && r.User == owner }) {
+		return dbtypes.RecipeSharing{}, fs.ErrNotExist
+	}
+
+	i := slices.IndexFunc(db.recipeSharing, func(s dbtypes.RecipeSharing) bool { return s.Recipe == id })
+	if i == -1 {
+		// Not shared with anyone
+		return dbtypes.RecipeSharing{Recipe: id, Owner: owner}, nil
+	}
+
+	return db.recipeSharing[i], nil
+}
+
+func (db *JSON) SetRecipeSharing(s dbtypes.RecipeSharing) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if !slices.ContainsFunc(db.recipes, func(r recipe.Recipe) bool { return r.ID == s.Recipe && r.User == s.Owner }) {
+		return fmt.Errorf("recipe %d does not belong to user %s: %w", s.Recipe, s.Owner, fs.ErrNotExist)
+	}
+
+	s.Users = slices.Clone(s.Users)
+	slices.Sort(s.Users)
+	s.Users = slices.Compact(s.Users)
+	if len(s.Users) == 0 {
+		s.Users = nil
+	}
+
+	db.recipeSharing = slices.DeleteFunc(db.recipeSharing, func(x dbtypes.RecipeSharing) bool { return x.Recipe == s.Recipe })
+	if s.Public || len(s.Users) > 0 {
+		db.recipeSharing = append(db.recipeSharing, s)
+	}
 
 	if err := db.save(); err != nil {
 		return err
@@ -371,6 +749,10 @@ func (db *JSON) DeleteRecipe(asUser string, id recipe.ID) error {
 	return nil
 }
 
+func sharedWith(s dbtypes.RecipeSharing, user string) bool {
+	return s.Public || slices.Contains(s.Users, user)
+}
+
 func (db *JSON) Menus(user string) ([]dbtypes.Menu, error) {
 	db.mu.RLock()
 	defer db.mu.RUnlock()
@@ -424,6 +806,28 @@ func (db *JSON) SetMenu(m dbtypes.Menu) error {
 	return nil
 }
 
+func (db *JSON) CreateMenu(m dbtypes.Menu) error {
+	if m.User == "" {
+		return errors.New("user cannot be empty")
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if slices.ContainsFunc(db.menus, func(entry dbtypes.Menu) bool {
+		return entry.User == m.User && entry.Name == m.Name
+	}) {
+		return fs.ErrExist
+	}
+
+	db.menus = append(db.menus, m)
+
+	if err := db.save(); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (db *JSON) DeleteMenu(user, name string) error {
 	db.mu.Lock()
 	defer db.mu.Unlock()
@@ -438,6 +842,65 @@ func (db *JSON) DeleteMenu(user, name string) error {
 
 	db.menus = append(db.menus[:i], db.menus[i+1:]...)
 
+	// Shopping lists cannot exist without their menu
+	db.shoppingLists = slices.DeleteFunc(db.shoppingLists, func(l dbtypes.ShoppingList) bool {
+		return l.User == user && l.Menu == name
+	})
+	db.cookedMeals = slices.DeleteFunc(db.cookedMeals, func(c dbtypes.CookedMeal) bool {
+		return c.User == user && c.Menu == name
+	})
+
+	if err := db.save(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RenameMenu changes the name of a menu, keeping its shopping lists.
+// It returns fs.ErrNotExist if the menu does not exist, and fs.ErrExist if the new name is taken.
+func (db *JSON) RenameMenu(user, oldName, newName string) error {
+	if newName == "" {
+		return errors.New("name cannot be empty")
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	i := slices.IndexFunc(db.menus, func(p dbtypes.Menu) bool {
+		return p.User == user && p.Name == oldName
+	})
+
+	if i == -1 {
+		return fs.ErrNotExist
+	}
+
+	if slices.ContainsFunc(db.menus, func(p dbtypes.Menu) bool {
+		return p.User == user && p.Name == newName
+	}) {
+		return fs.ErrExist
+	}
+
+	db.menus[i].Name = newName
+
+	for j := range db.shoppingLists {
+		if db.shoppingLists[j].User == user && db.shoppingLists[j].Menu == oldName {
+			db.shoppingLists[j].Menu = newName
+		}
+	}
+
+	for j := range db.cookedMeals {
+		if db.cookedMeals[j].User == user && db.cookedMeals[j].Menu == oldName {
+			db.cookedMeals[j].Menu = newName
+		}
+	}
+
+	for j := range db.purchases {
+		if db.purchases[j].User == user && db.purchases[j].Menu == oldName {
+			db.purchases[j].Menu = newName
+		}
+	}
+
 	if err := db.save(); err != nil {
 		return err
 	}
@@ -503,8 +966,10 @@ func (db *JSON) SetPantry(p dbtypes.Pantry) error {
 	})
 
 	if i == -1 {
+		db.appendPantryChange(dbtypes.Pantry{}, p, dbtypes.PantryManual)
 		db.pantries = append(db.pantries, p)
 	} else {
+		db.appendPantryChange(db.pantries[i], p, dbtypes.PantryManual)
 		db.pantries[i] = p
 	}
 
@@ -514,31 +979,338 @@ func (db *JSON) SetPantry(p dbtypes.Pantry) error {
 	return nil
 }
 
-func (db *JSON) DeletePantry(user, name string) error {
+func (db *JSON) UpdatePantry(user, name string, ops []dbtypes.PantryOp) (dbtypes.Pantry, error) {
 	if user == "" {
-		return errors.New("user cannot be empty")
+		return dbtypes.Pantry{}, errors.New("user cannot be empty")
 	} else if name == "" {
-		return errors.New("name cannot be empty")
+		return dbtypes.Pantry{}, errors.New("name cannot be empty")
 	}
 
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
-	i := slices.IndexFunc(db.pantries, func(p dbtypes.Pantry) bool {
-		return p.User == user && p.Name == name
-	})
-
+	i := slices.IndexFunc(db.pantries, func(p dbtypes.Pantry) bool { return p.User == user && p.Name == name })
 	if i == -1 {
-		return nil
+		return dbtypes.Pantry{}, fs.ErrNotExist
 	}
 
-	db.pantries = append(db.pantries[:i], db.pantries[i+1:]...)
+	p := db.pantries[i]
+	p.Contents = slices.Clone(p.Contents)
+	p.Lots = slices.Clone(p.Lots)
+
+	if err := p.Apply(ops); err != nil {
+		return dbtypes.Pantry{}, err
+	}
+
+	db.appendPantryChange(db.pantries[i], p, dbtypes.PantryManual)
+	db.pantries[i] = p
 
 	if err := db.save(); err != nil {
-		return err
+		return dbtypes.Pantry{}, err
 	}
 
-	return nil
+	return p, nil
+}
+
+// appendPantryChange adds an entry to the ledger of the pantry, unless nothing changed.
+func (db *JSON) appendPantryChange(from, to dbtypes.Pantry, reason dbtypes.PantryReason) {
+	if dbtypes.PantryUnchanged(from, to) {
+		return
+	}
+
+	last := 0
+	for _, c := range db.pantryLedger {
+		if c.Snapshot.User == to.User && c.Snapshot.Name == to.Name {
+			last = max(last, c.Entry)
+		}
+	}
+
+	// The snapshot must not share memory with the live pantry
+	to.Contents = slices.Clone(to.Contents)
+	to.Lots = slices.Clone(to.Lots)
+
+	db.pantryLedger = append(db.pantryLedger, dbtypes.PantryChange{
+		Entry:     last + 1,
+		Author:    to.User,
+		Timestamp: time.Now().UTC().Truncate(time.Second),
+		Reason:    reason,
+		Deltas:    dbtypes.PantryDeltas(from, to),
+		Snapshot:  to,
+	})
+}
+
+func (db *JSON) PantryLedger(user, name string) ([]dbtypes.PantryChange, error) {
+	if user == "" {
+		return nil, errors.New("user cannot be empty")
+	} else if name == "" {
+		return nil, errors.New("name cannot be empty")
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if !slices.ContainsFunc(db.pantries, func(p dbtypes.Pantry) bool { return p.User == user && p.Name == name }) {
+		return nil, fs.ErrNotExist
+	}
+
+	out := make([]dbtypes.PantryChange, 0)
+	for _, c := range db.pantryLedger {
+		if c.Snapshot.User == user && c.Snapshot.Name == name {
+			out = append(out, c)
+		}
+	}
+
+	return out, nil
+}
+
+func (db *JSON) RollbackPantry(user, name string, entry int) error {
+	if user == "" {
+		return errors.New("user cannot be empty")
+	} else if name == "" {
+		return errors.New("name cannot be empty")
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	i := slices.IndexFunc(db.pantries, func(p dbtypes.Pantry) bool { return p.User == user && p.Name == name })
+	if i == -1 {
+		return fs.ErrNotExist
+	}
+
+	j := slices.IndexFunc(db.pantryLedger, func(c dbtypes.PantryChange) bool {
+		return c.Snapshot.User == user && c.Snapshot.Name == name && c.Entry == entry
+	})
+	if j == -1 {
+		return fs.ErrNotExist
+	}
+
+	ledger := slices.DeleteFunc(slices.Clone(db.pantryLedger), func(c dbtypes.PantryChange) bool {
+		return c.Snapshot.User != user || c.Snapshot.Name != name
+	})
+
+	if dbtypes.CookedAfter(ledger, entry) && slices.ContainsFunc(db.cookedMeals, func(m dbtypes.CookedMeal) bool {
+		return m.User == user && m.Pantry == name
+	}) {
+		return fs.ErrExist
+	}
+
+	p := db.pantryLedger[j].Snapshot
+	p.Contents = slices.Clone(p.Contents)
+	p.Lots = slices.Clone(p.Lots)
+
+	db.appendPantryChange(db.pantries[i], p, dbtypes.PantryRollback)
+	db.pantries[i] = p
+
+	if err := db.save(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (db *JSON) DeletePantry(user, name string) error {
+	if user == "" {
+		return errors.New("user cannot be empty")
+	} else if name == "" {
+		return errors.New("name cannot be empty")
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	i := slices.IndexFunc(db.pantries, func(p dbtypes.Pantry) bool {
+		return p.User == user && p.Name == name
+	})
+
+	if i == -1 {
+		return nil
+	}
+
+	db.pantries = append(db.pantries[:i], db.pantries[i+1:]...)
+	db.pantryLedger = slices.DeleteFunc(db.pantryLedger, func(c dbtypes.PantryChange) bool {
+		return c.Snapshot.User == user && c.Snapshot.Name == name
+	})
+	db.pantryStaples = slices.DeleteFunc(db.pantryStaples, func(s dbtypes.PantryStaples) bool {
+		return s.User == user && s.Pantry == name
+	})
+	for j := range db.pantryGroups {
+		if db.pantryGroups[j].User == user {
+			db.pantryGroups[j].Pantries = slices.DeleteFunc(slices.Clone(db.pantryGroups[j].Pantries), func(p string) bool { return p == name })
+		}
+	}
+
+	// A group without pantries is not valid, so the groups left empty go away
+	db.pantryGroups = slices.DeleteFunc(db.pantryGroups, func(g dbtypes.PantryGroup) bool {
+		return g.User == user && len(g.Pantries) == 0
+	})
+
+	// The stock of cooked meals cannot be put back without their pantry
+	db.cookedMeals = slices.DeleteFunc(db.cookedMeals, func(c dbtypes.CookedMeal) bool {
+		return c.User == user && c.Pantry == name
+	})
+
+	if err := db.save(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (db *JSON) PantryStaples(user, pantry string) (dbtypes.PantryStaples, error) {
+	if user == "" {
+		return dbtypes.PantryStaples{}, errors.New("user cannot be empty")
+	} else if pantry == "" {
+		return dbtypes.PantryStaples{}, errors.New("pantry cannot be empty")
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if !slices.ContainsFunc(db.pantries, func(p dbtypes.Pantry) bool { return p.User == user && p.Name == pantry }) {
+		return dbtypes.PantryStaples{}, fs.ErrNotExist
+	}
+
+	out := dbtypes.PantryStaples{
+		User:    user,
+		Pantry:  pantry,
+		Staples: make([]dbtypes.Staple, 0),
+	}
+
+	i := slices.IndexFunc(db.pantryStaples, func(s dbtypes.PantryStaples) bool { return s.User == user && s.Pantry == pantry })
+	if i != -1 {
+		out.Staples = slices.Clone(db.pantryStaples[i].Staples)
+	}
+
+	return out, nil
+}
+
+func (db *JSON) SetPantryStaples(s dbtypes.PantryStaples) error {
+	if s.User == "" {
+		return errors.New("user cannot be empty")
+	} else if s.Pantry == "" {
+		return errors.New("pantry cannot be empty")
+	} else if err := s.Validate(); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if !slices.ContainsFunc(db.pantries, func(p dbtypes.Pantry) bool { return p.User == s.User && p.Name == s.Pantry }) {
+		return fs.ErrNotExist
+	}
+
+	s.Staples = slices.Clone(s.Staples)
+	slices.SortFunc(s.Staples, func(a, b dbtypes.Staple) int { return cmp.Compare(a.ProductID, b.ProductID) })
+
+	db.pantryStaples = slices.DeleteFunc(db.pantryStaples, func(entry dbtypes.PantryStaples) bool {
+		return entry.User == s.User && entry.Pantry == s.Pantry
+	})
+
+	if len(s.Staples) != 0 {
+		db.pantryStaples = append(db.pantryStaples, s)
+	}
+
+	if err := db.save(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (db *JSON) PantryGroups(user string) ([]dbtypes.PantryGroup, error) {
+	if user == "" {
+		return nil, errors.New("user cannot be empty")
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	out := make([]dbtypes.PantryGroup, 0)
+	for _, g := range db.pantryGroups {
+		if g.User == user {
+			out = append(out, g)
+		}
+	}
+
+	return out, nil
+}
+
+func (db *JSON) LookupPantryGroup(user, name string) (dbtypes.PantryGroup, error) {
+	if user == "" {
+		return dbtypes.PantryGroup{}, errors.New("user cannot be empty")
+	} else if name == "" {
+		return dbtypes.PantryGroup{}, errors.New("name cannot be empty")
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	i := slices.IndexFunc(db.pantryGroups, func(g dbtypes.PantryGroup) bool { return g.User == user && g.Name == name })
+	if i == -1 {
+		return dbtypes.PantryGroup{}, fs.ErrNotExist
+	}
+
+	return db.pantryGroups[i], nil
+}
+
+func (db *JSON) SetPantryGroup(g dbtypes.PantryGroup) error {
+	if g.User == "" {
+		return errors.New("user cannot be empty")
+	} else if g.Name == "" {
+		return errors.New("name cannot be empty")
+	} else if err := g.Validate(); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, p := range g.Pantries {
+		if !slices.ContainsFunc(db.pantries, func(entry dbtypes.Pantry) bool { return entry.User == g.User && entry.Name == p }) {
+			return fmt.Errorf("pantry %q: %w", p, fs.ErrNotExist)
+		}
+	}
+
+	g.Pantries = slices.Clone(g.Pantries)
+
+	i := slices.IndexFunc(db.pantryGroups, func(entry dbtypes.PantryGroup) bool { return entry.User == g.User && entry.Name == g.Name })
+	if i == -1 {
+		db.pantryGroups = append(db.pantryGroups, g)
+	} else {
+		db.pantryGroups[i] = g
+	}
+
+	if err := db.save(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (db *JSON) DeletePantryGroup(user, name string) error {
+	if user == "" {
+		return errors.New("user cannot be empty")
+	} else if name == "" {
+		return errors.New("name cannot be empty")
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	i := slices.IndexFunc(db.pantryGroups, func(g dbtypes.PantryGroup) bool { return g.User == user && g.Name == name })
+	if i == -1 {
+		return nil
+	}
+
+	db.pantryGroups = append(db.pantryGroups[:i], db.pantryGroups[i+1:]...)
+
+	if err := db.save(); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 func (db *JSON) ShoppingLists(user string) ([]dbtypes.ShoppingList, error) {
@@ -606,6 +1378,55 @@ func (db *JSON) SetShoppingList(p dbtypes.ShoppingList) error {
 	return nil
 }
 
+func (db *JSON) UpdateShoppingList(user, menu, pantry string, ops []dbtypes.ShoppingListOp) (dbtypes.ShoppingList, error) {
+	if user == "" {
+		return dbtypes.ShoppingList{}, errors.New("user cannot be empty")
+	} else if menu == "" {
+		return dbtypes.ShoppingList{}, errors.New("menu cannot be empty")
+	} else if pantry == "" {
+		return dbtypes.ShoppingList{}, errors.New("pantry cannot be empty")
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	l := db.updateShoppingList(user, menu, pantry, ops)
+
+	if err := db.save(); err != nil {
+		return dbtypes.ShoppingList{}, err
+	}
+
+	return l, nil
+}
+
+// updateShoppingList applies the operations to a shopping list and returns the list after them.
+// The caller must hold the lock.
+func (db *JSON) updateShoppingList(user, menu, pantry string, ops []dbtypes.ShoppingListOp) dbtypes.ShoppingList {
+	l := dbtypes.ShoppingList{User: user, Menu: menu, Pantry: pantry, Contents: make([]product.ID, 0)}
+
+	i := slices.IndexFunc(db.shoppingLists, func(p dbtypes.ShoppingList) bool {
+		return p.User == user && p.Menu == menu && p.Pantry == pantry
+	})
+
+	if i != -1 {
+		l.Contents = slices.Clone(db.shoppingLists[i].Contents)
+	}
+
+	l.Apply(ops)
+
+	// Empty lists are not stored, like in the other databases
+	switch {
+	case i == -1 && len(l.Contents) > 0:
+		db.shoppingLists = append(db.shoppingLists, l)
+	case i != -1 && len(l.Contents) > 0:
+		db.shoppingLists[i] = l
+	case i != -1:
+		db.shoppingLists = slices.Delete(db.shoppingLists, i, i+1)
+	}
+
+	return l
+}
+
 func (db *JSON) DeleteShoppingList(user, menu, pantry string) error {
 	if user == "" {
 		return errors.New("user cannot be empty")
@@ -635,6 +1456,209 @@ func (db *JSON) DeleteShoppingList(user, menu, pantry string) error {
 	return nil
 }
 
+func (db *JSON) CookedMeals(user, menu string) ([]dbtypes.CookedMeal, error) {
+	if user == "" {
+		return nil, errors.New("user cannot be empty")
+	} else if menu == "" {
+		return nil, errors.New("menu cannot be empty")
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	out := make([]dbtypes.CookedMeal, 0)
+	for _, c := range db.cookedMeals {
+		if c.User == user && c.Menu == menu {
+			out = append(out, c)
+		}
+	}
+
+	return out, nil
+}
+
+func (db *JSON) CookMeals(user, menu, pantry string, cooked time.Time, meals []dbtypes.MealToCook) ([]dbtypes.CookedMeal, error) {
+	if user == "" {
+		return nil, errors.New("user cannot be empty")
+	} else if menu == "" {
+		return nil, errors.New("menu cannot be empty")
+	} else if pantry == "" {
+		return nil, errors.New("pantry cannot be empty")
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	i := slices.IndexFunc(db.pantries, func(entry dbtypes.Pantry) bool {
+		return entry.User == user && entry.Name == pantry
+	})
+
+	if i == -1 {
+		return nil, fs.ErrNotExist
+	}
+
+	for _, m := range meals {
+		if slices.ContainsFunc(db.cookedMeals, func(c dbtypes.CookedMeal) bool {
+			return c.User == user && c.Menu == menu && c.Meal == m.Meal
+		}) {
+			return nil, fs.ErrExist
+		}
+	}
+
+	p := db.pantries[i]
+	p.Contents = slices.Clone(p.Contents)
+	p.Lots = slices.Clone(p.Lots)
+
+	today := dbtypes.NewDate(cooked)
+	records := make([]dbtypes.CookedMeal, 0, len(meals))
+	for _, m := range meals {
+		_, used := dbtypes.Consume(m.Need, p.Stock(), &today)
+		p.Take(used)
+
+		if used == nil {
+			used = make([]dbtypes.PantryLot, 0)
+		}
+
+		records = append(records, dbtypes.CookedMeal{
+			User:   user,
+			Menu:   menu,
+			Meal:   m.Meal,
+			Pantry: pantry,
+			Cooked: cooked,
+			Used:   used,
+		})
+	}
+
+	db.appendPantryChange(db.pantries[i], p, dbtypes.PantryCooked)
+	db.pantries[i] = p
+	db.cookedMeals = append(db.cookedMeals, records...)
+
+	if err := db.save(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func (db *JSON) UncookMeals(user, menu string, meals []dbtypes.MealRef) error {
+	if user == "" {
+		return errors.New("user cannot be empty")
+	} else if menu == "" {
+		return errors.New("menu cannot be empty")
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	isUncooked := func(c dbtypes.CookedMeal) bool {
+		return c.User == user && c.Menu == menu && slices.Contains(meals, c.Meal)
+	}
+
+	if !slices.ContainsFunc(db.cookedMeals, isUncooked) {
+		return fs.ErrNotExist
+	}
+
+	// The stock goes back to the pantry it was taken from
+	for i, p := range db.pantries {
+		if p.User != user {
+			continue
+		}
+
+		p.Contents = slices.Clone(p.Contents)
+		p.Lots = slices.Clone(p.Lots)
+
+		for _, c := range db.cookedMeals {
+			if isUncooked(c) && c.Pantry == p.Name {
+				p.Add(c.Used)
+			}
+		}
+
+		db.appendPantryChange(db.pantries[i], p, dbtypes.PantryUncooked)
+		db.pantries[i] = p
+	}
+
+	db.cookedMeals = slices.DeleteFunc(db.cookedMeals, isUncooked)
+
+	if err := db.save(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (db *JSON) Purchases(user, menu, pantry string) ([]dbtypes.Purchase, error) {
+	if user == "" {
+		return nil, errors.New("user cannot be empty")
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	out := make([]dbtypes.Purchase, 0)
+	for _, p := range db.purchases {
+		if p.User == user && p.Menu == menu && p.Pantry == pantry {
+			out = append(out, p)
+		}
+	}
+
+	return out, nil
+}
+
+func (db *JSON) AddPurchase(p dbtypes.Purchase, lots []dbtypes.PantryLot, updatePrices bool) (dbtypes.ShoppingList, error) {
+	if p.User == "" {
+		return dbtypes.ShoppingList{}, errors.New("user cannot be empty")
+	} else if p.Menu == "" {
+		return dbtypes.ShoppingList{}, errors.New("menu cannot be empty")
+	} else if p.Pantry == "" {
+		return dbtypes.ShoppingList{}, errors.New("pantry cannot be empty")
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	i := slices.IndexFunc(db.pantries, func(entry dbtypes.Pantry) bool {
+		return entry.User == p.User && entry.Name == p.Pantry
+	})
+
+	if i == -1 {
+		return dbtypes.ShoppingList{}, fs.ErrNotExist
+	}
+
+	pantry := db.pantries[i]
+	pantry.Contents = slices.Clone(pantry.Contents)
+	pantry.Lots = slices.Clone(pantry.Lots)
+	pantry.Add(lots)
+
+	db.appendPantryChange(db.pantries[i], pantry, dbtypes.PantryPurchased)
+	db.pantries[i] = pantry
+	db.purchases = append(db.purchases, p)
+
+	// The products bought are now in the pantry, so they are no longer marked done
+	ops := make([]dbtypes.ShoppingListOp, 0, len(p.Items))
+	for _, item := range p.Items {
+		ops = append(ops, dbtypes.ShoppingListOp{ProductID: item.ProductID, Done: false})
+	}
+
+	list := db.updateShoppingList(p.User, p.Menu, p.Pantry, ops)
+
+	if updatePrices {
+		for _, item := range p.Items {
+			j := slices.IndexFunc(db.products, func(entry product.Product) bool { return entry.ID == item.ProductID })
+			if j == -1 {
+				continue
+			}
+
+			db.products[j].Price = item.Price
+			db.products[j].PriceUpdated = p.Date
+		}
+	}
+
+	if err := db.save(); err != nil {
+		return dbtypes.ShoppingList{}, err
+	}
+
+	return list, nil
+}
+
 func load(path string, ptr interface{}) error {
 	out, err := os.ReadFile(path)
 	if errors.Is(err, os.ErrNotExist) {
@@ -656,22 +1680,48 @@ func load(path string, ptr interface{}) error {
 func (db *JSON) save() error {
 	slices.SortFunc(db.users, strings.Compare)
 	slices.SortFunc(db.sessions, func(a, b dbtypes.Session) int { return cmp.Compare(a.ID, b.ID) })
+	slices.SortFunc(db.calendarFeeds, func(a, b dbtypes.CalendarFeed) int { return cmp.Compare(a.User, b.User) })
 	slices.SortFunc(db.products, func(a, b product.Product) int { return cmp.Compare(a.ID, b.ID) })
 	slices.SortFunc(db.recipes, func(a, b recipe.Recipe) int { return cmp.Compare(a.ID, b.ID) })
+	slices.SortFunc(db.recipeSharing, func(a, b dbtypes.RecipeSharing) int { return cmp.Compare(a.Recipe, b.Recipe) })
+	slices.SortFunc(db.revisions, func(a, b dbtypes.RecipeRevision) int {
+		return multiCompare(c(a.Recipe.ID, b.Recipe.ID), c(a.Revision, b.Revision))
+	})
 	slices.SortFunc(db.menus, func(a, b dbtypes.Menu) int { return multiCompare(c(a.User, b.User), c(a.Name, b.Name)) })
 	slices.SortFunc(db.pantries, func(a, b dbtypes.Pantry) int { return multiCompare(c(a.User, b.User), c(a.Name, b.Name)) })
+	slices.SortFunc(db.pantryLedger, func(a, b dbtypes.PantryChange) int {
+		return multiCompare(c(a.Snapshot.User, b.Snapshot.User), c(a.Snapshot.Name, b.Snapshot.Name), c(a.Entry, b.Entry))
+	})
+	slices.SortFunc(db.pantryStaples, func(a, b dbtypes.PantryStaples) int {
+		return multiCompare(c(a.User, b.User), c(a.Pantry, b.Pantry))
+	})
+	slices.SortFunc(db.pantryGroups, func(a, b dbtypes.PantryGroup) int { return multiCompare(c(a.User, b.User), c(a.Name, b.Name)) })
 	slices.SortFunc(db.shoppingLists, func(a, b dbtypes.ShoppingList) int {
 		return multiCompare(c(a.User, b.User), c(a.Menu, b.Menu), c(a.Pantry, b.Pantry))
 	})
+	slices.SortFunc(db.cookedMeals, func(a, b dbtypes.CookedMeal) int {
+		return multiCompare(c(a.User, b.User), c(a.Menu, b.Menu), c(a.Meal.Day, b.Meal.Day), c(a.Meal.Meal, b.Meal.Meal))
+	})
+	slices.SortStableFunc(db.purchases, func(a, b dbtypes.Purchase) int {
+		return multiCompare(c(a.User, b.User), func() int { return a.Date.Compare(b.Date) })
+	})
 
 	return errors.Join(
 		save(db.log, db.usersPath, db.users),
 		save(db.log, db.sesionsPath, db.sessions),
+		save(db.log, db.calendarFeedsPath, db.calendarFeeds),
 		save(db.log, db.productsPath, db.products),
 		save(db.log, db.recipesPath, db.recipes),
+		save(db.log, db.recipeSharingPath, db.recipeSharing),
+		save(db.log, db.revisionsPath, db.revisions),
 		save(db.log, db.menusPath, db.menus),
 		save(db.log, db.pantriesPath, db.pantries),
+		save(db.log, db.pantryLedgerPath, db.pantryLedger),
+		save(db.log, db.pantryStaplesPath, db.pantryStaples),
+		save(db.log, db.pantryGroupsPath, db.pantryGroups),
 		save(db.log, db.shoppingListsPath, db.shoppingLists),
+		save(db.log, db.cookedMealsPath, db.cookedMeals),
+		save(db.log, db.purchasesPath, db.purchases),
 	)
 }
 
@@ -745,8 +1795,9 @@ func (b backup) remove(log logger.Logger) {
 	}
 }
 
+// removeIf removes the elements for which the predicate is true, keeping the order of the rest.
 func removeIf[T any](slice []T, predicate func(T) bool) []T {
-	return slice[:partition(slice, predicate)]
+	return slice[:partition(slice, func(x T) bool { return !predicate(x) })]
 }
 
 func partition[T any](slice []T, predicate func(T) bool) (p int) {
@@ -22,11 +22,22 @@ func TestJSONDB(t *testing.T) {
 	t.Parallel()
 
 	testCases := map[string]func(*testing.T, func() database.DB){
-		"Products": dbtestutils.ProductsTest,
-		"Recipes":  dbtestutils.RecipesTest,
-		"Menus":    dbtestutils.MenuTest,
-		"Pantries": dbtestutils.PantriesTest,
-		"Shopping": dbtestutils.ShoppingListsTest,
+		"Calendar":    dbtestutils.CalendarFeedsTest,
+		"Products":    dbtestutils.ProductsTest,
+		"Recipes":     dbtestutils.RecipesTest,
+		"Sharing":     dbtestutils.RecipeSharingTest,
+		"DeleteUser":  dbtestutils.DeleteUserTest,
+		"History":     dbtestutils.RecipeRevisionsTest,
+		"Menus":       dbtestutils.MenuTest,
+		"Pantries":    dbtestutils.PantriesTest,
+		"Shopping":    dbtestutils.ShoppingListsTest,
+		"Cooked":      dbtestutils.CookedMealsTest,
+		"Bought":      dbtestutils.PurchasesTest,
+		"Ledger":      dbtestutils.PantryLedgerTest,
+		"PantryOps":   dbtestutils.PantryUpdatesTest,
+		"Staples":     dbtestutils.PantryStaplesTest,
+		"Groups":      dbtestutils.PantryGroupsTest,
+		"ShoppingOps": dbtestutils.ShoppingListUpdatesTest,
 	}
 
 	for name, test := range testCases {
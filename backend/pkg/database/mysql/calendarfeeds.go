@@ -0,0 +1,97 @@
+package mysql
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+)
+
+var calendarFeedTables = []tableDef{
+	{
+		name: "calendar_feeds",
+		columns: []string{
+			"user VARCHAR(255) NOT NULL",
+			"token VARCHAR(255) NOT NULL",
+			"PRIMARY KEY (user)",
+			"UNIQUE (token)",
+			"FOREIGN KEY (user) REFERENCES users(id) ON DELETE CASCADE",
+		},
+	},
+}
+
+func (s *SQL) LookupCalendarFeed(token string) (dbtypes.CalendarFeed, error) {
+	if token == "" {
+		return dbtypes.CalendarFeed{}, fs.ErrNotExist
+	}
+
+	return s.lookupCalendarFeed("SELECT user, token FROM calendar_feeds WHERE token = ?", token)
+}
+
+func (s *SQL) LookupUserCalendarFeed(user string) (dbtypes.CalendarFeed, error) {
+	return s.lookupCalendarFeed("SELECT user, token FROM calendar_feeds WHERE user = ?", user)
+}
+
+func (s *SQL) lookupCalendarFeed(query string, arg string) (dbtypes.CalendarFeed, error) {
+	s.log.Trace(query)
+
+	var f dbtypes.CalendarFeed
+	err := s.db.QueryRowContext(s.ctx, query, arg).Scan(&f.User, &f.Token)
+	if errorIs(err, errKeyNotFound) {
+		return dbtypes.CalendarFeed{}, fs.ErrNotExist
+	} else if err != nil {
+		return dbtypes.CalendarFeed{}, fmt.Errorf("could not lookup calendar feed: %v", err)
+	}
+
+	return f, nil
+}
+
+func (s *SQL) SetCalendarFeed(f dbtypes.CalendarFeed) error {
+	if f.User == "" {
+		return errors.New("user cannot be empty")
+	} else if f.Token == "" {
+		return errors.New("token cannot be empty")
+	}
+
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %v", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
+
+	// REPLACE would silently delete the feed of another user with the same token
+	query := "SELECT COUNT(*) FROM calendar_feeds WHERE token = ? AND user != ?"
+	s.log.Trace(query)
+
+	var count int
+	if err := tx.QueryRowContext(s.ctx, query, f.Token, f.User).Scan(&count); err != nil {
+		return fmt.Errorf("could not query calendar feeds: %v", err)
+	} else if count > 0 {
+		return fs.ErrExist
+	}
+
+	query = "REPLACE INTO calendar_feeds (user, token) VALUES (?, ?)"
+	s.log.Trace(query)
+
+	if _, err := tx.ExecContext(s.ctx, query, f.User, f.Token); err != nil {
+		return fmt.Errorf("could not set calendar feed: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SQL) DeleteCalendarFeed(user string) error {
+	query := "DELETE FROM calendar_feeds WHERE user = ?"
+	s.log.Trace(query)
+
+	if _, err := s.db.ExecContext(s.ctx, query, user); err != nil {
+		return fmt.Errorf("could not delete calendar feed: %v", err)
+	}
+
+	return nil
+}
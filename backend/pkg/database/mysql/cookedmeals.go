@@ -0,0 +1,342 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"time"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+)
+
+var cookedMealTables = []tableDef{
+	{
+		// Cooked meals do not reference the menu, because saving a menu replaces it.
+		name: "cooked_meals",
+		columns: []string{
+			"user VARCHAR(255) NOT NULL",
+			"menu VARCHAR(255) NOT NULL",
+			"day INT NOT NULL",
+			"meal INT NOT NULL",
+			"pantry VARCHAR(255) NOT NULL",
+			"cooked DATETIME NOT NULL",
+			"FOREIGN KEY (user) REFERENCES users(id) ON DELETE CASCADE",
+			"FOREIGN KEY (user, pantry) REFERENCES pantries(user, name) ON DELETE CASCADE",
+			"PRIMARY KEY (user, menu, day, meal)",
+		},
+	},
+	{
+		name: "cooked_meal_lots",
+		columns: []string{
+			"user VARCHAR(255) NOT NULL",
+			"menu VARCHAR(255) NOT NULL",
+			"day INT NOT NULL",
+			"meal INT NOT NULL",
+			"pos INT NOT NULL",
+			"product INT UNSIGNED NOT NULL",
+			"amount FLOAT NOT NULL",
+			"purchased DATE",
+			"best_before DATE",
+			"FOREIGN KEY (user) REFERENCES users(id) ON DELETE CASCADE",
+			"FOREIGN KEY (product) REFERENCES products(id) ON DELETE CASCADE",
+			"FOREIGN KEY (user, menu, day, meal) REFERENCES cooked_meals(user, menu, day, meal) ON DELETE CASCADE ON UPDATE CASCADE",
+			"PRIMARY KEY (user, menu, day, meal, pos)",
+		},
+	},
+}
+
+func (s *SQL) CookedMeals(user, menu string) ([]dbtypes.CookedMeal, error) {
+	if user == "" {
+		return nil, errors.New("user cannot be empty")
+	} else if menu == "" {
+		return nil, errors.New("menu cannot be empty")
+	}
+
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not begin transaction: %v", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
+
+	meals, err := s.queryCookedMeals(tx, user, menu)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.queryCookedMealLots(tx, user, menu, meals); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return meals, nil
+}
+
+func (s *SQL) queryCookedMeals(tx *sql.Tx, user, menu string) ([]dbtypes.CookedMeal, error) {
+	query := `
+		SELECT
+			day, meal, pantry, cooked
+		FROM
+			cooked_meals
+		WHERE
+			user = ? AND menu = ?
+		ORDER BY
+			day, meal`
+	s.log.Trace(query)
+
+	r, err := tx.QueryContext(s.ctx, query, user, menu)
+	if err != nil {
+		return nil, fmt.Errorf("could not query cooked meals: %v", err)
+	}
+	defer r.Close()
+
+	meals := make([]dbtypes.CookedMeal, 0)
+	for r.Next() {
+		c := dbtypes.CookedMeal{
+			User: user,
+			Menu: menu,
+			Used: make([]dbtypes.PantryLot, 0),
+		}
+
+		var cooked string
+		if err := r.Scan(&c.Meal.Day, &c.Meal.Meal, &c.Pantry, &cooked); err != nil {
+			return nil, fmt.Errorf("could not scan cooked meal: %v", err)
+		}
+
+		if c.Cooked, err = time.ParseInLocation(dateTimeLayout, cooked, time.UTC); err != nil {
+			return nil, fmt.Errorf("could not parse cooking time: %v", err)
+		}
+
+		meals = append(meals, c)
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, fmt.Errorf("could not get cooked meals: %v", err)
+	}
+
+	return meals, nil
+}
+
+// queryCookedMealLots fills in the lots used by every meal.
+func (s *SQL) queryCookedMealLots(tx *sql.Tx, user, menu string, meals []dbtypes.CookedMeal) error {
+	query := `
+		SELECT
+			day, meal, product, amount, purchased, best_before
+		FROM
+			cooked_meal_lots
+		WHERE
+			user = ? AND menu = ?
+		ORDER BY
+			day, meal, pos`
+	s.log.Trace(query)
+
+	r, err := tx.QueryContext(s.ctx, query, user, menu)
+	if err != nil {
+		return fmt.Errorf("could not query cooked meal lots: %v", err)
+	}
+	defer r.Close()
+
+	idx := make(map[dbtypes.MealRef]int, len(meals))
+	for i, c := range meals {
+		idx[c.Meal] = i
+	}
+
+	for r.Next() {
+		var ref dbtypes.MealRef
+		var lot dbtypes.PantryLot
+		var purchased, bestBefore sql.NullString
+		if err := r.Scan(&ref.Day, &ref.Meal, &lot.ProductID, &lot.Amount, &purchased, &bestBefore); err != nil {
+			return fmt.Errorf("could not scan cooked meal lot: %v", err)
+		}
+
+		if lot.Purchased, err = parseNullDate(purchased); err != nil {
+			return fmt.Errorf("could not parse purchase date: %v", err)
+		}
+
+		if lot.BestBefore, err = parseNullDate(bestBefore); err != nil {
+			return fmt.Errorf("could not parse best-before date: %v", err)
+		}
+
+		i, ok := idx[ref]
+		if !ok {
+			s.log.Warningf("Cooked meal lot for day %d meal %d of menu %q has no cooked meal", ref.Day, ref.Meal, menu)
+			continue
+		}
+
+		meals[i].Used = append(meals[i].Used, lot)
+	}
+
+	if err := r.Err(); err != nil {
+		return fmt.Errorf("could not get cooked meal lots: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SQL) CookMeals(user, menu, pantry string, cooked time.Time, meals []dbtypes.MealToCook) ([]dbtypes.CookedMeal, error) {
+	if user == "" {
+		return nil, errors.New("user cannot be empty")
+	} else if menu == "" {
+		return nil, errors.New("menu cannot be empty")
+	} else if pantry == "" {
+		return nil, errors.New("pantry cannot be empty")
+	}
+
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not begin transaction: %v", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
+
+	p, err := s.lockPantry(tx, user, pantry)
+	if err != nil {
+		return nil, err
+	}
+
+	today := dbtypes.NewDate(cooked)
+	records := make([]dbtypes.CookedMeal, 0, len(meals))
+	for _, m := range meals {
+		_, used := dbtypes.Consume(m.Need, p.Stock(), &today)
+		p.Take(used)
+
+		if used == nil {
+			used = make([]dbtypes.PantryLot, 0)
+		}
+
+		records = append(records, dbtypes.CookedMeal{
+			User:   user,
+			Menu:   menu,
+			Meal:   m.Meal,
+			Pantry: pantry,
+			Cooked: cooked,
+			Used:   used,
+		})
+	}
+
+	if err := s.setPantry(tx, p, dbtypes.PantryCooked); err != nil {
+		return nil, err
+	}
+
+	for _, c := range records {
+		// The primary key rejects meals that were already cooked, even from another pantry
+		_, err := tx.ExecContext(s.ctx, `
+			INSERT INTO
+				cooked_meals (user, menu, day, meal, pantry, cooked)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			c.User, c.Menu, c.Meal.Day, c.Meal.Meal, c.Pantry, c.Cooked.UTC().Format(dateTimeLayout))
+		if errorIs(err, errKeyExists) {
+			return nil, fs.ErrExist
+		} else if err != nil {
+			return nil, fmt.Errorf("could not insert cooked meal: %v", err)
+		}
+
+		type lotRow struct {
+			pos int
+			dbtypes.PantryLot
+		}
+
+		lots := make([]lotRow, 0, len(c.Used))
+		for i, l := range c.Used {
+			lots = append(lots, lotRow{pos: i, PantryLot: l})
+		}
+
+		err = bulkInsert(s, tx,
+			"cooked_meal_lots (user, menu, day, meal, pos, product, amount, purchased, best_before)",
+			lots,
+			func(l lotRow) []any {
+				return []any{c.User, c.Menu, c.Meal.Day, c.Meal.Meal, l.pos, l.ProductID, l.Amount, formatNullDate(l.Purchased), formatNullDate(l.BestBefore)}
+			})
+		if err != nil {
+			return nil, fmt.Errorf("could not insert cooked meal lots: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return records, nil
+}
+
+func (s *SQL) UncookMeals(user, menu string, meals []dbtypes.MealRef) error {
+	if user == "" {
+		return errors.New("user cannot be empty")
+	} else if menu == "" {
+		return errors.New("menu cannot be empty")
+	}
+
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %v", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
+
+	// Locking the records makes concurrent requests to uncook the same meals wait for each other
+	var count int
+	q := `SELECT COUNT(*) FROM cooked_meals WHERE user = ? AND menu = ? FOR UPDATE`
+	s.log.Trace(q)
+
+	if err := tx.QueryRowContext(s.ctx, q, user, menu).Scan(&count); err != nil {
+		return fmt.Errorf("could not query cooked meals: %v", err)
+	}
+
+	cooked, err := s.queryCookedMeals(tx, user, menu)
+	if err != nil {
+		return err
+	}
+
+	if err := s.queryCookedMealLots(tx, user, menu, cooked); err != nil {
+		return err
+	}
+
+	cooked = slices.DeleteFunc(cooked, func(c dbtypes.CookedMeal) bool { return !slices.Contains(meals, c.Meal) })
+	if len(cooked) == 0 {
+		return fs.ErrNotExist
+	}
+
+	// The stock goes back to the pantry it was taken from
+	pantries := make([]string, 0)
+	for _, c := range cooked {
+		if !slices.Contains(pantries, c.Pantry) {
+			pantries = append(pantries, c.Pantry)
+		}
+	}
+	slices.Sort(pantries)
+
+	for _, name := range pantries {
+		p, err := s.lockPantry(tx, user, name)
+		if err != nil {
+			return fmt.Errorf("could not get pantry %q: %v", name, err)
+		}
+
+		for _, c := range cooked {
+			if c.Pantry == name {
+				p.Add(c.Used)
+			}
+		}
+
+		if err := s.setPantry(tx, p, dbtypes.PantryUncooked); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range cooked {
+		if _, err := tx.ExecContext(s.ctx, `
+			DELETE FROM
+				cooked_meals
+			WHERE
+				user = ? AND menu = ? AND day = ? AND meal = ?`, c.User, c.Menu, c.Meal.Day, c.Meal.Meal); err != nil {
+			return fmt.Errorf("could not delete cooked meal: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return nil
+}
@@ -20,6 +20,28 @@ var menuTables = []tableDef{
 			"PRIMARY KEY (user, name)",
 		},
 	},
+	{
+		name: "menu_dates",
+		columns: []string{
+			"user VARCHAR(255) NOT NULL",
+			"menu VARCHAR(255) NOT NULL",
+			"start_date DATE NOT NULL",
+			"FOREIGN KEY (user) REFERENCES users(id) ON DELETE CASCADE",
+			"FOREIGN KEY (user, menu) REFERENCES menus(user, name) ON DELETE CASCADE",
+			"PRIMARY KEY (user, menu)",
+		},
+	},
+	{
+		name: "menu_households",
+		columns: []string{
+			"user VARCHAR(255) NOT NULL",
+			"menu VARCHAR(255) NOT NULL",
+			"size INT NOT NULL",
+			"FOREIGN KEY (user) REFERENCES users(id) ON DELETE CASCADE",
+			"FOREIGN KEY (user, menu) REFERENCES menus(user, name) ON DELETE CASCADE",
+			"PRIMARY KEY (user, menu)",
+		},
+	},
 	{
 		name: "menu_days",
 		columns: []string{
@@ -45,6 +67,19 @@ var menuTables = []tableDef{
 			"PRIMARY KEY (user, menu, day, pos)",
 		},
 	},
+	{
+		name: "menu_meal_attendees",
+		columns: []string{
+			"user VARCHAR(255) NOT NULL",
+			"menu VARCHAR(255) NOT NULL",
+			"day INT NOT NULL",
+			"meal INT NOT NULL",
+			"attendees INT NOT NULL",
+			"FOREIGN KEY (user) REFERENCES users(id) ON DELETE CASCADE",
+			"FOREIGN KEY (user, menu, day, meal) REFERENCES menu_meals(user, menu, day, pos) ON DELETE CASCADE",
+			"PRIMARY KEY (user, menu, day, meal)",
+		},
+	},
 	{
 		name: "menu_dishes",
 		columns: []string{
@@ -61,6 +96,21 @@ var menuTables = []tableDef{
 			"PRIMARY KEY (user, menu, day, meal, pos)",
 		},
 	},
+	{
+		name: "menu_dish_leftovers",
+		columns: []string{
+			"user VARCHAR(255) NOT NULL",
+			"menu VARCHAR(255) NOT NULL",
+			"day INT NOT NULL",
+			"meal INT NOT NULL",
+			"pos INT NOT NULL",
+			"from_day INT NOT NULL",
+			"from_meal INT NOT NULL",
+			"FOREIGN KEY (user) REFERENCES users(id) ON DELETE CASCADE",
+			"FOREIGN KEY (user, menu, day, meal, pos) REFERENCES menu_dishes(user, menu, day, meal, pos) ON DELETE CASCADE",
+			"PRIMARY KEY (user, menu, day, meal, pos)",
+		},
+	},
 }
 
 func (s *SQL) Menus(user string) ([]dbtypes.Menu, error) {
@@ -121,6 +171,18 @@ func (s *SQL) queryMenuContents(tx *sql.Tx, user string, names []string) ([]dbty
 
 	builder := newMenuBuilder(user, names)
 
+	dates, err := s.queryMenuDates(tx, user)
+	if err != nil {
+		return nil, fmt.Errorf("could not query menu dates: %v", err)
+	}
+	builder.setDates(dates)
+
+	households, err := s.queryMenuHouseholds(tx, user)
+	if err != nil {
+		return nil, fmt.Errorf("could not query menu households: %v", err)
+	}
+	builder.setHouseholds(households)
+
 	days, err := s.queryMenuDays(tx, user)
 	if err != nil {
 		return nil, fmt.Errorf("could not query menu days: %v", err)
@@ -133,15 +195,92 @@ func (s *SQL) queryMenuContents(tx *sql.Tx, user string, names []string) ([]dbty
 	}
 	builder.setMeals(meals)
 
+	attendees, err := s.queryMealAttendees(tx, user)
+	if err != nil {
+		return nil, fmt.Errorf("could not query meal attendees: %v", err)
+	}
+	builder.setAttendees(attendees)
+
 	items, err := s.queryMealItems(tx, user)
 	if err != nil {
 		return nil, fmt.Errorf("could not query meal items: %v", err)
 	}
 	builder.setItems(items)
 
+	leftovers, err := s.queryDishLeftovers(tx, user)
+	if err != nil {
+		return nil, fmt.Errorf("could not query dish leftovers: %v", err)
+	}
+	builder.setLeftovers(leftovers)
+
 	return builder.menus, nil
 }
 
+type menuDateRow struct {
+	User      string
+	Menu      string
+	StartDate dbtypes.Date
+}
+
+func (s *SQL) queryMenuDates(tx *sql.Tx, user string) ([]menuDateRow, error) {
+	rows, err := tx.QueryContext(s.ctx, "SELECT menu, start_date FROM menu_dates WHERE user = ?", user)
+	if err != nil {
+		return nil, fmt.Errorf("could not query menu dates: %v", err)
+	}
+	defer rows.Close()
+
+	var dates []menuDateRow
+	for rows.Next() {
+		var d menuDateRow
+		var date string
+		if err := rows.Scan(&d.Menu, &date); err != nil {
+			return nil, fmt.Errorf("could not scan menu date: %v", err)
+		}
+
+		d.StartDate, err = dbtypes.ParseDate(date)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse menu date: %v", err)
+		}
+
+		dates = append(dates, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not iterate over menu dates: %v", err)
+	}
+
+	return dates, nil
+}
+
+type menuHouseholdRow struct {
+	User string
+	Menu string
+	Size int
+}
+
+func (s *SQL) queryMenuHouseholds(tx *sql.Tx, user string) ([]menuHouseholdRow, error) {
+	rows, err := tx.QueryContext(s.ctx, "SELECT menu, size FROM menu_households WHERE user = ?", user)
+	if err != nil {
+		return nil, fmt.Errorf("could not query menu households: %v", err)
+	}
+	defer rows.Close()
+
+	var households []menuHouseholdRow
+	for rows.Next() {
+		var h menuHouseholdRow
+		if err := rows.Scan(&h.Menu, &h.Size); err != nil {
+			return nil, fmt.Errorf("could not scan menu household: %v", err)
+		}
+		households = append(households, h)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not iterate over menu households: %v", err)
+	}
+
+	return households, nil
+}
+
 type menuDayRow struct {
 	User string
 	Menu string
@@ -211,6 +350,45 @@ func (s *SQL) queryMenuMeals(tx *sql.Tx, user string) ([]menuMealRow, error) {
 	return meals, nil
 }
 
+type menuAttendeesRow struct {
+	User      string
+	Menu      string
+	Day       int
+	Meal      int
+	Attendees int
+}
+
+func (s *SQL) queryMealAttendees(tx *sql.Tx, user string) ([]menuAttendeesRow, error) {
+	query := `
+		SELECT
+			menu, day, meal, attendees
+		FROM
+			menu_meal_attendees
+		WHERE
+			user = ?`
+
+	rows, err := tx.QueryContext(s.ctx, query, user)
+	if err != nil {
+		return nil, fmt.Errorf("could not query meal attendees: %v", err)
+	}
+	defer rows.Close()
+
+	var attendees []menuAttendeesRow
+	for rows.Next() {
+		var a menuAttendeesRow
+		if err := rows.Scan(&a.Menu, &a.Day, &a.Meal, &a.Attendees); err != nil {
+			return nil, fmt.Errorf("could not scan meal attendees: %v", err)
+		}
+		attendees = append(attendees, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not iterate over meal attendees: %v", err)
+	}
+
+	return attendees, nil
+}
+
 type menuDishRow struct {
 	User   string
 	Menu   string
@@ -251,6 +429,46 @@ func (s *SQL) queryMealItems(tx *sql.Tx, user string) ([]menuDishRow, error) {
 	return items, nil
 }
 
+type menuLeftoverRow struct {
+	User string
+	Menu string
+	Day  int
+	Meal int
+	Pos  int
+	From dbtypes.MealRef
+}
+
+func (s *SQL) queryDishLeftovers(tx *sql.Tx, user string) ([]menuLeftoverRow, error) {
+	query := `
+		SELECT
+			menu, day, meal, pos, from_day, from_meal
+		FROM
+			menu_dish_leftovers
+		WHERE
+			user = ?`
+
+	rows, err := tx.QueryContext(s.ctx, query, user)
+	if err != nil {
+		return nil, fmt.Errorf("could not query dish leftovers: %v", err)
+	}
+	defer rows.Close()
+
+	var leftovers []menuLeftoverRow
+	for rows.Next() {
+		var l menuLeftoverRow
+		if err := rows.Scan(&l.Menu, &l.Day, &l.Meal, &l.Pos, &l.From.Day, &l.From.Meal); err != nil {
+			return nil, fmt.Errorf("could not scan dish leftover: %v", err)
+		}
+		leftovers = append(leftovers, l)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not iterate over dish leftovers: %v", err)
+	}
+
+	return leftovers, nil
+}
+
 type menuBuilder struct {
 	menus []dbtypes.Menu
 }
@@ -268,6 +486,29 @@ func newMenuBuilder(user string, names []string) menuBuilder {
 	return p
 }
 
+func (p *menuBuilder) setDates(d []menuDateRow) {
+	for _, row := range d {
+		menu, ok := getMenu(p.menus, row.Menu)
+		if !ok {
+			continue
+		}
+
+		date := row.StartDate
+		menu.StartDate = &date
+	}
+}
+
+func (p *menuBuilder) setHouseholds(h []menuHouseholdRow) {
+	for _, row := range h {
+		menu, ok := getMenu(p.menus, row.Menu)
+		if !ok {
+			continue
+		}
+
+		menu.Household = row.Size
+	}
+}
+
 func (p *menuBuilder) setDays(d []menuDayRow) {
 	for _, row := range d {
 		menu, ok := getMenu(p.menus, row.Menu)
@@ -294,6 +535,26 @@ func (p *menuBuilder) setMeals(m []menuMealRow) {
 	}
 }
 
+func (p *menuBuilder) setAttendees(a []menuAttendeesRow) {
+	for _, row := range a {
+		menu, ok := getMenu(p.menus, row.Menu)
+		if !ok {
+			continue
+		}
+
+		if len(menu.Days) <= row.Day {
+			continue
+		}
+
+		if len(menu.Days[row.Day].Meals) <= row.Meal {
+			continue
+		}
+
+		attendees := row.Attendees
+		menu.Days[row.Day].Meals[row.Meal].Attendees = &attendees
+	}
+}
+
 func (p *menuBuilder) setItems(i []menuDishRow) {
 	for _, row := range i {
 		menu, ok := getMenu(p.menus, row.Menu)
@@ -316,6 +577,30 @@ func (p *menuBuilder) setItems(i []menuDishRow) {
 	}
 }
 
+func (p *menuBuilder) setLeftovers(l []menuLeftoverRow) {
+	for _, row := range l {
+		menu, ok := getMenu(p.menus, row.Menu)
+		if !ok {
+			continue
+		}
+
+		if len(menu.Days) <= row.Day {
+			continue
+		}
+
+		if len(menu.Days[row.Day].Meals) <= row.Meal {
+			continue
+		}
+
+		if len(menu.Days[row.Day].Meals[row.Meal].Dishes) <= row.Pos {
+			continue
+		}
+
+		from := row.From
+		menu.Days[row.Day].Meals[row.Meal].Dishes[row.Pos].LeftoverFrom = &from
+	}
+}
+
 func getMenu(s []dbtypes.Menu, name string) (*dbtypes.Menu, bool) {
 	idx := slices.IndexFunc(s, func(v dbtypes.Menu) bool { return v.Name == name })
 	if idx == -1 {
@@ -374,6 +659,71 @@ func (s *SQL) LookupMenu(user, name string) (dbtypes.Menu, error) {
 }
 
 func (s *SQL) SetMenu(m dbtypes.Menu) error {
+	if err := validateMenuKey(m); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %v", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
+
+	// Delete old menu dependencies via cascade
+	if _, err := tx.ExecContext(s.ctx, `
+		DELETE FROM
+			menu_days
+		WHERE 
+			menu = ? AND user = ?
+		`, m.Name, m.User); err != nil {
+		return fmt.Errorf("could not delete extra meal items: %v", err)
+	}
+
+	// Insert new menu from top to bottom
+	if err := s.setMenu(tx, m.User, m.Name); err != nil {
+		return fmt.Errorf("could not set menu: %v", err)
+	}
+
+	if err := s.setMenuContents(tx, m); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CreateMenu stores a new menu. It returns fs.ErrExist if the user already has a menu with the same name.
+func (s *SQL) CreateMenu(m dbtypes.Menu) error {
+	if err := validateMenuKey(m); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %v", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
+
+	q := `INSERT INTO menus (user, name) VALUES (?, ?)`
+	s.log.Trace(q)
+
+	if _, err := tx.ExecContext(s.ctx, q, m.User, m.Name); errorIs(err, errKeyExists) {
+		return fs.ErrExist
+	} else if err != nil {
+		return fmt.Errorf("could not insert menu: %v", err)
+	}
+
+	if err := s.setMenuContents(tx, m); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+func validateMenuKey(m dbtypes.Menu) error {
 	if m.User == "" {
 		return fmt.Errorf("user cannot be empty")
 	}
@@ -382,10 +732,17 @@ func (s *SQL) SetMenu(m dbtypes.Menu) error {
 		return fmt.Errorf("name cannot be empty")
 	}
 
+	return nil
+}
+
+// setMenuContents stores everything in the menu below its row in the menus table, which must exist.
+func (s *SQL) setMenuContents(tx *sql.Tx, m dbtypes.Menu) error {
 	var dc struct {
-		days  []menuDayRow
-		meals []menuMealRow
-		items []menuDishRow
+		days      []menuDayRow
+		meals     []menuMealRow
+		attendees []menuAttendeesRow
+		items     []menuDishRow
+		leftovers []menuLeftoverRow
 	}
 
 	for dayIdx, day := range m.Days {
@@ -405,6 +762,16 @@ func (s *SQL) SetMenu(m dbtypes.Menu) error {
 				Name: meal.Name,
 			})
 
+			if meal.Attendees != nil {
+				dc.attendees = append(dc.attendees, menuAttendeesRow{
+					User:      m.User,
+					Menu:      m.Name,
+					Day:       dayIdx,
+					Meal:      mealIdx,
+					Attendees: *meal.Attendees,
+				})
+			}
+
 			for k, dish := range meal.Dishes {
 				dc.items = append(dc.items, menuDishRow{
 					User:   m.User,
@@ -415,29 +782,27 @@ func (s *SQL) SetMenu(m dbtypes.Menu) error {
 					Recipe: dish.ID,
 					Amount: dish.Amount,
 				})
+
+				if !dish.Cooked() {
+					dc.leftovers = append(dc.leftovers, menuLeftoverRow{
+						User: m.User,
+						Menu: m.Name,
+						Day:  dayIdx,
+						Meal: mealIdx,
+						Pos:  k,
+						From: *dish.LeftoverFrom,
+					})
+				}
 			}
 		}
 	}
 
-	tx, err := s.db.BeginTx(s.ctx, nil)
-	if err != nil {
-		return fmt.Errorf("could not begin transaction: %v", err)
-	}
-	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
-
-	// Delete old menu dependencies via cascade
-	if _, err := tx.ExecContext(s.ctx, `
-		DELETE FROM
-			menu_days
-		WHERE 
-			menu = ? AND user = ?
-		`, m.Name, m.User); err != nil {
-		return fmt.Errorf("could not delete extra meal items: %v", err)
+	if err := s.setDate(tx, m.User, m.Name, m.StartDate); err != nil {
+		return fmt.Errorf("could not set menu date: %v", err)
 	}
 
-	// Insert new menu from top to bottom
-	if err := s.setMenu(tx, m.User, m.Name); err != nil {
-		return fmt.Errorf("could not set menu: %v", err)
+	if err := s.setHousehold(tx, m.User, m.Name, m.Household); err != nil {
+		return fmt.Errorf("could not set menu household: %v", err)
 	}
 
 	if err := s.setDays(tx, dc.days); err != nil {
@@ -448,11 +813,19 @@ func (s *SQL) SetMenu(m dbtypes.Menu) error {
 		return fmt.Errorf("could not set menu meals: %v", err)
 	}
 
+	if err := s.setAttendees(tx, dc.attendees); err != nil {
+		return fmt.Errorf("could not set meal attendees: %v", err)
+	}
+
 	if err := s.setItems(tx, dc.items); err != nil {
 		return fmt.Errorf("could not set meal items: %v", err)
 	}
 
-	return tx.Commit()
+	if err := s.setLeftovers(tx, dc.leftovers); err != nil {
+		return fmt.Errorf("could not set dish leftovers: %v", err)
+	}
+
+	return nil
 }
 
 func (s *SQL) DeleteMenu(user, name string) error {
@@ -474,6 +847,100 @@ func (s *SQL) DeleteMenu(user, name string) error {
 		return fmt.Errorf("could not delete menu: %v", err)
 	}
 
+	// Cooked meals do not reference the menu, so that they survive it being saved again
+	if _, err := tx.ExecContext(s.ctx, `
+		DELETE FROM
+			cooked_meals
+		WHERE
+			menu = ? AND user = ?`, name, user); err != nil {
+		return fmt.Errorf("could not delete cooked meals: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+// RenameMenu changes the name of a menu, keeping its shopping lists.
+// It returns fs.ErrNotExist if the menu does not exist, and fs.ErrExist if the new name is taken.
+func (s *SQL) RenameMenu(user, oldName, newName string) error {
+	if user == "" {
+		return fs.ErrNotExist
+	}
+
+	if newName == "" {
+		return fmt.Errorf("name cannot be empty")
+	}
+
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %v", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
+
+	for _, check := range []struct {
+		name string
+		want bool
+		err  error
+	}{
+		{name: oldName, want: true, err: fs.ErrNotExist},
+		{name: newName, want: false, err: fs.ErrExist},
+	} {
+		var count int
+		q := `SELECT COUNT(*) FROM menus WHERE user = ? AND name = ?`
+		s.log.Trace(q)
+
+		if err := tx.QueryRowContext(s.ctx, q, user, check.name).Scan(&count); err != nil {
+			return fmt.Errorf("could not query menus: %v", err)
+		}
+
+		if (count > 0) != check.want {
+			return check.err
+		}
+	}
+
+	if err := s.setMenu(tx, user, newName); err != nil {
+		return err
+	}
+
+	// Foreign keys do not cascade updates, so the contents are copied under the new name
+	// and the old menu is deleted afterwards.
+	for _, q := range []string{
+		`INSERT INTO menu_dates (user, menu, start_date)
+			SELECT user, ?, start_date FROM menu_dates WHERE user = ? AND menu = ?`,
+		`INSERT INTO menu_households (user, menu, size)
+			SELECT user, ?, size FROM menu_households WHERE user = ? AND menu = ?`,
+		`INSERT INTO menu_days (user, menu, pos, name)
+			SELECT user, ?, pos, name FROM menu_days WHERE user = ? AND menu = ?`,
+		`INSERT INTO menu_meals (user, menu, day, pos, name)
+			SELECT user, ?, day, pos, name FROM menu_meals WHERE user = ? AND menu = ?`,
+		`INSERT INTO menu_meal_attendees (user, menu, day, meal, attendees)
+			SELECT user, ?, day, meal, attendees FROM menu_meal_attendees WHERE user = ? AND menu = ?`,
+		`INSERT INTO menu_dishes (user, menu, day, meal, pos, recipe, amount)
+			SELECT user, ?, day, meal, pos, recipe, amount FROM menu_dishes WHERE user = ? AND menu = ?`,
+		`INSERT INTO menu_dish_leftovers (user, menu, day, meal, pos, from_day, from_meal)
+			SELECT user, ?, day, meal, pos, from_day, from_meal FROM menu_dish_leftovers WHERE user = ? AND menu = ?`,
+		`UPDATE shopping_list_items SET menu = ? WHERE user = ? AND menu = ?`,
+		`UPDATE cooked_meals SET menu = ? WHERE user = ? AND menu = ?`,
+		`UPDATE purchases SET menu = ? WHERE user = ? AND menu = ?`,
+	} {
+		s.log.Trace(q)
+
+		if _, err := tx.ExecContext(s.ctx, q, newName, user, oldName); err != nil {
+			return fmt.Errorf("could not move menu contents: %v", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(s.ctx, `
+		DELETE FROM
+			menus
+		WHERE
+			name = ? AND user = ?`, oldName, user); err != nil {
+		return fmt.Errorf("could not delete old menu: %v", err)
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("could not commit transaction: %v", err)
 	}
@@ -494,6 +961,52 @@ func (s *SQL) setMenu(tx *sql.Tx, user, name string) error {
 	return nil
 }
 
+func (s *SQL) setDate(tx *sql.Tx, user, name string, date *dbtypes.Date) error {
+	if _, err := tx.ExecContext(s.ctx, `
+		DELETE FROM
+			menu_dates
+		WHERE
+			menu = ? AND user = ?`, name, user); err != nil {
+		return fmt.Errorf("could not delete menu date: %v", err)
+	}
+
+	if date == nil {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(s.ctx, `
+		INSERT INTO
+			menu_dates (user, menu, start_date)
+		VALUES (?, ?, ?)`, user, name, date.String()); err != nil {
+		return fmt.Errorf("could not insert menu date: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SQL) setHousehold(tx *sql.Tx, user, name string, size int) error {
+	if _, err := tx.ExecContext(s.ctx, `
+		DELETE FROM
+			menu_households
+		WHERE
+			menu = ? AND user = ?`, name, user); err != nil {
+		return fmt.Errorf("could not delete menu household: %v", err)
+	}
+
+	if size <= 0 {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(s.ctx, `
+		INSERT INTO
+			menu_households (user, menu, size)
+		VALUES (?, ?, ?)`, user, name, size); err != nil {
+		return fmt.Errorf("could not insert menu household: %v", err)
+	}
+
+	return nil
+}
+
 func (s *SQL) setDays(tx *sql.Tx, rows []menuDayRow) error {
 	return bulkInsert(s, tx,
 		"menu_days (user, menu, pos, name)", rows,
@@ -510,6 +1023,14 @@ func (s *SQL) setMeals(tx *sql.Tx, rows []menuMealRow) error {
 		})
 }
 
+func (s *SQL) setAttendees(tx *sql.Tx, rows []menuAttendeesRow) error {
+	return bulkInsert(s, tx,
+		"menu_meal_attendees (user, menu, day, meal, attendees)", rows,
+		func(row menuAttendeesRow) []any {
+			return []any{row.User, row.Menu, row.Day, row.Meal, row.Attendees}
+		})
+}
+
 func (s *SQL) setItems(tx *sql.Tx, rows []menuDishRow) error {
 	return bulkInsert(s, tx,
 		"menu_dishes (user, menu, day, meal, pos, recipe, amount)", rows,
@@ -517,3 +1038,11 @@ func (s *SQL) setItems(tx *sql.Tx, rows []menuDishRow) error {
 			return []any{row.User, row.Menu, row.Day, row.Meal, row.Pos, row.Recipe, row.Amount}
 		})
 }
+
+func (s *SQL) setLeftovers(tx *sql.Tx, rows []menuLeftoverRow) error {
+	return bulkInsert(s, tx,
+		"menu_dish_leftovers (user, menu, day, meal, pos, from_day, from_meal)", rows,
+		func(row menuLeftoverRow) []any {
+			return []any{row.User, row.Menu, row.Day, row.Meal, row.Pos, row.From.Day, row.From.Meal}
+		})
+}
@@ -90,11 +90,17 @@ func New(ctx context.Context, log logger.Logger, sett Settings) (*SQL, error) {
 func allTables() []tableDef {
 	return slices.Concat(
 		userTables,
+		calendarFeedTables,
 		productTables,
 		recipeTables,
+		recipeSharingTables,
 		menuTables,
 		pantryTables,
+		pantryStapleTables,
+		pantryGroupTables,
 		shoppingListTables,
+		cookedMealTables,
+		purchaseTables,
 	)
 }
 
@@ -86,11 +86,22 @@ options:
 
 func TestMySQL(t *testing.T) {
 	testCases := map[string]func(*testing.T, func() database.DB){
-		"Products": dbtestutils.ProductsTest,
-		"Recipes":  dbtestutils.RecipesTest,
-		"Menus":    dbtestutils.MenuTest,
-		"Pantries": dbtestutils.PantriesTest,
-		"Shopping": dbtestutils.ShoppingListsTest,
+		"Calendar":    dbtestutils.CalendarFeedsTest,
+		"Products":    dbtestutils.ProductsTest,
+		"Recipes":     dbtestutils.RecipesTest,
+		"Sharing":     dbtestutils.RecipeSharingTest,
+		"DeleteUser":  dbtestutils.DeleteUserTest,
+		"History":     dbtestutils.RecipeRevisionsTest,
+		"Menus":       dbtestutils.MenuTest,
+		"Pantries":    dbtestutils.PantriesTest,
+		"Shopping":    dbtestutils.ShoppingListsTest,
+		"Cooked":      dbtestutils.CookedMealsTest,
+		"Bought":      dbtestutils.PurchasesTest,
+		"Ledger":      dbtestutils.PantryLedgerTest,
+		"PantryOps":   dbtestutils.PantryUpdatesTest,
+		"Staples":     dbtestutils.PantryStaplesTest,
+		"Groups":      dbtestutils.PantryGroupsTest,
+		"ShoppingOps": dbtestutils.ShoppingListUpdatesTest,
 	}
 
 	for name, test := range testCases {
@@ -165,6 +176,67 @@ func TestMySQLProducts(t *testing.T) {
 	require.Empty(t, products)
 }
 
+// TestMySQLProductUpdate checks that updating a product does not delete the rows that reference it.
+func TestMySQLProductUpdate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log := testutils.NewLogger(t)
+	log.SetLevel(int(logrus.DebugLevel))
+
+	options := mysql.DefaultSettings()
+	options.PasswordFile = "./testdata/db_root_password.txt"
+	mysql.ClearDB(t, ctx, log, options)
+
+	db, err := mysql.New(ctx, log, options)
+	require.NoError(t, err)
+	defer db.Close()
+
+	const user = "test-user-123"
+	require.NoError(t, db.SetUser(user))
+
+	p := product.Product{
+		Name:         "Flour",
+		BatchSize:    1,
+		Price:        1.20,
+		Provider:     blank.Provider{},
+		PriceUpdated: time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	p.ID, err = db.SetProduct(p)
+	require.NoError(t, err)
+
+	lot := dbtypes.PantryLot{ProductID: p.ID, Amount: 2, BestBefore: &dbtypes.Date{Year: 2024, Month: time.April, Day: 3}}
+	require.NoError(t, db.SetPantry(dbtypes.Pantry{
+		User:     user,
+		Name:     "Pantry #1",
+		Contents: []recipe.Ingredient{{ProductID: p.ID, Amount: 2}},
+		Lots:     []dbtypes.PantryLot{lot},
+	}))
+
+	staples := dbtypes.PantryStaples{User: user, Pantry: "Pantry #1", Staples: []dbtypes.Staple{{ProductID: p.ID, Minimum: 1}}}
+	require.NoError(t, db.SetPantryStaples(staples))
+
+	p.Price = 1.35
+	p.PriceUpdated = p.PriceUpdated.Add(24 * time.Hour)
+	id, err := db.SetProduct(p)
+	require.NoError(t, err)
+	require.Equal(t, p.ID, id, "Updating a product should keep its ID")
+
+	got, err := db.LookupProduct(p.ID)
+	require.NoError(t, err)
+	require.Equal(t, p, got, "Product was not updated")
+
+	pantry, err := db.LookupPantry(user, "Pantry #1")
+	require.NoError(t, err)
+	require.Equal(t, []recipe.Ingredient{{ProductID: p.ID, Amount: 2}}, pantry.Contents, "Pantry contents should survive a product update")
+	require.Equal(t, []dbtypes.PantryLot{lot}, pantry.Lots, "Pantry lots should survive a product update")
+
+	gotStaples, err := db.PantryStaples(user, "Pantry #1")
+	require.NoError(t, err)
+	require.Equal(t, staples.Staples, gotStaples.Staples, "Staples should survive a product update")
+}
+
 func TestMySQLRecipes(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
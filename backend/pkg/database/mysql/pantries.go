@@ -33,6 +33,38 @@ var pantryTables = []tableDef{
 			"PRIMARY KEY (user, pantry, product)",
 		},
 	},
+	{
+		name: "pantry_lots",
+		columns: []string{
+			"user VARCHAR(255) NOT NULL",
+			"pantry VARCHAR(255) NOT NULL",
+			"pos INT NOT NULL",
+			"product INT UNSIGNED NOT NULL",
+			"amount FLOAT NOT NULL",
+			"purchased DATE",
+			"best_before DATE",
+			"FOREIGN KEY (user) REFERENCES users(id) ON DELETE CASCADE",
+			"FOREIGN KEY (product) REFERENCES products(id) ON DELETE CASCADE",
+			"FOREIGN KEY (user, pantry) REFERENCES pantries(user, name) ON DELETE CASCADE",
+			"PRIMARY KEY (user, pantry, pos)",
+		},
+	},
+	{
+		name: "pantry_ledger",
+		columns: []string{
+			"user VARCHAR(255) NOT NULL",
+			"pantry VARCHAR(255) NOT NULL",
+			"entry INT UNSIGNED NOT NULL",
+			"author VARCHAR(255) NOT NULL",
+			"timestamp BIGINT NOT NULL",
+			"reason VARCHAR(32) NOT NULL",
+			"deltas TEXT NOT NULL",
+			"snapshot TEXT NOT NULL",
+			"FOREIGN KEY (user) REFERENCES users(id) ON DELETE CASCADE",
+			"FOREIGN KEY (user, pantry) REFERENCES pantries(user, name) ON DELETE CASCADE",
+			"PRIMARY KEY (user, pantry, entry)",
+		},
+	},
 }
 
 func (s *SQL) Pantries(user string) ([]dbtypes.Pantry, error) {
@@ -53,10 +85,17 @@ func (s *SQL) Pantries(user string) ([]dbtypes.Pantry, error) {
 		if err != nil {
 			return nil, fmt.Errorf("could not query pantry items: %v", err)
 		}
+
+		lots, err := s.queryPantryLots(tx, user, name)
+		if err != nil {
+			return nil, fmt.Errorf("could not query pantry lots: %v", err)
+		}
+
 		pantries = append(pantries, dbtypes.Pantry{
 			User:     user,
 			Name:     name,
 			Contents: contents,
+			Lots:     lots,
 		})
 	}
 
@@ -115,6 +154,68 @@ func (s *SQL) queryPantryContents(tx *sql.Tx, user, name string) ([]recipe.Ingre
 	return items, nil
 }
 
+func (s *SQL) queryPantryLots(tx *sql.Tx, user, name string) ([]dbtypes.PantryLot, error) {
+	r, err := tx.QueryContext(s.ctx, `
+		SELECT
+			product, amount, purchased, best_before
+		FROM
+			pantry_lots
+		WHERE
+			user = ? AND pantry = ?
+		ORDER BY
+			pos`, user, name)
+	if err != nil {
+		return nil, fmt.Errorf("could not query pantry lots: %v", err)
+	}
+	defer r.Close()
+
+	var lots []dbtypes.PantryLot
+	for r.Next() {
+		var lot dbtypes.PantryLot
+		var purchased, bestBefore sql.NullString
+		if err := r.Scan(&lot.ProductID, &lot.Amount, &purchased, &bestBefore); err != nil {
+			return nil, fmt.Errorf("could not scan pantry lot: %v", err)
+		}
+
+		if lot.Purchased, err = parseNullDate(purchased); err != nil {
+			return nil, fmt.Errorf("could not parse purchase date: %v", err)
+		}
+
+		if lot.BestBefore, err = parseNullDate(bestBefore); err != nil {
+			return nil, fmt.Errorf("could not parse best-before date: %v", err)
+		}
+
+		lots = append(lots, lot)
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, fmt.Errorf("could not get pantry lots: %v", err)
+	}
+
+	return lots, nil
+}
+
+func parseNullDate(s sql.NullString) (*dbtypes.Date, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+
+	d, err := dbtypes.ParseDate(s.String)
+	if err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+// formatNullDate formats an optional date for a nullable DATE column.
+func formatNullDate(d *dbtypes.Date) any {
+	if d == nil {
+		return nil
+	}
+	return d.String()
+}
+
 func (s *SQL) LookupPantry(user, name string) (dbtypes.Pantry, error) {
 	if user == "" {
 		return dbtypes.Pantry{}, errors.New("user cannot be empty")
@@ -146,16 +247,7 @@ func (s *SQL) LookupPantry(user, name string) (dbtypes.Pantry, error) {
 		return dbtypes.Pantry{}, fmt.Errorf("could not get pantry %s: %v", name, err)
 	}
 
-	contents, err := s.queryPantryContents(tx, user, name)
-	if err != nil {
-		return dbtypes.Pantry{}, err
-	}
-
-	return dbtypes.Pantry{
-		User:     user,
-		Name:     name,
-		Contents: contents,
-	}, nil
+	return s.queryPantry(tx, user, name)
 }
 
 func (s *SQL) SetPantry(p dbtypes.Pantry) error {
@@ -171,6 +263,84 @@ func (s *SQL) SetPantry(p dbtypes.Pantry) error {
 	}
 	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
 
+	// The pantry is created first so that it can be locked: concurrent writes must not read
+	// stale contents, nor append the same ledger entry.
+	_, err = tx.ExecContext(s.ctx, "INSERT INTO pantries (user, name) VALUES (?, ?)", p.User, p.Name)
+	if err != nil && !errorIs(err, errKeyExists) {
+		return fmt.Errorf("could not insert pantry: %v", err)
+	}
+
+	if _, err := s.lockPantry(tx, p.User, p.Name); err != nil {
+		return err
+	}
+
+	if err := s.setPantry(tx, p, dbtypes.PantryManual); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SQL) UpdatePantry(user, name string, ops []dbtypes.PantryOp) (dbtypes.Pantry, error) {
+	if user == "" {
+		return dbtypes.Pantry{}, errors.New("user cannot be empty")
+	} else if name == "" {
+		return dbtypes.Pantry{}, errors.New("name cannot be empty")
+	}
+
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return dbtypes.Pantry{}, fmt.Errorf("could not begin transaction: %v", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
+
+	p, err := s.lockPantry(tx, user, name)
+	if err != nil {
+		return dbtypes.Pantry{}, err
+	}
+
+	if err := p.Apply(ops); err != nil {
+		return dbtypes.Pantry{}, err
+	}
+
+	if err := s.setPantry(tx, p, dbtypes.PantryManual); err != nil {
+		return dbtypes.Pantry{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return dbtypes.Pantry{}, fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return p, nil
+}
+
+// lockPantry reads the pantry and locks its row until the end of the transaction, so that concurrent
+// updates of the same pantry wait for each other. It returns fs.ErrNotExist if the pantry does not exist.
+func (s *SQL) lockPantry(tx *sql.Tx, user, name string) (dbtypes.Pantry, error) {
+	var count int
+	q := `SELECT COUNT(*) FROM pantries WHERE user = ? AND name = ? FOR UPDATE`
+	s.log.Trace(q)
+
+	if err := tx.QueryRowContext(s.ctx, q, user, name).Scan(&count); err != nil {
+		return dbtypes.Pantry{}, fmt.Errorf("could not query pantries: %v", err)
+	} else if count == 0 {
+		return dbtypes.Pantry{}, fs.ErrNotExist
+	}
+
+	return s.queryPantry(tx, user, name)
+}
+
+// setPantry writes the pantry and replaces all of its items and lots, recording the change in its ledger.
+func (s *SQL) setPantry(tx *sql.Tx, p dbtypes.Pantry, reason dbtypes.PantryReason) error {
+	old, err := s.queryPantry(tx, p.User, p.Name)
+	if err != nil {
+		return err
+	}
+
 	_, err = tx.ExecContext(s.ctx, "INSERT INTO pantries (user, name) VALUES (?, ?)", p.User, p.Name)
 	if err != nil && !errorIs(err, errKeyExists) {
 		return fmt.Errorf("could not insert pantry: %v", err)
@@ -196,13 +366,62 @@ func (s *SQL) SetPantry(p dbtypes.Pantry) error {
 		return fmt.Errorf("could not insert new pantry items: %v", err)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("could not commit transaction: %v", err)
+	_, err = tx.ExecContext(s.ctx, `
+		DELETE FROM
+			pantry_lots
+		WHERE
+			user = ?  AND pantry = ?`, p.User, p.Name)
+	if err != nil {
+		return fmt.Errorf("could not delete old pantry lots: %v", err)
+	}
+
+	type lotRow struct {
+		pos int
+		dbtypes.PantryLot
+	}
+
+	lots := make([]lotRow, 0, len(p.Lots))
+	for i, l := range p.Lots {
+		lots = append(lots, lotRow{pos: i, PantryLot: l})
+	}
+
+	err = bulkInsert(s, tx,
+		"pantry_lots (user, pantry, pos, product, amount, purchased, best_before)",
+		lots,
+		func(l lotRow) []any {
+			return []any{p.User, p.Name, l.pos, l.ProductID, l.Amount, formatNullDate(l.Purchased), formatNullDate(l.BestBefore)}
+		})
+	if err != nil {
+		return fmt.Errorf("could not insert new pantry lots: %v", err)
+	}
+
+	if err := s.appendPantryChange(tx, old, p, reason); err != nil {
+		return err
 	}
 
 	return nil
 }
 
+// queryPantry returns the items and lots of a pantry. A pantry that does not exist is empty.
+func (s *SQL) queryPantry(tx *sql.Tx, user, name string) (dbtypes.Pantry, error) {
+	contents, err := s.queryPantryContents(tx, user, name)
+	if err != nil {
+		return dbtypes.Pantry{}, err
+	}
+
+	lots, err := s.queryPantryLots(tx, user, name)
+	if err != nil {
+		return dbtypes.Pantry{}, err
+	}
+
+	return dbtypes.Pantry{
+		User:     user,
+		Name:     name,
+		Contents: contents,
+		Lots:     lots,
+	}, nil
+}
+
 func (s *SQL) DeletePantry(user, name string) error {
 	if user == "" {
 		return errors.New("user cannot be empty")
@@ -225,6 +444,18 @@ func (s *SQL) DeletePantry(user, name string) error {
 		return fmt.Errorf("could not delete pantry: %v", err)
 	}
 
+	// A group without pantries is not valid, so the groups left empty go away
+	_, err = tx.ExecContext(s.ctx, `
+		DELETE FROM
+			pantry_groups
+		WHERE
+			user = ? AND NOT EXISTS (
+				SELECT 1 FROM pantry_group_members m WHERE m.user = pantry_groups.user AND m.grp = pantry_groups.name
+			)`, user)
+	if err != nil {
+		return fmt.Errorf("could not delete empty pantry groups: %v", err)
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("could not commit transaction: %v", err)
 	}
@@ -0,0 +1,211 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+)
+
+var pantryGroupTables = []tableDef{
+	{
+		name: "pantry_groups",
+		columns: []string{
+			"user VARCHAR(255) NOT NULL",
+			"name VARCHAR(255) NOT NULL",
+			"FOREIGN KEY (user) REFERENCES users(id) ON DELETE CASCADE",
+			"PRIMARY KEY (user, name)",
+		},
+	},
+	{
+		name: "pantry_group_members",
+		columns: []string{
+			"user VARCHAR(255) NOT NULL",
+			"grp VARCHAR(255) NOT NULL",
+			"pos INT NOT NULL",
+			"pantry VARCHAR(255) NOT NULL",
+			"FOREIGN KEY (user, grp) REFERENCES pantry_groups(user, name) ON DELETE CASCADE",
+			"FOREIGN KEY (user, pantry) REFERENCES pantries(user, name) ON DELETE CASCADE",
+			"PRIMARY KEY (user, grp, pantry)",
+		},
+	},
+}
+
+func (s *SQL) PantryGroups(user string) ([]dbtypes.PantryGroup, error) {
+	if user == "" {
+		return nil, errors.New("user cannot be empty")
+	}
+
+	tx, err := s.db.BeginTx(s.ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("could not begin transaction: %v", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
+
+	out, err := s.queryPantryGroups(tx, user, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return out, nil
+}
+
+func (s *SQL) LookupPantryGroup(user, name string) (dbtypes.PantryGroup, error) {
+	if user == "" {
+		return dbtypes.PantryGroup{}, errors.New("user cannot be empty")
+	} else if name == "" {
+		return dbtypes.PantryGroup{}, errors.New("name cannot be empty")
+	}
+
+	tx, err := s.db.BeginTx(s.ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return dbtypes.PantryGroup{}, fmt.Errorf("could not begin transaction: %v", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
+
+	out, err := s.queryPantryGroups(tx, user, "AND g.name = ?", name)
+	if err != nil {
+		return dbtypes.PantryGroup{}, err
+	} else if len(out) == 0 {
+		return dbtypes.PantryGroup{}, fs.ErrNotExist
+	}
+
+	if err := tx.Commit(); err != nil {
+		return dbtypes.PantryGroup{}, fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return out[0], nil
+}
+
+// queryPantryGroups returns the pantry groups of the user that satisfy the condition, with their pantries.
+func (s *SQL) queryPantryGroups(tx *sql.Tx, user string, condition string, args ...any) ([]dbtypes.PantryGroup, error) {
+	//nolint:gosec // The condition is constructed by the code, not user input
+	query := `
+		SELECT
+			g.name, m.pantry
+		FROM
+			pantry_groups g
+			LEFT JOIN pantry_group_members m ON m.user = g.user AND m.grp = g.name
+		WHERE
+			g.user = ?
+			` + condition + `
+		ORDER BY
+			g.name, m.pos`
+	s.log.Trace(query)
+
+	r, err := tx.QueryContext(s.ctx, query, append([]any{user}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("could not query pantry groups: %v", err)
+	}
+	defer r.Close()
+
+	out := make([]dbtypes.PantryGroup, 0)
+	for r.Next() {
+		var name string
+		var pantry sql.NullString
+		if err := r.Scan(&name, &pantry); err != nil {
+			return nil, fmt.Errorf("could not scan pantry group: %v", err)
+		}
+
+		if len(out) == 0 || out[len(out)-1].Name != name {
+			out = append(out, dbtypes.PantryGroup{User: user, Name: name, Pantries: make([]string, 0)})
+		}
+
+		if pantry.Valid {
+			g := &out[len(out)-1]
+			g.Pantries = append(g.Pantries, pantry.String)
+		}
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, fmt.Errorf("could not get pantry groups: %v", err)
+	}
+
+	return out, nil
+}
+
+func (s *SQL) SetPantryGroup(g dbtypes.PantryGroup) error {
+	if g.User == "" {
+		return errors.New("user cannot be empty")
+	} else if g.Name == "" {
+		return errors.New("name cannot be empty")
+	} else if err := g.Validate(); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %v", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
+
+	q := `SELECT COUNT(*) FROM pantries WHERE user = ? AND name = ?`
+	s.log.Trace(q)
+
+	for _, p := range g.Pantries {
+		var count int
+		if err := tx.QueryRowContext(s.ctx, q, g.User, p).Scan(&count); err != nil {
+			return fmt.Errorf("could not query pantries: %v", err)
+		} else if count == 0 {
+			return fmt.Errorf("pantry %q: %w", p, fs.ErrNotExist)
+		}
+	}
+
+	_, err = tx.ExecContext(s.ctx, "INSERT INTO pantry_groups (user, name) VALUES (?, ?)", g.User, g.Name)
+	if err != nil && !errorIs(err, errKeyExists) {
+		return fmt.Errorf("could not insert pantry group: %v", err)
+	}
+
+	query := `DELETE FROM pantry_group_members WHERE user = ? AND grp = ?`
+	s.log.Trace(query)
+
+	if _, err := tx.ExecContext(s.ctx, query, g.User, g.Name); err != nil {
+		return fmt.Errorf("could not delete old pantry group members: %v", err)
+	}
+
+	type memberRow struct {
+		pos    int
+		pantry string
+	}
+
+	members := make([]memberRow, 0, len(g.Pantries))
+	for i, p := range g.Pantries {
+		members = append(members, memberRow{pos: i, pantry: p})
+	}
+
+	err = bulkInsert(s, tx, "pantry_group_members (user, grp, pos, pantry)", members, func(m memberRow) []any {
+		return []any{g.User, g.Name, m.pos, m.pantry}
+	})
+	if err != nil {
+		return fmt.Errorf("could not insert pantry group members: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SQL) DeletePantryGroup(user, name string) error {
+	if user == "" {
+		return errors.New("user cannot be empty")
+	} else if name == "" {
+		return errors.New("name cannot be empty")
+	}
+
+	query := `DELETE FROM pantry_groups WHERE user = ? AND name = ?`
+	s.log.Trace(query)
+
+	if _, err := s.db.ExecContext(s.ctx, query, user, name); err != nil {
+		return fmt.Errorf("could not delete pantry group: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,181 @@
+package mysql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+)
+
+// appendPantryChange adds an entry to the ledger of the pantry, unless nothing changed.
+func (s *SQL) appendPantryChange(tx *sql.Tx, from, to dbtypes.Pantry, reason dbtypes.PantryReason) error {
+	if dbtypes.PantryUnchanged(from, to) {
+		return nil
+	}
+
+	query := `SELECT COALESCE(MAX(entry), 0) FROM pantry_ledger WHERE user = ? AND pantry = ?`
+	s.log.Trace(query)
+
+	var last int
+	if err := tx.QueryRowContext(s.ctx, query, to.User, to.Name).Scan(&last); err != nil {
+		return fmt.Errorf("could not query last ledger entry: %v", err)
+	}
+
+	deltas, err := json.Marshal(dbtypes.PantryDeltas(from, to))
+	if err != nil {
+		return fmt.Errorf("could not marshal deltas: %v", err)
+	}
+
+	snapshot, err := json.Marshal(to)
+	if err != nil {
+		return fmt.Errorf("could not marshal snapshot: %v", err)
+	}
+
+	query = `INSERT INTO pantry_ledger (user, pantry, entry, author, timestamp, reason, deltas, snapshot) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	s.log.Trace(query)
+
+	_, err = tx.ExecContext(s.ctx, query, to.User, to.Name, last+1, to.User, time.Now().Unix(), reason, string(deltas), string(snapshot))
+	if err != nil {
+		return fmt.Errorf("could not insert ledger entry: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SQL) PantryLedger(user, name string) ([]dbtypes.PantryChange, error) {
+	if user == "" {
+		return nil, errors.New("user cannot be empty")
+	} else if name == "" {
+		return nil, errors.New("name cannot be empty")
+	}
+
+	tx, err := s.db.BeginTx(s.ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("could not begin transaction: %v", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
+
+	out, err := s.queryPantryLedger(tx, user, name, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return out, nil
+}
+
+func (s *SQL) RollbackPantry(user, name string, entry int) error {
+	if user == "" {
+		return errors.New("user cannot be empty")
+	} else if name == "" {
+		return errors.New("name cannot be empty")
+	}
+
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %v", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
+
+	if _, err := s.lockPantry(tx, user, name); err != nil {
+		return err
+	}
+
+	changes, err := s.queryPantryLedger(tx, user, name, "AND entry >= ?", entry)
+	if err != nil {
+		return err
+	} else if len(changes) == 0 || changes[0].Entry != entry {
+		return fs.ErrNotExist
+	}
+
+	if dbtypes.CookedAfter(changes, entry) {
+		var count int
+		q := `SELECT COUNT(*) FROM cooked_meals WHERE user = ? AND pantry = ?`
+		s.log.Trace(q)
+
+		if err := tx.QueryRowContext(s.ctx, q, user, name).Scan(&count); err != nil {
+			return fmt.Errorf("could not query cooked meals: %v", err)
+		} else if count > 0 {
+			return fs.ErrExist
+		}
+	}
+
+	if err := s.setPantry(tx, changes[0].Snapshot, dbtypes.PantryRollback); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+// queryPantryLedger returns the ledger entries of a pantry that satisfy the condition.
+// It returns fs.ErrNotExist if the pantry does not exist.
+func (s *SQL) queryPantryLedger(tx *sql.Tx, user, name string, condition string, args ...any) ([]dbtypes.PantryChange, error) {
+	var count int
+	q := `SELECT COUNT(*) FROM pantries WHERE user = ? AND name = ?`
+	s.log.Trace(q)
+
+	if err := tx.QueryRowContext(s.ctx, q, user, name).Scan(&count); err != nil {
+		return nil, fmt.Errorf("could not query pantries: %v", err)
+	} else if count == 0 {
+		return nil, fs.ErrNotExist
+	}
+
+	//nolint:gosec // The condition is constructed by the code, not user input
+	query := `
+	SELECT
+		entry, author, timestamp, reason, deltas, snapshot
+	FROM
+		pantry_ledger
+	WHERE
+		user = ? AND pantry = ?
+		` + condition + `
+	ORDER BY
+		entry
+	`
+	s.log.Trace(query)
+
+	rows, err := tx.QueryContext(s.ctx, query, append([]any{user, name}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ledger: %v", err)
+	}
+	defer rows.Close()
+
+	out := make([]dbtypes.PantryChange, 0)
+	for rows.Next() {
+		var c dbtypes.PantryChange
+		var timestamp int64
+		var deltas, snapshot string
+
+		if err := rows.Scan(&c.Entry, &c.Author, &timestamp, &c.Reason, &deltas, &snapshot); err != nil {
+			return nil, fmt.Errorf("could not scan ledger entry: %v", err)
+		}
+
+		if err := json.Unmarshal([]byte(deltas), &c.Deltas); err != nil {
+			return nil, fmt.Errorf("could not unmarshal deltas of entry %d: %v", c.Entry, err)
+		}
+
+		if err := json.Unmarshal([]byte(snapshot), &c.Snapshot); err != nil {
+			return nil, fmt.Errorf("could not unmarshal snapshot of entry %d: %v", c.Entry, err)
+		}
+
+		c.Timestamp = time.Unix(timestamp, 0).UTC()
+		out = append(out, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not get ledger: %v", err)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,136 @@
+package mysql
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+)
+
+var pantryStapleTables = []tableDef{
+	{
+		name: "pantry_staples",
+		columns: []string{
+			"user VARCHAR(255) NOT NULL",
+			"pantry VARCHAR(255) NOT NULL",
+			"product INT UNSIGNED NOT NULL",
+			"minimum FLOAT NOT NULL",
+			"FOREIGN KEY (user) REFERENCES users(id) ON DELETE CASCADE",
+			"FOREIGN KEY (user, pantry) REFERENCES pantries(user, name) ON DELETE CASCADE",
+			"FOREIGN KEY (product) REFERENCES products(id) ON DELETE CASCADE",
+			"PRIMARY KEY (user, pantry, product)",
+		},
+	},
+}
+
+func (s *SQL) PantryStaples(user, pantry string) (dbtypes.PantryStaples, error) {
+	if user == "" {
+		return dbtypes.PantryStaples{}, errors.New("user cannot be empty")
+	} else if pantry == "" {
+		return dbtypes.PantryStaples{}, errors.New("pantry cannot be empty")
+	}
+
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return dbtypes.PantryStaples{}, fmt.Errorf("could not begin transaction: %v", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
+
+	var count int
+	q := `SELECT COUNT(*) FROM pantries WHERE user = ? AND name = ?`
+	s.log.Trace(q)
+
+	if err := tx.QueryRowContext(s.ctx, q, user, pantry).Scan(&count); err != nil {
+		return dbtypes.PantryStaples{}, fmt.Errorf("could not query pantries: %v", err)
+	} else if count == 0 {
+		return dbtypes.PantryStaples{}, fs.ErrNotExist
+	}
+
+	query := `
+		SELECT
+			product, minimum
+		FROM
+			pantry_staples
+		WHERE
+			user = ? AND pantry = ?
+		ORDER BY
+			product`
+	s.log.Trace(query)
+
+	r, err := tx.QueryContext(s.ctx, query, user, pantry)
+	if err != nil {
+		return dbtypes.PantryStaples{}, fmt.Errorf("could not query pantry staples: %v", err)
+	}
+	defer r.Close()
+
+	out := dbtypes.PantryStaples{
+		User:    user,
+		Pantry:  pantry,
+		Staples: make([]dbtypes.Staple, 0),
+	}
+
+	for r.Next() {
+		var st dbtypes.Staple
+		if err := r.Scan(&st.ProductID, &st.Minimum); err != nil {
+			return dbtypes.PantryStaples{}, fmt.Errorf("could not scan pantry staple: %v", err)
+		}
+		out.Staples = append(out.Staples, st)
+	}
+
+	if err := r.Err(); err != nil {
+		return dbtypes.PantryStaples{}, fmt.Errorf("could not get pantry staples: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return dbtypes.PantryStaples{}, fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return out, nil
+}
+
+func (s *SQL) SetPantryStaples(p dbtypes.PantryStaples) error {
+	if p.User == "" {
+		return errors.New("user cannot be empty")
+	} else if p.Pantry == "" {
+		return errors.New("pantry cannot be empty")
+	} else if err := p.Validate(); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %v", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
+
+	var count int
+	q := `SELECT COUNT(*) FROM pantries WHERE user = ? AND name = ?`
+	s.log.Trace(q)
+
+	if err := tx.QueryRowContext(s.ctx, q, p.User, p.Pantry).Scan(&count); err != nil {
+		return fmt.Errorf("could not query pantries: %v", err)
+	} else if count == 0 {
+		return fs.ErrNotExist
+	}
+
+	query := `DELETE FROM pantry_staples WHERE user = ? AND pantry = ?`
+	s.log.Trace(query)
+
+	if _, err := tx.ExecContext(s.ctx, query, p.User, p.Pantry); err != nil {
+		return fmt.Errorf("could not delete old pantry staples: %v", err)
+	}
+
+	err = bulkInsert(s, tx, "pantry_staples (user, pantry, product, minimum)", p.Staples, func(st dbtypes.Staple) []any {
+		return []any{p.User, p.Pantry, st.ProductID, st.Minimum}
+	})
+	if err != nil {
+		return fmt.Errorf("could not insert pantry staples: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return nil
+}
@@ -1,8 +1,10 @@
 package mysql
 
 import (
+	"database/sql"
 	"fmt"
 	"io/fs"
+	"time"
 
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
@@ -24,8 +26,27 @@ var productTables = []tableDef{
 			"provider_id2 VARCHAR(255) NOT NULL",
 		},
 	},
+	{
+		name: "product_prices",
+		columns: []string{
+			"product INT UNSIGNED PRIMARY KEY",
+			"updated DATETIME NOT NULL",
+			"FOREIGN KEY (product) REFERENCES products(id) ON DELETE CASCADE",
+		},
+	},
+	{
+		name: "product_units",
+		columns: []string{
+			"product INT UNSIGNED PRIMARY KEY",
+			"unit VARCHAR(32) NOT NULL",
+			"FOREIGN KEY (product) REFERENCES products(id) ON DELETE CASCADE",
+		},
+	},
 }
 
+// dateTimeLayout is the format of DATETIME columns, which are scanned as strings.
+const dateTimeLayout = time.DateTime
+
 func (s *SQL) Products() ([]product.Product, error) {
 	query := `
 	SELECT 
@@ -36,8 +57,12 @@ func (s *SQL) Products() ([]product.Product, error) {
 		provider,
 		provider_id0,
 		provider_id1,
-		provider_id2
+		provider_id2,
+		updated,
+		unit
 	FROM products
+	LEFT JOIN product_prices ON product_prices.product = products.id
+	LEFT JOIN product_units ON product_units.product = products.id
 	`
 	s.log.Trace(query)
 
@@ -74,8 +99,12 @@ func (s *SQL) LookupProduct(ID product.ID) (product.Product, error) {
 		provider,
 		provider_id0,
 		provider_id1,
-		provider_id2
+		provider_id2,
+		updated,
+		unit
 	FROM products
+	LEFT JOIN product_prices ON product_prices.product = products.id
+	LEFT JOIN product_units ON product_units.product = products.id
 	WHERE id = ?
 	`
 	s.log.Trace(query)
@@ -100,23 +129,34 @@ func (s *SQL) SetProduct(p product.Product) (product.ID, error) {
 	}
 	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
 
-	verb := "REPLACE"
+	// Existing products are updated in place: deleting the row would cascade to
+	// everything that references it, such as pantry lots, staples and purchases.
+	upsert := `
+		ON DUPLICATE KEY UPDATE
+			name = new.name,
+			batch_size = new.batch_size,
+			price = new.price,
+			provider = new.provider,
+			provider_id0 = new.provider_id0,
+			provider_id1 = new.provider_id1,
+			provider_id2 = new.provider_id2`
+
 	if p.ID == 0 {
 		// Generate a new IDs until we find one that doesn't exist
 		// We never expect to have anywhere near 2^32 (4.3 billion) products
 		// Collisions are extremely unlikely, but taken care of with the loop
-		verb = "INSERT"
+		upsert = ""
 		p.ID = product.NewRandomID()
 	}
 
 	for {
 		//nolint:gosec // This is safe because both halves of the query are hardcoded
-		query := verb + ` INTO
+		query := `INSERT INTO
 			products
 			(id, name, batch_size, price, provider, provider_id0, provider_id1, provider_id2)
 		VALUES 
 			(?, ?, ?, ?, ?, ?, ?, ?)
-		`
+		AS new` + upsert
 		s.log.Trace(query)
 
 		argv := []any{p.ID, p.Name, p.BatchSize, p.Price, p.Provider.Name(), p.ProductCode[0], p.ProductCode[1], p.ProductCode[2]}
@@ -137,6 +177,14 @@ func (s *SQL) SetProduct(p product.Product) (product.ID, error) {
 		return 0, fmt.Errorf("could not insert into table products: %v", err)
 	}
 
+	if err := s.setProductPrice(tx, p); err != nil {
+		return 0, err
+	}
+
+	if err := s.setProductUnit(tx, p); err != nil {
+		return 0, err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return 0, fmt.Errorf("could not commit transaction: %v", err)
 	}
@@ -144,6 +192,45 @@ func (s *SQL) SetProduct(p product.Product) (product.ID, error) {
 	return p.ID, nil
 }
 
+// setProductPrice stores when the price of the product was last updated. A zero time means it is not
+// known by the caller, so the stored time is kept.
+func (s *SQL) setProductPrice(tx *sql.Tx, p product.Product) error {
+	if p.PriceUpdated.IsZero() {
+		return nil
+	}
+
+	query := `REPLACE INTO product_prices (product, updated) VALUES (?, ?)`
+	s.log.Trace(query)
+
+	if _, err := tx.ExecContext(s.ctx, query, p.ID, p.PriceUpdated.UTC().Format(dateTimeLayout)); err != nil {
+		return fmt.Errorf("could not insert into table product_prices: %v", err)
+	}
+
+	return nil
+}
+
+// setProductUnit stores the unit the product is measured in. An empty unit means it is unknown.
+func (s *SQL) setProductUnit(tx *sql.Tx, p product.Product) error {
+	if p.Unit == "" {
+		query := `DELETE FROM product_units WHERE product = ?`
+		s.log.Trace(query)
+
+		if _, err := tx.ExecContext(s.ctx, query, p.ID); err != nil {
+			return fmt.Errorf("could not delete from table product_units: %v", err)
+		}
+		return nil
+	}
+
+	query := `REPLACE INTO product_units (product, unit) VALUES (?, ?)`
+	s.log.Trace(query)
+
+	if _, err := tx.ExecContext(s.ctx, query, p.ID, p.Unit); err != nil {
+		return fmt.Errorf("could not insert into table product_units: %v", err)
+	}
+
+	return nil
+}
+
 func (s *SQL) DeleteProduct(ID product.ID) error {
 	query := `DELETE FROM products WHERE id = ?`
 	s.log.Trace(query)
@@ -163,14 +250,24 @@ func (s *SQL) DeleteProduct(ID product.ID) error {
 func parseProduct(log logger.Logger, r interface{ Scan(...any) error }) (p product.Product, err error) {
 	var provider string
 	var productCode [3]string
+	var updated, unit sql.NullString
 
-	err = r.Scan(&p.ID, &p.Name, &p.BatchSize, &p.Price, &provider, &productCode[0], &productCode[1], &productCode[2])
+	err = r.Scan(&p.ID, &p.Name, &p.BatchSize, &p.Price, &provider, &productCode[0], &productCode[1], &productCode[2], &updated, &unit)
 	if errorIs(err, errKeyNotFound) {
 		return p, fs.ErrNotExist
 	} else if err != nil {
 		return p, fmt.Errorf("could not scan product: %v", err)
 	}
 
+	if updated.Valid {
+		p.PriceUpdated, err = time.ParseInLocation(dateTimeLayout, updated.String, time.UTC)
+		if err != nil {
+			return p, fmt.Errorf("could not parse price timestamp: %v", err)
+		}
+	}
+
+	p.Unit = unit.String
+
 	if prov, ok := providers.Lookup(provider); !ok {
 		log.Warningf("could not find provider %q", provider)
 		p.Provider = blank.Provider{}
@@ -0,0 +1,258 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+)
+
+var purchaseTables = []tableDef{
+	{
+		// Purchases keep the names of their menu and pantry, so that the spend is not lost when they are deleted.
+		name: "purchases",
+		columns: []string{
+			"id INT UNSIGNED NOT NULL AUTO_INCREMENT",
+			"user VARCHAR(255) NOT NULL",
+			"menu VARCHAR(255) NOT NULL",
+			"pantry VARCHAR(255) NOT NULL",
+			"date DATETIME NOT NULL",
+			"FOREIGN KEY (user) REFERENCES users(id) ON DELETE CASCADE",
+			"PRIMARY KEY (id)",
+		},
+	},
+	{
+		// Items do not reference the products either, so that deleting a product keeps the purchases that included it.
+		name: "purchase_items",
+		columns: []string{
+			"purchase INT UNSIGNED NOT NULL",
+			"pos INT NOT NULL",
+			"product INT UNSIGNED NOT NULL",
+			"packs INT NOT NULL",
+			"price FLOAT NOT NULL",
+			"FOREIGN KEY (purchase) REFERENCES purchases(id) ON DELETE CASCADE",
+			"PRIMARY KEY (purchase, pos)",
+		},
+	},
+}
+
+func (s *SQL) Purchases(user, menu, pantry string) ([]dbtypes.Purchase, error) {
+	if user == "" {
+		return nil, errors.New("user cannot be empty")
+	}
+
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not begin transaction: %v", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
+
+	ids, purchases, err := s.queryPurchases(tx, user, menu, pantry)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.queryPurchaseItems(tx, ids, purchases); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return purchases, nil
+}
+
+// queryPurchases returns the purchases without their items, and their IDs in the same order.
+func (s *SQL) queryPurchases(tx *sql.Tx, user, menu, pantry string) ([]int, []dbtypes.Purchase, error) {
+	query := `
+		SELECT
+			id, date
+		FROM
+			purchases
+		WHERE
+			user = ? AND menu = ? AND pantry = ?
+		ORDER BY
+			date, id`
+	s.log.Trace(query)
+
+	r, err := tx.QueryContext(s.ctx, query, user, menu, pantry)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not query purchases: %v", err)
+	}
+	defer r.Close()
+
+	ids := make([]int, 0)
+	purchases := make([]dbtypes.Purchase, 0)
+	for r.Next() {
+		var id int
+		var date string
+		if err := r.Scan(&id, &date); err != nil {
+			return nil, nil, fmt.Errorf("could not scan purchase: %v", err)
+		}
+
+		p := dbtypes.Purchase{
+			User:   user,
+			Menu:   menu,
+			Pantry: pantry,
+			Items:  make([]dbtypes.PurchaseItem, 0),
+		}
+
+		if p.Date, err = time.ParseInLocation(dateTimeLayout, date, time.UTC); err != nil {
+			return nil, nil, fmt.Errorf("could not parse purchase date: %v", err)
+		}
+
+		ids = append(ids, id)
+		purchases = append(purchases, p)
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, nil, fmt.Errorf("could not get purchases: %v", err)
+	}
+
+	return ids, purchases, nil
+}
+
+// queryPurchaseItems fills in the items of every purchase.
+func (s *SQL) queryPurchaseItems(tx *sql.Tx, ids []int, purchases []dbtypes.Purchase) error {
+	query := `
+		SELECT
+			product, packs, price
+		FROM
+			purchase_items
+		WHERE
+			purchase = ?
+		ORDER BY
+			pos`
+	s.log.Trace(query)
+
+	for i, id := range ids {
+		if err := func() error {
+			r, err := tx.QueryContext(s.ctx, query, id)
+			if err != nil {
+				return fmt.Errorf("could not query purchase items: %v", err)
+			}
+			defer r.Close()
+
+			for r.Next() {
+				var item dbtypes.PurchaseItem
+				if err := r.Scan(&item.ProductID, &item.Packs, &item.Price); err != nil {
+					return fmt.Errorf("could not scan purchase item: %v", err)
+				}
+
+				purchases[i].Items = append(purchases[i].Items, item)
+			}
+
+			if err := r.Err(); err != nil {
+				return fmt.Errorf("could not get purchase items: %v", err)
+			}
+
+			return nil
+		}(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SQL) AddPurchase(p dbtypes.Purchase, lots []dbtypes.PantryLot, updatePrices bool) (dbtypes.ShoppingList, error) {
+	if p.User == "" {
+		return dbtypes.ShoppingList{}, errors.New("user cannot be empty")
+	} else if p.Menu == "" {
+		return dbtypes.ShoppingList{}, errors.New("menu cannot be empty")
+	} else if p.Pantry == "" {
+		return dbtypes.ShoppingList{}, errors.New("pantry cannot be empty")
+	}
+
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return dbtypes.ShoppingList{}, fmt.Errorf("could not begin transaction: %v", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
+
+	pantry, err := s.lockPantry(tx, p.User, p.Pantry)
+	if err != nil {
+		return dbtypes.ShoppingList{}, err
+	}
+
+	pantry.Add(lots)
+
+	if err := s.setPantry(tx, pantry, dbtypes.PantryPurchased); err != nil {
+		return dbtypes.ShoppingList{}, err
+	}
+
+	// The products bought are now in the pantry, so they are no longer marked done
+	ops := make([]dbtypes.ShoppingListOp, 0, len(p.Items))
+	for _, item := range p.Items {
+		ops = append(ops, dbtypes.ShoppingListOp{ProductID: item.ProductID, Done: false})
+	}
+
+	list, err := s.updateShoppingList(tx, p.User, p.Menu, p.Pantry, ops)
+	if err != nil {
+		return dbtypes.ShoppingList{}, err
+	}
+
+	if updatePrices {
+		if err := s.setPurchasePrices(tx, p); err != nil {
+			return dbtypes.ShoppingList{}, err
+		}
+	}
+
+	query := `INSERT INTO purchases (user, menu, pantry, date) VALUES (?, ?, ?, ?)`
+	s.log.Trace(query)
+
+	result, err := tx.ExecContext(s.ctx, query, p.User, p.Menu, p.Pantry, p.Date.UTC().Format(dateTimeLayout))
+	if err != nil {
+		return dbtypes.ShoppingList{}, fmt.Errorf("could not insert purchase: %v", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return dbtypes.ShoppingList{}, fmt.Errorf("could not get last insert ID: %v", err)
+	}
+
+	type itemRow struct {
+		pos int
+		dbtypes.PurchaseItem
+	}
+
+	items := make([]itemRow, 0, len(p.Items))
+	for i, item := range p.Items {
+		items = append(items, itemRow{pos: i, PurchaseItem: item})
+	}
+
+	err = bulkInsert(s, tx, "purchase_items (purchase, pos, product, packs, price)", items, func(i itemRow) []any {
+		return []any{id, i.pos, i.ProductID, i.Packs, i.Price}
+	})
+	if err != nil {
+		return dbtypes.ShoppingList{}, fmt.Errorf("could not insert purchase items: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return dbtypes.ShoppingList{}, fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return list, nil
+}
+
+// setPurchasePrices makes the prices paid in the purchase the new prices of the products.
+func (s *SQL) setPurchasePrices(tx *sql.Tx, p dbtypes.Purchase) error {
+	query := `UPDATE products SET price = ? WHERE id = ?`
+	s.log.Trace(query)
+
+	for _, item := range p.Items {
+		if _, err := tx.ExecContext(s.ctx, query, item.Price, item.ProductID); err != nil {
+			return fmt.Errorf("could not update the price of product %d: %v", item.ProductID, err)
+		}
+
+		if err := s.setProductPrice(tx, product.Product{ID: item.ProductID, PriceUpdated: p.Date}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
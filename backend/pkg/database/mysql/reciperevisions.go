@@ -0,0 +1,115 @@
+package mysql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+)
+
+// appendRevision stores a snapshot of the recipe as its latest revision.
+func (s *SQL) appendRevision(tx *sql.Tx, r recipe.Recipe) error {
+	query := `SELECT COALESCE(MAX(revision), 0) FROM recipe_revisions WHERE recipe = ?`
+	s.log.Tracef(query)
+
+	var last int
+	if err := tx.QueryRowContext(s.ctx, query, r.ID).Scan(&last); err != nil {
+		return fmt.Errorf("could not query last revision: %v", err)
+	}
+
+	snapshot, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("could not marshal snapshot: %v", err)
+	}
+
+	query = `INSERT INTO recipe_revisions (recipe, revision, author, timestamp, snapshot) VALUES (?, ?, ?, ?, ?)`
+	s.log.Tracef(query)
+
+	_, err = tx.ExecContext(s.ctx, query, r.ID, last+1, r.User, time.Now().Unix(), string(snapshot))
+	if err != nil {
+		return fmt.Errorf("could not insert revision: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SQL) RecipeRevisions(asUser string, id recipe.ID) ([]dbtypes.RecipeRevision, error) {
+	return s.queryRevisions(asUser, id, "")
+}
+
+func (s *SQL) LookupRecipeRevision(asUser string, id recipe.ID, revision int) (dbtypes.RecipeRevision, error) {
+	revs, err := s.queryRevisions(asUser, id, "AND recipe_revisions.revision = ?", revision)
+	if err != nil {
+		return dbtypes.RecipeRevision{}, err
+	}
+
+	if len(revs) == 0 {
+		return dbtypes.RecipeRevision{}, fs.ErrNotExist
+	}
+
+	return revs[0], nil
+}
+
+func (s *SQL) queryRevisions(asUser string, id recipe.ID, condition string, args ...any) ([]dbtypes.RecipeRevision, error) {
+	tx, err := s.db.BeginTx(s.ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("could not begin transaction: %v", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
+
+	if err := s.checkRecipeOwner(tx, asUser, id); err != nil {
+		return nil, err
+	}
+
+	//nolint:gosec // The condition is constructed by the code, not user input
+	query := `
+	SELECT
+		revision, author, timestamp, snapshot
+	FROM
+		recipe_revisions
+	WHERE
+		recipe = ?
+		` + condition + `
+	ORDER BY
+		revision
+	`
+	s.log.Tracef(query)
+
+	rows, err := tx.QueryContext(s.ctx, query, append([]any{id}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("could not query revisions: %v", err)
+	}
+	defer rows.Close()
+
+	out := make([]dbtypes.RecipeRevision, 0)
+	for rows.Next() {
+		var rev dbtypes.RecipeRevision
+		var timestamp int64
+		var snapshot string
+
+		if err := rows.Scan(&rev.Revision, &rev.Author, &timestamp, &snapshot); err != nil {
+			return nil, fmt.Errorf("could not scan revision: %v", err)
+		}
+
+		if err := json.Unmarshal([]byte(snapshot), &rev.Recipe); err != nil {
+			return nil, fmt.Errorf("could not unmarshal revision %d: %v", rev.Revision, err)
+		}
+
+		rev.Timestamp = time.Unix(timestamp, 0).UTC()
+		out = append(out, rev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not get revisions: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return out, nil
+}
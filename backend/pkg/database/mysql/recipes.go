@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"slices"
 
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/utils"
@@ -31,22 +32,61 @@ var recipeTables = []tableDef{
 			"PRIMARY KEY (recipe, product)",
 		},
 	},
+	{
+		name: "recipe_subrecipes",
+		columns: []string{
+			"recipe INT UNSIGNED REFERENCES recipes(id) ON DELETE CASCADE",
+			"subrecipe INT UNSIGNED REFERENCES recipes(id) ON DELETE CASCADE",
+			"amount FLOAT NOT NULL",
+			"PRIMARY KEY (recipe, subrecipe)",
+		},
+	},
+	{
+		// The original is not a foreign key: forks outlive the recipe they were copied from
+		name: "recipe_forks",
+		columns: []string{
+			"recipe INT UNSIGNED NOT NULL",
+			"original INT UNSIGNED NOT NULL",
+			"FOREIGN KEY (recipe) REFERENCES recipes(id) ON DELETE CASCADE",
+			"PRIMARY KEY (recipe)",
+		},
+	},
+	{
+		name: "recipe_revisions",
+		columns: []string{
+			"recipe INT UNSIGNED NOT NULL",
+			"revision INT UNSIGNED NOT NULL",
+			"author VARCHAR(255) NOT NULL",
+			"timestamp BIGINT NOT NULL",
+			"snapshot TEXT NOT NULL",
+			"FOREIGN KEY (recipe) REFERENCES recipes(id) ON DELETE CASCADE",
+			"PRIMARY KEY (recipe, revision)",
+		},
+	},
 }
 
 func (s *SQL) Recipes(user string) ([]recipe.Recipe, error) {
+	return s.recipesWhere("recipes.user = ?", user)
+}
+
+// recipesWhere returns the recipes that satisfy the condition, including their ingredients.
+func (s *SQL) recipesWhere(condition string, args ...any) ([]recipe.Recipe, error) {
 	tx, err := s.db.BeginTx(s.ctx, &sql.TxOptions{ReadOnly: true})
 	if err != nil {
 		return nil, fmt.Errorf("could not begin transaction: %v", err)
 	}
 	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
 
-	recs, err := s.queryRecipes(tx, user)
+	recs, err := s.queryRecipes(tx, condition, args...)
 	if err != nil {
 		return nil, fmt.Errorf("could not query recipes: %v", err)
 	}
 
 	for i := range recs {
 		err := s.queryIngredients(tx, &recs[i])
+		if err == nil {
+			err = s.querySubRecipes(tx, &recs[i])
+		}
 		if err != nil {
 			s.log.Warningf("could not get recipe %d %s: %v", recs[i].ID, recs[i].Name, err)
 			recs[i].ID = 0 // Mark as invalid
@@ -74,8 +114,20 @@ func (s *SQL) LookupRecipe(asUser string, id recipe.ID) (recipe.Recipe, error) {
 		ID: id,
 	}
 
-	row := tx.QueryRowContext(s.ctx, "SELECT user, name FROM recipes WHERE id = ? AND user = ?", id, asUser)
-	if err := row.Scan(&rec.User, &rec.Name); errorIs(err, errKeyNotFound) {
+	query := `
+	SELECT
+		recipes.user, recipes.name, COALESCE(recipe_forks.original, 0)
+	FROM
+		recipes
+		LEFT JOIN recipe_forks ON recipe_forks.recipe = recipes.id
+	WHERE
+		recipes.id = ?
+		AND recipes.user = ?
+	`
+	s.log.Tracef(query)
+
+	row := tx.QueryRowContext(s.ctx, query, id, asUser)
+	if err := row.Scan(&rec.User, &rec.Name, &rec.ForkedFrom); errorIs(err, errKeyNotFound) {
 		return rec, fs.ErrNotExist
 	} else if err != nil {
 		return rec, fmt.Errorf("could not scan: %v", err)
@@ -90,6 +142,10 @@ func (s *SQL) LookupRecipe(asUser string, id recipe.ID) (recipe.Recipe, error) {
 		return rec, fmt.Errorf("could not get recipe %d %s: %v", rec.ID, rec.Name, err)
 	}
 
+	if err := s.querySubRecipes(tx, &rec); err != nil {
+		return rec, fmt.Errorf("could not get recipe %d %s: %v", rec.ID, rec.Name, err)
+	}
+
 	if err := tx.Commit(); err != nil {
 		return rec, fmt.Errorf("could not commit transaction: %v", err)
 	}
@@ -97,11 +153,20 @@ func (s *SQL) LookupRecipe(asUser string, id recipe.ID) (recipe.Recipe, error) {
 	return rec, nil
 }
 
-func (s *SQL) queryRecipes(tx *sql.Tx, user string) ([]recipe.Recipe, error) {
-	query := `SELECT id, user, name FROM recipes WHERE user = ?`
+// queryRecipes returns the recipes that satisfy the condition, without their ingredients.
+func (s *SQL) queryRecipes(tx *sql.Tx, condition string, args ...any) ([]recipe.Recipe, error) {
+	//nolint:gosec // The condition is constructed by the code, not user input
+	query := `
+	SELECT
+		recipes.id, recipes.user, recipes.name, COALESCE(recipe_forks.original, 0)
+	FROM
+		recipes
+		LEFT JOIN recipe_forks ON recipe_forks.recipe = recipes.id
+	WHERE
+		` + condition
 
 	s.log.Tracef(query)
-	r, err := tx.QueryContext(s.ctx, query, user)
+	r, err := tx.QueryContext(s.ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("could not query: %v", err)
 	}
@@ -110,7 +175,7 @@ func (s *SQL) queryRecipes(tx *sql.Tx, user string) ([]recipe.Recipe, error) {
 	var recs []recipe.Recipe
 	for r.Next() {
 		var rec recipe.Recipe
-		if err := r.Scan(&rec.ID, &rec.User, &rec.Name); err != nil {
+		if err := r.Scan(&rec.ID, &rec.User, &rec.Name, &rec.ForkedFrom); err != nil {
 			s.log.Warnf("could not scan: %v", err)
 			continue
 		}
@@ -157,19 +222,130 @@ func (s *SQL) queryIngredients(tx *sql.Tx, rec *recipe.Recipe) error {
 	return nil
 }
 
-func (s *SQL) SetRecipe(r recipe.Recipe) (recipe.ID, error) {
-	if r.User == "" {
-		return 0, errors.New("user cannot be empty")
-	} else if r.Name == "" {
-		return 0, errors.New("name cannot be empty")
+func (s *SQL) querySubRecipes(tx *sql.Tx, rec *recipe.Recipe) error {
+	query := `
+	SELECT
+		subrecipe, amount
+	FROM
+		recipe_subrecipes
+	WHERE
+		recipe = ?
+	`
+
+	s.log.Tracef(query)
+	rows, err := tx.QueryContext(s.ctx, query, rec.ID)
+	if err != nil {
+		return fmt.Errorf("could not query sub-recipes: %v", err)
+	}
+	defer rows.Close()
+
+	// Left as nil when empty, so that recipes without sub-recipes look the same in every database
+	rec.SubRecipes = nil
+	for rows.Next() {
+		var sub recipe.SubRecipe
+		if err := rows.Scan(&sub.RecipeID, &sub.Amount); err != nil {
+			return fmt.Errorf("could not scan sub-recipes: %v", err)
+		}
+		rec.SubRecipes = append(rec.SubRecipes, sub)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("could not get sub-recipes: %v", err)
 	}
 
+	return nil
+}
+
+func (s *SQL) SetRecipe(r recipe.Recipe) (recipe.ID, error) {
 	tx, err := s.db.BeginTx(s.ctx, nil)
 	if err != nil {
 		return 0, fmt.Errorf("could not begin transaction: %v", err)
 	}
 	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
 
+	id, err := s.setRecipe(tx, r)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return id, nil
+}
+
+func (s *SQL) SetRecipes(rs []recipe.Recipe) ([]recipe.ID, error) {
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not begin transaction: %v", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
+
+	ids := make([]recipe.ID, 0, len(rs))
+	for _, r := range rs {
+		id, err := s.setRecipe(tx, r)
+		if err != nil {
+			return nil, fmt.Errorf("could not set recipe %q: %v", r.Name, err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return ids, nil
+}
+
+func (s *SQL) ForkRecipes(copies []recipe.Recipe) ([]recipe.ID, error) {
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not begin transaction: %v", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
+
+	// forked maps the IDs of the original recipes to their copies
+	forked := make(map[recipe.ID]recipe.ID, len(copies))
+
+	ids := make([]recipe.ID, 0, len(copies))
+	for _, r := range copies {
+		if r.ForkedFrom == 0 {
+			return nil, fmt.Errorf("recipe %q is not a fork", r.Name)
+		}
+
+		r.ID = 0
+		r.SubRecipes = slices.Clone(r.SubRecipes)
+		for i, sub := range r.SubRecipes {
+			if id, ok := forked[sub.RecipeID]; ok {
+				r.SubRecipes[i].RecipeID = id
+			}
+		}
+
+		id, err := s.setRecipe(tx, r)
+		if err != nil {
+			return nil, fmt.Errorf("could not set copy of recipe %d: %v", r.ForkedFrom, err)
+		}
+
+		forked[r.ForkedFrom] = id
+		ids = append(ids, id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return ids, nil
+}
+
+func (s *SQL) setRecipe(tx *sql.Tx, r recipe.Recipe) (recipe.ID, error) {
+	if r.User == "" {
+		return 0, errors.New("user cannot be empty")
+	} else if r.Name == "" {
+		return 0, errors.New("name cannot be empty")
+	}
+
+	var err error
 	if r.ID == 0 {
 		if r.ID, err = s.insertNewRecipe(tx, r); err != nil {
 			return 0, fmt.Errorf("could not insert recipe: %v", err)
@@ -193,8 +369,21 @@ func (s *SQL) SetRecipe(r recipe.Recipe) (recipe.ID, error) {
 		return 0, fmt.Errorf("could not insert ingredients: %v", err)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return 0, fmt.Errorf("could not commit transaction: %v", err)
+	err = bulkInsert(s, tx,
+		"recipe_subrecipes(recipe, subrecipe, amount)",
+		r.SubRecipes, func(sub recipe.SubRecipe) []any {
+			return []any{uint(r.ID), uint(sub.RecipeID), sub.Amount}
+		})
+	if err != nil {
+		return 0, fmt.Errorf("could not insert sub-recipes: %v", err)
+	}
+
+	if err := s.setRecipeFork(tx, r); err != nil {
+		return 0, fmt.Errorf("could not set fork: %v", err)
+	}
+
+	if err := s.appendRevision(tx, r); err != nil {
+		return 0, fmt.Errorf("could not store revision: %v", err)
 	}
 
 	return r.ID, nil
@@ -267,6 +456,28 @@ func (s *SQL) insertNewRecipe(tx *sql.Tx, r recipe.Recipe) (recipe.ID, error) {
 	return utils.SafeIntConvert[recipe.ID](id)
 }
 
+func (s *SQL) setRecipeFork(tx *sql.Tx, r recipe.Recipe) error {
+	query := `DELETE FROM recipe_forks WHERE recipe = ?`
+	s.log.Tracef(query)
+
+	if _, err := tx.ExecContext(s.ctx, query, r.ID); err != nil {
+		return fmt.Errorf("could not delete old fork: %v", err)
+	}
+
+	if r.ForkedFrom == 0 {
+		return nil
+	}
+
+	query = `INSERT INTO recipe_forks (recipe, original) VALUES (?, ?)`
+	s.log.Tracef(query)
+
+	if _, err := tx.ExecContext(s.ctx, query, r.ID, r.ForkedFrom); err != nil {
+		return fmt.Errorf("could not insert fork: %v", err)
+	}
+
+	return nil
+}
+
 func (s *SQL) DeleteRecipe(asUser string, id recipe.ID) error {
 	tx, err := s.db.BeginTx(s.ctx, nil)
 	if err != nil {
@@ -313,5 +524,13 @@ func (s *SQL) deleteRecipeIngredients(tx *sql.Tx, id recipe.ID) error {
 		return fmt.Errorf("could not delete ingredients: %v", err)
 	}
 
+	query = `DELETE FROM recipe_subrecipes WHERE recipe = ?`
+	s.log.Tracef(query)
+
+	_, err = tx.ExecContext(s.ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("could not delete sub-recipes: %v", err)
+	}
+
 	return nil
 }
@@ -0,0 +1,175 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"slices"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+)
+
+var recipeSharingTables = []tableDef{
+	{
+		name: "recipe_public",
+		columns: []string{
+			"recipe INT UNSIGNED NOT NULL",
+			"FOREIGN KEY (recipe) REFERENCES recipes(id) ON DELETE CASCADE",
+			"PRIMARY KEY (recipe)",
+		},
+	},
+	{
+		name: "recipe_shares",
+		columns: []string{
+			"recipe INT UNSIGNED NOT NULL",
+			"user VARCHAR(255) NOT NULL",
+			"FOREIGN KEY (recipe) REFERENCES recipes(id) ON DELETE CASCADE",
+			"FOREIGN KEY (user) REFERENCES users(id) ON DELETE CASCADE",
+			"PRIMARY KEY (recipe, user)",
+		},
+	},
+}
+
+// sharedCondition selects the recipes that belong to someone else and are visible to the user.
+const sharedCondition = `
+	recipes.user != ?
+	AND (
+		recipes.id IN (SELECT recipe FROM recipe_public)
+		OR recipes.id IN (SELECT recipe FROM recipe_shares WHERE user = ?)
+	)`
+
+func (s *SQL) SharedRecipes(asUser string) ([]recipe.Recipe, error) {
+	return s.recipesWhere(sharedCondition, asUser, asUser)
+}
+
+func (s *SQL) LookupSharedRecipe(asUser string, id recipe.ID) (recipe.Recipe, error) {
+	recs, err := s.recipesWhere(`recipes.id = ? AND (recipes.user = ? OR (`+sharedCondition+`))`, id, asUser, asUser, asUser)
+	if err != nil {
+		return recipe.Recipe{}, err
+	}
+
+	if len(recs) == 0 {
+		return recipe.Recipe{}, fs.ErrNotExist
+	}
+
+	return recs[0], nil
+}
+
+func (s *SQL) LookupRecipeSharing(owner string, id recipe.ID) (dbtypes.RecipeSharing, error) {
+	tx, err := s.db.BeginTx(s.ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return dbtypes.RecipeSharing{}, fmt.Errorf("could not begin transaction: %v", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
+
+	if err := s.checkRecipeOwner(tx, owner, id); err != nil {
+		return dbtypes.RecipeSharing{}, err
+	}
+
+	sh := dbtypes.RecipeSharing{
+		Recipe: id,
+		Owner:  owner,
+	}
+
+	query := `SELECT COUNT(*) FROM recipe_public WHERE recipe = ?`
+	s.log.Tracef(query)
+
+	var count int
+	if err := tx.QueryRowContext(s.ctx, query, id).Scan(&count); err != nil {
+		return sh, fmt.Errorf("could not query public recipes: %v", err)
+	}
+	sh.Public = count > 0
+
+	query = `SELECT user FROM recipe_shares WHERE recipe = ? ORDER BY user`
+	s.log.Tracef(query)
+
+	rows, err := tx.QueryContext(s.ctx, query, id)
+	if err != nil {
+		return sh, fmt.Errorf("could not query shares: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user string
+		if err := rows.Scan(&user); err != nil {
+			return sh, fmt.Errorf("could not scan shares: %v", err)
+		}
+		sh.Users = append(sh.Users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return sh, fmt.Errorf("could not get shares: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return sh, fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return sh, nil
+}
+
+func (s *SQL) SetRecipeSharing(sh dbtypes.RecipeSharing) error {
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %v", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
+
+	if err := s.checkRecipeOwner(tx, sh.Owner, sh.Recipe); err != nil {
+		return fmt.Errorf("recipe %d does not belong to user %s: %w", sh.Recipe, sh.Owner, err)
+	}
+
+	for _, query := range []string{
+		`DELETE FROM recipe_public WHERE recipe = ?`,
+		`DELETE FROM recipe_shares WHERE recipe = ?`,
+	} {
+		s.log.Tracef(query)
+		if _, err := tx.ExecContext(s.ctx, query, sh.Recipe); err != nil {
+			return fmt.Errorf("could not delete old sharing: %v", err)
+		}
+	}
+
+	if sh.Public {
+		query := `INSERT INTO recipe_public (recipe) VALUES (?)`
+		s.log.Tracef(query)
+		if _, err := tx.ExecContext(s.ctx, query, sh.Recipe); err != nil {
+			return fmt.Errorf("could not publish recipe: %v", err)
+		}
+	}
+
+	users := slices.Clone(sh.Users)
+	slices.Sort(users)
+	users = slices.Compact(users)
+
+	err = bulkInsert(s, tx,
+		"recipe_shares(recipe, user)",
+		users, func(u string) []any {
+			return []any{uint(sh.Recipe), u}
+		})
+	if err != nil {
+		return fmt.Errorf("could not insert shares: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SQL) checkRecipeOwner(tx *sql.Tx, owner string, id recipe.ID) error {
+	query := `SELECT COUNT(*) FROM recipes WHERE id = ? AND user = ?`
+	s.log.Tracef(query)
+
+	var count int
+	if err := tx.QueryRowContext(s.ctx, query, id, owner).Scan(&count); err != nil {
+		return fmt.Errorf("could not query recipe: %v", err)
+	}
+
+	if count == 0 {
+		return fs.ErrNotExist
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package mysql
 
 import (
 	"cmp"
+	"database/sql"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -179,7 +180,94 @@ func (s *SQL) SetShoppingList(list dbtypes.ShoppingList) error {
 	}
 	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
 
-	_, err = tx.ExecContext(s.ctx, `
+	if err := s.setShoppingList(tx, list); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SQL) UpdateShoppingList(user, menu, pantry string, ops []dbtypes.ShoppingListOp) (dbtypes.ShoppingList, error) {
+	if user == "" {
+		return dbtypes.ShoppingList{}, errors.New("user cannot be empty")
+	} else if menu == "" {
+		return dbtypes.ShoppingList{}, errors.New("menu cannot be empty")
+	} else if pantry == "" {
+		return dbtypes.ShoppingList{}, errors.New("pantry cannot be empty")
+	}
+
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return dbtypes.ShoppingList{}, fmt.Errorf("could not start transaction: %v", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // The error is irrelevant
+
+	l, err := s.updateShoppingList(tx, user, menu, pantry, ops)
+	if err != nil {
+		return dbtypes.ShoppingList{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return dbtypes.ShoppingList{}, fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return l, nil
+}
+
+// updateShoppingList applies the operations to a shopping list and returns the list after them.
+func (s *SQL) updateShoppingList(tx *sql.Tx, user, menu, pantry string, ops []dbtypes.ShoppingListOp) (dbtypes.ShoppingList, error) {
+	// Each operation only touches its own row, so that concurrent updates to other products are kept
+	for _, o := range ops {
+		var err error
+		if o.Done {
+			_, err = tx.ExecContext(s.ctx, `INSERT INTO shopping_list_items (user, menu, pantry, product) VALUES (?, ?, ?, ?)`,
+				user, menu, pantry, o.ProductID)
+			if errorIs(err, errKeyExists) {
+				err = nil
+			}
+		} else {
+			_, err = tx.ExecContext(s.ctx, `DELETE FROM shopping_list_items WHERE user = ? AND menu = ? AND pantry = ? AND product = ?`,
+				user, menu, pantry, o.ProductID)
+		}
+
+		if err != nil {
+			return dbtypes.ShoppingList{}, fmt.Errorf("could not update shopping list item %d: %v", o.ProductID, err)
+		}
+	}
+
+	query := `SELECT product FROM shopping_list_items WHERE user = ? AND menu = ? AND pantry = ? ORDER BY product`
+	s.log.Trace(query)
+
+	r, err := tx.QueryContext(s.ctx, query, user, menu, pantry)
+	if err != nil {
+		return dbtypes.ShoppingList{}, fmt.Errorf("could not query shopping list items: %v", err)
+	}
+	defer r.Close()
+
+	l := dbtypes.ShoppingList{User: user, Menu: menu, Pantry: pantry, Contents: make([]product.ID, 0)}
+	for r.Next() {
+		var ID product.ID
+		if err := r.Scan(&ID); err != nil {
+			return dbtypes.ShoppingList{}, fmt.Errorf("could not scan shopping list item: %v", err)
+		}
+
+		l.Contents = append(l.Contents, ID)
+	}
+
+	if err := r.Err(); err != nil {
+		return dbtypes.ShoppingList{}, fmt.Errorf("could not get shopping list items: %v", err)
+	}
+
+	return l, nil
+}
+
+// setShoppingList replaces the items of the shopping list.
+func (s *SQL) setShoppingList(tx *sql.Tx, list dbtypes.ShoppingList) error {
+	_, err := tx.ExecContext(s.ctx, `
 		DELETE FROM
 			shopping_list_items
 		WHERE 
@@ -198,10 +286,6 @@ func (s *SQL) SetShoppingList(list dbtypes.ShoppingList) error {
 		return fmt.Errorf("could not insert shopping list items: %v", err)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("could not commit transaction: %v", err)
-	}
-
 	return nil
 }
 
@@ -0,0 +1,132 @@
+package fuzzy
+
+import (
+	"strings"
+	"unicode"
+)
+
+// foldings maps accented and special characters to their plain ASCII counterparts.
+// It covers the characters found in Catalan, Spanish, and the most common
+// Western European languages.
+var foldings = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ä': "a", 'ã': "a", 'å': "a",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i",
+	'ò': "o", 'ó': "o", 'ô': "o", 'ö': "o", 'õ': "o",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u",
+	'ç': "c", 'ñ': "n", 'ý': "y", 'ÿ': "y",
+	'æ': "ae", 'œ': "oe", 'ß': "ss",
+	'·': "", // Catalan "ela geminada" (l·l)
+}
+
+// Normalize lowercases a string, removes its accents, and replaces every
+// sequence of non-alphanumeric characters with a single space.
+func Normalize(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	space := true // Avoids leading spaces
+	for _, r := range strings.ToLower(s) {
+		if f, ok := foldings[r]; ok {
+			b.WriteString(f)
+			space = false
+			continue
+		}
+
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			space = false
+			continue
+		}
+
+		if !space {
+			b.WriteByte(' ')
+			space = true
+		}
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+// Tokens splits a string into its normalized words.
+func Tokens(s string) []string {
+	return strings.Fields(Normalize(s))
+}
+
+// Similarity returns a score between 0 and 1 representing how similar two strings are,
+// 1 meaning they are equal after normalization.
+//
+// Each word of the shortest string is paired with its most similar word in the other
+// string, and the result is the average of these pairings. Hence, a string whose words
+// are all contained in the other is considered very similar: "tomàquet" and "tomàquet
+// triturat" have a score of 1.
+func Similarity(a, b string) float64 {
+	ta, tb := Tokens(a), Tokens(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+
+	if len(ta) > len(tb) {
+		ta, tb = tb, ta
+	}
+
+	var total float64
+	for _, x := range ta {
+		var best float64
+		for _, y := range tb {
+			best = max(best, wordSimilarity(x, y))
+		}
+		total += best
+	}
+
+	// Penalize extra words a little, so that exact matches rank first
+	extra := float64(len(tb)-len(ta)) / float64(len(tb))
+	return total / float64(len(ta)) * (1 - 0.1*extra)
+}
+
+// wordSimilarity compares two normalized words using their edit distance.
+func wordSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+
+	// Plurals and other suffixes are very common ("tomàquet" vs "tomàquets")
+	if strings.HasPrefix(a, b) || strings.HasPrefix(b, a) {
+		shortest := min(len(ra), len(rb))
+		if shortest >= 3 && longest-shortest <= 2 {
+			return 0.95
+		}
+	}
+
+	return 1 - float64(Levenshtein(ra, rb))/float64(longest)
+}
+
+// Levenshtein computes the edit distance between two sequences of runes.
+func Levenshtein(a, b []rune) int {
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := range a {
+		curr[0] = i + 1
+		for j := range b {
+			cost := 1
+			if a[i] == b[j] {
+				cost = 0
+			}
+			curr[j+1] = min(prev[j+1]+1, curr[j]+1, prev[j]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
@@ -0,0 +1,166 @@
+package fuzzy_test
+
+import (
+	"testing"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/fuzzy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		input string
+		want  string
+	}{
+		"Empty string":              {input: "", want: ""},
+		"Already normalized":        {input: "pa de motllo", want: "pa de motllo"},
+		"Uppercase":                 {input: "PA DE MOTLLO", want: "pa de motllo"},
+		"Mixed case":                {input: "Pa de Motllo", want: "pa de motllo"},
+		"Catalan accents":           {input: "Tomàquet Triturat", want: "tomaquet triturat"},
+		"Spanish accents":           {input: "Jamón Ñora Pingüino", want: "jamon nora pinguino"},
+		"Uppercase accents":         {input: "ÀLBER ÉS", want: "alber es"},
+		"Cedilla":                   {input: "Xoriço", want: "xorico"},
+		"Ela geminada":              {input: "Col·liflor", want: "colliflor"},
+		"Ligatures":                 {input: "Straße Œuf", want: "strasse oeuf"},
+		"Punctuation becomes space": {input: "Pa,de-motllo!", want: "pa de motllo"},
+		"Collapses separators":      {input: "  pa ,  de   motllo  ", want: "pa de motllo"},
+		"Keeps digits":              {input: "Llet 1,5L", want: "llet 1 5l"},
+		"Only punctuation":          {input: " ,.- ", want: ""},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.want, fuzzy.Normalize(tc.input))
+		})
+	}
+}
+
+func TestTokens(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, []string{"tomaquet", "triturat"}, fuzzy.Tokens("  Tomàquet, TRITURAT "))
+	require.Empty(t, fuzzy.Tokens(" - "))
+}
+
+func TestSimilarity(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		a, b string
+		want float64
+	}{
+		// Equality after normalization
+		"Equal":                {a: "farina", b: "farina", want: 1},
+		"Different case":       {a: "FARINA", b: "farina", want: 1},
+		"Different accents":    {a: "Tomàquet", b: "tomaquet", want: 1},
+		"Different separators": {a: "pa-de-motllo", b: "Pa de motllo", want: 1},
+
+		// Empty strings
+		"Empty left":  {a: "", b: "farina", want: 0},
+		"Empty right": {a: "farina", b: "", want: 0},
+		"Both empty":  {a: "", b: "", want: 0},
+
+		// Extra words
+		"One extra word":  {a: "Farina", b: "Farina integral", want: 0.95},
+		"Two extra words": {a: "Farina", b: "Farina de blat", want: 0.9333},
+
+		// Suffixes
+		"Plural":                      {a: "tomàquet", b: "tomàquets", want: 0.95},
+		"Two-letter suffix":           {a: "tomàquet", b: "tomàquetes", want: 0.95},
+		"Three-letter suffix":         {a: "tomàquet", b: "tomàquetets", want: 1 - 3.0/11},
+		"Suffix of a too-short word":  {a: "pa", b: "pas", want: 1 - 1.0/3},
+		"Suffix of a three-rune word": {a: "oli", b: "olis", want: 0.95},
+
+		// Edit distance
+		"One typo":     {a: "farina", b: "farine", want: 1 - 1.0/6},
+		"Dissimilar":   {a: "llet", b: "oli", want: 0.25},
+		"Nothing same": {a: "abc", b: "xyz", want: 0},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			require.InDelta(t, tc.want, fuzzy.Similarity(tc.a, tc.b), 1e-4)
+			require.InDelta(t, tc.want, fuzzy.Similarity(tc.b, tc.a), 1e-4, "Similarity should be symmetric")
+		})
+	}
+}
+
+func TestSimilarityThreshold(t *testing.T) {
+	t.Parallel()
+
+	// The threshold used to propose substitutes and import matches
+	const threshold = 0.7
+
+	testCases := map[string]struct {
+		a, b  string
+		above bool
+	}{
+		"Same product, different brand": {a: "Tomàquet triturat", b: "Tomàquet triturat marca blanca", above: true},
+		"Same product, plural":          {a: "Ou de gallina", b: "Ous de gallina", above: true},
+		"Same product, with a typo":     {a: "Mantega", b: "Mantequilla", above: false},
+		"Shared word only":              {a: "Llet sencera", b: "Llet de coco", above: false},
+		"Different products":            {a: "Oli d'oliva", b: "Arròs bomba", above: false},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := fuzzy.Similarity(tc.a, tc.b)
+			if tc.above {
+				require.GreaterOrEqual(t, got, threshold)
+			} else {
+				require.Less(t, got, threshold)
+			}
+		})
+	}
+}
+
+func TestSimilarityTies(t *testing.T) {
+	t.Parallel()
+
+	// Both suffixes are short enough to count as plurals, so neither ranks first
+	require.Equal(t, fuzzy.Similarity("tomàquet", "tomàquets"), fuzzy.Similarity("tomàquet", "tomàquetes"))
+
+	// Word order does not matter
+	require.InDelta(t, 1.0, fuzzy.Similarity("pa de motllo", "motllo de pa"), 1e-9)
+
+	// An exact match ranks above a match with extra words
+	require.Greater(t, fuzzy.Similarity("farina", "farina"), fuzzy.Similarity("farina", "farina integral"))
+
+	// Fewer extra words rank first
+	require.Greater(t, fuzzy.Similarity("farina", "farina integral"), fuzzy.Similarity("farina", "farina de blat"))
+}
+
+func TestLevenshtein(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		a, b string
+		want int
+	}{
+		"Both empty":   {a: "", b: "", want: 0},
+		"One empty":    {a: "", b: "oli", want: 3},
+		"Equal":        {a: "oli", b: "oli", want: 0},
+		"Substitution": {a: "farina", b: "farine", want: 1},
+		"Insertion":    {a: "ou", b: "ous", want: 1},
+		"Deletion":     {a: "llets", b: "llet", want: 1},
+		"Mixed":        {a: "kitten", b: "sitting", want: 3},
+		"Multibyte":    {a: "tomàquet", b: "tomaquet", want: 1},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.want, fuzzy.Levenshtein([]rune(tc.a), []rune(tc.b)))
+			require.Equal(t, tc.want, fuzzy.Levenshtein([]rune(tc.b), []rune(tc.a)), "Levenshtein should be symmetric")
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package httputils_test
 
 import (
+	"net/http"
 	"testing"
 
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
@@ -62,3 +63,52 @@ func TestMediatypes(t *testing.T) {
 		})
 	}
 }
+
+func TestNegotiate(t *testing.T) {
+	t.Parallel()
+
+	offers := []httputils.MediaType{httputils.MediaTypeJSON, httputils.MediaTypeHTML, httputils.MediaTypeMarkdown}
+
+	testCases := map[string]struct {
+		Accept string
+		Want   httputils.MediaType
+		Reject bool
+	}{
+		"Pick the only matching type":     {Accept: "text/markdown", Want: httputils.MediaTypeMarkdown},
+		"Pick the first type in the list": {Accept: "text/html, application/json", Want: httputils.MediaTypeHTML},
+		"Skip types that are not offered": {Accept: "application/xml, text/html", Want: httputils.MediaTypeHTML},
+		"Pick the first offer on empty":   {Accept: "", Want: httputils.MediaTypeJSON},
+		"Pick the first offer on *":       {Accept: "*/*", Want: httputils.MediaTypeJSON},
+		"Pick the first subtype match":    {Accept: "text/*", Want: httputils.MediaTypeHTML},
+		"Pick with browser header":        {Accept: "text/html,application/xhtml+xml,*/*;q=0.8", Want: httputils.MediaTypeHTML},
+
+		"Pick the highest quality":                   {Accept: "text/html;q=0.5, text/markdown", Want: httputils.MediaTypeMarkdown},
+		"Pick the highest quality over a wildcard":   {Accept: "*/*;q=0.1, text/markdown;q=0.2", Want: httputils.MediaTypeMarkdown},
+		"Pick the first type on equal quality":       {Accept: "text/markdown;q=0.5, text/html;q=0.5", Want: httputils.MediaTypeMarkdown},
+		"Skip types with quality zero":               {Accept: "application/json;q=0, */*", Want: httputils.MediaTypeHTML},
+		"Skip types with quality zero in a wildcard": {Accept: "text/html;q=0, text/*", Want: httputils.MediaTypeMarkdown},
+		"Skip types with an invalid quality":         {Accept: "text/html;q=high, text/markdown", Want: httputils.MediaTypeMarkdown},
+
+		"Reject types that are not offered":  {Accept: "application/xml", Reject: true},
+		"Reject types with quality zero":     {Accept: "text/html;q=0", Reject: true},
+		"Reject wildcards with quality zero": {Accept: "*/*;q=0", Reject: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := http.NewRequest(http.MethodGet, "/", nil)
+			require.NoError(t, err)
+			r.Header.Set("Accept", tc.Accept)
+
+			got, err := httputils.Negotiate(r, offers...)
+			if tc.Reject {
+				require.Error(t, err, "Negotiation should fail")
+				return
+			}
+			require.NoError(t, err, "Negotiation should pass")
+			require.Equal(t, tc.Want, got)
+		})
+	}
+}
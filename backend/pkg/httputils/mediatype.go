@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
@@ -11,6 +12,14 @@ var (
 	MediaTypeJSON = NewMediaType("application", "json")
 	MediaTypeText = NewMediaType("text", "plain")
 	MediaTypeHTML = NewMediaType("text", "html")
+
+	MediaTypeJSONLD   = NewMediaType("application", "ld+json")
+	MediaTypeMarkdown = NewMediaType("text", "markdown")
+	MediaTypeZip      = NewMediaType("application", "zip")
+	MediaTypeCalendar = NewMediaType("text", "calendar")
+	MediaTypeCSV      = NewMediaType("text", "csv")
+
+	MediaTypeEventStream = NewMediaType("text", "event-stream")
 )
 
 // MediaType represents a media type as defined in RFC 6838,
@@ -121,3 +130,114 @@ func ValidateContentType(r *http.Request, want MediaType) error {
 
 	return Errorf(http.StatusNotAcceptable, "Incompatible Content-Type header: %v. Only %s is accepted", got, want)
 }
+
+// Negotiate picks the media type of the response among the ones offered by the endpoint.
+// Each offer gets the quality (the q parameter) of the most specific type in the Accept header
+// that matches it, and the offer with the highest quality is chosen. Offers with quality zero
+// are never chosen. Ties are broken by the order of the Accept header, and then by the order of
+// the offers. Hence a wildcard selects the first offer.
+func Negotiate(r *http.Request, offers ...MediaType) (MediaType, error) {
+	got, err := parseAccept(r.Header.Get("Accept"))
+	if len(got) == 0 && err != nil {
+		// Be lenient and only fail if we could not extract any media types
+		return MediaType{}, Errorf(http.StatusNotAcceptable, "Invalid Accept header: %v", err)
+	}
+
+	var best MediaType
+	var bestQ float64
+	var bestIdx int
+	for _, o := range offers {
+		idx := -1
+		for i, g := range got {
+			if o.Match(g.MediaType) && (idx == -1 || specificity(g.MediaType) > specificity(got[idx].MediaType)) {
+				idx = i
+			}
+		}
+
+		if idx == -1 || got[idx].q <= 0 {
+			continue
+		}
+
+		if q := got[idx].q; q > bestQ || (q == bestQ && idx < bestIdx) {
+			best, bestQ, bestIdx = o, q, idx
+		}
+	}
+
+	if bestQ == 0 {
+		return MediaType{}, Errorf(http.StatusNotAcceptable, "Incompatible Accept header: %v. Only %v are accepted", got, offers)
+	}
+
+	return best, nil
+}
+
+// acceptedType is a media type in an Accept header, with its quality.
+type acceptedType struct {
+	MediaType
+	q float64
+}
+
+// parseAccept parses the media types of an Accept header along with their quality,
+// which defaults to 1.
+func parseAccept(s string) ([]acceptedType, error) {
+	var out []acceptedType
+	var errs error
+
+	var empty bool
+	for _, entry := range strings.Split(s, ",") {
+		if len(entry) == 0 {
+			empty = true
+			continue
+		}
+
+		m, err := ParseMediaType(entry)
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+
+		q, err := parseQuality(entry)
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+
+		out = append(out, acceptedType{MediaType: m, q: q})
+	}
+
+	if empty {
+		out = append(out, acceptedType{MediaType: NewMediaType("*", "*"), q: 1})
+	}
+
+	return out, errs
+}
+
+// parseQuality returns the value of the q parameter of a media type, or 1 if there is none.
+func parseQuality(s string) (float64, error) {
+	params := strings.Split(s, ";")
+	for _, p := range params[1:] {
+		key, value, _ := strings.Cut(strings.TrimSpace(p), "=")
+		if !strings.EqualFold(strings.TrimSpace(key), "q") {
+			continue
+		}
+
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || q < 0 || q > 1 {
+			return 0, fmt.Errorf("invalid quality in media type %q", s)
+		}
+		return q, nil
+	}
+
+	return 1, nil
+}
+
+// specificity ranks how specific a media type is: wildcards match more types than exact ones.
+func specificity(m MediaType) int {
+	switch {
+	case m.Type == "*":
+		return 0
+	case m.Subtype == "*":
+		return 1
+	default:
+		return 2
+	}
+}
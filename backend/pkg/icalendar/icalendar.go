@@ -0,0 +1,143 @@
+// Package icalendar writes calendars in the iCalendar format (RFC 5545),
+// so that menus can be imported into and subscribed from calendar apps.
+package icalendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+)
+
+const (
+	productID = "-//grocery-price-fetcher//menus//EN"
+
+	// maxLineLength is the maximum length of a line in octets, excluding the line break.
+	maxLineLength = 75
+
+	dateLayout     = "20060102"
+	dateTimeLayout = "20060102T150405"
+)
+
+// Calendar is a named collection of events.
+type Calendar struct {
+	Name   string
+	Events []Event
+
+	// Stamp is the time the calendar was generated.
+	Stamp time.Time
+}
+
+// Event is a single event in a calendar.
+type Event struct {
+	// UID must be globally unique, and stable across exports so that calendar apps
+	// update the event instead of duplicating it.
+	UID         string
+	Summary     string
+	Description string
+
+	Date dbtypes.Date
+
+	// Start is the time of day the event starts, as an offset from midnight in local time.
+	// A nil start makes the event last all day.
+	Start    *time.Duration
+	Duration time.Duration
+}
+
+// Write writes the calendar to w.
+func (c Calendar) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	lw := lineWriter{w: bw}
+
+	lw.line("BEGIN", "VCALENDAR")
+	lw.line("VERSION", "2.0")
+	lw.line("PRODID", productID)
+	lw.line("CALSCALE", "GREGORIAN")
+	lw.line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		lw.line("X-WR-CALNAME", escape(c.Name))
+	}
+
+	stamp := c.Stamp.UTC().Format(dateTimeLayout) + "Z"
+	for _, e := range c.Events {
+		lw.line("BEGIN", "VEVENT")
+		lw.line("UID", escape(e.UID))
+		lw.line("DTSTAMP", stamp)
+
+		if e.Start == nil {
+			lw.line("DTSTART;VALUE=DATE", e.Date.Time().Format(dateLayout))
+			lw.line("DTEND;VALUE=DATE", e.Date.AddDays(1).Time().Format(dateLayout))
+		} else {
+			// Times are floating, so they are shown in the time zone of the calendar app
+			start := e.Date.Time().Add(*e.Start)
+			lw.line("DTSTART", start.Format(dateTimeLayout))
+			lw.line("DTEND", start.Add(e.Duration).Format(dateTimeLayout))
+		}
+
+		lw.line("SUMMARY", escape(e.Summary))
+		if e.Description != "" {
+			lw.line("DESCRIPTION", escape(e.Description))
+		}
+		lw.line("TRANSP", "TRANSPARENT")
+		lw.line("END", "VEVENT")
+	}
+
+	lw.line("END", "VCALENDAR")
+
+	if lw.err != nil {
+		return lw.err
+	}
+
+	return bw.Flush()
+}
+
+// lineWriter writes content lines, folding them when they are too long.
+// It keeps the first error so that callers only need to check it once.
+type lineWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (lw *lineWriter) line(name, value string) {
+	if lw.err != nil {
+		return
+	}
+
+	l := name + ":" + value
+	for len(l) > maxLineLength {
+		// Do not split multi-byte characters
+		cut := maxLineLength
+		for cut > 0 && !isRuneStart(l[cut]) {
+			cut--
+		}
+
+		if _, lw.err = fmt.Fprintf(lw.w, "%s\r\n", l[:cut]); lw.err != nil {
+			return
+		}
+
+		// Continuation lines start with a space, which counts towards their length
+		l = " " + l[cut:]
+	}
+
+	_, lw.err = fmt.Fprintf(lw.w, "%s\r\n", l)
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+var escaper = strings.NewReplacer(
+	`\`, `\\`,
+	`;`, `\;`,
+	`,`, `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+)
+
+// escape escapes a text value.
+func escape(s string) string {
+	return escaper.Replace(s)
+}
@@ -0,0 +1,240 @@
+// Package menucheck finds the problems that make the shopping list of a menu incomplete or inaccurate.
+package menucheck
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"time"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+)
+
+// Kind is the type of problem found.
+type Kind string
+
+const (
+	// MissingRecipe is a dish or sub-recipe whose recipe does not exist. Its ingredients are not counted.
+	MissingRecipe Kind = "missing-recipe"
+
+	// MissingProduct is an ingredient whose product does not exist. It is left out of the shopping list.
+	MissingProduct Kind = "missing-product"
+
+	// NoPrice is a product without a price or batch size, so its cost cannot be computed.
+	NoPrice Kind = "no-price"
+
+	// StalePrice is a product whose price has not been fetched recently.
+	StalePrice Kind = "stale-price"
+
+	// InvalidAmount is a dish, ingredient or attendance that is zero or negative.
+	InvalidAmount Kind = "invalid-amount"
+)
+
+// Warning is a single problem found in a menu.
+type Warning struct {
+	Kind      Kind       `json:"kind"`
+	Day       string     `json:"day,omitempty"`
+	Meal      string     `json:"meal,omitempty"`
+	RecipeID  recipe.ID  `json:"recipe_id,omitempty"`
+	ProductID product.ID `json:"product_id,omitempty"`
+	Message   string     `json:"message"`
+}
+
+// DefaultMaxPriceAge is long enough for prices to survive a few failed refreshes before being reported.
+const DefaultMaxPriceAge = 48 * time.Hour
+
+// Options configure what is considered a problem.
+type Options struct {
+	// MaxPriceAge is how old a price can be before it is stale. Zero disables the check.
+	MaxPriceAge time.Duration
+
+	// Now is the time prices are compared against.
+	Now time.Time
+}
+
+// Check finds the problems of a menu.
+//
+// Warnings about dishes come first, in menu order. They are followed by the warnings
+// about recipes and products, each reported only once and sorted by ID.
+func Check(db database.DB, m dbtypes.Menu, opts Options) []Warning {
+	out := make([]Warning, 0)
+
+	if m.Household < 0 {
+		out = append(out, Warning{
+			Kind:    InvalidAmount,
+			Message: fmt.Sprintf("household size is %d", m.Household),
+		})
+	}
+
+	cached := database.NewCachedUserLookup(m.User, db.LookupRecipe)
+	recipes := make(map[recipe.ID]recipe.Recipe)
+
+	for _, day := range m.Days {
+		for _, meal := range day.Meals {
+			if meal.Attendees != nil && *meal.Attendees <= 0 {
+				out = append(out, Warning{
+					Kind:    InvalidAmount,
+					Day:     day.Name,
+					Meal:    meal.Name,
+					Message: fmt.Sprintf("%d attendees", *meal.Attendees),
+				})
+			}
+
+			for _, dish := range meal.Dishes {
+				rec, err := cached.Lookup(dish.ID)
+				if errors.Is(err, fs.ErrNotExist) {
+					out = append(out, Warning{
+						Kind:     MissingRecipe,
+						Day:      day.Name,
+						Meal:     meal.Name,
+						RecipeID: dish.ID,
+						Message:  fmt.Sprintf("recipe %d does not exist", dish.ID),
+					})
+					continue
+				} else if err != nil {
+					out = append(out, Warning{
+						Kind:     MissingRecipe,
+						Day:      day.Name,
+						Meal:     meal.Name,
+						RecipeID: dish.ID,
+						Message:  fmt.Sprintf("could not get recipe %d: %v", dish.ID, err),
+					})
+					continue
+				}
+
+				if dish.Amount <= 0 {
+					out = append(out, Warning{
+						Kind:     InvalidAmount,
+						Day:      day.Name,
+						Meal:     meal.Name,
+						RecipeID: dish.ID,
+						Message:  fmt.Sprintf("%s has an amount of %g", rec.Name, dish.Amount),
+					})
+				}
+
+				recipes[rec.ID] = rec
+			}
+		}
+	}
+
+	out = append(out, checkRecipes(db, recipes, cached.Lookup, opts)...)
+	return out
+}
+
+// checkRecipes checks the ingredients and sub-recipes of every recipe, and the products they use.
+func checkRecipes(db database.DB, recipes map[recipe.ID]recipe.Recipe, lookup func(recipe.ID) (recipe.Recipe, error), opts Options) []Warning {
+	ids := make([]recipe.ID, 0, len(recipes))
+	for id := range recipes {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	var out []Warning
+	products := make(map[product.ID]struct{})
+
+	for _, id := range ids {
+		rec := recipes[id]
+
+		for _, i := range rec.Ingredients {
+			if i.Amount <= 0 {
+				out = append(out, Warning{
+					Kind:      InvalidAmount,
+					RecipeID:  rec.ID,
+					ProductID: i.ProductID,
+					Message:   fmt.Sprintf("%s uses an amount of %g of product %d", rec.Name, i.Amount, i.ProductID),
+				})
+			}
+		}
+
+		for _, sub := range rec.SubRecipes {
+			if sub.Amount <= 0 {
+				out = append(out, Warning{
+					Kind:     InvalidAmount,
+					RecipeID: rec.ID,
+					Message:  fmt.Sprintf("%s uses an amount of %g of recipe %d", rec.Name, sub.Amount, sub.RecipeID),
+				})
+			}
+		}
+
+		ingredients, err := recipe.Flatten(rec, lookup)
+		if err != nil {
+			out = append(out, Warning{
+				Kind:     MissingRecipe,
+				RecipeID: rec.ID,
+				Message:  err.Error(),
+			})
+			continue
+		}
+
+		for _, i := range ingredients {
+			products[i.ProductID] = struct{}{}
+		}
+	}
+
+	pids := make([]product.ID, 0, len(products))
+	for id := range products {
+		pids = append(pids, id)
+	}
+	slices.SortFunc(pids, cmp.Compare)
+
+	for _, id := range pids {
+		if w, ok := checkProduct(db, id, opts); ok {
+			out = append(out, w)
+		}
+	}
+
+	return out
+}
+
+// checkProduct returns the most important problem of a product, if any.
+func checkProduct(db database.DB, id product.ID, opts Options) (Warning, bool) {
+	p, err := db.LookupProduct(id)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Warning{
+			Kind:      MissingProduct,
+			ProductID: id,
+			Message:   fmt.Sprintf("product %d does not exist", id),
+		}, true
+	} else if err != nil {
+		return Warning{
+			Kind:      MissingProduct,
+			ProductID: id,
+			Message:   fmt.Sprintf("could not get product %d: %v", id, err),
+		}, true
+	}
+
+	if p.Price <= 0 || p.BatchSize <= 0 {
+		return Warning{
+			Kind:      NoPrice,
+			ProductID: id,
+			Message:   fmt.Sprintf("%s has a price of %g for a batch of %g", p.Name, p.Price, p.BatchSize),
+		}, true
+	}
+
+	if opts.MaxPriceAge == 0 {
+		return Warning{}, false
+	}
+
+	if p.PriceUpdated.IsZero() {
+		return Warning{
+			Kind:      StalePrice,
+			ProductID: id,
+			Message:   fmt.Sprintf("the price of %s has never been fetched", p.Name),
+		}, true
+	}
+
+	if opts.Now.Sub(p.PriceUpdated) > opts.MaxPriceAge {
+		return Warning{
+			Kind:      StalePrice,
+			ProductID: id,
+			Message:   fmt.Sprintf("the price of %s was last fetched on %s", p.Name, p.PriceUpdated.Format(time.DateOnly)),
+		}, true
+	}
+
+	return Warning{}, false
+}
@@ -0,0 +1,193 @@
+// Package menucost breaks down the cost of a menu into its days, meals and dishes.
+package menucost
+
+import (
+	"math"
+	"slices"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+)
+
+// Cost is the cost of a part of a menu.
+type Cost struct {
+	// Marginal is the cost of all the products used, ignoring the pantry.
+	Marginal float32 `json:"marginal"`
+
+	// Cash is the cost of the products that must be bought, after using the pantry. For the whole
+	// menu it is the cost of the packs to buy, as in the shopping list. Days, meals and dishes are
+	// charged pro-rata for the amounts they use, so together they may cost less than the menu.
+	Cash float32 `json:"cash"`
+}
+
+func (c *Cost) add(other Cost) {
+	c.Marginal += other.Marginal
+	c.Cash += other.Cash
+}
+
+type Dish struct {
+	RecipeID recipe.ID `json:"recipe_id"`
+	Name     string    `json:"name"`
+	Amount   float32   `json:"amount"`
+
+	// Servings is the amount scaled by the attendees of the meal.
+	Servings float32 `json:"servings"`
+
+	// LeftoverFrom is set for leftovers, which cost nothing because they were paid for when cooked.
+	LeftoverFrom *dbtypes.MealRef `json:"leftover_from,omitempty"`
+	Cost         Cost             `json:"cost"`
+}
+
+type Meal struct {
+	Name      string `json:"name"`
+	Attendees int    `json:"attendees"`
+	Dishes    []Dish `json:"dishes"`
+	Cost      Cost   `json:"cost"`
+}
+
+type Day struct {
+	Name  string        `json:"name"`
+	Date  *dbtypes.Date `json:"date,omitempty"`
+	Meals []Meal        `json:"meals"`
+	Cost  Cost          `json:"cost"`
+}
+
+// Breakdown is the cost of every part of a menu.
+type Breakdown struct {
+	Menu string `json:"menu"`
+	Days []Day  `json:"days"`
+	Cost Cost   `json:"cost"`
+}
+
+// Compute breaks down the cost of the menu.
+//
+// The pantry is used up in the order of the menu, so earlier dishes are the ones that
+// benefit from it. Dishes whose recipe cannot be found are skipped.
+func Compute(log logger.Logger, db database.DB, m dbtypes.Menu, pantry []recipe.Ingredient) Breakdown {
+	stock := make(map[product.ID]float32, len(pantry))
+	for _, i := range pantry {
+		stock[i.ProductID] += i.Amount
+	}
+
+	products := make(map[product.ID]product.Product)
+	lookup := func(id product.ID) product.Product {
+		if p, ok := products[id]; ok {
+			return p
+		}
+
+		p, err := db.LookupProduct(id)
+		if err != nil {
+			log.Warningf("Product %d not found: %v", id, err)
+		}
+
+		products[id] = p
+		return p
+	}
+
+	// toBuy is the amount of every product that the pantry does not cover
+	toBuy := make(map[product.ID]float32)
+
+	cached := database.NewCachedUserLookup(m.User, db.LookupRecipe)
+	dates := m.Dates()
+
+	out := Breakdown{
+		Menu: m.Name,
+		Days: make([]Day, 0, len(m.Days)),
+	}
+
+	for i, d := range m.Days {
+		day := Day{
+			Name:  d.Name,
+			Meals: make([]Meal, 0, len(d.Meals)),
+		}
+
+		if dates != nil {
+			day.Date = &dates[i]
+		}
+
+		for _, ml := range d.Meals {
+			meal := Meal{
+				Name:      ml.Name,
+				Attendees: m.Attendees(ml),
+				Dishes:    make([]Dish, 0, len(ml.Dishes)),
+			}
+
+			for _, ds := range ml.Dishes {
+				rec, err := cached.Lookup(ds.ID)
+				if err != nil {
+					log.Warningf("%s: %s: Recipe %d not found: %v", d.Name, ml.Name, ds.ID, err)
+					continue
+				}
+
+				ingredients, err := recipe.Flatten(rec, cached.Lookup)
+				if err != nil {
+					log.Warningf("Recipe %d %s: %v", rec.ID, rec.Name, err)
+					continue
+				}
+
+				dish := Dish{
+					RecipeID:     rec.ID,
+					Name:         rec.Name,
+					Amount:       ds.Amount,
+					Servings:     m.Servings(ml, ds),
+					LeftoverFrom: ds.LeftoverFrom,
+				}
+
+				if !ds.Cooked() {
+					ingredients = nil
+				}
+
+				for _, ing := range ingredients {
+					need := ing.Amount * dish.Servings
+					have := min(stock[ing.ProductID], need)
+					stock[ing.ProductID] -= have
+
+					price := lookup(ing.ProductID).UnitPrice()
+					dish.Cost.Marginal += need * price
+					dish.Cost.Cash += (need - have) * price
+					toBuy[ing.ProductID] += need - have
+				}
+
+				meal.Cost.add(dish.Cost)
+				meal.Dishes = append(meal.Dishes, dish)
+			}
+
+			day.Cost.add(meal.Cost)
+			day.Meals = append(day.Meals, meal)
+		}
+
+		out.Cost.add(day.Cost)
+		out.Days = append(out.Days, day)
+	}
+
+	out.Cost.Cash = PacksCost(toBuy, lookup)
+
+	return out
+}
+
+// PacksCost is the cost of buying the given amounts of products in whole packs.
+func PacksCost(amounts map[product.ID]float32, lookup func(product.ID) product.Product) float32 {
+	ids := make([]product.ID, 0, len(amounts))
+	for id := range amounts {
+		ids = append(ids, id)
+	}
+
+	// Sorting makes the sum independent of the order of the map
+	slices.Sort(ids)
+
+	var cost float32
+	for _, id := range ids {
+		p := lookup(id)
+		if amounts[id] <= 0 || p.BatchSize <= 0 {
+			continue
+		}
+
+		packs := int(math.Ceil(float64(amounts[id] / p.BatchSize)))
+		cost += float32(packs) * p.Price
+	}
+
+	return cost
+}
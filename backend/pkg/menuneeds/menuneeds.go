@@ -3,7 +3,9 @@ package menuneeds
 import (
 	"cmp"
 	"errors"
+	"fmt"
 	"io/fs"
+	"slices"
 
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
@@ -26,6 +28,11 @@ func ComputeNeeds(log logger.Logger, db database.DB, m dbtypes.Menu) []recipe.In
 	for _, day := range m.Days {
 		for _, meal := range day.Meals {
 			for _, dish := range meal.Dishes {
+				if !dish.Cooked() {
+					// Leftovers were counted when the batch was cooked
+					continue
+				}
+
 				rpe, err := cached.Lookup(dish.ID)
 				if errors.Is(err, fs.ErrNotExist) {
 					log.Warningf("%s: %s: Recipe %d is not registered", day.Name, meal.Name, dish.ID)
@@ -33,7 +40,7 @@ func ComputeNeeds(log logger.Logger, db database.DB, m dbtypes.Menu) []recipe.In
 				}
 				recipes[rpe.ID] = recipeAmount{
 					recipe: rpe,
-					amount: recipes[rpe.ID].amount + dish.Amount,
+					amount: recipes[rpe.ID].amount + m.Servings(meal, dish),
 				}
 			}
 		}
@@ -42,11 +49,13 @@ func ComputeNeeds(log logger.Logger, db database.DB, m dbtypes.Menu) []recipe.In
 	// Compute the amount of each product needed
 	need := make(map[product.ID]float32)
 	for _, rec := range recipes {
-		for _, i := range rec.recipe.Ingredients {
-			_, ok := need[i.ProductID]
-			if !ok {
-				need[i.ProductID] = 0
-			}
+		ingredients, err := recipe.Flatten(rec.recipe, cached.Lookup)
+		if err != nil {
+			log.Warningf("Recipe %d %s: %v", rec.recipe.ID, rec.recipe.Name, err)
+			continue
+		}
+
+		for _, i := range ingredients {
 			need[i.ProductID] += rec.amount * i.Amount
 		}
 	}
@@ -86,3 +95,59 @@ func Subtract(need []recipe.Ingredient, have []recipe.Ingredient) []recipe.Ingre
 
 	return items
 }
+
+// AddStaples adds the minimum stock of every staple to the needed ingredients, so that
+// the pantry still has that much left once the needs are met.
+//
+// The input slice need must be sorted by ProductID. The output slice is also sorted by ProductID.
+func AddStaples(need []recipe.Ingredient, staples []dbtypes.Staple) []recipe.Ingredient {
+	staples = slices.Clone(staples)
+	slices.SortFunc(staples, func(a, b dbtypes.Staple) int { return cmp.Compare(a.ProductID, b.ProductID) })
+
+	items := make([]recipe.Ingredient, 0, len(need)+len(staples))
+
+	utils.Zipper(need, staples, func(n recipe.Ingredient, s dbtypes.Staple) int { return cmp.Compare(n.ProductID, s.ProductID) },
+		func(n recipe.Ingredient) {
+			// This product is needed but not a staple
+			items = append(items, n)
+		},
+		func(n recipe.Ingredient, s dbtypes.Staple) {
+			// This product is needed and a staple
+			n.Amount += s.Minimum
+			items = append(items, n)
+		},
+		func(s dbtypes.Staple) {
+			// This product is a staple but not needed
+			items = append(items, recipe.Ingredient{ProductID: s.ProductID, Amount: s.Minimum})
+		})
+
+	return items
+}
+
+// LookupPantries returns the pantries with the given names followed by the pantries of the group,
+// if any, without repeating any of them. The error wraps fs.ErrNotExist if a pantry or the group
+// does not exist.
+func LookupPantries(db database.DB, user string, names []string, group string) ([]dbtypes.Pantry, error) {
+	if group != "" {
+		g, err := db.LookupPantryGroup(user, group)
+		if err != nil {
+			return nil, fmt.Errorf("pantry group %q: %w", group, err)
+		}
+		names = append(slices.Clone(names), g.Pantries...)
+	}
+
+	out := make([]dbtypes.Pantry, 0, len(names))
+	for _, name := range names {
+		if slices.ContainsFunc(out, func(p dbtypes.Pantry) bool { return p.Name == name }) {
+			continue
+		}
+
+		p, err := db.LookupPantry(user, name)
+		if err != nil {
+			return nil, fmt.Errorf("pantry %q: %w", name, err)
+		}
+		out = append(out, p)
+	}
+
+	return out, nil
+}
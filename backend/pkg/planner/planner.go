@@ -0,0 +1,332 @@
+// Package planner proposes menus that fit a budget, using the recipes of the user.
+package planner
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand"
+	"slices"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/menucost"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/menuneeds"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipecard"
+)
+
+// Objective is what the planner tries to minimise.
+type Objective string
+
+const (
+	// MinimiseCost picks the recipes that are cheapest to buy, after using the pantry.
+	MinimiseCost Objective = "cost"
+
+	// MinimiseWaste picks the recipes that use the most of the pantry, then the cheapest ones.
+	MinimiseWaste Objective = "waste"
+)
+
+// Request describes the menu to plan.
+type Request struct {
+	// Name and StartDate are copied into the draft menu.
+	Name      string
+	StartDate *dbtypes.Date
+
+	// Days and Meals are the slots to fill: every meal of every day gets one dish.
+	Days  []string
+	Meals []string
+
+	// Servings is the amount of every dish.
+	Servings float32
+
+	// Budget is the most that can be spent on products, bought in whole packs after using the pantry.
+	// Zero means no limit.
+	Budget float32
+
+	// MaxRepeats is the most times a recipe can appear in the menu. Zero means no limit.
+	MaxRepeats int
+
+	// Pantry is the contents of the pantry to use first. It may be empty.
+	Pantry []recipe.Ingredient
+
+	// Recipes restricts the candidates to the given recipes. Empty means all recipes of the user.
+	Recipes []recipe.ID
+
+	Objective Objective
+
+	// Seed breaks ties between equally good recipes. The same seed always produces the same plan.
+	Seed int64
+}
+
+// Plan is a draft menu, with an explanation of the choices made.
+type Plan struct {
+	Menu dbtypes.Menu
+
+	// Cost is the cost of the products to buy in whole packs, after using the pantry.
+	Cost float32
+
+	// PantryUsed is the amount of each pantry product the menu uses, sorted by product ID.
+	PantryUsed []recipe.Ingredient
+
+	// Explanation contains one human-readable line per decision.
+	Explanation []string
+}
+
+// ErrNoCandidates is returned when the user has no recipe that can be planned.
+var ErrNoCandidates = errors.New("there are no recipes with ingredients to choose from")
+
+// candidate is a recipe that can be planned.
+type candidate struct {
+	recipe      recipe.Recipe
+	ingredients []recipe.Ingredient
+	cost        float32
+	uses        int
+}
+
+// New plans a menu for the user.
+//
+// Slots are filled in order, picking for each one the best recipe according to the objective,
+// among those that have not reached the maximum repetitions and still fit in the budget.
+// Slots where no recipe fits are left empty.
+func New(log logger.Logger, db database.DB, user string, req Request) (Plan, error) {
+	if req.Servings <= 0 {
+		return Plan{}, fmt.Errorf("servings must be positive")
+	}
+
+	switch req.Objective {
+	case MinimiseCost, MinimiseWaste:
+	default:
+		return Plan{}, fmt.Errorf("unknown objective %q", req.Objective)
+	}
+
+	candidates, catalog, err := loadCandidates(log, db, user, req.Recipes)
+	if err != nil {
+		return Plan{}, err
+	}
+
+	if len(candidates) == 0 {
+		return Plan{}, ErrNoCandidates
+	}
+
+	// Shuffling before a stable sort makes the seed decide between ties
+	rng := rand.New(rand.NewSource(req.Seed)) //nolint:gosec // Not used for security
+	rng.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+
+	stock := make(map[product.ID]float32, len(req.Pantry))
+	for _, i := range req.Pantry {
+		stock[i.ProductID] += i.Amount
+	}
+
+	// toBuy is the amount of every product that the pantry does not cover for the dishes chosen so far.
+	// Products are bought in whole packs, so what is left of a pack can be used by later dishes.
+	toBuy := make(map[product.ID]float32)
+	lookup := func(id product.ID) product.Product { return catalog[id] }
+
+	plan := Plan{
+		Menu: dbtypes.Menu{
+			User:      user,
+			Name:      req.Name,
+			StartDate: req.StartDate,
+		},
+	}
+
+	for _, dayName := range req.Days {
+		day := dbtypes.Day{Name: dayName}
+
+		for _, mealName := range req.Meals {
+			meal := dbtypes.Meal{Name: mealName}
+			slot := fmt.Sprintf("%s / %s", dayName, mealName)
+
+			best, buy, covered := pick(candidates, stock, toBuy, lookup, req)
+			if best == nil {
+				plan.Explanation = append(plan.Explanation, fmt.Sprintf("%s: left empty, no recipe fits the budget and repetition limits", slot))
+				day.Meals = append(day.Meals, meal)
+				continue
+			}
+
+			best.uses++
+			for _, i := range best.ingredients {
+				need := i.Amount * req.Servings
+				toBuy[i.ProductID] += need - min(stock[i.ProductID], need)
+				stock[i.ProductID] = max(stock[i.ProductID]-need, 0)
+			}
+			plan.Cost = menucost.PacksCost(toBuy, lookup)
+
+			meal.Dishes = append(meal.Dishes, dbtypes.Dish{ID: best.recipe.ID, Amount: req.Servings})
+			day.Meals = append(day.Meals, meal)
+
+			plan.Explanation = append(plan.Explanation, explain(slot, best, buy, covered, req))
+		}
+
+		plan.Menu.Days = append(plan.Menu.Days, day)
+	}
+
+	plan.PantryUsed = pantryUsed(log, db, plan.Menu, req.Pantry)
+
+	plan.Explanation = append(plan.Explanation, fmt.Sprintf("Total cost: %.2f €", plan.Cost))
+	if req.Budget > 0 {
+		plan.Explanation = append(plan.Explanation, fmt.Sprintf("Remaining budget: %.2f €", req.Budget-plan.Cost))
+	}
+
+	return plan, nil
+}
+
+// loadCandidates returns the recipes that can be planned, and every product by ID.
+func loadCandidates(log logger.Logger, db database.DB, user string, only []recipe.ID) ([]*candidate, map[product.ID]product.Product, error) {
+	recipes, err := db.Recipes(user)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not get recipes: %v", err)
+	}
+
+	products, err := db.Products()
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not get products: %v", err)
+	}
+
+	catalog := make(map[product.ID]product.Product, len(products))
+	for _, p := range products {
+		catalog[p.ID] = p
+	}
+
+	// Recipes are sorted so that the shuffle only depends on the seed
+	slices.SortFunc(recipes, func(a, b recipe.Recipe) int { return cmp.Compare(a.ID, b.ID) })
+
+	cached := database.NewCachedUserLookup(user, db.LookupRecipe)
+
+	var out []*candidate
+	for _, r := range recipes {
+		if len(only) > 0 && !slices.Contains(only, r.ID) {
+			continue
+		}
+
+		ingredients, err := recipe.Flatten(r, cached.Lookup)
+		if err != nil {
+			log.Warningf("Recipe %d %s: %v", r.ID, r.Name, err)
+			continue
+		}
+
+		if len(ingredients) == 0 {
+			continue
+		}
+
+		cost, err := recipecard.Cost(log, db, r, cached.Lookup)
+		if err != nil {
+			log.Warningf("Recipe %d %s: %v", r.ID, r.Name, err)
+			continue
+		}
+
+		out = append(out, &candidate{
+			recipe:      r,
+			ingredients: ingredients,
+			cost:        cost,
+		})
+	}
+
+	return out, catalog, nil
+}
+
+// pick returns the best candidate for a slot, how much would have to be bought to cook it,
+// and how much of its ingredients the pantry covers. It returns nil if no candidate is allowed.
+//
+// Candidates are ranked by the price of what they need to buy, but the budget is checked against
+// the whole packs of all products to buy once the candidate is added. The coverage is the sum of the
+// fraction of every ingredient covered by the pantry, so that products measured in different units
+// can be compared.
+func pick(candidates []*candidate, stock, toBuy map[product.ID]float32, lookup func(product.ID) product.Product, req Request) (best *candidate, buy, covered float32) {
+	type option struct {
+		c       *candidate
+		buy     float32
+		covered float32
+	}
+
+	var options []option
+	for _, c := range candidates {
+		if req.MaxRepeats > 0 && c.uses >= req.MaxRepeats {
+			continue
+		}
+
+		o := option{c: c}
+		after := maps.Clone(toBuy)
+		for _, i := range c.ingredients {
+			need := i.Amount * req.Servings
+			if need <= 0 {
+				continue
+			}
+
+			have := min(stock[i.ProductID], need)
+			o.covered += have / need
+			o.buy += (need - have) * lookup(i.ProductID).UnitPrice()
+			after[i.ProductID] += need - have
+		}
+
+		if req.Budget > 0 && menucost.PacksCost(after, lookup) > req.Budget {
+			continue
+		}
+
+		options = append(options, o)
+	}
+
+	if len(options) == 0 {
+		return nil, 0, 0
+	}
+
+	slices.SortStableFunc(options, func(a, b option) int {
+		if req.Objective == MinimiseWaste {
+			if c := cmp.Compare(b.covered, a.covered); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(a.buy, b.buy)
+	})
+
+	return options[0].c, options[0].buy, options[0].covered
+}
+
+func explain(slot string, c *candidate, buy, covered float32, req Request) string {
+	s := fmt.Sprintf("%s: %s, costs %.2f € per serving", slot, c.recipe.Name, c.cost)
+
+	if covered > 0 {
+		s += fmt.Sprintf(", %.2f € to buy after using the pantry", buy)
+	}
+
+	if req.Objective == MinimiseWaste && covered > 0 {
+		s += "; chosen for using the most of the pantry"
+	} else {
+		s += "; chosen for being the cheapest"
+	}
+
+	if req.MaxRepeats > 0 {
+		s += fmt.Sprintf(" (%d of %d allowed uses)", c.uses, req.MaxRepeats)
+	}
+
+	return s
+}
+
+// pantryUsed computes how much of each pantry product is needed by the menu.
+func pantryUsed(log logger.Logger, db database.DB, m dbtypes.Menu, pantry []recipe.Ingredient) []recipe.Ingredient {
+	if len(pantry) == 0 {
+		return nil
+	}
+
+	need := menuneeds.ComputeNeeds(log, db, m)
+	slices.SortFunc(need, func(a, b recipe.Ingredient) int { return cmp.Compare(a.ProductID, b.ProductID) })
+
+	have := slices.Clone(pantry)
+	slices.SortFunc(have, func(a, b recipe.Ingredient) int { return cmp.Compare(a.ProductID, b.ProductID) })
+
+	// The pantry used is whatever is needed minus what is left to buy
+	missing := menuneeds.Subtract(need, have)
+
+	var out []recipe.Ingredient
+	for i, n := range need {
+		if used := n.Amount - missing[i].Amount; used > 0 {
+			out = append(out, recipe.Ingredient{ProductID: n.ProductID, Amount: used})
+		}
+	}
+
+	return out
+}
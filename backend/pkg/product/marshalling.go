@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers"
 	"github.com/ubuntu/decorate"
@@ -57,12 +58,14 @@ func (p *Product) UnmarshalTSV(args []string) (err error) {
 }
 
 type jsonHelper struct {
-	ID          ID        `json:"id"`
-	Name        string    `json:"name"`
-	BatchSize   float32   `json:"batch_size"`
-	Price       float32   `json:"price"`
-	Provider    string    `json:"provider"`
-	ProductCode [3]string `json:"product_code"`
+	ID           ID         `json:"id"`
+	Name         string     `json:"name"`
+	BatchSize    float32    `json:"batch_size"`
+	Price        float32    `json:"price"`
+	Unit         string     `json:"unit,omitempty"`
+	PriceUpdated *time.Time `json:"price_updated,omitempty"`
+	Provider     string     `json:"provider"`
+	ProductCode  [3]string  `json:"product_code"`
 }
 
 func (p *Product) UnmarshalJSON(b []byte) (err error) {
@@ -77,6 +80,11 @@ func (p *Product) UnmarshalJSON(b []byte) (err error) {
 	p.Name = helper.Name
 	p.BatchSize = helper.BatchSize
 	p.Price = helper.Price
+	p.Unit = helper.Unit
+
+	if helper.PriceUpdated != nil {
+		p.PriceUpdated = *helper.PriceUpdated
+	}
 
 	if p.ID == 0 {
 		return errors.New("product ID must be a number greater than 0")
@@ -108,9 +116,14 @@ func (p *Product) MarshalJSON() (b []byte, err error) {
 		Name:        p.Name,
 		BatchSize:   p.BatchSize,
 		Price:       p.Price,
+		Unit:        p.Unit,
 		Provider:    providerName,
 		ProductCode: p.ProductCode,
 	}
 
+	if !p.PriceUpdated.IsZero() {
+		helper.PriceUpdated = &p.PriceUpdated
+	}
+
 	return json.Marshal(helper)
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"time"
 
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers"
 )
@@ -23,10 +24,26 @@ type Product struct {
 	BatchSize float32
 	Price     float32
 
+	// Unit is what the batch size and the amounts in recipes are measured in, such as
+	// "kg" or "L". It is empty if unknown.
+	Unit string
+
+	// PriceUpdated is when the price was last fetched from the provider.
+	// It is zero if the price has never been fetched.
+	PriceUpdated time.Time
+
 	Provider    providers.Provider
 	ProductCode providers.ProductCode
 }
 
+// UnitPrice returns the price of one unit of the product. It is zero if the batch size is unknown.
+func (p Product) UnitPrice() float32 {
+	if p.BatchSize <= 0 {
+		return 0
+	}
+	return p.Price / p.BatchSize
+}
+
 func (p *Product) FetchPrice(ctx context.Context) error {
 	price, err := p.Provider.FetchPrice(ctx, p.ProductCode)
 	if err != nil {
@@ -34,5 +51,6 @@ func (p *Product) FetchPrice(ctx context.Context) error {
 	}
 
 	p.Price = price
+	p.PriceUpdated = time.Now()
 	return nil
 }
@@ -0,0 +1,96 @@
+package recipe
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+)
+
+// Diff represents the changes between two versions of a recipe.
+type Diff struct {
+	// OldName and NewName are only set if the name changed.
+	OldName string
+	NewName string
+
+	Ingredients []IngredientChange
+	SubRecipes  []SubRecipeChange
+}
+
+// IngredientChange represents an ingredient that was added, removed, or whose amount changed.
+// Added ingredients have an old amount of zero, and removed ones have a new amount of zero.
+type IngredientChange struct {
+	ProductID product.ID
+	OldAmount float32
+	NewAmount float32
+}
+
+// SubRecipeChange is the same as IngredientChange, but for sub-recipes.
+type SubRecipeChange struct {
+	RecipeID  ID
+	OldAmount float32
+	NewAmount float32
+}
+
+// Compare computes the changes needed to go from the old recipe to the new one.
+// The changes are sorted by product and recipe ID.
+func Compare(older, newer Recipe) Diff {
+	var d Diff
+
+	if older.Name != newer.Name {
+		d.OldName = older.Name
+		d.NewName = newer.Name
+	}
+
+	oldIngr := make(map[product.ID]float32)
+	for _, i := range older.Ingredients {
+		oldIngr[i.ProductID] += i.Amount
+	}
+
+	newIngr := make(map[product.ID]float32)
+	for _, i := range newer.Ingredients {
+		newIngr[i.ProductID] += i.Amount
+	}
+
+	for id, change := range changes(oldIngr, newIngr) {
+		d.Ingredients = append(d.Ingredients, IngredientChange{ProductID: id, OldAmount: change[0], NewAmount: change[1]})
+	}
+
+	oldSub := make(map[ID]float32)
+	for _, s := range older.SubRecipes {
+		oldSub[s.RecipeID] += s.Amount
+	}
+
+	newSub := make(map[ID]float32)
+	for _, s := range newer.SubRecipes {
+		newSub[s.RecipeID] += s.Amount
+	}
+
+	for id, change := range changes(oldSub, newSub) {
+		d.SubRecipes = append(d.SubRecipes, SubRecipeChange{RecipeID: id, OldAmount: change[0], NewAmount: change[1]})
+	}
+
+	slices.SortFunc(d.Ingredients, func(a, b IngredientChange) int { return cmp.Compare(a.ProductID, b.ProductID) })
+	slices.SortFunc(d.SubRecipes, func(a, b SubRecipeChange) int { return cmp.Compare(a.RecipeID, b.RecipeID) })
+
+	return d
+}
+
+// changes returns the old and new amount of every key whose amount differs.
+func changes[K comparable](older, newer map[K]float32) map[K][2]float32 {
+	out := make(map[K][2]float32)
+
+	for k, o := range older {
+		if n, ok := newer[k]; !ok || n != o {
+			out[k] = [2]float32{o, newer[k]}
+		}
+	}
+
+	for k, n := range newer {
+		if _, ok := older[k]; !ok {
+			out[k] = [2]float32{0, n}
+		}
+	}
+
+	return out
+}
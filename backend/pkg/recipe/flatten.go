@@ -0,0 +1,68 @@
+package recipe
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+)
+
+// CycleError is returned when a recipe contains itself, directly or through its sub-recipes.
+type CycleError struct {
+	// Path is the sequence of recipe names that forms the cycle, starting and ending with the same one.
+	Path []string
+}
+
+func (e CycleError) Error() string {
+	return fmt.Sprintf("recipe contains itself: %s", strings.Join(e.Path, " -> "))
+}
+
+// Flatten expands the sub-recipes of a recipe recursively, and returns the amount of each product
+// needed to prepare one serving. The output is sorted by product ID.
+//
+// The lookup function is used to find sub-recipes. A CycleError is returned if a recipe contains itself.
+func Flatten(r Recipe, lookup func(ID) (Recipe, error)) ([]Ingredient, error) {
+	need := make(map[product.ID]float32)
+	if err := flatten(r, 1, lookup, nil, need); err != nil {
+		return nil, err
+	}
+
+	out := make([]Ingredient, 0, len(need))
+	for id, amount := range need {
+		out = append(out, Ingredient{ProductID: id, Amount: amount})
+	}
+
+	slices.SortFunc(out, func(a, b Ingredient) int { return cmp.Compare(a.ProductID, b.ProductID) })
+	return out, nil
+}
+
+func flatten(r Recipe, servings float32, lookup func(ID) (Recipe, error), stack []Recipe, need map[product.ID]float32) error {
+	stack = append(stack, r)
+
+	for _, i := range r.Ingredients {
+		need[i.ProductID] += servings * i.Amount
+	}
+
+	for _, sub := range r.SubRecipes {
+		if i := slices.IndexFunc(stack, func(s Recipe) bool { return s.ID == sub.RecipeID }); i != -1 {
+			path := make([]string, 0, len(stack)-i+1)
+			for _, s := range stack[i:] {
+				path = append(path, s.Name)
+			}
+			return CycleError{Path: append(path, stack[i].Name)}
+		}
+
+		child, err := lookup(sub.RecipeID)
+		if err != nil {
+			return fmt.Errorf("could not find sub-recipe %d of %q: %w", sub.RecipeID, r.Name, err)
+		}
+
+		if err := flatten(child, servings*sub.Amount, lookup, stack, need); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
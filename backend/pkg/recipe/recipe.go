@@ -16,6 +16,10 @@ type Recipe struct {
 	User        string       `json:"user"`
 	Name        string       `json:"name"`
 	Ingredients []Ingredient `json:"ingredients"`
+	SubRecipes  []SubRecipe  `json:"subrecipes,omitempty"`
+
+	// ForkedFrom is the ID of the recipe this one was copied from, if any.
+	ForkedFrom ID `json:"forked_from,omitempty"`
 }
 
 // Ingredient represents a single ingredient that is part of a recipe.
@@ -24,6 +28,13 @@ type Ingredient struct {
 	Amount    float32    `json:"amount"`
 }
 
+// SubRecipe represents another recipe used as an ingredient, such as a sauce or a dough.
+// The amount is measured in servings of the sub-recipe.
+type SubRecipe struct {
+	RecipeID ID      `json:"recipe_id"`
+	Amount   float32 `json:"amount"`
+}
+
 func NewRandomID() ID {
 	//nolint:gosec // This is not used for security purposes
 	return ID(rand.Uint32())
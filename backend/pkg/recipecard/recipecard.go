@@ -0,0 +1,197 @@
+// Package recipecard renders recipes as printable cards.
+package recipecard
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+)
+
+// Card contains a recipe with the names and prices of its ingredients and sub-recipes.
+type Card struct {
+	ID          recipe.ID
+	Name        string
+	ForkedFrom  recipe.ID
+	Ingredients []Ingredient
+	SubRecipes  []SubRecipe
+
+	// Cost is the cost of a single serving, including sub-recipes.
+	Cost float32
+}
+
+type Ingredient struct {
+	ID        product.ID
+	Name      string
+	Amount    float32
+	UnitPrice float32
+}
+
+// SubRecipe is a recipe used as an ingredient. Its unit price is the cost of one serving.
+type SubRecipe struct {
+	ID        recipe.ID
+	Name      string
+	Amount    float32
+	UnitPrice float32
+}
+
+// New looks up the products and sub-recipes of a recipe to build its card.
+// Missing products and sub-recipes are logged and left out.
+func New(log logger.Logger, db database.DB, rec recipe.Recipe) (Card, error) {
+	card := Card{
+		ID:          rec.ID,
+		Name:        rec.Name,
+		ForkedFrom:  rec.ForkedFrom,
+		Ingredients: make([]Ingredient, 0, len(rec.Ingredients)),
+		SubRecipes:  make([]SubRecipe, 0, len(rec.SubRecipes)),
+	}
+
+	for _, ing := range rec.Ingredients {
+		prod, err := db.LookupProduct(ing.ProductID)
+		if err != nil {
+			log.Warningf("Product %d not found: %v", ing.ProductID, err)
+			continue
+		}
+
+		card.Ingredients = append(card.Ingredients, Ingredient{
+			ID:        prod.ID,
+			Name:      prod.Name,
+			Amount:    ing.Amount,
+			UnitPrice: prod.UnitPrice(),
+		})
+	}
+
+	cached := database.NewCachedUserLookup(rec.User, db.LookupRecipe)
+	for _, sub := range rec.SubRecipes {
+		child, err := cached.Lookup(sub.RecipeID)
+		if err != nil {
+			log.Warningf("Sub-recipe %d not found: %v", sub.RecipeID, err)
+			continue
+		}
+
+		unitPrice, err := Cost(log, db, child, cached.Lookup)
+		if err != nil {
+			log.Warningf("Could not compute the cost of sub-recipe %d: %v", sub.RecipeID, err)
+			continue
+		}
+
+		card.SubRecipes = append(card.SubRecipes, SubRecipe{
+			ID:        child.ID,
+			Name:      child.Name,
+			Amount:    sub.Amount,
+			UnitPrice: unitPrice,
+		})
+	}
+
+	var err error
+	card.Cost, err = Cost(log, db, rec, cached.Lookup)
+	if err != nil {
+		return card, fmt.Errorf("failed to compute recipe cost: %v", err)
+	}
+
+	return card, nil
+}
+
+// Cost computes the cost of a single serving of a recipe, including its sub-recipes.
+func Cost(log logger.Logger, db database.DB, rec recipe.Recipe, lookup func(recipe.ID) (recipe.Recipe, error)) (float32, error) {
+	ingredients, err := recipe.Flatten(rec, lookup)
+	if err != nil {
+		return 0, err
+	}
+
+	var cost float32
+	for _, ing := range ingredients {
+		prod, err := db.LookupProduct(ing.ProductID)
+		if err != nil {
+			log.Warningf("Product %d not found: %v", ing.ProductID, err)
+			continue
+		}
+		cost += ing.Amount * prod.UnitPrice()
+	}
+
+	return cost, nil
+}
+
+var funcs = map[string]any{
+	"amount": func(x float32) string { return strconv.FormatFloat(float64(x), 'f', -1, 32) },
+	"price":  func(x float32) string { return fmt.Sprintf("%.2f €", x) },
+	"mul":    func(a, b float32) float32 { return a * b },
+	"cell":   func(s string) string { return strings.ReplaceAll(s, "|", `\|`) },
+}
+
+var markdown = texttemplate.Must(texttemplate.New("markdown").Funcs(funcs).Parse(`# {{ .Name }}
+{{ if .Ingredients }}
+## Ingredients
+
+| Ingredient | Amount | Unit price | Cost |
+|------------|-------:|-----------:|-----:|
+{{ range .Ingredients -}}
+| {{ cell .Name }} | {{ amount .Amount }} | {{ price .UnitPrice }} | {{ price (mul .Amount .UnitPrice) }} |
+{{ end -}}
+{{ end -}}
+{{ if .SubRecipes }}
+## Sub-recipes
+
+| Recipe | Servings | Cost per serving | Cost |
+|--------|---------:|-----------------:|-----:|
+{{ range .SubRecipes -}}
+| {{ cell .Name }} | {{ amount .Amount }} | {{ price .UnitPrice }} | {{ price (mul .Amount .UnitPrice) }} |
+{{ end -}}
+{{ end }}
+**Total cost:** {{ price .Cost }} per serving
+`))
+
+var html = htmltemplate.Must(htmltemplate.New("html").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{ .Name }}</title>
+<style>
+body { font-family: sans-serif; max-width: 40em; margin: 2em auto; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 1em; }
+th, td { border-bottom: 1px solid #ccc; padding: 0.3em; text-align: left; }
+td.number, th.number { text-align: right; }
+@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h1>{{ .Name }}</h1>
+{{- if .Ingredients }}
+<h2>Ingredients</h2>
+<table>
+<tr><th>Ingredient</th><th class="number">Amount</th><th class="number">Unit price</th><th class="number">Cost</th></tr>
+{{- range .Ingredients }}
+<tr><td>{{ .Name }}</td><td class="number">{{ amount .Amount }}</td><td class="number">{{ price .UnitPrice }}</td><td class="number">{{ price (mul .Amount .UnitPrice) }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+{{- if .SubRecipes }}
+<h2>Sub-recipes</h2>
+<table>
+<tr><th>Recipe</th><th class="number">Servings</th><th class="number">Cost per serving</th><th class="number">Cost</th></tr>
+{{- range .SubRecipes }}
+<tr><td>{{ .Name }}</td><td class="number">{{ amount .Amount }}</td><td class="number">{{ price .UnitPrice }}</td><td class="number">{{ price (mul .Amount .UnitPrice) }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+<p><strong>Total cost:</strong> {{ price .Cost }} per serving</p>
+</body>
+</html>
+`))
+
+// WriteMarkdown renders the card as Markdown.
+func (c Card) WriteMarkdown(w io.Writer) error {
+	return markdown.Execute(w, c)
+}
+
+// WriteHTML renders the card as a standalone HTML page, ready to print.
+func (c Card) WriteHTML(w io.Writer) error {
+	return html.Execute(w, c)
+}
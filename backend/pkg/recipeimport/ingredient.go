@@ -0,0 +1,218 @@
+package recipeimport
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/fuzzy"
+)
+
+// Dimension is the physical magnitude a unit measures.
+type Dimension string
+
+const (
+	Mass   Dimension = "mass"
+	Volume Dimension = "volume"
+	Count  Dimension = "count"
+)
+
+type unit struct {
+	name      string
+	dimension Dimension
+	// factor converts the unit to the base unit of its dimension:
+	// kilograms for mass, litres for volume, and pieces for count.
+	factor float32
+}
+
+// units is indexed by the normalized spelling of each unit in English, Catalan and Spanish.
+var units = func() map[string]unit {
+	table := []struct {
+		unit
+		spellings []string
+	}{
+		{unit{"mg", Mass, 1e-6}, []string{"mg", "miligram", "miligrams", "miligramo", "miligramos"}},
+		{unit{"g", Mass, 1e-3}, []string{"g", "gr", "grs", "gram", "grams", "gramo", "gramos", "gramm"}},
+		{unit{"kg", Mass, 1}, []string{"kg", "kgs", "kilo", "kilos", "kilogram", "kilograms", "kilogramo", "kilogramos"}},
+		{unit{"oz", Mass, 0.0283495}, []string{"oz", "ounce", "ounces", "unca", "unces", "onza", "onzas"}},
+		{unit{"lb", Mass, 0.453592}, []string{"lb", "lbs", "pound", "pounds", "lliura", "lliures", "libra", "libras"}},
+		{unit{"ml", Volume, 1e-3}, []string{"ml", "mililitre", "mililitres", "mililitro", "mililitros", "milliliter", "milliliters", "millilitre", "millilitres"}},
+		{unit{"cl", Volume, 1e-2}, []string{"cl", "centilitre", "centilitres", "centilitro", "centilitros"}},
+		{unit{"dl", Volume, 1e-1}, []string{"dl", "decilitre", "decilitres", "decilitro", "decilitros"}},
+		{unit{"l", Volume, 1}, []string{"l", "lt", "litre", "litres", "liter", "liters", "litro", "litros"}},
+		{unit{"cup", Volume, 0.24}, []string{"cup", "cups", "tassa", "tasses", "taza", "tazas", "got", "gots", "vaso", "vasos"}},
+		{unit{"tbsp", Volume, 0.015}, []string{"tbsp", "tbs", "tablespoon", "tablespoons", "cullerada", "cullerades", "cucharada", "cucharadas"}},
+		{unit{"tsp", Volume, 0.005}, []string{"tsp", "teaspoon", "teaspoons", "culleradeta", "culleradetes", "cucharadita", "cucharaditas"}},
+		{unit{"piece", Count, 1}, []string{"unit", "units", "unitat", "unitats", "unidad", "unidades", "piece", "pieces", "peca", "peces", "pieza", "piezas"}},
+		{unit{"clove", Count, 1}, []string{"clove", "cloves", "gra", "grans", "diente", "dientes"}},
+		// A pinch is too small to be worth buying
+		{unit{"pinch", Count, 0}, []string{"pinch", "pinches", "pessic", "pessics", "pizca", "pizcas"}},
+	}
+
+	m := make(map[string]unit)
+	for _, entry := range table {
+		for _, s := range entry.spellings {
+			m[s] = entry.unit
+		}
+	}
+	return m
+}()
+
+// fillers are words between the quantity and the ingredient name, such as in "200 g de farina".
+var fillers = map[string]bool{
+	"de": true, "d": true, "del": true, "of": true,
+}
+
+// articles are words that mean "one" when they precede the ingredient, such as in "un pessic de sal".
+var articles = map[string]bool{
+	"a": true, "an": true, "one": true, "un": true, "una": true, "uno": true,
+}
+
+var vulgarFractions = map[rune]float32{
+	'½': 1. / 2, '⅓': 1. / 3, '⅔': 2. / 3, '¼': 1. / 4, '¾': 3. / 4,
+	'⅕': 1. / 5, '⅖': 2. / 5, '⅗': 3. / 5, '⅘': 4. / 5, '⅙': 1. / 6,
+	'⅚': 5. / 6, '⅛': 1. / 8, '⅜': 3. / 8, '⅝': 5. / 8, '⅞': 7. / 8,
+}
+
+var (
+	// number matches mixed fractions (1 1/2), fractions (1/2) and decimals (1.5 or 1,5).
+	number        = `\d+\s+\d+/\d+|\d+/\d+|\d+(?:[.,]\d+)?`
+	quantityRegex = regexp.MustCompile(`^(` + number + `)(?:\s*(?:-|–|a|to)\s*(` + number + `))?\s*`)
+	parensRegex   = regexp.MustCompile(`\([^)]*\)`)
+)
+
+// Quantity is the result of parsing an ingredient line such as "200 g de farina".
+type Quantity struct {
+	// Amount is the number written in the line, or zero if there was none.
+	Amount float32
+	// Unit is the canonical name of the unit written in the line, or empty if there was none.
+	Unit string
+	// Dimension is the magnitude of the unit. Lines without units count pieces.
+	Dimension Dimension
+	// Base is the amount converted to kilograms, litres, or pieces.
+	Base float32
+	// Name is what remains of the line after removing the quantity and the unit.
+	Name string
+}
+
+// ParseIngredient splits an ingredient line into its quantity, unit and name.
+//
+// Ranges such as "2-3 tomàquets" use the upper bound, to err on the side of buying enough.
+func ParseIngredient(line string) Quantity {
+	q := Quantity{
+		Dimension: Count,
+	}
+
+	s := strings.TrimSpace(replaceVulgarFractions(line))
+
+	if m := quantityRegex.FindStringSubmatch(s); m != nil {
+		q.Amount = parseNumber(m[1])
+		if m[2] != "" {
+			q.Amount = max(q.Amount, parseNumber(m[2]))
+		}
+		s = s[len(m[0]):]
+	} else if word, rest, ok := strings.Cut(s, " "); ok && articles[fuzzy.Normalize(word)] {
+		q.Amount = 1
+		s = rest
+	}
+
+	// Look for a unit in the first word, which may be glued to the number ("200g")
+	// or be followed by a period ("1 tbsp. sugar").
+	factor := float32(1)
+	if word, rest, _ := strings.Cut(s, " "); word != "" {
+		if u, ok := units[fuzzy.Normalize(word)]; ok && q.Amount != 0 {
+			q.Unit = u.name
+			q.Dimension = u.dimension
+			factor = u.factor
+			s = rest
+		}
+	}
+
+	q.Base = q.Amount * factor
+	q.Name = cleanName(s)
+
+	return q
+}
+
+// cleanName removes fillers, parentheticals, and preparation notes from an ingredient name.
+func cleanName(s string) string {
+	s = parensRegex.ReplaceAllString(s, "")
+
+	// Preparation notes come after a comma: "2 cebes, tallades a daus"
+	s, _, _ = strings.Cut(s, ",")
+
+	words := strings.Fields(s)
+	for len(words) > 1 {
+		w := fuzzy.Normalize(words[0])
+		if !fillers[w] {
+			break
+		}
+		words = words[1:]
+	}
+
+	// Elided articles: "d'oli" -> "oli"
+	if len(words) > 0 {
+		for _, prefix := range []string{"d'", "d’", "l'", "l’"} {
+			if rest, ok := strings.CutPrefix(strings.ToLower(words[0]), prefix); ok && rest != "" {
+				words[0] = words[0][len(words[0])-len(rest):]
+				break
+			}
+		}
+	}
+
+	return strings.Join(words, " ")
+}
+
+// replaceVulgarFractions replaces characters such as ½ with their decimal value,
+// adding it to the previous number if there is one ("1½" -> "1.5").
+func replaceVulgarFractions(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+
+	for i := 0; i < len(runes); i++ {
+		f, ok := vulgarFractions[runes[i]]
+		if !ok {
+			b.WriteRune(runes[i])
+			continue
+		}
+
+		// Find the integer part, if any
+		str := b.String()
+		j := len(str)
+		for j > 0 && str[j-1] >= '0' && str[j-1] <= '9' {
+			j--
+		}
+
+		whole, _ := strconv.Atoi(str[j:])
+		b.Reset()
+		b.WriteString(str[:j])
+		b.WriteString(strconv.FormatFloat(float64(float32(whole)+f), 'f', -1, 32))
+	}
+
+	return b.String()
+}
+
+func parseNumber(s string) float32 {
+	s = strings.TrimSpace(s)
+
+	// Mixed fraction: "1 1/2"
+	if whole, frac, ok := strings.Cut(s, " "); ok {
+		return parseNumber(whole) + parseNumber(frac)
+	}
+
+	// Fraction: "1/2"
+	if num, den, ok := strings.Cut(s, "/"); ok {
+		n, d := parseNumber(num), parseNumber(den)
+		if d == 0 {
+			return 0
+		}
+		return n / d
+	}
+
+	f, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 32)
+	if err != nil {
+		return 0
+	}
+
+	return float32(f)
+}
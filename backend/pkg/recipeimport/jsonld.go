@@ -0,0 +1,196 @@
+package recipeimport
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// schemaRecipe contains the fields of a schema.org Recipe that we care about.
+// See https://schema.org/Recipe.
+type schemaRecipe struct {
+	Name         string
+	Yield        float32
+	Ingredients  []string
+	Instructions []string
+}
+
+var scriptRegex = regexp.MustCompile(`(?is)<script[^>]*type\s*=\s*["']?application/ld\+json["']?[^>]*>(.*?)</script>`)
+
+// extractHTML finds all JSON-LD blocks in an HTML document and returns the first recipe in them.
+func extractHTML(doc []byte) (schemaRecipe, error) {
+	matches := scriptRegex.FindAllSubmatch(doc, -1)
+	if len(matches) == 0 {
+		return schemaRecipe{}, errors.New("no JSON-LD found in document")
+	}
+
+	var errs error
+	for _, m := range matches {
+		r, err := extractJSONLD(m[1])
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+		return r, nil
+	}
+
+	return schemaRecipe{}, errs
+}
+
+// extractJSONLD finds the first recipe in a JSON-LD document.
+func extractJSONLD(doc []byte) (schemaRecipe, error) {
+	var data any
+	if err := json.Unmarshal(bytes.TrimSpace(doc), &data); err != nil {
+		return schemaRecipe{}, fmt.Errorf("could not parse JSON-LD: %v", err)
+	}
+
+	obj, ok := findRecipe(data)
+	if !ok {
+		return schemaRecipe{}, errors.New("no schema.org Recipe found")
+	}
+
+	r := schemaRecipe{
+		Name:  cleanText(asString(obj["name"])),
+		Yield: parseYield(obj["recipeYield"]),
+	}
+
+	ingredients := obj["recipeIngredient"]
+	if ingredients == nil {
+		// Deprecated name of the same property
+		ingredients = obj["ingredients"]
+	}
+
+	for _, i := range asList(ingredients) {
+		if s := cleanText(asString(i)); s != "" {
+			r.Ingredients = append(r.Ingredients, s)
+		}
+	}
+
+	r.Instructions = parseInstructions(obj["recipeInstructions"])
+
+	return r, nil
+}
+
+// findRecipe walks a JSON-LD tree looking for an object of type Recipe.
+func findRecipe(data any) (map[string]any, bool) {
+	switch t := data.(type) {
+	case []any:
+		for _, v := range t {
+			if r, ok := findRecipe(v); ok {
+				return r, true
+			}
+		}
+	case map[string]any:
+		if isRecipe(t["@type"]) {
+			return t, true
+		}
+		// Recipes can be nested under @graph, mainEntity, etc.
+		for _, v := range t {
+			if r, ok := findRecipe(v); ok {
+				return r, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+func isRecipe(typ any) bool {
+	for _, t := range asList(typ) {
+		if s := asString(t); s == "Recipe" || s == "http://schema.org/Recipe" || s == "https://schema.org/Recipe" {
+			return true
+		}
+	}
+	return false
+}
+
+var yieldRegex = regexp.MustCompile(`\d+(?:[.,]\d+)?`)
+
+// parseYield returns the number of servings of a recipe, defaulting to 1.
+func parseYield(v any) float32 {
+	for _, item := range asList(v) {
+		if f, ok := item.(float64); ok {
+			if f > 0 {
+				return float32(f)
+			}
+			continue
+		}
+
+		m := yieldRegex.FindString(asString(item))
+		if m == "" {
+			continue
+		}
+
+		f, err := strconv.ParseFloat(strings.Replace(m, ",", ".", 1), 32)
+		if err == nil && f > 0 {
+			return float32(f)
+		}
+	}
+
+	return 1
+}
+
+// parseInstructions flattens the many shapes recipeInstructions can take:
+// a block of text, a list of strings, a list of HowToStep, or a list of HowToSection.
+func parseInstructions(v any) []string {
+	var steps []string
+
+	for _, item := range asList(v) {
+		switch t := item.(type) {
+		case string:
+			for _, line := range strings.Split(t, "\n") {
+				if s := cleanText(line); s != "" {
+					steps = append(steps, s)
+				}
+			}
+		case map[string]any:
+			if sub, ok := t["itemListElement"]; ok {
+				steps = append(steps, parseInstructions(sub)...)
+				continue
+			}
+			if s := cleanText(asString(t["text"])); s != "" {
+				steps = append(steps, s)
+			} else if s := cleanText(asString(t["name"])); s != "" {
+				steps = append(steps, s)
+			}
+		}
+	}
+
+	return steps
+}
+
+func asList(v any) []any {
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case []any:
+		return t
+	default:
+		return []any{t}
+	}
+}
+
+func asString(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+var tagRegex = regexp.MustCompile(`<[^>]*>`)
+
+// cleanText removes HTML tags and entities, and collapses whitespace.
+func cleanText(s string) string {
+	s = tagRegex.ReplaceAllString(s, " ")
+	s = html.UnescapeString(s)
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,151 @@
+// Package recipeimport parses recipes published as schema.org Recipe JSON-LD,
+// and matches their ingredients against the product catalog.
+package recipeimport
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/fuzzy"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+)
+
+const (
+	// MinScore is the minimum similarity for a product to be proposed as a match.
+	MinScore = 0.6
+
+	// MaxCandidates is the maximum number of products proposed for each ingredient.
+	MaxCandidates = 3
+)
+
+// Format is the format of the document to import.
+type Format int
+
+const (
+	HTML Format = iota
+	JSONLD
+)
+
+// Draft is a recipe parsed from a document, pending confirmation by the user.
+type Draft struct {
+	Name        string
+	Yield       float32
+	Steps       []string
+	Ingredients []Line
+
+	// Recipe contains the best match for every ingredient. Its ID and user are not set.
+	Recipe recipe.Recipe
+}
+
+// Line is an ingredient line of the imported recipe.
+type Line struct {
+	Text     string
+	Quantity Quantity
+
+	// Amount is the quantity per serving, in the units of the products (kg, l, or pieces).
+	Amount float32
+
+	// Candidates are products that may match this ingredient, best match first.
+	Candidates []Candidate
+}
+
+// Candidate is a product that may match an ingredient.
+type Candidate struct {
+	Product product.Product
+	Score   float64
+}
+
+// Import parses a document and matches its ingredients against the products.
+func Import(doc []byte, format Format, products []product.Product) (Draft, error) {
+	var src schemaRecipe
+	var err error
+
+	switch format {
+	case HTML:
+		src, err = extractHTML(doc)
+	case JSONLD:
+		src, err = extractJSONLD(doc)
+	default:
+		err = errors.New("unknown format")
+	}
+
+	if err != nil {
+		return Draft{}, err
+	}
+
+	if len(src.Ingredients) == 0 {
+		return Draft{}, errors.New("recipe has no ingredients")
+	}
+
+	d := Draft{
+		Name:        src.Name,
+		Yield:       src.Yield,
+		Steps:       src.Instructions,
+		Ingredients: make([]Line, 0, len(src.Ingredients)),
+		Recipe: recipe.Recipe{
+			Name: src.Name,
+		},
+	}
+
+	for _, text := range src.Ingredients {
+		q := ParseIngredient(text)
+
+		line := Line{
+			Text:       text,
+			Quantity:   q,
+			Amount:     q.Base / src.Yield,
+			Candidates: Match(q.Name, products),
+		}
+
+		d.Ingredients = append(d.Ingredients, line)
+
+		if len(line.Candidates) == 0 || line.Amount == 0 {
+			continue
+		}
+
+		best := line.Candidates[0].Product.ID
+		i := slices.IndexFunc(d.Recipe.Ingredients, func(ing recipe.Ingredient) bool {
+			return ing.ProductID == best
+		})
+
+		if i == -1 {
+			d.Recipe.Ingredients = append(d.Recipe.Ingredients, recipe.Ingredient{
+				ProductID: best,
+				Amount:    line.Amount,
+			})
+		} else {
+			// The same product can appear in multiple lines (e.g. salt for the dough and for the filling)
+			d.Recipe.Ingredients[i].Amount += line.Amount
+		}
+	}
+
+	return d, nil
+}
+
+// Match returns the products whose name is most similar to the ingredient, best match first.
+func Match(name string, products []product.Product) []Candidate {
+	var c []Candidate
+
+	for _, p := range products {
+		score := fuzzy.Similarity(name, p.Name)
+		if score < MinScore {
+			continue
+		}
+		c = append(c, Candidate{Product: p, Score: score})
+	}
+
+	slices.SortStableFunc(c, func(a, b Candidate) int {
+		if n := cmp.Compare(b.Score, a.Score); n != 0 {
+			return n
+		}
+		return cmp.Compare(a.Product.ID, b.Product.ID)
+	})
+
+	if len(c) > MaxCandidates {
+		c = c[:MaxCandidates]
+	}
+
+	return c
+}
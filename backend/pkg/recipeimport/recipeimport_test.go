@@ -0,0 +1,142 @@
+package recipeimport_test
+
+import (
+	"testing"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipeimport"
+	"github.com/stretchr/testify/require"
+)
+
+var products = []product.Product{
+	{ID: 1, Name: "Farina de blat", BatchSize: 1},
+	{ID: 2, Name: "Tomàquet triturat", BatchSize: 0.5},
+	{ID: 3, Name: "Oli d'oliva", BatchSize: 1},
+}
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		doc    string
+		format recipeimport.Format
+
+		wantErr   bool
+		wantName  string
+		wantYield float32
+		wantSteps []string
+		wantLines []string
+	}{
+		// Valid documents
+		"JSON-LD":                 {doc: `{"@type":"Recipe","name":"Pa","recipeYield":"4 racions","recipeIngredient":["500 g de farina"]}`, format: recipeimport.JSONLD, wantName: "Pa", wantYield: 4, wantLines: []string{"500 g de farina"}},
+		"JSON-LD in a graph":      {doc: `{"@graph":[{"@type":"WebPage"},{"@type":["Thing","Recipe"],"name":"Pa","recipeIngredient":["500 g de farina"]}]}`, format: recipeimport.JSONLD, wantName: "Pa", wantYield: 1, wantLines: []string{"500 g de farina"}},
+		"JSON-LD with HTML":       {doc: `{"@type":"Recipe","name":"<b>Pa</b> &amp; oli","recipeIngredient":["  500 g   de <i>farina</i>  "]}`, format: recipeimport.JSONLD, wantName: "Pa & oli", wantYield: 1, wantLines: []string{"500 g de farina"}},
+		"Deprecated ingredients":  {doc: `{"@type":"Recipe","name":"Pa","ingredients":"500 g de farina"}`, format: recipeimport.JSONLD, wantName: "Pa", wantYield: 1, wantLines: []string{"500 g de farina"}},
+		"HTML":                    {doc: `<html><script type="application/ld+json">{"@type":"Recipe","name":"Pa","recipeIngredient":["500 g de farina"]}</script></html>`, format: recipeimport.HTML, wantName: "Pa", wantYield: 1, wantLines: []string{"500 g de farina"}},
+		"HTML after a bad script": {doc: `<script type="application/ld+json">{oops</script><script type='application/ld+json'>{"@type":"Recipe","name":"Pa","recipeIngredient":["500 g de farina"]}</script>`, format: recipeimport.HTML, wantName: "Pa", wantYield: 1, wantLines: []string{"500 g de farina"}},
+
+		// Malformed fields are ignored
+		"Yield without number":      {doc: `{"@type":"Recipe","recipeYield":"moltes","recipeIngredient":["500 g de farina"]}`, format: recipeimport.JSONLD, wantYield: 1, wantLines: []string{"500 g de farina"}},
+		"Zero yield":                {doc: `{"@type":"Recipe","recipeYield":0,"recipeIngredient":["500 g de farina"]}`, format: recipeimport.JSONLD, wantYield: 1, wantLines: []string{"500 g de farina"}},
+		"Negative yield":            {doc: `{"@type":"Recipe","recipeYield":-2,"recipeIngredient":["500 g de farina"]}`, format: recipeimport.JSONLD, wantYield: 1, wantLines: []string{"500 g de farina"}},
+		"Yield in a list":           {doc: `{"@type":"Recipe","recipeYield":["", "6 persones"],"recipeIngredient":["500 g de farina"]}`, format: recipeimport.JSONLD, wantYield: 6, wantLines: []string{"500 g de farina"}},
+		"Name of the wrong type":    {doc: `{"@type":"Recipe","name":{"text":"Pa"},"recipeIngredient":["500 g de farina"]}`, format: recipeimport.JSONLD, wantYield: 1, wantLines: []string{"500 g de farina"}},
+		"Empty ingredients skipped": {doc: `{"@type":"Recipe","recipeIngredient":["", "  ", "<br>", "500 g de farina", {"name":"sal"}]}`, format: recipeimport.JSONLD, wantYield: 1, wantLines: []string{"500 g de farina"}},
+		"Numeric ingredient":        {doc: `{"@type":"Recipe","recipeIngredient":[2]}`, format: recipeimport.JSONLD, wantYield: 1, wantLines: []string{"2"}},
+		"Instructions as text":      {doc: `{"@type":"Recipe","recipeIngredient":["500 g de farina"],"recipeInstructions":"Pastar.\n\n  Coure.  "}`, format: recipeimport.JSONLD, wantYield: 1, wantSteps: []string{"Pastar.", "Coure."}, wantLines: []string{"500 g de farina"}},
+		"Instructions as sections":  {doc: `{"@type":"Recipe","recipeIngredient":["500 g de farina"],"recipeInstructions":[{"@type":"HowToSection","itemListElement":[{"text":"Pastar."},{"name":"Coure."},{}]}]}`, format: recipeimport.JSONLD, wantYield: 1, wantSteps: []string{"Pastar.", "Coure."}, wantLines: []string{"500 g de farina"}},
+		"Instructions of bad type":  {doc: `{"@type":"Recipe","recipeIngredient":["500 g de farina"],"recipeInstructions":[3, true, null]}`, format: recipeimport.JSONLD, wantYield: 1, wantLines: []string{"500 g de farina"}},
+
+		// Errors
+		"Error on unknown format":            {doc: `{"@type":"Recipe","recipeIngredient":["500 g de farina"]}`, format: recipeimport.Format(42), wantErr: true},
+		"Error on empty document":            {doc: ``, format: recipeimport.JSONLD, wantErr: true},
+		"Error on invalid JSON":              {doc: `{"@type":"Recipe",`, format: recipeimport.JSONLD, wantErr: true},
+		"Error on JSON without recipe":       {doc: `{"@type":"Article","name":"Pa"}`, format: recipeimport.JSONLD, wantErr: true},
+		"Error on JSON scalar":               {doc: `"Recipe"`, format: recipeimport.JSONLD, wantErr: true},
+		"Error on recipe without ingredient": {doc: `{"@type":"Recipe","name":"Pa"}`, format: recipeimport.JSONLD, wantErr: true},
+		"Error on empty ingredients":         {doc: `{"@type":"Recipe","recipeIngredient":["", " "]}`, format: recipeimport.JSONLD, wantErr: true},
+		"Error on ingredients as objects":    {doc: `{"@type":"Recipe","recipeIngredient":[{"name":"farina"}]}`, format: recipeimport.JSONLD, wantErr: true},
+		"Error on HTML without JSON-LD":      {doc: `<html><script>var x = 1;</script></html>`, format: recipeimport.HTML, wantErr: true},
+		"Error on HTML with invalid JSON-LD": {doc: `<script type="application/ld+json">{oops</script>`, format: recipeimport.HTML, wantErr: true},
+		"Error on HTML without recipe":       {doc: `<script type="application/ld+json">{"@type":"Article"}</script>`, format: recipeimport.HTML, wantErr: true},
+		"Error on HTML given as JSON-LD":     {doc: `<script type="application/ld+json">{"@type":"Recipe","recipeIngredient":["500 g de farina"]}</script>`, format: recipeimport.JSONLD, wantErr: true},
+		"Error on JSON-LD given as HTML":     {doc: `{"@type":"Recipe","recipeIngredient":["500 g de farina"]}`, format: recipeimport.HTML, wantErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			d, err := recipeimport.Import([]byte(tc.doc), tc.format, products)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			require.Equal(t, tc.wantName, d.Name)
+			require.Equal(t, tc.wantName, d.Recipe.Name)
+			require.InDelta(t, tc.wantYield, d.Yield, 1e-6)
+			require.Equal(t, tc.wantSteps, d.Steps)
+
+			lines := make([]string, 0, len(d.Ingredients))
+			for _, l := range d.Ingredients {
+				lines = append(lines, l.Text)
+			}
+			require.Equal(t, tc.wantLines, lines)
+		})
+	}
+}
+
+func TestParseIngredient(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		line string
+		want recipeimport.Quantity
+	}{
+		// Well-formed lines
+		"Grams":           {line: "200 g de farina", want: recipeimport.Quantity{Amount: 200, Unit: "g", Dimension: recipeimport.Mass, Base: 0.2, Name: "farina"}},
+		"Glued unit":      {line: "200g farina", want: recipeimport.Quantity{Amount: 200, Unit: "g", Dimension: recipeimport.Mass, Base: 0.2, Name: "farina"}},
+		"Decimal comma":   {line: "1,5 l de llet", want: recipeimport.Quantity{Amount: 1.5, Unit: "l", Dimension: recipeimport.Volume, Base: 1.5, Name: "llet"}},
+		"Mixed fraction":  {line: "1 1/2 cullerades d'oli", want: recipeimport.Quantity{Amount: 1.5, Unit: "tbsp", Dimension: recipeimport.Volume, Base: 0.0225, Name: "oli"}},
+		"Vulgar fraction": {line: "1½ kg de patates", want: recipeimport.Quantity{Amount: 1.5, Unit: "kg", Dimension: recipeimport.Mass, Base: 1.5, Name: "patates"}},
+		"Range":           {line: "2-3 tomàquets", want: recipeimport.Quantity{Amount: 3, Dimension: recipeimport.Count, Base: 3, Name: "tomàquets"}},
+		"Article":         {line: "un pessic de sal", want: recipeimport.Quantity{Amount: 1, Unit: "pinch", Dimension: recipeimport.Count, Base: 0, Name: "sal"}},
+		"Notes":           {line: "2 cebes (grans), tallades a daus", want: recipeimport.Quantity{Amount: 2, Dimension: recipeimport.Count, Base: 2, Name: "cebes"}},
+
+		// Malformed lines
+		"Empty":                   {line: "", want: recipeimport.Quantity{Dimension: recipeimport.Count}},
+		"Only whitespace":         {line: "   ", want: recipeimport.Quantity{Dimension: recipeimport.Count}},
+		"Only a number":           {line: "200", want: recipeimport.Quantity{Amount: 200, Dimension: recipeimport.Count, Base: 200}},
+		"Only a unit":             {line: "g", want: recipeimport.Quantity{Dimension: recipeimport.Count, Name: "g"}},
+		"Unit without a number":   {line: "g de farina", want: recipeimport.Quantity{Dimension: recipeimport.Count, Name: "g de farina"}},
+		"Only a filler":           {line: "200 g de", want: recipeimport.Quantity{Amount: 200, Unit: "g", Dimension: recipeimport.Mass, Base: 0.2, Name: "de"}},
+		"Division by zero":        {line: "1/0 kg de farina", want: recipeimport.Quantity{Dimension: recipeimport.Count, Name: "kg de farina"}},
+		"Unknown unit":            {line: "3 grapats de farina", want: recipeimport.Quantity{Amount: 3, Dimension: recipeimport.Count, Base: 3, Name: "grapats de farina"}},
+		"Unclosed parenthesis":    {line: "2 ous (grans", want: recipeimport.Quantity{Amount: 2, Dimension: recipeimport.Count, Base: 2, Name: "ous (grans"}},
+		"Only a parenthetical":    {line: "(opcional)", want: recipeimport.Quantity{Dimension: recipeimport.Count}},
+		"Only a note":             {line: ", al gust", want: recipeimport.Quantity{Dimension: recipeimport.Count}},
+		"Lone elided article":     {line: "d'", want: recipeimport.Quantity{Dimension: recipeimport.Count, Name: "d'"}},
+		"Lone vulgar fraction":    {line: "½", want: recipeimport.Quantity{Amount: 0.5, Dimension: recipeimport.Count, Base: 0.5}},
+		"Article without a name":  {line: "un", want: recipeimport.Quantity{Dimension: recipeimport.Count, Name: "un"}},
+		"Number inside the name":  {line: "farina 00", want: recipeimport.Quantity{Dimension: recipeimport.Count, Name: "farina 00"}},
+		"Range with missing end":  {line: "2- tomàquets", want: recipeimport.Quantity{Amount: 2, Dimension: recipeimport.Count, Base: 2, Name: "- tomàquets"}},
+		"Punctuation after unit":  {line: "1 tbsp. sucre", want: recipeimport.Quantity{Amount: 1, Unit: "tbsp", Dimension: recipeimport.Volume, Base: 0.015, Name: "sucre"}},
+		"Trailing comma and name": {line: "sal,", want: recipeimport.Quantity{Dimension: recipeimport.Count, Name: "sal"}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := recipeimport.ParseIngredient(tc.line)
+
+			require.InDelta(t, tc.want.Amount, got.Amount, 1e-6, "Amount mismatch")
+			require.InDelta(t, tc.want.Base, got.Base, 1e-6, "Base mismatch")
+			got.Amount, got.Base = tc.want.Amount, tc.want.Base
+
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package search
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+)
+
+// IndexedDB wraps a database and keeps a search index for every user in sync with it.
+//
+// Indexes are built the first time a user searches, and discarded whenever a write
+// may have changed their contents, so that the next search rebuilds them.
+type IndexedDB struct {
+	database.DB
+
+	mu      sync.Mutex
+	indexes map[string]*Index
+}
+
+// NewIndexedDB wraps the database.
+func NewIndexedDB(db database.DB) *IndexedDB {
+	return &IndexedDB{
+		DB:      db,
+		indexes: make(map[string]*Index),
+	}
+}
+
+// Search searches the recipes, menus and products visible to the user.
+func (db *IndexedDB) Search(user, query string, kinds []Kind, limit int) ([]Result, error) {
+	ix, err := db.index(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return ix.Search(query, kinds, limit), nil
+}
+
+func (db *IndexedDB) index(user string) (*Index, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if ix, ok := db.indexes[user]; ok {
+		return ix, nil
+	}
+
+	docs, err := db.documents(user)
+	if err != nil {
+		return nil, fmt.Errorf("could not build search index: %v", err)
+	}
+
+	ix := NewIndex(docs)
+	db.indexes[user] = ix
+
+	return ix, nil
+}
+
+// documents collects all the documents visible to the user.
+func (db *IndexedDB) documents(user string) ([]Document, error) {
+	products, err := db.Products()
+	if err != nil {
+		return nil, fmt.Errorf("could not get products: %v", err)
+	}
+
+	recipes, err := db.Recipes(user)
+	if err != nil {
+		return nil, fmt.Errorf("could not get recipes: %v", err)
+	}
+
+	menus, err := db.Menus(user)
+	if err != nil {
+		return nil, fmt.Errorf("could not get menus: %v", err)
+	}
+
+	productNames := make(map[product.ID]string, len(products))
+	recipeNames := make(map[recipe.ID]string, len(recipes))
+
+	docs := make([]Document, 0, len(products)+len(recipes)+len(menus))
+
+	for _, p := range products {
+		productNames[p.ID] = p.Name
+		docs = append(docs, Document{
+			Kind:      KindProduct,
+			ProductID: p.ID,
+			Name:      p.Name,
+		})
+	}
+
+	for _, r := range recipes {
+		recipeNames[r.ID] = r.Name
+	}
+
+	for _, r := range recipes {
+		d := Document{
+			Kind:     KindRecipe,
+			RecipeID: r.ID,
+			Name:     r.Name,
+		}
+
+		for _, ing := range r.Ingredients {
+			d.Keywords = append(d.Keywords, productNames[ing.ProductID])
+		}
+
+		for _, sub := range r.SubRecipes {
+			d.Keywords = append(d.Keywords, recipeNames[sub.RecipeID])
+		}
+
+		docs = append(docs, d)
+	}
+
+	for _, m := range menus {
+		menuDoc := Document{
+			Kind: KindMenu,
+			Menu: m.Name,
+			Name: m.Name,
+		}
+
+		for _, day := range m.Days {
+			for _, meal := range day.Meals {
+				for _, dish := range meal.Dishes {
+					name, ok := recipeNames[dish.ID]
+					if !ok {
+						continue
+					}
+
+					menuDoc.Keywords = append(menuDoc.Keywords, name)
+					docs = append(docs, Document{
+						Kind:     KindDish,
+						RecipeID: dish.ID,
+						Menu:     m.Name,
+						Name:     name,
+						Context:  fmt.Sprintf("%s / %s / %s", m.Name, day.Name, meal.Name),
+					})
+				}
+			}
+		}
+
+		docs = append(docs, menuDoc)
+	}
+
+	return docs, nil
+}
+
+// invalidate discards the index of the user.
+func (db *IndexedDB) invalidate(user string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	delete(db.indexes, user)
+}
+
+// invalidateAll discards the indexes of all users.
+func (db *IndexedDB) invalidateAll() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	clear(db.indexes)
+}
+
+func (db *IndexedDB) DeleteUser(id string) error {
+	defer db.invalidate(id)
+	return db.DB.DeleteUser(id)
+}
+
+func (db *IndexedDB) SetProduct(p product.Product) (product.ID, error) {
+	// Prices are updated often, but only names are indexed
+	if old, err := db.DB.LookupProduct(p.ID); err == nil && old.Name == p.Name {
+		return db.DB.SetProduct(p)
+	}
+
+	defer db.invalidateAll()
+	return db.DB.SetProduct(p)
+}
+
+func (db *IndexedDB) DeleteProduct(id product.ID) error {
+	defer db.invalidateAll()
+	return db.DB.DeleteProduct(id)
+}
+
+func (db *IndexedDB) SetRecipe(r recipe.Recipe) (recipe.ID, error) {
+	defer db.invalidate(r.User)
+	return db.DB.SetRecipe(r)
+}
+
+func (db *IndexedDB) SetRecipes(rs []recipe.Recipe) ([]recipe.ID, error) {
+	defer func() {
+		for _, r := range rs {
+			db.invalidate(r.User)
+		}
+	}()
+	return db.DB.SetRecipes(rs)
+}
+
+func (db *IndexedDB) ForkRecipes(copies []recipe.Recipe) ([]recipe.ID, error) {
+	defer func() {
+		for _, r := range copies {
+			db.invalidate(r.User)
+		}
+	}()
+	return db.DB.ForkRecipes(copies)
+}
+
+func (db *IndexedDB) DeleteRecipe(asUser string, id recipe.ID) error {
+	defer db.invalidate(asUser)
+	return db.DB.DeleteRecipe(asUser, id)
+}
+
+func (db *IndexedDB) SetMenu(m dbtypes.Menu) error {
+	defer db.invalidate(m.User)
+	return db.DB.SetMenu(m)
+}
+
+func (db *IndexedDB) CreateMenu(m dbtypes.Menu) error {
+	defer db.invalidate(m.User)
+	return db.DB.CreateMenu(m)
+}
+
+func (db *IndexedDB) DeleteMenu(user, name string) error {
+	defer db.invalidate(user)
+	return db.DB.DeleteMenu(user, name)
+}
+
+func (db *IndexedDB) RenameMenu(user, oldName, newName string) error {
+	defer db.invalidate(user)
+	return db.DB.RenameMenu(user, oldName, newName)
+}
@@ -0,0 +1,135 @@
+package search_test
+
+import (
+	"testing"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers/blank"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/search"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	user   = "test-user-123"
+	friend = "friend-user-456"
+)
+
+func TestMain(m *testing.M) {
+	providers.Register(blank.Provider{})
+	m.Run()
+}
+
+func TestIndexedDBSetMenu(t *testing.T) {
+	t.Parallel()
+
+	db := search.NewIndexedDB(testutils.Database(t, testutils.FixturePath(t, "database")))
+
+	res, err := db.Search(user, "vegetal", nil, 0)
+	require.NoError(t, err)
+	require.Empty(t, res, "Search should not find a menu that does not exist yet")
+
+	require.NoError(t, db.SetMenu(dbtypes.Menu{
+		User: user,
+		Name: "Setmana vegetal",
+		Days: []dbtypes.Day{{
+			Name: "Dimarts",
+			Meals: []dbtypes.Meal{{
+				Name:   "Dinar",
+				Dishes: []dbtypes.Dish{{ID: 1, Amount: 1}},
+			}},
+		}},
+	}))
+
+	res, err = db.Search(user, "vegetal", []search.Kind{search.KindMenu}, 0)
+	require.NoError(t, err)
+	require.Len(t, res, 1, "Search should find the new menu")
+	require.Equal(t, "Setmana vegetal", res[0].Menu)
+
+	res, err = db.Search(user, "massa", []search.Kind{search.KindDish}, 0)
+	require.NoError(t, err)
+	require.Len(t, res, 1, "Search should find the dish in the new menu")
+	require.Equal(t, "Setmana vegetal", res[0].Menu)
+	require.Equal(t, "Setmana vegetal / Dimarts / Dinar", res[0].Context)
+
+	res, err = db.Search(friend, "vegetal", nil, 0)
+	require.NoError(t, err)
+	require.Empty(t, res, "Search should not find menus of other users")
+}
+
+func TestIndexedDBSetProduct(t *testing.T) {
+	t.Parallel()
+
+	db := search.NewIndexedDB(testutils.Database(t, testutils.FixturePath(t, "database")))
+
+	// Build the indexes of both users
+	for _, u := range []string{user, friend} {
+		res, err := db.Search(u, "tomaq", []search.Kind{search.KindProduct}, 0)
+		require.NoError(t, err)
+		require.Len(t, res, 1, "Search should find the product before renaming it")
+	}
+
+	p, err := db.LookupProduct(2)
+	require.NoError(t, err)
+
+	// Updating the price alone must not invalidate the index, so a rename
+	// that bypasses the index goes unnoticed.
+	renamed := p
+	renamed.Name = "Salsa de tomata"
+	_, err = db.DB.SetProduct(renamed)
+	require.NoError(t, err)
+
+	p.Price++
+	_, err = db.SetProduct(p)
+	require.NoError(t, err)
+
+	res, err := db.Search(user, "tomaq", []search.Kind{search.KindProduct}, 0)
+	require.NoError(t, err)
+	require.Len(t, res, 1, "Updating the price should not rebuild the index")
+
+	// Renaming the product invalidates the indexes of every user
+	_, err = db.SetProduct(renamed)
+	require.NoError(t, err)
+
+	for _, u := range []string{user, friend} {
+		res, err := db.Search(u, "tomaq", nil, 0)
+		require.NoError(t, err)
+		require.Empty(t, res, "Search should not find the old name of the product")
+
+		res, err = db.Search(u, "salsa", []search.Kind{search.KindProduct}, 0)
+		require.NoError(t, err)
+		require.Len(t, res, 1, "Search should find the new name of the product")
+		require.Equal(t, "Salsa de tomata", res[0].Name)
+	}
+
+	res, err = db.Search(user, "salsa", []search.Kind{search.KindRecipe}, 0)
+	require.NoError(t, err)
+	require.Len(t, res, 1, "Search should find the recipes using the renamed product")
+	require.Equal(t, "Pizza margarita", res[0].Name)
+}
+
+func TestIndexedDBDeleteUser(t *testing.T) {
+	t.Parallel()
+
+	db := search.NewIndexedDB(testutils.Database(t, testutils.FixturePath(t, "database")))
+
+	// Build the indexes of both users
+	res, err := db.Search(user, "pizza", nil, 0)
+	require.NoError(t, err)
+	require.NotEmpty(t, res, "Search should find the recipes of the user before deleting them")
+
+	res, err = db.Search(friend, "pizza", nil, 0)
+	require.NoError(t, err)
+	require.NotEmpty(t, res, "Search should find the recipes of the friend")
+
+	require.NoError(t, db.DeleteUser(user))
+
+	res, err = db.Search(user, "pizza", nil, 0)
+	require.NoError(t, err)
+	require.Empty(t, res, "Search should not find anything of a deleted user")
+
+	res, err = db.Search(friend, "pizza", []search.Kind{search.KindRecipe, search.KindMenu}, 0)
+	require.NoError(t, err)
+	require.Len(t, res, 2, "Deleting a user should not affect other users")
+}
@@ -0,0 +1,190 @@
+// Package search implements an in-memory inverted index over recipes, products, and menus.
+//
+// Matching is accent-insensitive and every word in the query matches the words that start with it,
+// so that "tomaq" finds "Tomàquet triturat".
+package search
+
+import (
+	"cmp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/fuzzy"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+)
+
+// Kind is the type of entity a document represents.
+type Kind string
+
+const (
+	KindProduct Kind = "product"
+	KindRecipe  Kind = "recipe"
+	KindMenu    Kind = "menu"
+	KindDish    Kind = "dish"
+)
+
+// Document is an entry in the index.
+type Document struct {
+	Kind Kind
+
+	// Only the identifiers relevant to the kind are set. Dishes set both the menu and the recipe.
+	ProductID product.ID
+	RecipeID  recipe.ID
+	Menu      string
+
+	// Name is the main text of the document.
+	Name string
+
+	// Context is a human-readable description of where the document is found, such as "Monday / Lunch".
+	Context string
+
+	// Keywords are secondary texts that can also be matched, such as the ingredients of a recipe.
+	Keywords []string
+}
+
+// Result is a document matching a query.
+type Result struct {
+	Document
+	Score float64
+}
+
+const (
+	// Weights of the different parts of a document.
+	nameWeight    = 1.0
+	keywordWeight = 0.5
+
+	// Penalty for matching a prefix instead of the whole word.
+	prefixPenalty = 0.8
+)
+
+// Index is an immutable inverted index.
+type Index struct {
+	docs     []Document
+	postings map[string][]posting
+
+	// tokens contains the keys of postings, sorted to allow prefix searches.
+	tokens []string
+}
+
+type posting struct {
+	doc    int
+	weight float64
+}
+
+// NewIndex indexes the documents.
+func NewIndex(docs []Document) *Index {
+	ix := &Index{
+		docs:     docs,
+		postings: make(map[string][]posting),
+	}
+
+	for i, d := range docs {
+		weights := make(map[string]float64)
+		for _, t := range fuzzy.Tokens(d.Name) {
+			weights[t] = nameWeight
+		}
+
+		for _, k := range d.Keywords {
+			for _, t := range fuzzy.Tokens(k) {
+				weights[t] = max(weights[t], keywordWeight)
+			}
+		}
+
+		for t, w := range weights {
+			ix.postings[t] = append(ix.postings[t], posting{doc: i, weight: w})
+		}
+	}
+
+	ix.tokens = make([]string, 0, len(ix.postings))
+	for t := range ix.postings {
+		ix.tokens = append(ix.tokens, t)
+	}
+	slices.Sort(ix.tokens)
+
+	return ix
+}
+
+// Len returns the number of documents in the index.
+func (ix *Index) Len() int {
+	return len(ix.docs)
+}
+
+// Search returns the documents that match every word in the query, best matches first.
+// Only documents of the given kinds are returned; no kinds means all of them.
+// A limit of zero or less means no limit.
+func (ix *Index) Search(query string, kinds []Kind, limit int) []Result {
+	words := fuzzy.Tokens(query)
+	if len(words) == 0 {
+		return nil
+	}
+
+	// scores[doc] is the sum of the best score of each word
+	scores := make(map[int]float64)
+	for i, w := range words {
+		best := ix.match(w)
+
+		if i == 0 {
+			scores = best
+			continue
+		}
+
+		// Documents must match every word
+		for doc, s := range scores {
+			if b, ok := best[doc]; ok {
+				scores[doc] = s + b
+			} else {
+				delete(scores, doc)
+			}
+		}
+	}
+
+	out := make([]Result, 0, len(scores))
+	for doc, s := range scores {
+		d := ix.docs[doc]
+		if len(kinds) > 0 && !slices.Contains(kinds, d.Kind) {
+			continue
+		}
+		out = append(out, Result{Document: d, Score: s / float64(len(words))})
+	}
+
+	slices.SortFunc(out, func(a, b Result) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(string(a.Kind), string(b.Kind))
+	})
+
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+
+	return out
+}
+
+// match returns the best score of every document containing a word that starts with the given one.
+func (ix *Index) match(word string) map[int]float64 {
+	best := make(map[int]float64)
+
+	start := sort.SearchStrings(ix.tokens, word)
+	for _, t := range ix.tokens[start:] {
+		if !strings.HasPrefix(t, word) {
+			break
+		}
+
+		factor := 1.0
+		if t != word {
+			factor = prefixPenalty
+		}
+
+		for _, p := range ix.postings[t] {
+			best[p.doc] = max(best[p.doc], factor*p.weight)
+		}
+	}
+
+	return best
+}
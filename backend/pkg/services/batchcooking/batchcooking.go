@@ -0,0 +1,215 @@
+package batchcooking
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"slices"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+)
+
+// Service groups the repeated dishes of a menu, to plan batch cooking.
+//
+// Batches are dishes cooked once and eaten again as leftovers. Repeated dishes are recipes
+// cooked in more than one meal, which could be turned into a batch.
+type Service struct {
+	settings Settings
+	db       database.DB
+	auth     auth.Getter
+}
+
+type Settings struct {
+	Enable bool
+}
+
+func (Settings) Defaults() Settings {
+	return Settings{
+		Enable: true,
+	}
+}
+
+func New(s Settings, db database.DB, auth auth.Getter) *Service {
+	if !s.Enable {
+		return nil
+	}
+
+	return &Service{
+		settings: s,
+		db:       db,
+		auth:     auth,
+	}
+}
+
+func (s Service) Name() string {
+	return "batch-cooking"
+}
+
+func (s Service) Path() string {
+	return "/api/menu/{menu}/batch-cooking"
+}
+
+func (s Service) Enabled() bool {
+	return s.settings.Enable
+}
+
+func (s *Service) Handle(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return s.handleGet(log, w, r)
+	default:
+		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+// occurrenceMsg is a dish in a specific meal of the menu.
+type occurrenceMsg struct {
+	Day      int           `json:"day"`
+	Meal     int           `json:"meal"`
+	DayName  string        `json:"day_name"`
+	MealName string        `json:"meal_name"`
+	Date     *dbtypes.Date `json:"date,omitempty"`
+	Servings float32       `json:"servings"`
+}
+
+type batchMsg struct {
+	RecipeID  recipe.ID       `json:"recipe_id"`
+	Name      string          `json:"name"`
+	Cook      occurrenceMsg   `json:"cook"`
+	Leftovers []occurrenceMsg `json:"leftovers"`
+
+	// Servings is the amount to cook, which includes the leftovers.
+	Servings float32 `json:"servings"`
+}
+
+type repeatedMsg struct {
+	RecipeID    recipe.ID       `json:"recipe_id"`
+	Name        string          `json:"name"`
+	Occurrences []occurrenceMsg `json:"occurrences"`
+	Servings    float32         `json:"servings"`
+}
+
+type responseMsg struct {
+	Menu     string        `json:"menu"`
+	Batches  []batchMsg    `json:"batches"`
+	Repeated []repeatedMsg `json:"repeated"`
+}
+
+func (s *Service) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	name := r.PathValue("menu")
+	m, err := s.db.LookupMenu(user, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "menu %s not found", name)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get menu: %v", err)
+	}
+
+	resp := group(log, s.db, m)
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not write response: %v", err)
+	}
+
+	log.Debugf("Menu %s has %d batches and %d repeated dishes", m.Name, len(resp.Batches), len(resp.Repeated))
+	return nil
+}
+
+// group finds the batches and repeated dishes of a menu, in the order they are first cooked.
+func group(log logger.Logger, db database.DB, m dbtypes.Menu) responseMsg {
+	out := responseMsg{
+		Menu:     m.Name,
+		Batches:  []batchMsg{},
+		Repeated: []repeatedMsg{},
+	}
+
+	type batchKey struct {
+		recipe recipe.ID
+		from   dbtypes.MealRef
+	}
+
+	batches := make(map[batchKey]int)
+	repeated := make(map[recipe.ID]int)
+
+	cache := database.NewCachedUserLookup(m.User, db.LookupRecipe)
+	dates := m.Dates()
+
+	for i, day := range m.Days {
+		for j, meal := range day.Meals {
+			occ := occurrenceMsg{
+				Day:      i,
+				Meal:     j,
+				DayName:  day.Name,
+				MealName: meal.Name,
+			}
+
+			if dates != nil {
+				occ.Date = &dates[i]
+			}
+
+			for _, dish := range meal.Dishes {
+				rec, err := cache.Lookup(dish.ID)
+				if err != nil {
+					log.Warningf("%s: %s: Recipe %d not found: %v", day.Name, meal.Name, dish.ID, err)
+					continue
+				}
+
+				occ.Servings = m.Servings(meal, dish)
+
+				if !dish.Cooked() {
+					idx, ok := batches[batchKey{recipe: dish.ID, from: *dish.LeftoverFrom}]
+					if !ok {
+						log.Warningf("%s: %s: Recipe %d is a leftover from a meal where it is not cooked", day.Name, meal.Name, dish.ID)
+						continue
+					}
+
+					out.Batches[idx].Leftovers = append(out.Batches[idx].Leftovers, occ)
+					out.Batches[idx].Servings += occ.Servings
+					continue
+				}
+
+				batches[batchKey{recipe: dish.ID, from: dbtypes.MealRef{Day: i, Meal: j}}] = len(out.Batches)
+				out.Batches = append(out.Batches, batchMsg{
+					RecipeID:  rec.ID,
+					Name:      rec.Name,
+					Cook:      occ,
+					Leftovers: []occurrenceMsg{},
+					Servings:  occ.Servings,
+				})
+
+				idx, ok := repeated[dish.ID]
+				if !ok {
+					idx = len(out.Repeated)
+					repeated[dish.ID] = idx
+					out.Repeated = append(out.Repeated, repeatedMsg{
+						RecipeID: rec.ID,
+						Name:     rec.Name,
+					})
+				}
+
+				out.Repeated[idx].Occurrences = append(out.Repeated[idx].Occurrences, occ)
+				out.Repeated[idx].Servings += occ.Servings
+			}
+		}
+	}
+
+	// Only dishes with leftovers are batches, and only dishes cooked more than once are repeated
+	out.Batches = slices.DeleteFunc(out.Batches, func(b batchMsg) bool { return len(b.Leftovers) == 0 })
+	out.Repeated = slices.DeleteFunc(out.Repeated, func(r repeatedMsg) bool { return len(r.Occurrences) < 2 })
+
+	return out
+}
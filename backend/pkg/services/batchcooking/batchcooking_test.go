@@ -0,0 +1,54 @@
+package batchcooking_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers/blank"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/batchcooking"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	providers.Register(blank.Provider{})
+	m.Run()
+}
+
+func TestBatchCookingEndpoint(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		method string
+		path   string
+
+		wantCode int
+		wantBody string
+	}{
+		"GET":                {method: "GET", path: "/api/menu/week/batch-cooking", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET menu not found": {method: "GET", path: "/api/menu/nonexistent/batch-cooking", wantCode: http.StatusNotFound},
+
+		"POST": {method: "POST", path: "/api/menu/week/batch-cooking", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db := testutils.Database(t, testutils.FixturePath(t, "database"))
+
+			sv := batchcooking.New(batchcooking.Settings{}.Defaults(), db, testutils.MockAuthGetter())
+			require.True(t, sv.Enabled())
+
+			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
+				ServePath: sv.Path(),
+				ReqPath:   tc.path,
+				Endpoint:  sv.Handle,
+				Method:    tc.method,
+				WantCode:  tc.wantCode,
+				WantBody:  tc.wantBody,
+			})
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package calendar
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+)
+
+// Service shows the meals planned for a range of dates, across all dated menus.
+// Menus without a start date are templates, and are not shown.
+type Service struct {
+	settings Settings
+	db       database.DB
+	auth     auth.Getter
+}
+
+// Settings are shared by all the calendar services.
+type Settings struct {
+	Enable bool
+
+	// MaxDays is the longest range of dates that can be requested.
+	MaxDays int
+
+	// MealTimes maps the name of a meal, in lower case, to the time it starts, such as "13:30".
+	// Meals not listed here are exported as all-day events.
+	MealTimes map[string]string
+
+	// MealDuration is the length of the exported meal events.
+	MealDuration time.Duration
+}
+
+func (Settings) Defaults() Settings {
+	return Settings{
+		Enable:  true,
+		MaxDays: 366,
+		MealTimes: map[string]string{
+			"breakfast": "08:00",
+			"lunch":     "13:30",
+			"dinner":    "20:30",
+		},
+		MealDuration: time.Hour,
+	}
+}
+
+func New(s Settings, db database.DB, auth auth.Getter) *Service {
+	if !s.Enable {
+		return nil
+	}
+
+	return &Service{
+		settings: s,
+		db:       db,
+		auth:     auth,
+	}
+}
+
+func (s Service) Name() string {
+	return "calendar"
+}
+
+func (s Service) Path() string {
+	return "/api/calendar"
+}
+
+func (s Service) Enabled() bool {
+	return s.settings.Enable
+}
+
+func (s *Service) Handle(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return s.handleGet(log, w, r)
+	default:
+		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+type dishMsg struct {
+	RecipeID recipe.ID `json:"recipe_id"`
+	Name     string    `json:"name"`
+	Amount   float32   `json:"amount"`
+}
+
+type mealMsg struct {
+	Name   string    `json:"name"`
+	Dishes []dishMsg `json:"dishes"`
+}
+
+// entryMsg is a day of a menu planned for a date.
+type entryMsg struct {
+	Menu  string    `json:"menu"`
+	Day   string    `json:"day"`
+	Meals []mealMsg `json:"meals"`
+}
+
+type dateMsg struct {
+	Date    dbtypes.Date `json:"date"`
+	Weekday string       `json:"weekday"`
+	Entries []entryMsg   `json:"entries"`
+}
+
+// handleGet returns every date between the query parameters from and to, both included,
+// with the days of the menus planned for them. If to is missing, a week is returned.
+func (s *Service) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	from, to, err := s.parseRange(r)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	menus, err := s.db.Menus(user)
+	if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get menus: %v", err)
+	}
+
+	// Overlapping menus are listed in alphabetical order
+	slices.SortFunc(menus, func(a, b dbtypes.Menu) int { return strings.Compare(a.Name, b.Name) })
+
+	var out []dateMsg
+	for d := from; d.Compare(to) <= 0; d = d.AddDays(1) {
+		out = append(out, dateMsg{
+			Date:    d,
+			Weekday: d.Weekday().String(),
+			Entries: []entryMsg{},
+		})
+	}
+
+	cache := database.NewCachedUserLookup(user, s.db.LookupRecipe)
+	for _, m := range menus {
+		for i, date := range m.Dates() {
+			if date.Compare(from) < 0 || date.Compare(to) > 0 {
+				continue
+			}
+
+			day := m.Days[i]
+			entry := entryMsg{
+				Menu:  m.Name,
+				Day:   day.Name,
+				Meals: make([]mealMsg, 0, len(day.Meals)),
+			}
+
+			for _, meal := range day.Meals {
+				dishes := make([]dishMsg, 0, len(meal.Dishes))
+				for _, dish := range meal.Dishes {
+					rec, err := cache.Lookup(dish.ID)
+					if err != nil {
+						log.Warningf("Recipe %d not found: %v", dish.ID, err)
+						continue
+					}
+
+					dishes = append(dishes, dishMsg{
+						RecipeID: rec.ID,
+						Name:     rec.Name,
+						Amount:   m.Servings(meal, dish),
+					})
+				}
+
+				entry.Meals = append(entry.Meals, mealMsg{
+					Name:   meal.Name,
+					Dishes: dishes,
+				})
+			}
+
+			pos := date.Sub(from)
+			out[pos].Entries = append(out[pos].Entries, entry)
+		}
+	}
+
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not write calendar: %v", err)
+	}
+
+	log.Debugf("Responded with %d dates", len(out))
+	return nil
+}
+
+func (s *Service) parseRange(r *http.Request) (from, to dbtypes.Date, err error) {
+	q := r.URL.Query()
+
+	f := q.Get("from")
+	if f == "" {
+		return from, to, httputils.Error(http.StatusBadRequest, "missing query parameter from")
+	}
+
+	from, err = dbtypes.ParseDate(f)
+	if err != nil {
+		return from, to, httputils.Errorf(http.StatusBadRequest, "invalid parameter from: %v", err)
+	}
+
+	to = from.AddDays(6)
+	if t := q.Get("to"); t != "" {
+		to, err = dbtypes.ParseDate(t)
+		if err != nil {
+			return from, to, httputils.Errorf(http.StatusBadRequest, "invalid parameter to: %v", err)
+		}
+	}
+
+	if to.Compare(from) < 0 {
+		return from, to, httputils.Errorf(http.StatusBadRequest, "date %s is before %s", to, from)
+	}
+
+	if to.Sub(from) >= s.settings.MaxDays {
+		return from, to, httputils.Errorf(http.StatusBadRequest, "cannot request more than %d days", s.settings.MaxDays)
+	}
+
+	return from, to, nil
+}
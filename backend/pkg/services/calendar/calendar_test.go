@@ -0,0 +1,203 @@
+package calendar_test
+
+import (
+	"io/fs"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers/blank"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/calendar"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	providers.Register(blank.Provider{})
+	m.Run()
+}
+
+func TestCalendarEndpoint(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		method string
+		path   string
+
+		wantCode int
+		wantBody string
+	}{
+		"GET":               {method: "GET", path: "/api/calendar?from=2024-03-24&to=2024-03-28", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET default range": {method: "GET", path: "/api/calendar?from=2024-03-25", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET with empty DB": {method: "GET", path: "/api/calendar?from=2024-03-25&to=2024-03-25", wantCode: http.StatusOK, wantBody: `[{"date":"2024-03-25","weekday":"Monday","entries":[]}]` + "\n"},
+		"GET missing from":  {method: "GET", path: "/api/calendar?to=2024-03-25", wantCode: http.StatusBadRequest},
+		"GET invalid date":  {method: "GET", path: "/api/calendar?from=25/03/2024", wantCode: http.StatusBadRequest},
+		"GET reversed":      {method: "GET", path: "/api/calendar?from=2024-03-25&to=2024-03-24", wantCode: http.StatusBadRequest},
+		"GET too long":      {method: "GET", path: "/api/calendar?from=2024-01-01&to=2025-01-01", wantCode: http.StatusBadRequest},
+
+		"POST": {method: "POST", path: "/api/calendar?from=2024-03-25", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db := testutils.Database(t, testutils.FixturePath(t, "database"))
+
+			sv := calendar.New(calendar.Settings{}.Defaults(), db, testutils.MockAuthGetter())
+			require.True(t, sv.Enabled())
+
+			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
+				ServePath: sv.Path(),
+				ReqPath:   tc.path,
+				Endpoint:  sv.Handle,
+				Method:    tc.method,
+				WantCode:  tc.wantCode,
+				WantBody:  tc.wantBody,
+			})
+		})
+	}
+}
+
+// stamp is the fixed time used to generate the calendars, so that they can be compared to golden files.
+var stamp = time.Date(2024, time.March, 20, 12, 0, 0, 0, time.UTC)
+
+func TestMenuExportEndpoint(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		method string
+		path   string
+		accept string
+
+		wantCode int
+		wantBody string
+	}{
+		"GET":              {method: "GET", path: "/api/menu/Week 13/ics", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET template":     {method: "GET", path: "/api/menu/Weekly template/ics", wantCode: http.StatusUnprocessableEntity},
+		"GET not found":    {method: "GET", path: "/api/menu/nonexistent/ics", wantCode: http.StatusNotFound},
+		"GET unacceptable": {method: "GET", path: "/api/menu/Week 13/ics", accept: "application/json", wantCode: http.StatusNotAcceptable},
+
+		"POST": {method: "POST", path: "/api/menu/Week 13/ics", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db := testutils.Database(t, testutils.FixturePath(t, "database"))
+
+			sv := calendar.NewMenuExport(calendar.Settings{}.Defaults(), db, testutils.MockAuthGetter())
+			require.True(t, sv.Enabled())
+			sv.SetClock(stamp)
+
+			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
+				ServePath:  sv.Path(),
+				ReqPath:    tc.path,
+				Endpoint:   sv.Handle,
+				Method:     tc.method,
+				Accept:     tc.accept,
+				WantCode:   tc.wantCode,
+				WantBody:   tc.wantBody,
+				GoldenFile: "menu.ics",
+			})
+		})
+	}
+}
+
+func TestFeedEndpoint(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		method string
+
+		wantCode  int
+		wantBody  string
+		wantToken bool
+	}{
+		"GET":                {method: "GET", wantCode: http.StatusOK, wantBody: "!golden", wantToken: true},
+		"GET not enabled":    {method: "GET", wantCode: http.StatusNotFound},
+		"POST":               {method: "POST", wantCode: http.StatusCreated, wantToken: true},
+		"POST rotates token": {method: "POST", wantCode: http.StatusCreated, wantToken: true},
+		"DELETE":             {method: "DELETE", wantCode: http.StatusNoContent},
+
+		"PUT": {method: "PUT", wantCode: http.StatusMethodNotAllowed, wantToken: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db := testutils.Database(t, testutils.FixturePath(t, "database"))
+
+			sv := calendar.NewFeed(calendar.Settings{}.Defaults(), db, testutils.MockAuthGetter())
+			require.True(t, sv.Enabled())
+
+			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
+				ServePath: sv.Path(),
+				ReqPath:   "/api/calendar/feed",
+				Endpoint:  sv.Handle,
+				Method:    tc.method,
+				WantCode:  tc.wantCode,
+				WantBody:  tc.wantBody,
+			})
+
+			f, err := db.LookupUserCalendarFeed("test-user-123")
+			if !tc.wantToken {
+				require.ErrorIs(t, err, fs.ErrNotExist, "Should not have a calendar feed")
+				return
+			}
+			require.NoError(t, err, "Should have a calendar feed")
+
+			if tc.method == http.MethodPost {
+				require.NotEqual(t, "secret-token", f.Token, "Token should have been replaced")
+				require.Len(t, f.Token, 43, "Token should be 32 random bytes")
+			}
+
+			// The feed of other users is unaffected
+			_, err = db.LookupCalendarFeed("other-token")
+			require.NoError(t, err, "Calendar feed of another user should not be affected")
+		})
+	}
+}
+
+func TestSubscriptionEndpoint(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		method string
+		path   string
+
+		wantCode int
+		wantBody string
+	}{
+		"GET":               {method: "GET", path: "/api/calendar/feed/secret-token", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET with suffix":   {method: "GET", path: "/api/calendar/feed/secret-token.ics", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET unknown token": {method: "GET", path: "/api/calendar/feed/guessed-token", wantCode: http.StatusNotFound},
+
+		"POST": {method: "POST", path: "/api/calendar/feed/secret-token", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db := testutils.Database(t, testutils.FixturePath(t, "database"))
+
+			sv := calendar.NewSubscription(calendar.Settings{}.Defaults(), db)
+			require.True(t, sv.Enabled())
+			sv.SetClock(stamp)
+
+			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
+				ServePath:  sv.Path(),
+				ReqPath:    tc.path,
+				Endpoint:   sv.Handle,
+				Method:     tc.method,
+				WantCode:   tc.wantCode,
+				WantBody:   tc.wantBody,
+				GoldenFile: "menus.ics",
+			})
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package calendar
+
+import "time"
+
+// SetClock makes the service use a fixed time to stamp the calendars.
+func (s *MenuExportService) SetClock(now time.Time) {
+	s.now = func() time.Time { return now }
+}
+
+// SetClock makes the service use a fixed time to stamp the calendars.
+func (s *SubscriptionService) SetClock(now time.Time) {
+	s.now = func() time.Time { return now }
+}
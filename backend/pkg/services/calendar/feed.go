@@ -0,0 +1,241 @@
+package calendar
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/icalendar"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+)
+
+const feedPath = "/api/calendar/feed"
+
+// FeedService manages the secret token of the calendar subscription of the user.
+type FeedService struct {
+	settings Settings
+	db       database.DB
+	auth     auth.Getter
+}
+
+func NewFeed(s Settings, db database.DB, auth auth.Getter) *FeedService {
+	if !s.Enable {
+		return nil
+	}
+
+	return &FeedService{
+		settings: s,
+		db:       db,
+		auth:     auth,
+	}
+}
+
+func (s FeedService) Name() string {
+	return "calendar-feed"
+}
+
+func (s FeedService) Path() string {
+	return feedPath
+}
+
+func (s FeedService) Enabled() bool {
+	return s.settings.Enable
+}
+
+func (s *FeedService) Handle(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return s.handleGet(log, w, r)
+	case http.MethodPost:
+		return s.handlePost(log, w, r)
+	case http.MethodDelete:
+		return s.handleDelete(log, w, r)
+	default:
+		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+type feedMsg struct {
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+func newFeedMsg(f dbtypes.CalendarFeed) feedMsg {
+	return feedMsg{
+		Token: f.Token,
+		URL:   path.Join(feedPath, f.Token+".ics"),
+	}
+}
+
+func (s *FeedService) handleGet(_ logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	f, err := s.db.LookupUserCalendarFeed(user)
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Error(http.StatusNotFound, "calendar feed not enabled")
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get calendar feed: %v", err)
+	}
+
+	if err := json.NewEncoder(w).Encode(newFeedMsg(f)); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not write response: %v", err)
+	}
+
+	return nil
+}
+
+// handlePost creates a new token, revoking the previous one if there was any.
+func (s *FeedService) handlePost(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not generate token: %v", err)
+	}
+
+	f := dbtypes.CalendarFeed{User: user, Token: token}
+	if err := s.db.SetCalendarFeed(f); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not save calendar feed: %v", err)
+	}
+
+	log.Debug("Created a new calendar feed token")
+
+	w.Header().Set("Location", newFeedMsg(f).URL)
+	w.WriteHeader(http.StatusCreated)
+
+	if err := json.NewEncoder(w).Encode(newFeedMsg(f)); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not write response: %v", err)
+	}
+
+	return nil
+}
+
+func (s *FeedService) handleDelete(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	if err := s.db.DeleteCalendarFeed(user); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not delete calendar feed: %v", err)
+	}
+
+	log.Debug("Revoked calendar feed token")
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// newToken generates a random URL-safe token.
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// SubscriptionService serves all the dated menus of a user as an iCalendar feed.
+//
+// Calendar apps cannot send the authentication cookie, so the user is identified by
+// the secret token in the path instead.
+type SubscriptionService struct {
+	settings Settings
+	db       database.DB
+	now      func() time.Time
+}
+
+func NewSubscription(s Settings, db database.DB) *SubscriptionService {
+	if !s.Enable {
+		return nil
+	}
+
+	return &SubscriptionService{
+		settings: s,
+		db:       db,
+		now:      time.Now,
+	}
+}
+
+func (s SubscriptionService) Name() string {
+	return "calendar-subscription"
+}
+
+func (s SubscriptionService) Path() string {
+	return feedPath + "/{token}"
+}
+
+func (s SubscriptionService) Enabled() bool {
+	return s.settings.Enable
+}
+
+func (s *SubscriptionService) Handle(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return s.handleGet(log, w, r)
+	default:
+		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+func (s *SubscriptionService) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeCalendar); err != nil {
+		return err
+	}
+
+	token := strings.TrimSuffix(r.PathValue("token"), ".ics")
+
+	// Unknown tokens are reported as not found, to avoid revealing anything
+	f, err := s.db.LookupCalendarFeed(token)
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Error(http.StatusNotFound, "calendar not found")
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get calendar feed: %v", err)
+	}
+
+	menus, err := s.db.Menus(f.User)
+	if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get menus: %v", err)
+	}
+
+	slices.SortFunc(menus, func(a, b dbtypes.Menu) int { return strings.Compare(a.Name, b.Name) })
+
+	cal := icalendar.Calendar{
+		Name:  "Menus",
+		Stamp: s.now(),
+	}
+
+	for _, m := range menus {
+		cal.Events = append(cal.Events, s.settings.events(log, s.db, m)...)
+	}
+
+	log.Debugf("Serving calendar feed with %d events", len(cal.Events))
+
+	return writeCalendar(w, cal, "menus.ics")
+}
@@ -0,0 +1,165 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/icalendar"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+)
+
+// MenuExportService exports a dated menu as an iCalendar file.
+type MenuExportService struct {
+	settings Settings
+	db       database.DB
+	auth     auth.Getter
+	now      func() time.Time
+}
+
+func NewMenuExport(s Settings, db database.DB, auth auth.Getter) *MenuExportService {
+	if !s.Enable {
+		return nil
+	}
+
+	return &MenuExportService{
+		settings: s,
+		db:       db,
+		auth:     auth,
+		now:      time.Now,
+	}
+}
+
+func (s MenuExportService) Name() string {
+	return "menu-ics"
+}
+
+func (s MenuExportService) Path() string {
+	return "/api/menu/{menu}/ics"
+}
+
+func (s MenuExportService) Enabled() bool {
+	return s.settings.Enable
+}
+
+func (s *MenuExportService) Handle(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return s.handleGet(log, w, r)
+	default:
+		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+func (s *MenuExportService) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeCalendar); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	name := r.PathValue("menu")
+	m, err := s.db.LookupMenu(user, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "menu %s not found", name)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get menu: %v", err)
+	}
+
+	if m.StartDate == nil {
+		return httputils.Errorf(http.StatusUnprocessableEntity, "menu %s has no start date", name)
+	}
+
+	cal := icalendar.Calendar{
+		Name:   m.Name,
+		Events: s.settings.events(log, s.db, m),
+		Stamp:  s.now(),
+	}
+
+	return writeCalendar(w, cal, m.Name+".ics")
+}
+
+// events creates an event for every meal of a dated menu. Meals without dishes are skipped.
+func (s Settings) events(log logger.Logger, db database.DB, m dbtypes.Menu) []icalendar.Event {
+	cache := database.NewCachedUserLookup(m.User, db.LookupRecipe)
+
+	var out []icalendar.Event
+	for i, date := range m.Dates() {
+		day := m.Days[i]
+
+		for j, meal := range day.Meals {
+			var names []string
+			description := []string{"Menu: " + m.Name}
+
+			for _, dish := range meal.Dishes {
+				rec, err := cache.Lookup(dish.ID)
+				if err != nil {
+					log.Warningf("Recipe %d not found: %v", dish.ID, err)
+					continue
+				}
+
+				names = append(names, rec.Name)
+				description = append(description, fmt.Sprintf("%s (%s)", rec.Name, strconv.FormatFloat(float64(m.Servings(meal, dish)), 'f', -1, 32)))
+			}
+
+			if len(names) == 0 {
+				continue
+			}
+
+			out = append(out, icalendar.Event{
+				UID:         fmt.Sprintf("%s/%s/%d/%d@grocery-price-fetcher", url.PathEscape(m.User), url.PathEscape(m.Name), i, j),
+				Summary:     fmt.Sprintf("%s: %s", meal.Name, strings.Join(names, ", ")),
+				Description: strings.Join(description, "\n"),
+				Date:        date,
+				Start:       s.mealTime(log, meal.Name),
+				Duration:    s.MealDuration,
+			})
+		}
+	}
+
+	return out
+}
+
+// mealTime returns the time a meal starts, or nil if it is not known.
+func (s Settings) mealTime(log logger.Logger, meal string) *time.Duration {
+	t, ok := s.MealTimes[strings.ToLower(strings.TrimSpace(meal))]
+	if !ok {
+		return nil
+	}
+
+	parsed, err := time.Parse("15:04", t)
+	if err != nil {
+		log.Warningf("Invalid time %q for meal %s: %v", t, meal, err)
+		return nil
+	}
+
+	d := time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute
+	return &d
+}
+
+func writeCalendar(w http.ResponseWriter, cal icalendar.Calendar, filename string) error {
+	// Sorting makes the output stable regardless of the order of the menus
+	slices.SortStableFunc(cal.Events, func(a, b icalendar.Event) int { return a.Date.Compare(b.Date) })
+
+	w.Header().Set("Content-Type", httputils.MediaTypeCalendar.String()+"; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+
+	if err := cal.Write(w); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not write calendar: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,411 @@
+package cooking
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/menuneeds"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+)
+
+// Service marks the meals of a menu as cooked, taking their ingredients out of a pantry.
+//
+// Every cooked meal is recorded with the stock it used, so that it can be put back if the
+// meal is marked as not cooked.
+type Service struct {
+	settings Settings
+	db       database.DB
+	auth     auth.Getter
+
+	now func() time.Time
+}
+
+type Settings struct {
+	Enable bool
+}
+
+func (Settings) Defaults() Settings {
+	return Settings{
+		Enable: true,
+	}
+}
+
+func New(s Settings, db database.DB, auth auth.Getter) *Service {
+	if !s.Enable {
+		return nil
+	}
+
+	return &Service{
+		settings: s,
+		db:       db,
+		auth:     auth,
+		now:      time.Now,
+	}
+}
+
+func (s Service) Name() string {
+	return "cooked-meals"
+}
+
+func (s Service) Path() string {
+	return "/api/menu/{menu}/cooked"
+}
+
+func (s Service) Enabled() bool {
+	return s.settings.Enable
+}
+
+func (s *Service) Handle(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return s.handleGet(log, w, r)
+	case http.MethodPost:
+		return s.handlePost(log, w, r)
+	case http.MethodDelete:
+		return s.handleDelete(log, w, r)
+	default:
+		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+type lotMsg struct {
+	ProductID  product.ID    `json:"product_id"`
+	Name       string        `json:"name"`
+	Amount     float32       `json:"amount"`
+	Purchased  *dbtypes.Date `json:"purchased,omitempty"`
+	BestBefore *dbtypes.Date `json:"best_before,omitempty"`
+}
+
+type cookedMsg struct {
+	Day      int       `json:"day"`
+	Meal     int       `json:"meal"`
+	DayName  string    `json:"day_name"`
+	MealName string    `json:"meal_name"`
+	Pantry   string    `json:"pantry"`
+	Cooked   time.Time `json:"cooked"`
+	Used     []lotMsg  `json:"used"`
+}
+
+type missingMsg struct {
+	ProductID product.ID `json:"product_id"`
+	Name      string     `json:"name"`
+	Amount    float32    `json:"amount"`
+}
+
+func (s *Service) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	m, err := s.lookupMenu(user, r.PathValue("menu"))
+	if err != nil {
+		return err
+	}
+
+	cooked, err := s.db.CookedMeals(user, m.Name)
+	if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get cooked meals: %v", err)
+	}
+
+	names := newProductNames(log, s.db)
+	out := make([]cookedMsg, 0, len(cooked))
+	for _, c := range cooked {
+		out = append(out, newCookedMsg(m, c, names))
+	}
+
+	if err := json.NewEncoder(w).Encode(map[string]any{
+		"menu":   m.Name,
+		"cooked": out,
+	}); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not write response: %v", err)
+	}
+
+	return nil
+}
+
+type postMsg struct {
+	Pantry string `json:"pantry"`
+	Day    int    `json:"day"`
+
+	// Meal is the meal of the day that was cooked. If it is not set, the whole day was cooked.
+	Meal *int `json:"meal"`
+}
+
+func (s *Service) handlePost(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateContentType(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	out, err := io.ReadAll(r.Body)
+	if err != nil {
+		return httputils.Error(http.StatusBadRequest, "failed to read request")
+	}
+	r.Body.Close()
+
+	var body postMsg
+	if err := json.Unmarshal(out, &body); err != nil {
+		return httputils.Errorf(http.StatusBadRequest, "could not unmarshal request: %v", err)
+	}
+
+	if body.Pantry == "" {
+		return httputils.Error(http.StatusBadRequest, "missing pantry")
+	}
+
+	m, err := s.lookupMenu(user, r.PathValue("menu"))
+	if err != nil {
+		return err
+	}
+
+	refs, err := mealRefs(m, body.Day, body.Meal)
+	if err != nil {
+		return err
+	}
+
+	cooked, err := s.db.CookedMeals(user, m.Name)
+	if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get cooked meals: %v", err)
+	}
+
+	// Meals that were already cooked are skipped, unless they are all that was asked for
+	refs = slices.DeleteFunc(refs, func(ref dbtypes.MealRef) bool {
+		return slices.ContainsFunc(cooked, func(c dbtypes.CookedMeal) bool { return c.Meal == ref })
+	})
+	if len(refs) == 0 {
+		return httputils.Error(http.StatusConflict, "meal already cooked")
+	}
+
+	meals := make([]dbtypes.MealToCook, 0, len(refs))
+	missing := make(map[product.ID]float32)
+
+	for _, ref := range refs {
+		need := mealNeeds(log, s.db, m, ref)
+		for _, i := range need {
+			missing[i.ProductID] += i.Amount
+		}
+
+		meals = append(meals, dbtypes.MealToCook{Meal: ref, Need: need})
+	}
+
+	// The stock is taken from the latest contents of the pantry, so that concurrent changes to it are kept
+	records, err := s.db.CookMeals(user, m.Name, body.Pantry, s.now(), meals)
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "pantry %s not found", body.Pantry)
+	} else if errors.Is(err, fs.ErrExist) {
+		return httputils.Error(http.StatusConflict, "meal already cooked")
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not store cooked meals: %v", err)
+	}
+
+	for _, c := range records {
+		for _, l := range c.Used {
+			missing[l.ProductID] -= l.Amount
+		}
+	}
+
+	names := newProductNames(log, s.db)
+
+	msgs := make([]cookedMsg, 0, len(records))
+	for _, c := range records {
+		msgs = append(msgs, newCookedMsg(m, c, names))
+	}
+
+	miss := make([]missingMsg, 0, len(missing))
+	for id, amount := range missing {
+		if amount <= 0 {
+			continue
+		}
+		miss = append(miss, missingMsg{ProductID: id, Name: names.get(id), Amount: amount})
+	}
+	slices.SortFunc(miss, func(a, b missingMsg) int { return cmp.Compare(a.ProductID, b.ProductID) })
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(map[string]any{
+		"menu":    m.Name,
+		"pantry":  body.Pantry,
+		"cooked":  msgs,
+		"missing": miss,
+	}); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not write response: %v", err)
+	}
+
+	log.Debugf("Cooked %d meals of menu %s from pantry %s", len(records), m.Name, body.Pantry)
+	return nil
+}
+
+func (s *Service) handleDelete(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	day, err := strconv.Atoi(r.URL.Query().Get("day"))
+	if err != nil {
+		return httputils.Errorf(http.StatusBadRequest, "invalid day: %v", err)
+	}
+
+	var meal *int
+	if q := r.URL.Query().Get("meal"); q != "" {
+		v, err := strconv.Atoi(q)
+		if err != nil {
+			return httputils.Errorf(http.StatusBadRequest, "invalid meal: %v", err)
+		}
+		meal = &v
+	}
+
+	name := r.PathValue("menu")
+	cooked, err := s.db.CookedMeals(user, name)
+	if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get cooked meals: %v", err)
+	}
+
+	cooked = slices.DeleteFunc(cooked, func(c dbtypes.CookedMeal) bool {
+		return c.Meal.Day != day || (meal != nil && c.Meal.Meal != *meal)
+	})
+	if len(cooked) == 0 {
+		return httputils.Error(http.StatusNotFound, "meal not cooked")
+	}
+
+	refs := make([]dbtypes.MealRef, 0, len(cooked))
+	for _, c := range cooked {
+		refs = append(refs, c.Meal)
+	}
+
+	// The stock goes back to the pantries it was taken from, in the same transaction that forgets the meals
+	if err := s.db.UncookMeals(user, name, refs); errors.Is(err, fs.ErrNotExist) {
+		return httputils.Error(http.StatusNotFound, "meal not cooked")
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not delete cooked meals: %v", err)
+	}
+
+	log.Debugf("Uncooked %d meals of menu %s", len(cooked), name)
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *Service) lookupMenu(user, name string) (dbtypes.Menu, error) {
+	m, err := s.db.LookupMenu(user, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, httputils.Errorf(http.StatusNotFound, "menu %s not found", name)
+	} else if err != nil {
+		return m, httputils.Errorf(http.StatusInternalServerError, "could not get menu: %v", err)
+	}
+	return m, nil
+}
+
+// mealRefs returns the meals of the given day, or only the given meal if it is not nil.
+func mealRefs(m dbtypes.Menu, day int, meal *int) ([]dbtypes.MealRef, error) {
+	if day < 0 || day >= len(m.Days) {
+		return nil, httputils.Errorf(http.StatusBadRequest, "menu %s has no day %d", m.Name, day)
+	}
+
+	if meal != nil {
+		ref := dbtypes.MealRef{Day: day, Meal: *meal}
+		if _, ok := m.Meal(ref); !ok {
+			return nil, httputils.Errorf(http.StatusBadRequest, "day %d of menu %s has no meal %d", day, m.Name, *meal)
+		}
+		return []dbtypes.MealRef{ref}, nil
+	}
+
+	refs := make([]dbtypes.MealRef, 0, len(m.Days[day].Meals))
+	for i := range m.Days[day].Meals {
+		refs = append(refs, dbtypes.MealRef{Day: day, Meal: i})
+	}
+
+	return refs, nil
+}
+
+// mealNeeds computes the ingredients needed for a single meal of the menu, sorted by product.
+func mealNeeds(log logger.Logger, db database.DB, m dbtypes.Menu, ref dbtypes.MealRef) []recipe.Ingredient {
+	meal, _ := m.Meal(ref)
+
+	single := m
+	single.Days = []dbtypes.Day{{
+		Name:  m.Days[ref.Day].Name,
+		Meals: []dbtypes.Meal{meal},
+	}}
+
+	need := menuneeds.ComputeNeeds(log, db, single)
+	slices.SortFunc(need, func(a, b recipe.Ingredient) int { return cmp.Compare(a.ProductID, b.ProductID) })
+	return need
+}
+
+func newCookedMsg(m dbtypes.Menu, c dbtypes.CookedMeal, names productNames) cookedMsg {
+	msg := cookedMsg{
+		Day:    c.Meal.Day,
+		Meal:   c.Meal.Meal,
+		Pantry: c.Pantry,
+		Cooked: c.Cooked,
+		Used:   make([]lotMsg, 0, len(c.Used)),
+	}
+
+	// The menu may have changed since the meal was cooked
+	if meal, ok := m.Meal(c.Meal); ok {
+		msg.DayName = m.Days[c.Meal.Day].Name
+		msg.MealName = meal.Name
+	}
+
+	for _, l := range c.Used {
+		msg.Used = append(msg.Used, lotMsg{
+			ProductID:  l.ProductID,
+			Name:       names.get(l.ProductID),
+			Amount:     l.Amount,
+			Purchased:  l.Purchased,
+			BestBefore: l.BestBefore,
+		})
+	}
+
+	return msg
+}
+
+// productNames looks up the names of products, only once each. Missing products have no name.
+type productNames struct {
+	log   logger.Logger
+	db    database.DB
+	cache map[product.ID]string
+}
+
+func newProductNames(log logger.Logger, db database.DB) productNames {
+	return productNames{log: log, db: db, cache: make(map[product.ID]string)}
+}
+
+func (p productNames) get(id product.ID) string {
+	if name, ok := p.cache[id]; ok {
+		return name
+	}
+
+	prod, err := p.db.LookupProduct(id)
+	if err != nil {
+		p.log.Warnf("Product %d not found: %v", id, err)
+	}
+
+	p.cache[id] = prod.Name
+	return prod.Name
+}
@@ -0,0 +1,134 @@
+package cooking_test
+
+import (
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers/blank"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/cooking"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	providers.Register(blank.Provider{})
+	m.Run()
+}
+
+func TestCookingEndpoint(t *testing.T) {
+	t.Parallel()
+
+	// The pantry before any of the meals is cooked
+	fresh := []recipe.Ingredient{{ProductID: 1, Amount: 5}, {ProductID: 2, Amount: 6}}
+
+	afterLunch := []recipe.Ingredient{{ProductID: 1, Amount: 3}, {ProductID: 2, Amount: 2}}
+	afterLunchLots := []dbtypes.PantryLot{
+		{ProductID: 1, Amount: 1, Purchased: date("2024-03-20")},
+		{ProductID: 2, Amount: 1, BestBefore: date("2024-03-30")},
+	}
+
+	testCases := map[string]struct {
+		method string
+		path   string
+
+		wantCode int
+		wantBody string
+		check    func(*testing.T, database.DB)
+	}{
+		"GET":                {method: "GET", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET menu not found": {method: "GET", path: "/api/menu/nonexistent/cooked", wantCode: http.StatusNotFound},
+
+		"POST meal": {method: "POST", wantCode: http.StatusCreated, wantBody: "!golden", check: wantPantry(1, afterLunch, afterLunchLots)},
+		"POST day": {method: "POST", wantCode: http.StatusCreated, wantBody: "!golden", check: wantPantry(2,
+			[]recipe.Ingredient{{ProductID: 1, Amount: 3}, {ProductID: 2, Amount: 1}}, afterLunchLots)},
+		"POST day partly cooked": {method: "POST", wantCode: http.StatusCreated, wantBody: "!golden", check: wantPantry(2, afterLunch, afterLunchLots)},
+		"POST already cooked":    {method: "POST", wantCode: http.StatusConflict, check: wantPantry(1, fresh, nil)},
+		"POST invalid meal":      {method: "POST", wantCode: http.StatusBadRequest, check: wantPantry(0, fresh, nil)},
+		"POST invalid day":       {method: "POST", wantCode: http.StatusBadRequest, check: wantPantry(0, fresh, nil)},
+		"POST missing pantry":    {method: "POST", wantCode: http.StatusBadRequest, check: wantPantry(0, fresh, nil)},
+		"POST pantry not found":  {method: "POST", wantCode: http.StatusNotFound, check: wantPantry(0, fresh, nil)},
+
+		"DELETE": {method: "DELETE", path: "/api/menu/week/cooked?day=0&meal=1", wantCode: http.StatusNoContent, check: wantPantry(0,
+			[]recipe.Ingredient{{ProductID: 1, Amount: 5}, {ProductID: 2, Amount: 8}, {ProductID: 3, Amount: 1}},
+			[]dbtypes.PantryLot{
+				{ProductID: 1, Amount: 3, Purchased: date("2024-03-20")},
+				{ProductID: 2, Amount: 4, BestBefore: date("2024-04-03")},
+				{ProductID: 2, Amount: 1, BestBefore: date("2024-03-30")},
+			})},
+		"DELETE not cooked": {method: "DELETE", path: "/api/menu/week/cooked?day=1", wantCode: http.StatusNotFound, check: wantPantry(1, fresh, nil)},
+
+		"PUT": {method: "PUT", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db := testutils.Database(t, testutils.FixturePath(t, "database"))
+
+			sv := cooking.New(cooking.Settings{}.Defaults(), db, testutils.MockAuthGetter())
+			require.True(t, sv.Enabled())
+			sv.SetClock(time.Date(2024, time.April, 1, 12, 0, 0, 0, time.UTC))
+
+			fixture := testutils.FixturePath(t, "message", "body.json")
+			out, err := os.ReadFile(fixture)
+			if err != nil {
+				require.ErrorIs(t, err, os.ErrNotExist)
+				out = nil
+				t.Logf("No golden file found at %s", fixture)
+			}
+
+			if tc.path == "" {
+				tc.path = "/api/menu/week/cooked"
+			}
+
+			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
+				ServePath: sv.Path(),
+				ReqPath:   tc.path,
+				Endpoint:  sv.Handle,
+				Method:    tc.method,
+				Body:      string(out),
+				WantCode:  tc.wantCode,
+				WantBody:  tc.wantBody,
+			})
+
+			if tc.check != nil {
+				tc.check(t, db)
+			}
+		})
+	}
+}
+
+// wantPantry returns a check that the menu has the given number of cooked meals, and that the pantry
+// has the given contents. The lots are only checked if they are not nil.
+func wantPantry(cooked int, contents []recipe.Ingredient, lots []dbtypes.PantryLot) func(*testing.T, database.DB) {
+	return func(t *testing.T, db database.DB) {
+		t.Helper()
+
+		meals, err := db.CookedMeals("test-user-123", "week")
+		require.NoError(t, err)
+		require.Len(t, meals, cooked, "Cooked meals do not match")
+
+		p, err := db.LookupPantry("test-user-123", "pantry1")
+		require.NoError(t, err)
+		require.ElementsMatch(t, contents, p.Contents, "Pantry contents do not match")
+
+		if lots != nil {
+			require.ElementsMatch(t, lots, p.Lots, "Pantry lots do not match")
+		}
+	}
+}
+
+func date(s string) *dbtypes.Date {
+	d, err := dbtypes.ParseDate(s)
+	if err != nil {
+		panic(err)
+	}
+	return &d
+}
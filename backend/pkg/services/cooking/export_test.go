@@ -0,0 +1,8 @@
+package cooking
+
+import "time"
+
+// SetClock makes the service use a fixed time for the meals it cooks.
+func (s *Service) SetClock(now time.Time) {
+	s.now = func() time.Time { return now }
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/utils"
 )
 
@@ -59,7 +60,7 @@ type respBodyItem struct {
 	Amount float32 `json:"amount"`
 }
 
-func (s Service) Handle(_ logger.Logger, w http.ResponseWriter, r *http.Request) error {
+func (s Service) Handle(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
 	if r.Method != http.MethodGet {
 		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
 	}
@@ -86,7 +87,7 @@ func (s Service) Handle(_ logger.Logger, w http.ResponseWriter, r *http.Request)
 		return httputils.Errorf(http.StatusBadRequest, "invalid ingredient ID %q: %v", ingredientRaw, err)
 	}
 
-	resp, err := s.compute(user, menu, ingredient)
+	resp, err := s.compute(log, user, menu, ingredient)
 	if err != nil {
 		return err
 	}
@@ -98,7 +99,7 @@ func (s Service) Handle(_ logger.Logger, w http.ResponseWriter, r *http.Request)
 	return nil
 }
 
-func (s *Service) compute(user, menuName string, ingredientID product.ID) ([]respBodyItem, error) {
+func (s *Service) compute(log logger.Logger, user, menuName string, ingredientID product.ID) ([]respBodyItem, error) {
 	menu, err := s.db.LookupMenu(user, menuName)
 	if errors.Is(err, fs.ErrNotExist) {
 		return nil, httputils.Errorf(http.StatusNotFound, "menu %q not found", menuName)
@@ -112,18 +113,29 @@ func (s *Service) compute(user, menuName string, ingredientID product.ID) ([]res
 	for _, day := range menu.Days {
 		for _, meal := range day.Meals {
 			for _, dish := range meal.Dishes {
-				recipe, err := cached.Lookup(dish.ID)
+				if !dish.Cooked() {
+					continue
+				}
+
+				rec, err := cached.Lookup(dish.ID)
+				if err != nil {
+					continue
+				}
+
+				// Sub-recipes are expanded so that the product is found even if it is not used directly
+				ingredients, err := recipe.Flatten(rec, cached.Lookup)
 				if err != nil {
+					log.Warningf("Recipe %d %s: %v", rec.ID, rec.Name, err)
 					continue
 				}
 
-				for _, ingredient := range recipe.Ingredients {
+				for _, ingredient := range ingredients {
 					if ingredient.ProductID == ingredientID {
 						resp = append(resp, respBodyItem{
 							Day:    day.Name,
 							Meal:   meal.Name,
-							Dish:   recipe.Name,
-							Amount: ingredient.Amount * dish.Amount,
+							Dish:   rec.Name,
+							Amount: ingredient.Amount * menu.Servings(meal, dish),
 						})
 					}
 				}
@@ -6,6 +6,8 @@ import (
 	"io"
 	"io/fs"
 	"net/http"
+	"net/url"
+	"path"
 
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
@@ -70,6 +72,12 @@ func (s *Service) Handle(log logger.Logger, w http.ResponseWriter, r *http.Reque
 		return s.handleGet(log, w, r)
 	case http.MethodPut:
 		return s.handlePut(log, w, r)
+	case http.MethodDelete:
+		return s.handleDelete(log, w, r)
+	case http.MethodPatch:
+		return s.handlePatch(log, w, r)
+	case http.MethodPost:
+		return s.handlePost(log, w, r)
 	default:
 		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
 	}
@@ -137,6 +145,10 @@ func (s *Service) handlePut(log logger.Logger, w http.ResponseWriter, r *http.Re
 
 	log.Debugf("Received request with %d days", len(menu.Days))
 
+	if err := menu.Validate(); err != nil {
+		return httputils.Errorf(http.StatusBadRequest, "invalid menu: %v", err)
+	}
+
 	if err := s.UpdateMenu(log, menu); err != nil {
 		return httputils.Errorf(http.StatusInternalServerError, "failed to update menu: %v", err)
 	}
@@ -146,6 +158,146 @@ func (s *Service) handlePut(log logger.Logger, w http.ResponseWriter, r *http.Re
 	return nil
 }
 
+// handleDelete deletes a menu and its shopping lists.
+func (s *Service) handleDelete(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	name := r.PathValue("menu")
+	if name == "" {
+		return httputils.Error(http.StatusBadRequest, "missing menu")
+	}
+
+	if _, err := s.db.LookupMenu(user, name); errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "menu %s not found", name)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get menu: %v", err)
+	}
+
+	if err := s.db.DeleteMenu(user, name); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to delete menu: %v", err)
+	}
+
+	log.Debugf("Deleted menu %s", name)
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+type nameMsg struct {
+	Name string `json:"name"`
+
+	// StartDate is only used when cloning. Setting it on a template plans it for the given dates.
+	StartDate *dbtypes.Date `json:"start_date,omitempty"`
+}
+
+// handlePatch renames a menu. Its shopping lists are kept.
+func (s *Service) handlePatch(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateContentType(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	name := r.PathValue("menu")
+	if name == "" {
+		return httputils.Error(http.StatusBadRequest, "missing menu")
+	}
+
+	body, err := readName(r)
+	if err != nil {
+		return err
+	}
+	newName := body.Name
+
+	if newName == name {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+
+	err = s.db.RenameMenu(user, name, newName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "menu %s not found", name)
+	} else if errors.Is(err, fs.ErrExist) {
+		return httputils.Errorf(http.StatusConflict, "menu %s already exists", newName)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to rename menu: %v", err)
+	}
+
+	log.Debugf("Renamed menu %s to %s", name, newName)
+
+	w.Header().Set("Location", path.Join("/api/menu", url.PathEscape(newName)))
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// handlePost clones a menu under a new name. Shopping lists are not cloned.
+// If a start date is provided, the clone is planned for it, which is how templates are instantiated.
+func (s *Service) handlePost(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateContentType(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	name := r.PathValue("menu")
+	if name == "" {
+		return httputils.Error(http.StatusBadRequest, "missing menu")
+	}
+
+	body, err := readName(r)
+	if err != nil {
+		return err
+	}
+	newName := body.Name
+
+	menu, err := s.db.LookupMenu(user, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "menu %s not found", name)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get menu: %v", err)
+	}
+
+	menu.Name = newName
+	if body.StartDate != nil {
+		menu.StartDate = body.StartDate
+	}
+
+	err = s.db.CreateMenu(menu)
+	if errors.Is(err, fs.ErrExist) {
+		return httputils.Errorf(http.StatusConflict, "menu %s already exists", newName)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to save menu: %v", err)
+	}
+
+	log.Debugf("Cloned menu %s as %s", name, newName)
+
+	w.Header().Set("Location", path.Join("/api/menu", url.PathEscape(newName)))
+	w.WriteHeader(http.StatusCreated)
+	return nil
+}
+
+func readName(r *http.Request) (nameMsg, error) {
+	var body nameMsg
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nameMsg{}, httputils.Errorf(http.StatusBadRequest, "failed to read request: %v", err)
+	}
+
+	if body.Name == "" {
+		return nameMsg{}, httputils.Error(http.StatusBadRequest, "menu name cannot be empty")
+	}
+
+	return body, nil
+}
+
 func (s Service) UpdateMenu(log logger.Logger, menu dbtypes.Menu) error {
 	if menu.Name == "" {
 		menu.Name = "default"
@@ -161,29 +313,36 @@ func (s Service) UpdateMenu(log logger.Logger, menu dbtypes.Menu) error {
 // writeMenu writes the menu to the output stream, adding the names to the dishes.
 func (s *Service) writeMenu(w io.Writer, m dbtypes.Menu) error {
 	type msgDish struct {
-		RecipeID recipe.ID `json:"recipe_id"`
-		Name     string    `json:"name"`
-		Amount   float32   `json:"amount"`
+		RecipeID     recipe.ID        `json:"recipe_id"`
+		Name         string           `json:"name"`
+		Amount       float32          `json:"amount"`
+		LeftoverFrom *dbtypes.MealRef `json:"leftover_from,omitempty"`
 	}
 
 	type msgMeal struct {
-		Name   string    `json:"name"`
-		Dishes []msgDish `json:"dishes"`
+		Name      string    `json:"name"`
+		Attendees *int      `json:"attendees,omitempty"`
+		Dishes    []msgDish `json:"dishes"`
 	}
 
 	type msgDay struct {
-		Name  string    `json:"name"`
-		Meals []msgMeal `json:"meals"`
+		Name  string        `json:"name"`
+		Date  *dbtypes.Date `json:"date,omitempty"`
+		Meals []msgMeal     `json:"meals"`
 	}
 
 	type msgMenu struct {
-		Name string   `json:"name"`
-		Days []msgDay `json:"days"`
+		Name      string        `json:"name"`
+		StartDate *dbtypes.Date `json:"start_date,omitempty"`
+		Household int           `json:"household,omitempty"`
+		Days      []msgDay      `json:"days"`
 	}
 
 	cache := database.NewCachedUserLookup(m.User, s.db.LookupRecipe)
+	dates := m.Dates()
+
 	var days []msgDay
-	for _, d := range m.Days {
+	for i, d := range m.Days {
 		var meals []msgMeal
 		for _, m := range d.Meals {
 			var dishes []msgDish
@@ -194,26 +353,36 @@ func (s *Service) writeMenu(w io.Writer, m dbtypes.Menu) error {
 				}
 
 				dishes = append(dishes, msgDish{
-					RecipeID: recipe.ID,
-					Name:     recipe.Name,
-					Amount:   dish.Amount,
+					RecipeID:     recipe.ID,
+					Name:         recipe.Name,
+					Amount:       dish.Amount,
+					LeftoverFrom: dish.LeftoverFrom,
 				})
 			}
 
 			meals = append(meals, msgMeal{
-				Name:   m.Name,
-				Dishes: dishes,
+				Name:      m.Name,
+				Attendees: m.Attendees,
+				Dishes:    dishes,
 			})
 		}
 
-		days = append(days, msgDay{
+		day := msgDay{
 			Name:  d.Name,
 			Meals: meals,
-		})
+		}
+
+		if dates != nil {
+			day.Date = &dates[i]
+		}
+
+		days = append(days, day)
 	}
 
 	return json.NewEncoder(w).Encode(msgMenu{
-		Name: m.Name,
-		Days: days,
+		Name:      m.Name,
+		StartDate: m.StartDate,
+		Household: m.Household,
+		Days:      days,
 	})
 }
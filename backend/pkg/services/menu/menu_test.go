@@ -5,6 +5,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers/blank"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/menu"
@@ -22,19 +23,34 @@ func TestMenuEndpoint(t *testing.T) {
 
 	testCases := map[string]struct {
 		method string
+		path   string
 
 		wantCode int
 		wantBody string
+
+		// check verifies the state of the database after the request
+		check func(t *testing.T, db database.DB)
 	}{
 		"GET":               {method: "GET", wantCode: http.StatusOK, wantBody: "!golden"},
 		"GET with empty DB": {method: "GET", wantCode: http.StatusNotFound},
+		"GET dated":         {method: "GET", wantCode: http.StatusOK, wantBody: "!golden"},
+
+		"PUT":                    {method: "PUT", wantCode: http.StatusCreated},
+		"PUT override":           {method: "PUT", wantCode: http.StatusCreated},
+		"PUT invalid leftover":   {method: "PUT", wantCode: http.StatusBadRequest},
+		"PUT negative household": {method: "PUT", wantCode: http.StatusBadRequest},
+		"PUT negative attendees": {method: "PUT", wantCode: http.StatusBadRequest},
+
+		"DELETE":           {method: "DELETE", wantCode: http.StatusNoContent, check: wantMenus([]string{"testmenu2"}, "testmenu2")},
+		"DELETE not found": {method: "DELETE", path: "/api/menu/nonexistent", wantCode: http.StatusNotFound},
 
-		"PUT":          {method: "PUT", wantCode: http.StatusCreated},
-		"PUT override": {method: "PUT", wantCode: http.StatusCreated},
+		"PATCH":           {method: "PATCH", wantCode: http.StatusNoContent, check: wantMenus([]string{"renamed", "testmenu2"}, "renamed", "testmenu2")},
+		"PATCH conflict":  {method: "PATCH", wantCode: http.StatusConflict, check: wantMenus([]string{"testmenu1", "testmenu2"}, "testmenu1", "testmenu2")},
+		"PATCH not found": {method: "PATCH", path: "/api/menu/nonexistent", wantCode: http.StatusNotFound},
 
-		"DELETE": {method: "DELETE", wantCode: http.StatusMethodNotAllowed},
-		"PATCH":  {method: "PATCH", wantCode: http.StatusMethodNotAllowed},
-		"POST":   {method: "POST", wantCode: http.StatusMethodNotAllowed},
+		"POST":          {method: "POST", wantCode: http.StatusCreated, check: wantMenus([]string{"renamed", "testmenu1", "testmenu2"}, "testmenu1", "testmenu2")},
+		"POST conflict": {method: "POST", wantCode: http.StatusConflict, check: wantMenus([]string{"testmenu1", "testmenu2"}, "testmenu1", "testmenu2")},
+		"POST template": {method: "POST", wantCode: http.StatusCreated, check: wantStartDate("renamed", "2024-03-25")},
 	}
 
 	for name, tc := range testCases {
@@ -54,15 +70,60 @@ func TestMenuEndpoint(t *testing.T) {
 				t.Logf("No golden file found at %s", fixture)
 			}
 
+			if tc.path == "" {
+				tc.path = "/api/menu/testmenu1"
+			}
+
 			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
 				ServePath: sv.Path(),
-				ReqPath:   "/api/menu/testmenu1",
+				ReqPath:   tc.path,
 				Endpoint:  sv.Handle,
 				Method:    tc.method,
 				Body:      string(out),
 				WantCode:  tc.wantCode,
 				WantBody:  tc.wantBody,
 			})
+
+			if tc.check != nil {
+				tc.check(t, db)
+			}
 		})
 	}
 }
+
+// wantMenus returns a check that the user has the given menus, and shopping lists for the given menus.
+func wantMenus(menus []string, shoppingLists ...string) func(*testing.T, database.DB) {
+	return func(t *testing.T, db database.DB) {
+		t.Helper()
+
+		got, err := db.Menus("test-user-123")
+		require.NoError(t, err)
+
+		var names []string
+		for _, m := range got {
+			names = append(names, m.Name)
+		}
+		require.ElementsMatch(t, menus, names, "Menus do not match")
+
+		lists, err := db.ShoppingLists("test-user-123")
+		require.NoError(t, err)
+
+		names = nil
+		for _, l := range lists {
+			names = append(names, l.Menu)
+		}
+		require.ElementsMatch(t, shoppingLists, names, "Shopping lists do not match")
+	}
+}
+
+// wantStartDate returns a check that the menu is planned to start on the given date.
+func wantStartDate(menu string, date string) func(*testing.T, database.DB) {
+	return func(t *testing.T, db database.DB) {
+		t.Helper()
+
+		m, err := db.LookupMenu("test-user-123", menu)
+		require.NoError(t, err)
+		require.NotNil(t, m.StartDate, "Menu should have a start date")
+		require.Equal(t, date, m.StartDate.String(), "Start date does not match")
+	}
+}
@@ -0,0 +1,105 @@
+package menucheck
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"time"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/menucheck"
+)
+
+// Service reports the problems that make the shopping list of a menu incomplete or inaccurate,
+// such as missing recipes and products, products without a price, and invalid amounts.
+type Service struct {
+	settings Settings
+	db       database.DB
+	auth     auth.Getter
+}
+
+type Settings struct {
+	Enable bool
+
+	// MaxPriceAge is how old a price can be before it is reported as stale.
+	MaxPriceAge time.Duration
+}
+
+func (Settings) Defaults() Settings {
+	return Settings{
+		Enable:      true,
+		MaxPriceAge: menucheck.DefaultMaxPriceAge,
+	}
+}
+
+func New(s Settings, db database.DB, auth auth.Getter) *Service {
+	if !s.Enable {
+		return nil
+	}
+
+	return &Service{
+		settings: s,
+		db:       db,
+		auth:     auth,
+	}
+}
+
+func (s Service) Name() string {
+	return "menu-check"
+}
+
+func (s Service) Path() string {
+	return "/api/menu/{menu}/check"
+}
+
+func (s Service) Enabled() bool {
+	return s.settings.Enable
+}
+
+func (s *Service) Handle(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return s.handleGet(log, w, r)
+	default:
+		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+func (s *Service) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	name := r.PathValue("menu")
+	m, err := s.db.LookupMenu(user, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "menu %s not found", name)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get menu: %v", err)
+	}
+
+	warnings := menucheck.Check(s.db, m, menucheck.Options{
+		MaxPriceAge: s.settings.MaxPriceAge,
+		Now:         time.Now(),
+	})
+
+	if err := json.NewEncoder(w).Encode(map[string]any{
+		"menu":     m.Name,
+		"feasible": len(warnings) == 0,
+		"warnings": warnings,
+	}); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not write response: %v", err)
+	}
+
+	log.Debugf("Menu %s has %d warnings", m.Name, len(warnings))
+	return nil
+}
@@ -0,0 +1,105 @@
+package menucost
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/menucost"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+)
+
+// Service breaks down the cost of a menu per dish, meal and day.
+// The pantry to subtract from the cash cost is chosen with the query parameter pantry.
+type Service struct {
+	settings Settings
+	db       database.DB
+	auth     auth.Getter
+}
+
+type Settings struct {
+	Enable bool
+}
+
+func (Settings) Defaults() Settings {
+	return Settings{
+		Enable: true,
+	}
+}
+
+func New(s Settings, db database.DB, auth auth.Getter) *Service {
+	if !s.Enable {
+		return nil
+	}
+
+	return &Service{
+		settings: s,
+		db:       db,
+		auth:     auth,
+	}
+}
+
+func (s Service) Name() string {
+	return "menu-cost"
+}
+
+func (s Service) Path() string {
+	return "/api/menu/{menu}/cost"
+}
+
+func (s Service) Enabled() bool {
+	return s.settings.Enable
+}
+
+func (s *Service) Handle(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return s.handleGet(log, w, r)
+	default:
+		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+func (s *Service) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	name := r.PathValue("menu")
+	m, err := s.db.LookupMenu(user, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "menu %s not found", name)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get menu: %v", err)
+	}
+
+	var pantry []recipe.Ingredient
+	if p := r.URL.Query().Get("pantry"); p != "" {
+		pt, err := s.db.LookupPantry(user, p)
+		if errors.Is(err, fs.ErrNotExist) {
+			return httputils.Errorf(http.StatusNotFound, "pantry %s not found", p)
+		} else if err != nil {
+			return httputils.Errorf(http.StatusInternalServerError, "could not get pantry: %v", err)
+		}
+		pantry = pt.Contents
+	}
+
+	breakdown := menucost.Compute(log, s.db, m, pantry)
+
+	if err := json.NewEncoder(w).Encode(breakdown); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not write response: %v", err)
+	}
+
+	log.Debugf("Menu %s costs %.2f, and %.2f must be spent on whole packs", m.Name, breakdown.Cost.Marginal, breakdown.Cost.Cash)
+	return nil
+}
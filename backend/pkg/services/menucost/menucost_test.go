@@ -0,0 +1,58 @@
+package menucost_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers/blank"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/menucost"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	providers.Register(blank.Provider{})
+	m.Run()
+}
+
+func TestMenuCostEndpoint(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		method string
+		path   string
+
+		wantCode int
+		wantBody string
+	}{
+		"GET":                  {method: "GET", path: "/api/menu/week/cost", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET with pantry":      {method: "GET", path: "/api/menu/week/cost?pantry=testpantry1", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET with attendance":  {method: "GET", path: "/api/menu/family/cost", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET with leftovers":   {method: "GET", path: "/api/menu/week/cost", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET menu not found":   {method: "GET", path: "/api/menu/nonexistent/cost", wantCode: http.StatusNotFound},
+		"GET pantry not found": {method: "GET", path: "/api/menu/week/cost?pantry=nonexistent", wantCode: http.StatusNotFound},
+
+		"POST": {method: "POST", path: "/api/menu/week/cost", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db := testutils.Database(t, testutils.FixturePath(t, "database"))
+
+			sv := menucost.New(menucost.Settings{}.Defaults(), db, testutils.MockAuthGetter())
+			require.True(t, sv.Enabled())
+
+			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
+				ServePath: sv.Path(),
+				ReqPath:   tc.path,
+				Endpoint:  sv.Handle,
+				Method:    tc.method,
+				WantCode:  tc.wantCode,
+				WantBody:  tc.wantBody,
+			})
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package menus
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/menuneeds"
+)
+
+// Service lists the menus of the user with some summary statistics.
+type Service struct {
+	settings Settings
+	db       database.DB
+	auth     auth.Getter
+}
+
+type Settings struct {
+	Enable bool
+}
+
+func (s Settings) Defaults() Settings {
+	return Settings{
+		Enable: true,
+	}
+}
+
+func New(s Settings, db database.DB, auth auth.Getter) Service {
+	return Service{
+		settings: s,
+		db:       db,
+		auth:     auth,
+	}
+}
+
+func (s Service) Name() string {
+	return "menus"
+}
+
+func (s Service) Path() string {
+	return "/api/menus"
+}
+
+func (s Service) Enabled() bool {
+	return s.settings.Enable
+}
+
+func (s Service) Handle(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodGet {
+		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	menus, err := s.db.Menus(user)
+	if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get menus: %v", err)
+	}
+
+	slices.SortFunc(menus, func(a, b dbtypes.Menu) int { return strings.Compare(a.Name, b.Name) })
+
+	type item struct {
+		Name      string        `json:"name"`
+		StartDate *dbtypes.Date `json:"start_date,omitempty"`
+		Days      int           `json:"days"`
+		Meals     int           `json:"meals"`
+		Dishes    int           `json:"dishes"`
+		Cost      float32       `json:"cost"`
+	}
+
+	items := make([]item, 0, len(menus))
+	for _, m := range menus {
+		it := item{
+			Name:      m.Name,
+			StartDate: m.StartDate,
+			Days:      len(m.Days),
+			Cost:      s.cost(log, m),
+		}
+
+		for _, day := range m.Days {
+			it.Meals += len(day.Meals)
+			for _, meal := range day.Meals {
+				it.Dishes += len(meal.Dishes)
+			}
+		}
+
+		items = append(items, it)
+	}
+
+	if err := json.NewEncoder(w).Encode(items); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not encode menus: %v", err)
+	}
+
+	log.Debugf("Responded with %d items", len(items))
+	return nil
+}
+
+// cost estimates the cost of the products needed to cook the menu, without considering the pantry.
+func (s Service) cost(log logger.Logger, m dbtypes.Menu) float32 {
+	var cost float32
+	for _, need := range menuneeds.ComputeNeeds(log, s.db, m) {
+		prod, err := s.db.LookupProduct(need.ProductID)
+		if err != nil {
+			log.Warningf("Product %d not found: %v", need.ProductID, err)
+			continue
+		}
+
+		cost += need.Amount * prod.UnitPrice()
+	}
+
+	return cost
+}
@@ -0,0 +1,52 @@
+package menus_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers/blank"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/menus"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	providers.Register(blank.Provider{})
+	m.Run()
+}
+
+func TestMenusEndpoint(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		method string
+
+		wantCode int
+		wantBody string
+	}{
+		"GET":               {method: "GET", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET with empty DB": {method: "GET", wantCode: http.StatusOK, wantBody: "[]\n"},
+		"POST":              {method: "POST", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db := testutils.Database(t, testutils.FixturePath(t, "database"))
+
+			sv := menus.New(menus.Settings{}.Defaults(), db, testutils.MockAuthGetter())
+			require.True(t, sv.Enabled())
+
+			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
+				ServePath: sv.Path(),
+				ReqPath:   "/api/menus",
+				Endpoint:  sv.Handle,
+				Method:    tc.method,
+				WantCode:  tc.wantCode,
+				WantBody:  tc.wantBody,
+			})
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package pantry
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+)
+
+// CookableService lists the recipes that can be cooked entirely, or almost entirely, with what is in a pantry.
+// The recipes are ranked by how much of them the pantry covers, and then by the cost of what is missing.
+//
+// The number of servings is chosen with the query parameter servings, and the minimum coverage
+// (a percentage) with the query parameter min_coverage.
+type CookableService struct {
+	settings Settings
+	db       database.DB
+	auth     auth.Getter
+}
+
+func NewCookable(s Settings, db database.DB, auth auth.Getter) *CookableService {
+	if !s.Enable {
+		return nil
+	}
+
+	return &CookableService{
+		settings: s,
+		db:       db,
+		auth:     auth,
+	}
+}
+
+func (s CookableService) Name() string {
+	return "pantry-cookable"
+}
+
+func (s CookableService) Path() string {
+	return "/api/pantry/{pantry}/cookable"
+}
+
+func (s CookableService) Enabled() bool {
+	return s.settings.Enable
+}
+
+func (s *CookableService) Handle(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return s.handleGet(log, w, r)
+	default:
+		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+type cookableMsg struct {
+	ID          recipe.ID    `json:"id"`
+	Name        string       `json:"name"`
+	Coverage    float32      `json:"coverage"`
+	MissingCost float32      `json:"missing_cost"`
+	Missing     []missingMsg `json:"missing"`
+}
+
+type missingMsg struct {
+	ProductID product.ID `json:"product_id"`
+	Name      string     `json:"name"`
+	Amount    float32    `json:"amount"`
+	Packs     int        `json:"packs"`
+	Cost      float32    `json:"cost"`
+}
+
+func (s *CookableService) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	servings := float32(1)
+	if q := r.URL.Query().Get("servings"); q != "" {
+		v, err := strconv.ParseFloat(q, 32)
+		if err != nil || v <= 0 {
+			return httputils.Errorf(http.StatusBadRequest, "invalid number of servings %q", q)
+		}
+		servings = float32(v)
+	}
+
+	minCoverage := s.settings.CookableCoverage
+	if q := r.URL.Query().Get("min_coverage"); q != "" {
+		v, err := strconv.ParseFloat(q, 32)
+		if err != nil || v < 0 || v > 100 {
+			return httputils.Errorf(http.StatusBadRequest, "invalid coverage %q: it must be a percentage", q)
+		}
+		minCoverage = float32(v)
+	}
+
+	name := r.PathValue("pantry")
+	pantry, err := s.db.LookupPantry(user, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "pantry %s not found", name)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get pantry: %v", err)
+	}
+
+	recs, err := s.db.Recipes(user)
+	if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get recipes: %v", err)
+	}
+
+	// Lots that have already expired cannot be cooked
+	stock := pantry.Stock()
+	today := dbtypes.NewDate(time.Now())
+
+	products := database.NewCachedLookup(s.db.LookupProduct)
+	cached := database.NewCachedUserLookup(user, s.db.LookupRecipe)
+
+	out := make([]cookableMsg, 0)
+	for _, rec := range recs {
+		ingredients, err := recipe.Flatten(rec, cached.Lookup)
+		if err != nil {
+			log.Warningf("Recipe %d %s: %v", rec.ID, rec.Name, err)
+			continue
+		} else if len(ingredients) == 0 {
+			continue
+		}
+
+		for i := range ingredients {
+			ingredients[i].Amount *= servings
+		}
+
+		msg := cookableMsg{
+			ID:      rec.ID,
+			Name:    rec.Name,
+			Missing: make([]missingMsg, 0),
+		}
+
+		// Each ingredient weighs the same in the coverage, because amounts in different units cannot be added up
+		var covered float32
+		missing, _ := dbtypes.Consume(ingredients, stock, &today)
+		for i, m := range missing {
+			need := ingredients[i].Amount
+			if need <= 0 {
+				covered++
+				continue
+			}
+
+			covered += 1 - m.Amount/need
+			if m.Amount <= 0 {
+				continue
+			}
+
+			miss := missingMsg{ProductID: m.ProductID, Amount: m.Amount}
+			if p, err := products.Lookup(m.ProductID); err != nil {
+				log.Warningf("Product %d not found: %v", m.ProductID, err)
+			} else if p.BatchSize > 0 {
+				miss.Name = p.Name
+				miss.Packs = int(math.Ceil(float64(m.Amount / p.BatchSize)))
+				miss.Cost = float32(miss.Packs) * p.Price
+			} else {
+				miss.Name = p.Name
+			}
+
+			msg.Missing = append(msg.Missing, miss)
+			msg.MissingCost += miss.Cost
+		}
+
+		msg.Coverage = 100 * covered / float32(len(ingredients))
+		if msg.Coverage < minCoverage {
+			continue
+		}
+
+		out = append(out, msg)
+	}
+
+	slices.SortStableFunc(out, func(a, b cookableMsg) int {
+		if c := cmp.Compare(b.Coverage, a.Coverage); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.MissingCost, b.MissingCost); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	if err := json.NewEncoder(w).Encode(map[string]any{
+		"pantry":   pantry.Name,
+		"servings": servings,
+		"recipes":  out,
+	}); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not write response: %v", err)
+	}
+
+	log.Debugf("Pantry %s covers at least %g%% of %d recipes", pantry.Name, minCoverage, len(out))
+	return nil
+}
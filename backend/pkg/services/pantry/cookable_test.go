@@ -0,0 +1,58 @@
+package pantry_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/pantry"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCookableEndpoint(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		method string
+		path   string
+		query  string
+
+		wantCode int
+		wantBody string
+	}{
+		"GET":                   {method: "GET", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET with servings":     {method: "GET", query: "?servings=2", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET with coverage":     {method: "GET", query: "?min_coverage=0", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET with expired lots": {method: "GET", query: "?min_coverage=0", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET invalid servings":  {method: "GET", query: "?servings=none", wantCode: http.StatusBadRequest},
+		"GET invalid coverage":  {method: "GET", query: "?min_coverage=150", wantCode: http.StatusBadRequest},
+		"GET not found":         {method: "GET", path: "/api/pantry/nonexistent/cookable", wantCode: http.StatusNotFound},
+
+		"POST": {method: "POST", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db := testutils.Database(t, testutils.FixturePath(t, "database"))
+
+			sv := pantry.NewCookable(pantry.Settings{}.Defaults(), db, testutils.MockAuthGetter())
+			require.True(t, sv.Enabled())
+
+			path := tc.path
+			if path == "" {
+				path = "/api/pantry/pantry1/cookable"
+			}
+
+			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
+				ServePath: sv.Path(),
+				ReqPath:   path + tc.query,
+				Endpoint:  sv.Handle,
+				Method:    tc.method,
+				WantCode:  tc.wantCode,
+				WantBody:  tc.wantBody,
+			})
+		})
+	}
+}
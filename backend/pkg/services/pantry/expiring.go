@@ -0,0 +1,146 @@
+package pantry
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+)
+
+// ExpiringService lists the lots of a pantry that are past their best-before date,
+// or will be within the number of days given by the query parameter days.
+// They are sorted so that the ones to use first come first.
+type ExpiringService struct {
+	settings Settings
+	db       database.DB
+	auth     auth.Getter
+	now      func() time.Time
+}
+
+func NewExpiring(s Settings, db database.DB, auth auth.Getter) *ExpiringService {
+	if !s.Enable {
+		return nil
+	}
+
+	return &ExpiringService{
+		settings: s,
+		db:       db,
+		auth:     auth,
+		now:      time.Now,
+	}
+}
+
+func (s ExpiringService) Name() string {
+	return "pantry-expiring"
+}
+
+func (s ExpiringService) Path() string {
+	return "/api/pantry/{pantry}/expiring"
+}
+
+func (s ExpiringService) Enabled() bool {
+	return s.settings.Enable
+}
+
+func (s *ExpiringService) Handle(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return s.handleGet(log, w, r)
+	default:
+		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+type expiringMsg struct {
+	ProductID  product.ID    `json:"product_id"`
+	Name       string        `json:"name"`
+	Amount     float32       `json:"amount"`
+	Purchased  *dbtypes.Date `json:"purchased,omitempty"`
+	BestBefore dbtypes.Date  `json:"best_before"`
+	DaysLeft   int           `json:"days_left"`
+	Expired    bool          `json:"expired"`
+}
+
+func (s *ExpiringService) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	days := s.settings.ExpiringDays
+	if q := r.URL.Query().Get("days"); q != "" {
+		days, err = strconv.Atoi(q)
+		if err != nil || days < 0 {
+			return httputils.Errorf(http.StatusBadRequest, "invalid number of days %q", q)
+		}
+	}
+
+	name := r.PathValue("pantry")
+	pantry, err := s.db.LookupPantry(user, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "pantry %s not found", name)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get pantry: %v", err)
+	}
+
+	today := dbtypes.NewDate(s.now())
+	until := today.AddDays(days)
+
+	items := make([]expiringMsg, 0)
+	for _, l := range pantry.Lots {
+		if l.BestBefore == nil || l.BestBefore.Compare(until) > 0 {
+			continue
+		}
+
+		msg := expiringMsg{
+			ProductID:  l.ProductID,
+			Amount:     l.Amount,
+			Purchased:  l.Purchased,
+			BestBefore: *l.BestBefore,
+			DaysLeft:   l.BestBefore.Sub(today),
+			Expired:    l.Expired(today),
+		}
+
+		if p, err := s.db.LookupProduct(l.ProductID); err == nil {
+			msg.Name = p.Name
+		} else {
+			log.Warningf("Product %d not found: %v", l.ProductID, err)
+		}
+
+		items = append(items, msg)
+	}
+
+	slices.SortStableFunc(items, func(a, b expiringMsg) int {
+		if c := a.BestBefore.Compare(b.BestBefore); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ProductID, b.ProductID)
+	})
+
+	if err := json.NewEncoder(w).Encode(map[string]any{
+		"pantry": pantry.Name,
+		"date":   today,
+		"until":  until,
+		"items":  items,
+	}); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not write response: %v", err)
+	}
+
+	log.Debugf("Pantry %s has %d lots expiring until %s", pantry.Name, len(items), until)
+	return nil
+}
@@ -0,0 +1,51 @@
+package pantry_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/pantry"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpiringEndpoint(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		method string
+		path   string
+
+		wantCode int
+		wantBody string
+	}{
+		"GET":              {method: "GET", path: "/api/pantry/pantry1/expiring", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET with days":    {method: "GET", path: "/api/pantry/pantry1/expiring?days=10", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET invalid days": {method: "GET", path: "/api/pantry/pantry1/expiring?days=-1", wantCode: http.StatusBadRequest},
+		"GET not found":    {method: "GET", path: "/api/pantry/nonexistent/expiring", wantCode: http.StatusNotFound},
+
+		"POST": {method: "POST", path: "/api/pantry/pantry1/expiring", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db := testutils.Database(t, testutils.FixturePath(t, "database"))
+
+			sv := pantry.NewExpiring(pantry.Settings{}.Defaults(), db, testutils.MockAuthGetter())
+			require.True(t, sv.Enabled())
+			sv.SetClock(time.Date(2024, time.April, 1, 12, 0, 0, 0, time.UTC))
+
+			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
+				ServePath: sv.Path(),
+				ReqPath:   tc.path,
+				Endpoint:  sv.Handle,
+				Method:    tc.method,
+				WantCode:  tc.wantCode,
+				WantBody:  tc.wantBody,
+			})
+		})
+	}
+}
@@ -0,0 +1,8 @@
+package pantry
+
+import "time"
+
+// SetClock makes the service use a fixed time to decide what is expiring.
+func (s *ExpiringService) SetClock(now time.Time) {
+	s.now = func() time.Time { return now }
+}
@@ -0,0 +1,149 @@
+package pantry
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+)
+
+// GroupService manages named groups of pantries, such as a kitchen and a freezer,
+// whose stock the shopping list and shopping needs can combine.
+type GroupService struct {
+	settings Settings
+	db       database.DB
+	auth     auth.Getter
+}
+
+func NewGroup(s Settings, db database.DB, auth auth.Getter) *GroupService {
+	if !s.Enable {
+		return nil
+	}
+
+	return &GroupService{
+		settings: s,
+		db:       db,
+		auth:     auth,
+	}
+}
+
+func (s GroupService) Name() string {
+	return "pantry-group"
+}
+
+func (s GroupService) Path() string {
+	return "/api/pantry-group/{group}"
+}
+
+func (s GroupService) Enabled() bool {
+	return s.settings.Enable
+}
+
+func (s *GroupService) Handle(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return s.handleGet(log, w, r)
+	case http.MethodPut:
+		return s.handlePut(log, w, r)
+	case http.MethodDelete:
+		return s.handleDelete(log, w, r)
+	default:
+		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+func (s *GroupService) handleGet(_ logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	name := r.PathValue("group")
+	g, err := s.db.LookupPantryGroup(user, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "pantry group %s not found", name)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get pantry group: %v", err)
+	}
+
+	if err := json.NewEncoder(w).Encode(map[string]any{
+		"name":     g.Name,
+		"pantries": g.Pantries,
+	}); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not write response: %v", err)
+	}
+
+	return nil
+}
+
+func (s *GroupService) handlePut(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateContentType(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	out, err := io.ReadAll(r.Body)
+	if err != nil {
+		return httputils.Error(http.StatusBadRequest, "failed to read request")
+	}
+	r.Body.Close()
+
+	var body struct {
+		Pantries []string `json:"pantries"`
+	}
+
+	if err := json.Unmarshal(out, &body); err != nil {
+		return httputils.Errorf(http.StatusBadRequest, "failed to unmarshal request: %v", err)
+	}
+
+	g := dbtypes.PantryGroup{
+		User:     user,
+		Name:     r.PathValue("group"),
+		Pantries: body.Pantries,
+	}
+
+	if err := g.Validate(); err != nil {
+		return httputils.Errorf(http.StatusBadRequest, "invalid pantry group: %v", err)
+	}
+
+	err = s.db.SetPantryGroup(g)
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusBadRequest, "%v", err)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not set pantry group: %v", err)
+	}
+
+	log.Debugf("Set pantry group %s with %d pantries", g.Name, len(g.Pantries))
+
+	w.WriteHeader(http.StatusCreated)
+	return nil
+}
+
+func (s *GroupService) handleDelete(_ logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	if err := s.db.DeletePantryGroup(user, r.PathValue("group")); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not delete pantry group: %v", err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
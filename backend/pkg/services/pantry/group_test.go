@@ -0,0 +1,90 @@
+package pantry_test
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/pantry"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupEndpoint(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		method string
+		path   string
+
+		wantCode int
+		wantBody string
+		check    func(*testing.T, database.DB)
+	}{
+		"GET":           {method: "GET", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET not found": {method: "GET", path: "/api/pantry-group/nonexistent", wantCode: http.StatusNotFound},
+
+		"PUT":                {method: "PUT", path: "/api/pantry-group/kitchen", wantCode: http.StatusCreated, check: wantGroup("kitchen", "pantry2")},
+		"PUT replace":        {method: "PUT", wantCode: http.StatusCreated, check: wantGroup("home", "pantry2", "pantry1")},
+		"PUT invalid":        {method: "PUT", wantCode: http.StatusBadRequest, check: wantGroup("home", "pantry1", "pantry2")},
+		"PUT unknown pantry": {method: "PUT", wantCode: http.StatusBadRequest, check: wantGroup("home", "pantry1", "pantry2")},
+
+		"DELETE": {method: "DELETE", wantCode: http.StatusNoContent, check: wantGroup("home")},
+		"POST":   {method: "POST", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db := testutils.Database(t, testutils.FixturePath(t, "database"))
+
+			sv := pantry.NewGroup(pantry.Settings{}.Defaults(), db, testutils.MockAuthGetter())
+			require.True(t, sv.Enabled())
+
+			fixture := testutils.FixturePath(t, "message", "body.json")
+			out, err := os.ReadFile(fixture)
+			if err != nil {
+				require.ErrorIs(t, err, os.ErrNotExist)
+				out = nil
+				t.Logf("No golden file found at %s", fixture)
+			}
+
+			path := tc.path
+			if path == "" {
+				path = "/api/pantry-group/home"
+			}
+
+			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
+				ServePath: sv.Path(),
+				ReqPath:   path,
+				Endpoint:  sv.Handle,
+				Method:    tc.method,
+				Body:      string(out),
+				WantCode:  tc.wantCode,
+				WantBody:  tc.wantBody,
+			})
+
+			if tc.check != nil {
+				tc.check(t, db)
+			}
+		})
+	}
+}
+
+// wantGroup checks the pantries of a group. No pantries means the group must not exist.
+func wantGroup(name string, pantries ...string) func(*testing.T, database.DB) {
+	return func(t *testing.T, db database.DB) {
+		t.Helper()
+
+		g, err := db.LookupPantryGroup("test-user-123", name)
+		if len(pantries) == 0 {
+			require.ErrorIs(t, err, os.ErrNotExist, "Pantry group should not exist")
+			return
+		}
+
+		require.NoError(t, err)
+		require.Equal(t, pantries, g.Pantries, "Pantry group does not have the expected pantries")
+	}
+}
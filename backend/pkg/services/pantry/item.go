@@ -0,0 +1,226 @@
+package pantry
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"strconv"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+)
+
+// ItemService reads and writes the stock of a single product in a pantry,
+// so that editing one item does not overwrite changes made to the others.
+type ItemService struct {
+	settings Settings
+	db       database.DB
+	auth     auth.Getter
+}
+
+func NewItem(s Settings, db database.DB, auth auth.Getter) *ItemService {
+	if !s.Enable {
+		return nil
+	}
+
+	return &ItemService{
+		settings: s,
+		db:       db,
+		auth:     auth,
+	}
+}
+
+func (s ItemService) Name() string {
+	return "pantry-item"
+}
+
+func (s ItemService) Path() string {
+	return "/api/pantry/{pantry}/{product}"
+}
+
+func (s ItemService) Enabled() bool {
+	return s.settings.Enable
+}
+
+func (s *ItemService) Handle(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return s.handleGet(log, w, r)
+	case http.MethodPut:
+		return s.handlePut(log, w, r)
+	case http.MethodDelete:
+		return s.handleDelete(log, w, r)
+	default:
+		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+type itemMsg struct {
+	ProductID product.ID          `json:"product_id"`
+	Name      string              `json:"name"`
+	Amount    float32             `json:"amount"`
+	Lots      []dbtypes.PantryLot `json:"lots"`
+}
+
+func (s *ItemService) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	prod, err := s.product(r)
+	if err != nil {
+		return err
+	}
+
+	name := r.PathValue("pantry")
+	pantry, err := s.db.LookupPantry(user, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "pantry %s not found", name)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get pantry: %v", err)
+	}
+
+	msg := newItemMsg(pantry, prod)
+	if msg.Amount == 0 {
+		return httputils.Errorf(http.StatusNotFound, "product %d is not in pantry %s", prod.ID, name)
+	}
+
+	if err := json.NewEncoder(w).Encode(msg); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not write response: %v", err)
+	}
+
+	log.Debugf("Pantry %s has %g of product %d", name, msg.Amount, prod.ID)
+	return nil
+}
+
+func (s *ItemService) handlePut(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateContentType(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	prod, err := s.product(r)
+	if err != nil {
+		return err
+	}
+
+	out, err := io.ReadAll(r.Body)
+	if err != nil {
+		return httputils.Error(http.StatusBadRequest, "failed to read request")
+	}
+	r.Body.Close()
+
+	var body struct {
+		Amount *float32 `json:"amount"`
+	}
+
+	if err := json.Unmarshal(out, &body); err != nil {
+		return httputils.Errorf(http.StatusBadRequest, "failed to unmarshal request: %v", err)
+	} else if body.Amount == nil {
+		return httputils.Error(http.StatusBadRequest, "missing amount")
+	}
+
+	op := dbtypes.PantryOp{ProductID: prod.ID, Op: dbtypes.PantryOpSet, Amount: *body.Amount}
+	if err := op.Validate(); err != nil {
+		return httputils.Errorf(http.StatusBadRequest, "invalid amount: %v", err)
+	}
+
+	name := r.PathValue("pantry")
+	pantry, err := s.db.UpdatePantry(user, name, []dbtypes.PantryOp{op})
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "pantry %s not found", name)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not update pantry: %v", err)
+	}
+
+	if err := json.NewEncoder(w).Encode(newItemMsg(pantry, prod)); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not write response: %v", err)
+	}
+
+	log.Debugf("Set product %d of pantry %s to %g", prod.ID, name, op.Amount)
+	return nil
+}
+
+func (s *ItemService) handleDelete(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	prod, err := s.product(r)
+	if err != nil {
+		return err
+	}
+
+	name := r.PathValue("pantry")
+	_, err = s.db.UpdatePantry(user, name, []dbtypes.PantryOp{{ProductID: prod.ID, Op: dbtypes.PantryOpSet, Amount: 0}})
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "pantry %s not found", name)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not update pantry: %v", err)
+	}
+
+	log.Debugf("Removed product %d from pantry %s", prod.ID, name)
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// product returns the product in the path of the request.
+func (s *ItemService) product(r *http.Request) (product.Product, error) {
+	raw := r.PathValue("product")
+	id, err := strconv.ParseUint(raw, 10, product.IDSize)
+	if err != nil {
+		return product.Product{}, httputils.Errorf(http.StatusBadRequest, "invalid product ID %q", raw)
+	}
+
+	prod, err := s.db.LookupProduct(product.ID(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return product.Product{}, httputils.Errorf(http.StatusNotFound, "product %d not found", id)
+	} else if err != nil {
+		return product.Product{}, httputils.Errorf(http.StatusInternalServerError, "could not lookup product %d: %v", id, err)
+	}
+
+	return prod, nil
+}
+
+func newItemMsg(pantry dbtypes.Pantry, prod product.Product) itemMsg {
+	msg := itemMsg{
+		ProductID: prod.ID,
+		Name:      prod.Name,
+		Lots:      make([]dbtypes.PantryLot, 0),
+	}
+
+	for _, c := range pantry.Contents {
+		if c.ProductID == prod.ID {
+			msg.Amount += c.Amount
+		}
+	}
+
+	for _, l := range pantry.Lots {
+		if l.ProductID == prod.ID {
+			msg.Lots = append(msg.Lots, l)
+		}
+	}
+
+	return msg
+}
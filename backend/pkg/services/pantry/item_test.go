@@ -0,0 +1,109 @@
+package pantry_test
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/pantry"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestItemEndpoint(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		method string
+		path   string
+
+		wantCode int
+		wantBody string
+		check    func(*testing.T, database.DB)
+	}{
+		"GET":                 {method: "GET", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET not in pantry":   {method: "GET", path: "/api/pantry/pantry1/3", wantCode: http.StatusNotFound},
+		"GET invalid product": {method: "GET", path: "/api/pantry/pantry1/apple", wantCode: http.StatusBadRequest},
+		"GET unknown product": {method: "GET", path: "/api/pantry/pantry1/99", wantCode: http.StatusNotFound},
+
+		"PUT": {method: "PUT", wantCode: http.StatusOK, wantBody: "!golden", check: wantItem(1, 2.5, []dbtypes.PantryLot{
+			{ProductID: 1, Amount: 0.5, Purchased: &dbtypes.Date{Year: 2024, Month: 3, Day: 20}, BestBefore: &dbtypes.Date{Year: 2024, Month: 4, Day: 8}},
+			{ProductID: 1, Amount: 1, Purchased: &dbtypes.Date{Year: 2024, Month: 3, Day: 28}},
+		})},
+		"PUT missing amount":   {method: "PUT", wantCode: http.StatusBadRequest},
+		"PUT negative amount":  {method: "PUT", wantCode: http.StatusBadRequest},
+		"PUT pantry not found": {method: "PUT", wantCode: http.StatusNotFound},
+
+		"DELETE": {method: "DELETE", wantCode: http.StatusNoContent, check: wantItem(1, 0, nil)},
+
+		"POST": {method: "POST", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db := testutils.Database(t, testutils.FixturePath(t, "database"))
+
+			sv := pantry.NewItem(pantry.Settings{}.Defaults(), db, testutils.MockAuthGetter())
+			require.True(t, sv.Enabled())
+
+			fixture := testutils.FixturePath(t, "message", "body.json")
+			out, err := os.ReadFile(fixture)
+			if err != nil {
+				require.ErrorIs(t, err, os.ErrNotExist)
+				out = nil
+				t.Logf("No golden file found at %s", fixture)
+			}
+
+			path := tc.path
+			if path == "" {
+				path = "/api/pantry/pantry1/1"
+			}
+
+			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
+				ServePath: sv.Path(),
+				ReqPath:   path,
+				Endpoint:  sv.Handle,
+				Method:    tc.method,
+				Body:      string(out),
+				WantCode:  tc.wantCode,
+				WantBody:  tc.wantBody,
+			})
+
+			if tc.check != nil {
+				tc.check(t, db)
+			}
+		})
+	}
+}
+
+func wantItem(id int, amount float32, lots []dbtypes.PantryLot) func(*testing.T, database.DB) {
+	return func(t *testing.T, db database.DB) {
+		t.Helper()
+
+		p, err := db.LookupPantry("test-user-123", "pantry1")
+		require.NoError(t, err)
+
+		var got float32
+		for _, c := range p.Contents {
+			if int(c.ProductID) == id {
+				got += c.Amount
+			}
+		}
+		require.InDelta(t, amount, got, 1e-6, "Amount of product %d does not match", id)
+
+		var gotLots []dbtypes.PantryLot
+		for _, l := range p.Lots {
+			if int(l.ProductID) == id {
+				gotLots = append(gotLots, l)
+			}
+		}
+		require.ElementsMatch(t, lots, gotLots, "Lots of product %d do not match", id)
+
+		require.Contains(t, p.Contents, recipe.Ingredient{ProductID: 2, Amount: 3}, "Other products should not change")
+	}
+}
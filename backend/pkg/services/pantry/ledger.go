@@ -0,0 +1,216 @@
+package pantry
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"time"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+)
+
+// LedgerService lists every change made to a pantry, and rolls it back to an earlier entry
+// of its ledger or to how it was at a point in time.
+type LedgerService struct {
+	settings Settings
+	db       database.DB
+	auth     auth.Getter
+}
+
+func NewLedger(s Settings, db database.DB, auth auth.Getter) *LedgerService {
+	if !s.Enable {
+		return nil
+	}
+
+	return &LedgerService{
+		settings: s,
+		db:       db,
+		auth:     auth,
+	}
+}
+
+func (s LedgerService) Name() string {
+	return "pantry-ledger"
+}
+
+func (s LedgerService) Path() string {
+	return "/api/pantry/{pantry}/ledger"
+}
+
+func (s LedgerService) Enabled() bool {
+	return s.settings.Enable
+}
+
+func (s *LedgerService) Handle(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return s.handleGet(log, w, r)
+	case http.MethodPost:
+		return s.handlePost(log, w, r)
+	default:
+		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+type deltaMsg struct {
+	ProductID product.ID `json:"product_id"`
+	Name      string     `json:"name"`
+	Amount    float32    `json:"amount"`
+}
+
+type changeMsg struct {
+	Entry     int                  `json:"entry"`
+	Author    string               `json:"author"`
+	Timestamp time.Time            `json:"timestamp"`
+	Reason    dbtypes.PantryReason `json:"reason"`
+	Deltas    []deltaMsg           `json:"deltas"`
+}
+
+func (s *LedgerService) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	name := r.PathValue("pantry")
+	ledger, err := s.db.PantryLedger(user, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "pantry %s not found", name)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get pantry ledger: %v", err)
+	}
+
+	names := make(map[product.ID]string)
+	entries := make([]changeMsg, 0, len(ledger))
+	for _, c := range ledger {
+		msg := changeMsg{
+			Entry:     c.Entry,
+			Author:    c.Author,
+			Timestamp: c.Timestamp,
+			Reason:    c.Reason,
+			Deltas:    make([]deltaMsg, 0, len(c.Deltas)),
+		}
+
+		for _, d := range c.Deltas {
+			n, ok := names[d.ProductID]
+			if !ok {
+				if p, err := s.db.LookupProduct(d.ProductID); err == nil {
+					n = p.Name
+				} else {
+					log.Warningf("Product %d not found: %v", d.ProductID, err)
+				}
+				names[d.ProductID] = n
+			}
+
+			msg.Deltas = append(msg.Deltas, deltaMsg{ProductID: d.ProductID, Name: n, Amount: d.Amount})
+		}
+
+		entries = append(entries, msg)
+	}
+
+	if err := json.NewEncoder(w).Encode(map[string]any{
+		"pantry":  name,
+		"entries": entries,
+	}); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not write response: %v", err)
+	}
+
+	log.Debugf("Pantry %s has %d ledger entries", name, len(entries))
+	return nil
+}
+
+type rollbackMsg struct {
+	// Entry is the ledger entry to roll back to.
+	Entry int `json:"entry"`
+
+	// Time rolls back to the last entry made at or before it. It is ignored if Entry is set.
+	Time *time.Time `json:"time"`
+}
+
+func (s *LedgerService) handlePost(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateContentType(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	out, err := io.ReadAll(r.Body)
+	if err != nil {
+		return httputils.Error(http.StatusBadRequest, "failed to read request")
+	}
+	r.Body.Close()
+
+	var body rollbackMsg
+	if err := json.Unmarshal(out, &body); err != nil {
+		return httputils.Errorf(http.StatusBadRequest, "failed to unmarshal request: %v", err)
+	}
+
+	if body.Entry < 0 || (body.Entry == 0 && body.Time == nil) {
+		return httputils.Error(http.StatusBadRequest, "an entry or a time to roll back to is required")
+	}
+
+	name := r.PathValue("pantry")
+	ledger, err := s.db.PantryLedger(user, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "pantry %s not found", name)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get pantry ledger: %v", err)
+	}
+
+	entry := body.Entry
+	if entry == 0 {
+		for _, c := range ledger {
+			if c.Timestamp.After(*body.Time) {
+				break
+			}
+			entry = c.Entry
+		}
+
+		if entry == 0 {
+			return httputils.Errorf(http.StatusBadRequest, "pantry %s has no changes before %s", name, body.Time.Format(time.RFC3339))
+		}
+	}
+
+	err = s.db.RollbackPantry(user, name, entry)
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "pantry %s has no ledger entry %d", name, entry)
+	} else if errors.Is(err, fs.ErrExist) {
+		return httputils.Errorf(http.StatusConflict, "meals were cooked from pantry %s after entry %d: uncook them first", name, entry)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not roll back pantry: %v", err)
+	}
+
+	p, err := s.db.LookupPantry(user, name)
+	if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get pantry: %v", err)
+	}
+
+	if err := json.NewEncoder(w).Encode(map[string]any{
+		"pantry":      p.Name,
+		"rolled_back": entry,
+		"contents":    p.Contents,
+	}); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not write response: %v", err)
+	}
+
+	log.Debugf("Rolled back pantry %s to ledger entry %d", name, entry)
+	return nil
+}
@@ -0,0 +1,97 @@
+package pantry_test
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/pantry"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLedgerEndpoint(t *testing.T) {
+	t.Parallel()
+
+	current := []recipe.Ingredient{{ProductID: 1, Amount: 4}, {ProductID: 2, Amount: 3}}
+
+	testCases := map[string]struct {
+		method string
+		path   string
+
+		wantCode int
+		wantBody string
+		check    func(*testing.T, database.DB)
+	}{
+		"GET":           {method: "GET", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET not found": {method: "GET", path: "/api/pantry/nonexistent/ledger", wantCode: http.StatusNotFound},
+
+		"POST entry":              {method: "POST", wantCode: http.StatusOK, wantBody: "!golden", check: wantRollback([]recipe.Ingredient{{ProductID: 1, Amount: 5}}, 4)},
+		"POST time":               {method: "POST", wantCode: http.StatusOK, wantBody: "!golden", check: wantRollback([]recipe.Ingredient{{ProductID: 1, Amount: 5}, {ProductID: 2, Amount: 3}}, 4)},
+		"POST time too early":     {method: "POST", wantCode: http.StatusBadRequest, check: wantRollback(current, 3)},
+		"POST missing entry":      {method: "POST", wantCode: http.StatusBadRequest, check: wantRollback(current, 3)},
+		"POST unknown entry":      {method: "POST", wantCode: http.StatusNotFound, check: wantRollback(current, 3)},
+		"POST past a cooked meal": {method: "POST", wantCode: http.StatusConflict, check: wantRollback(current, 3)},
+
+		"DELETE": {method: "DELETE", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db := testutils.Database(t, testutils.FixturePath(t, "database"))
+
+			sv := pantry.NewLedger(pantry.Settings{}.Defaults(), db, testutils.MockAuthGetter())
+			require.True(t, sv.Enabled())
+
+			fixture := testutils.FixturePath(t, "message", "body.json")
+			out, err := os.ReadFile(fixture)
+			if err != nil {
+				require.ErrorIs(t, err, os.ErrNotExist)
+				out = nil
+				t.Logf("No golden file found at %s", fixture)
+			}
+
+			path := tc.path
+			if path == "" {
+				path = "/api/pantry/pantry1/ledger"
+			}
+
+			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
+				ServePath: sv.Path(),
+				ReqPath:   path,
+				Endpoint:  sv.Handle,
+				Method:    tc.method,
+				Body:      string(out),
+				WantCode:  tc.wantCode,
+				WantBody:  tc.wantBody,
+			})
+
+			if tc.check != nil {
+				tc.check(t, db)
+			}
+		})
+	}
+}
+
+func wantRollback(contents []recipe.Ingredient, entries int) func(*testing.T, database.DB) {
+	return func(t *testing.T, db database.DB) {
+		t.Helper()
+
+		p, err := db.LookupPantry("test-user-123", "pantry1")
+		require.NoError(t, err)
+		require.Equal(t, contents, p.Contents, "Pantry contents do not match")
+
+		ledger, err := db.PantryLedger("test-user-123", "pantry1")
+		require.NoError(t, err)
+		require.Len(t, ledger, entries, "Ledger does not have the expected number of entries")
+
+		if entries > 3 {
+			require.Equal(t, dbtypes.PantryRollback, ledger[entries-1].Reason)
+		}
+	}
+}
@@ -23,11 +23,20 @@ type Service struct {
 
 type Settings struct {
 	Enable bool
+
+	// ExpiringDays is how many days ahead to look for expiring lots, unless the request says otherwise.
+	ExpiringDays int
+
+	// CookableCoverage is the minimum percentage of a recipe's ingredients that a pantry must cover
+	// for the recipe to be listed as cookable, unless the request says otherwise.
+	CookableCoverage float32
 }
 
 func (Settings) Defaults() Settings {
 	return Settings{
-		Enable: true,
+		Enable:           true,
+		ExpiringDays:     3,
+		CookableCoverage: 50,
 	}
 }
 
@@ -57,6 +66,8 @@ func (s Service) Handle(log logger.Logger, w http.ResponseWriter, r *http.Reques
 		return s.handleGet(log, w, r)
 	case http.MethodPut:
 		return s.handlePut(log, w, r)
+	case http.MethodPatch:
+		return s.handlePatch(log, w, r)
 	case http.MethodDelete:
 		return s.handleDelete(log, w, r)
 	default:
@@ -86,6 +97,11 @@ func (s *Service) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Re
 		return httputils.Errorf(http.StatusInternalServerError, "could not lookup pantry: %w", err)
 	}
 
+	return s.writePantry(log, w, pantry)
+}
+
+// writePantry writes the pantry to the response, with the names of its products.
+func (s *Service) writePantry(log logger.Logger, w http.ResponseWriter, pantry dbtypes.Pantry) error {
 	type Item struct {
 		recipe.Ingredient
 		Name string `json:"name"`
@@ -105,9 +121,29 @@ func (s *Service) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Re
 		})
 	}
 
+	type Lot struct {
+		dbtypes.PantryLot
+		Name string `json:"name"`
+	}
+
+	lots := make([]Lot, 0, len(pantry.Lots))
+	for _, l := range pantry.Lots {
+		p, err := s.db.LookupProduct(l.ProductID)
+		if err != nil {
+			log.Warnf("Product %d not found: %v", l.ProductID, err)
+			continue
+		}
+
+		lots = append(lots, Lot{
+			PantryLot: l,
+			Name:      p.Name,
+		})
+	}
+
 	if err := json.NewEncoder(w).Encode(map[string]any{
 		"name":     pantry.Name,
 		"contents": items,
+		"lots":     lots,
 	}); err != nil {
 		return httputils.Errorf(http.StatusInternalServerError, "could not write menus to output: %w", err)
 	}
@@ -148,6 +184,10 @@ func (s *Service) handlePut(log logger.Logger, w http.ResponseWriter, r *http.Re
 
 	log.Debugf("Received pantry with %d items", len(pantry.Contents))
 
+	if err := pantry.Validate(); err != nil {
+		return httputils.Errorf(http.StatusBadRequest, "invalid pantry: %v", err)
+	}
+
 	if err := s.db.SetPantry(pantry); err != nil {
 		return httputils.Errorf(http.StatusInternalServerError, "could not set pantry: %w", err)
 	}
@@ -156,6 +196,68 @@ func (s *Service) handlePut(log logger.Logger, w http.ResponseWriter, r *http.Re
 	return nil
 }
 
+type patchMsg struct {
+	Ops []dbtypes.PantryOp `json:"ops"`
+}
+
+func (s *Service) handlePatch(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateContentType(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	p := r.PathValue("pantry")
+	if p == "" {
+		return httputils.Error(http.StatusBadRequest, "missing pantry")
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %w", err)
+	}
+
+	out, err := io.ReadAll(r.Body)
+	if err != nil {
+		return httputils.Error(http.StatusBadRequest, "failed to read request")
+	}
+	r.Body.Close()
+
+	var body patchMsg
+	if err := json.Unmarshal(out, &body); err != nil {
+		return httputils.Errorf(http.StatusBadRequest, "could not unmarshal operations: %w", err)
+	}
+
+	if len(body.Ops) == 0 {
+		return httputils.Error(http.StatusBadRequest, "no operations to apply")
+	}
+
+	for _, o := range body.Ops {
+		if err := o.Validate(); err != nil {
+			return httputils.Errorf(http.StatusBadRequest, "invalid operation: %v", err)
+		}
+
+		if _, err := s.db.LookupProduct(o.ProductID); errors.Is(err, fs.ErrNotExist) {
+			return httputils.Errorf(http.StatusBadRequest, "product %d does not exist", o.ProductID)
+		} else if err != nil {
+			return httputils.Errorf(http.StatusInternalServerError, "could not lookup product %d: %w", o.ProductID, err)
+		}
+	}
+
+	pantry, err := s.db.UpdatePantry(user, p, body.Ops)
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Error(http.StatusNotFound, "pantry not found")
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not update pantry: %w", err)
+	}
+
+	log.Debugf("Applied %d operations to pantry %s", len(body.Ops), p)
+
+	return s.writePantry(log, w, pantry)
+}
+
 func (s *Service) handleDelete(_ logger.Logger, w http.ResponseWriter, r *http.Request) error {
 	p := r.PathValue("pantry")
 	if p == "" {
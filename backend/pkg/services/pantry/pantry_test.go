@@ -26,12 +26,17 @@ func TestPantryEndpoint(t *testing.T) {
 		wantCode int
 		wantBody string
 	}{
-		"GET":    {method: "GET", wantCode: http.StatusOK, wantBody: "!golden"},
-		"PUT":    {method: "PUT", wantCode: http.StatusCreated},
-		"DELETE": {method: "DELETE", wantCode: http.StatusNoContent},
+		"GET":                     {method: "GET", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET with lots":           {method: "GET", wantCode: http.StatusOK, wantBody: "!golden"},
+		"PUT":                     {method: "PUT", wantCode: http.StatusCreated},
+		"PUT invalid lots":        {method: "PUT", wantCode: http.StatusBadRequest},
+		"PATCH":                   {method: "PATCH", wantCode: http.StatusOK, wantBody: "!golden"},
+		"PATCH invalid operation": {method: "PATCH", wantCode: http.StatusBadRequest},
+		"PATCH unknown product":   {method: "PATCH", wantCode: http.StatusBadRequest},
+		"PATCH pantry not found":  {method: "PATCH", wantCode: http.StatusNotFound},
+		"DELETE":                  {method: "DELETE", wantCode: http.StatusNoContent},
 
-		"PATCH": {method: "PATCH", wantCode: http.StatusMethodNotAllowed},
-		"POST":  {method: "POST", wantCode: http.StatusMethodNotAllowed},
+		"POST": {method: "POST", wantCode: http.StatusMethodNotAllowed},
 	}
 
 	for name, tc := range testCases {
@@ -0,0 +1,183 @@
+package pantry
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+)
+
+// StaplesService manages the minimum-stock rules of a pantry: products that must always be
+// in stock, such as oil or salt, and are restocked by the shopping list when they run low.
+type StaplesService struct {
+	settings Settings
+	db       database.DB
+	auth     auth.Getter
+}
+
+func NewStaples(s Settings, db database.DB, auth auth.Getter) *StaplesService {
+	if !s.Enable {
+		return nil
+	}
+
+	return &StaplesService{
+		settings: s,
+		db:       db,
+		auth:     auth,
+	}
+}
+
+func (s StaplesService) Name() string {
+	return "pantry-staples"
+}
+
+func (s StaplesService) Path() string {
+	return "/api/pantry/{pantry}/staples"
+}
+
+func (s StaplesService) Enabled() bool {
+	return s.settings.Enable
+}
+
+func (s *StaplesService) Handle(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return s.handleGet(log, w, r)
+	case http.MethodPut:
+		return s.handlePut(log, w, r)
+	default:
+		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+type stapleMsg struct {
+	ProductID product.ID `json:"product_id"`
+	Name      string     `json:"name"`
+	Minimum   float32    `json:"minimum"`
+	Have      float32    `json:"have"`
+	Low       bool       `json:"low"`
+}
+
+func (s *StaplesService) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	name := r.PathValue("pantry")
+	pantry, err := s.db.LookupPantry(user, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "pantry %s not found", name)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get pantry: %v", err)
+	}
+
+	staples, err := s.db.PantryStaples(user, name)
+	if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get pantry staples: %v", err)
+	}
+
+	have := make(map[product.ID]float32, len(pantry.Contents))
+	for _, c := range pantry.Contents {
+		have[c.ProductID] += c.Amount
+	}
+
+	out := make([]stapleMsg, 0, len(staples.Staples))
+	for _, st := range staples.Staples {
+		msg := stapleMsg{
+			ProductID: st.ProductID,
+			Minimum:   st.Minimum,
+			Have:      have[st.ProductID],
+			Low:       have[st.ProductID] < st.Minimum,
+		}
+
+		if p, err := s.db.LookupProduct(st.ProductID); err == nil {
+			msg.Name = p.Name
+		} else {
+			log.Warningf("Product %d not found: %v", st.ProductID, err)
+		}
+
+		out = append(out, msg)
+	}
+
+	if err := json.NewEncoder(w).Encode(map[string]any{
+		"pantry":  name,
+		"staples": out,
+	}); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not write response: %v", err)
+	}
+
+	log.Debugf("Pantry %s has %d staples", name, len(out))
+	return nil
+}
+
+func (s *StaplesService) handlePut(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateContentType(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	out, err := io.ReadAll(r.Body)
+	if err != nil {
+		return httputils.Error(http.StatusBadRequest, "failed to read request")
+	}
+	r.Body.Close()
+
+	var body struct {
+		Staples []dbtypes.Staple `json:"staples"`
+	}
+
+	if err := json.Unmarshal(out, &body); err != nil {
+		return httputils.Errorf(http.StatusBadRequest, "failed to unmarshal request: %v", err)
+	}
+
+	staples := dbtypes.PantryStaples{
+		User:    user,
+		Pantry:  r.PathValue("pantry"),
+		Staples: body.Staples,
+	}
+
+	if staples.Staples == nil {
+		staples.Staples = make([]dbtypes.Staple, 0)
+	}
+
+	if err := staples.Validate(); err != nil {
+		return httputils.Errorf(http.StatusBadRequest, "invalid staples: %v", err)
+	}
+
+	for _, st := range staples.Staples {
+		if _, err := s.db.LookupProduct(st.ProductID); errors.Is(err, fs.ErrNotExist) {
+			return httputils.Errorf(http.StatusBadRequest, "product %d does not exist", st.ProductID)
+		} else if err != nil {
+			return httputils.Errorf(http.StatusInternalServerError, "could not lookup product %d: %v", st.ProductID, err)
+		}
+	}
+
+	err = s.db.SetPantryStaples(staples)
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "pantry %s not found", staples.Pantry)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not set pantry staples: %v", err)
+	}
+
+	log.Debugf("Set %d staples of pantry %s", len(staples.Staples), staples.Pantry)
+
+	w.WriteHeader(http.StatusCreated)
+	return nil
+}
@@ -0,0 +1,83 @@
+package pantry_test
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/pantry"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaplesEndpoint(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		method string
+
+		wantCode int
+		wantBody string
+		check    func(*testing.T, database.DB)
+	}{
+		"GET":           {method: "GET", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET not found": {method: "GET", wantCode: http.StatusNotFound},
+
+		"PUT":                  {method: "PUT", wantCode: http.StatusCreated, check: wantStaples(dbtypes.Staple{ProductID: 2, Minimum: 1.5})},
+		"PUT invalid":          {method: "PUT", wantCode: http.StatusBadRequest, check: wantStaples()},
+		"PUT unknown product":  {method: "PUT", wantCode: http.StatusBadRequest, check: wantStaples()},
+		"PUT pantry not found": {method: "PUT", wantCode: http.StatusNotFound},
+
+		"DELETE": {method: "DELETE", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db := testutils.Database(t, testutils.FixturePath(t, "database"))
+
+			sv := pantry.NewStaples(pantry.Settings{}.Defaults(), db, testutils.MockAuthGetter())
+			require.True(t, sv.Enabled())
+
+			fixture := testutils.FixturePath(t, "message", "body.json")
+			out, err := os.ReadFile(fixture)
+			if err != nil {
+				require.ErrorIs(t, err, os.ErrNotExist)
+				out = nil
+				t.Logf("No golden file found at %s", fixture)
+			}
+
+			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
+				ServePath: sv.Path(),
+				ReqPath:   "/api/pantry/pantry1/staples",
+				Endpoint:  sv.Handle,
+				Method:    tc.method,
+				Body:      string(out),
+				WantCode:  tc.wantCode,
+				WantBody:  tc.wantBody,
+			})
+
+			if tc.check != nil {
+				tc.check(t, db)
+			}
+		})
+	}
+}
+
+func wantStaples(staples ...dbtypes.Staple) func(*testing.T, database.DB) {
+	return func(t *testing.T, db database.DB) {
+		t.Helper()
+
+		got, err := db.PantryStaples("test-user-123", "pantry1")
+		require.NoError(t, err)
+
+		if len(staples) == 0 {
+			require.Empty(t, got.Staples, "Staples should not have been set")
+			return
+		}
+		require.Equal(t, staples, got.Staples, "Staples do not match")
+	}
+}
@@ -0,0 +1,187 @@
+package planner
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/planner"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+)
+
+// Service proposes a draft menu. The draft is not saved: the client can store it with the menu endpoint.
+type Service struct {
+	settings Settings
+	db       database.DB
+	auth     auth.Getter
+}
+
+type Settings struct {
+	Enable bool
+}
+
+func (Settings) Defaults() Settings {
+	return Settings{
+		Enable: true,
+	}
+}
+
+func New(s Settings, db database.DB, auth auth.Getter) *Service {
+	if !s.Enable {
+		return nil
+	}
+
+	return &Service{
+		settings: s,
+		db:       db,
+		auth:     auth,
+	}
+}
+
+func (s Service) Name() string {
+	return "planner"
+}
+
+func (s Service) Path() string {
+	return "/api/planner"
+}
+
+func (s Service) Enabled() bool {
+	return s.settings.Enable
+}
+
+func (s *Service) Handle(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodPost:
+		return s.handlePost(log, w, r)
+	default:
+		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+type requestMsg struct {
+	Name       string            `json:"name"`
+	StartDate  *dbtypes.Date     `json:"start_date,omitempty"`
+	Days       []string          `json:"days"`
+	Meals      []string          `json:"meals"`
+	Servings   float32           `json:"servings"`
+	Budget     float32           `json:"budget"`
+	MaxRepeats int               `json:"max_repeats"`
+	Pantry     string            `json:"pantry"`
+	Recipes    []recipe.ID       `json:"recipes"`
+	Objective  planner.Objective `json:"objective"`
+	Seed       int64             `json:"seed"`
+}
+
+type ingredientMsg struct {
+	ProductID product.ID `json:"product_id"`
+	Name      string     `json:"name"`
+	Amount    float32    `json:"amount"`
+}
+
+type responseMsg struct {
+	Menu        dbtypes.Menu    `json:"menu"`
+	Cost        float32         `json:"cost"`
+	Budget      float32         `json:"budget,omitempty"`
+	PantryUsed  []ingredientMsg `json:"pantry_used"`
+	Explanation []string        `json:"explanation"`
+}
+
+func (s *Service) handlePost(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateContentType(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	req := requestMsg{
+		Name:      "draft",
+		Servings:  1,
+		Objective: planner.MinimiseCost,
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return httputils.Errorf(http.StatusBadRequest, "failed to read request: %v", err)
+	}
+
+	if len(req.Days) == 0 || len(req.Meals) == 0 {
+		return httputils.Error(http.StatusBadRequest, "days and meals cannot be empty")
+	}
+
+	if req.Servings <= 0 || req.Budget < 0 || req.MaxRepeats < 0 {
+		return httputils.Error(http.StatusBadRequest, "servings must be positive, and budget and max_repeats cannot be negative")
+	}
+
+	switch req.Objective {
+	case planner.MinimiseCost, planner.MinimiseWaste:
+	default:
+		return httputils.Errorf(http.StatusBadRequest, "unknown objective %q", req.Objective)
+	}
+
+	var pantry []recipe.Ingredient
+	if req.Pantry != "" {
+		p, err := s.db.LookupPantry(user, req.Pantry)
+		if errors.Is(err, fs.ErrNotExist) {
+			return httputils.Errorf(http.StatusNotFound, "pantry %s not found", req.Pantry)
+		} else if err != nil {
+			return httputils.Errorf(http.StatusInternalServerError, "could not get pantry: %v", err)
+		}
+		pantry = p.Contents
+	}
+
+	plan, err := planner.New(log, s.db, user, planner.Request{
+		Name:       req.Name,
+		StartDate:  req.StartDate,
+		Days:       req.Days,
+		Meals:      req.Meals,
+		Servings:   req.Servings,
+		Budget:     req.Budget,
+		MaxRepeats: req.MaxRepeats,
+		Pantry:     pantry,
+		Recipes:    req.Recipes,
+		Objective:  req.Objective,
+		Seed:       req.Seed,
+	})
+	if errors.Is(err, planner.ErrNoCandidates) {
+		return httputils.Errorf(http.StatusUnprocessableEntity, "could not plan menu: %v", err)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not plan menu: %v", err)
+	}
+
+	resp := responseMsg{
+		Menu:        plan.Menu,
+		Cost:        plan.Cost,
+		Budget:      req.Budget,
+		PantryUsed:  make([]ingredientMsg, 0, len(plan.PantryUsed)),
+		Explanation: plan.Explanation,
+	}
+
+	for _, i := range plan.PantryUsed {
+		msg := ingredientMsg{ProductID: i.ProductID, Amount: i.Amount}
+		if p, err := s.db.LookupProduct(i.ProductID); err == nil {
+			msg.Name = p.Name
+		}
+		resp.PantryUsed = append(resp.PantryUsed, msg)
+	}
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not write response: %v", err)
+	}
+
+	log.Debugf("Planned menu with %d days at a cost of %.2f", len(plan.Menu.Days), plan.Cost)
+	return nil
+}
@@ -0,0 +1,103 @@
+package planner_test
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	pkgplanner "github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/planner"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers/blank"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/planner"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	providers.Register(blank.Provider{})
+	m.Run()
+}
+
+func TestPlannerEndpoint(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		method string
+
+		wantCode int
+		wantBody string
+	}{
+		"POST":                  {method: "POST", wantCode: http.StatusOK, wantBody: "!golden"},
+		"POST with budget":      {method: "POST", wantCode: http.StatusOK, wantBody: "!golden"},
+		"POST with pantry":      {method: "POST", wantCode: http.StatusOK, wantBody: "!golden"},
+		"POST with recipes":     {method: "POST", wantCode: http.StatusOK, wantBody: "!golden"},
+		"POST pantry not found": {method: "POST", wantCode: http.StatusNotFound},
+		"POST no slots":         {method: "POST", wantCode: http.StatusBadRequest},
+		"POST bad objective":    {method: "POST", wantCode: http.StatusBadRequest},
+		"POST with empty DB":    {method: "POST", wantCode: http.StatusUnprocessableEntity},
+
+		"GET": {method: "GET", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db := testutils.Database(t, testutils.FixturePath(t, "database"))
+
+			sv := planner.New(planner.Settings{}.Defaults(), db, testutils.MockAuthGetter())
+			require.True(t, sv.Enabled())
+
+			fixture := testutils.FixturePath(t, "message", "body.json")
+			out, err := os.ReadFile(fixture)
+			if err != nil {
+				require.ErrorIs(t, err, os.ErrNotExist)
+				out = nil
+				t.Logf("No golden file found at %s", fixture)
+			}
+
+			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
+				ServePath: sv.Path(),
+				ReqPath:   "/api/planner",
+				Endpoint:  sv.Handle,
+				Method:    tc.method,
+				Body:      string(out),
+				WantCode:  tc.wantCode,
+				WantBody:  tc.wantBody,
+			})
+		})
+	}
+}
+
+func TestPlannerSeed(t *testing.T) {
+	t.Parallel()
+
+	db := testutils.Database(t, testutils.FixturePath(t, "database"))
+	log := testutils.NewLogger(t)
+
+	req := pkgplanner.Request{
+		Name:       "draft",
+		Days:       []string{"Monday", "Tuesday", "Wednesday"},
+		Meals:      []string{"Lunch", "Dinner"},
+		Servings:   1,
+		MaxRepeats: 1,
+		Objective:  pkgplanner.MinimiseCost,
+	}
+
+	// Boiled and steamed rice cost the same, so the seed decides which one goes first
+	first := make(map[string]bool)
+	for seed := range int64(10) {
+		req.Seed = seed
+
+		plan, err := pkgplanner.New(log, db, "test-user-123", req)
+		require.NoError(t, err)
+
+		again, err := pkgplanner.New(log, db, "test-user-123", req)
+		require.NoError(t, err)
+		require.Equal(t, plan, again, "Plans with the same seed should be equal")
+
+		first[plan.Explanation[0]] = true
+	}
+
+	require.Len(t, first, 2, "Different seeds should break ties differently")
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipecard"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/utils"
 )
 
@@ -68,7 +69,8 @@ func (s Service) Handle(log logger.Logger, w http.ResponseWriter, r *http.Reques
 }
 
 func (s Service) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
-	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+	mediaType, err := httputils.Negotiate(r, httputils.MediaTypeJSON, httputils.MediaTypeHTML, httputils.MediaTypeMarkdown)
+	if err != nil {
 		return err
 	}
 
@@ -89,28 +91,23 @@ func (s Service) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Req
 		return httputils.Errorf(http.StatusInternalServerError, "failed to lookup recipe: %v", err)
 	}
 
-	body := recipeMsg{
-		ID:          rec.ID,
-		Name:        rec.Name,
-		Ingredients: make([]ingredient, 0, len(rec.Ingredients)),
+	card, err := recipecard.New(log, s.db, rec)
+	if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "%v", err)
 	}
 
-	for _, ing := range rec.Ingredients {
-		prod, err := s.db.LookupProduct(ing.ProductID)
-		if err != nil {
-			log.Warningf("Product %d not found: %v", ing.ProductID, err)
-			continue
-		}
+	w.Header().Set("Content-Type", mediaType.String()+"; charset=utf-8")
 
-		body.Ingredients = append(body.Ingredients, ingredient{
-			ID:        prod.ID,
-			Name:      prod.Name,
-			Amount:    ing.Amount,
-			UnitPrice: prod.Price / prod.BatchSize,
-		})
+	switch mediaType {
+	case httputils.MediaTypeHTML:
+		err = card.WriteHTML(w)
+	case httputils.MediaTypeMarkdown:
+		err = card.WriteMarkdown(w)
+	default:
+		err = json.NewEncoder(w).Encode(newRecipeMsg(card))
 	}
 
-	if err := json.NewEncoder(w).Encode(body); err != nil {
+	if err != nil {
 		return httputils.Errorf(http.StatusInternalServerError, "failed to write response: %v", err)
 	}
 
@@ -163,6 +160,28 @@ func (s Service) handlePost(_ logger.Logger, w http.ResponseWriter, r *http.Requ
 		})
 	}
 
+	if len(body.SubRecipes) > 100 {
+		return httputils.Error(http.StatusBadRequest, "recipe cannot have more than 100 sub-recipes")
+	}
+
+	for _, sub := range body.SubRecipes {
+		dbRecipe.SubRecipes = append(dbRecipe.SubRecipes, recipe.SubRecipe{
+			RecipeID: sub.ID,
+			Amount:   sub.Amount,
+		})
+	}
+
+	if err := s.validateSubRecipes(dbRecipe); err != nil {
+		return err
+	}
+
+	// Forks keep the link to their original when edited
+	if old, err := s.db.LookupRecipe(user, dbRecipe.ID); err == nil {
+		dbRecipe.ForkedFrom = old.ForkedFrom
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to lookup recipe: %v", err)
+	}
+
 	newID, err := s.db.SetRecipe(dbRecipe)
 	if err != nil {
 		return httputils.Errorf(http.StatusInternalServerError, "failed to save recipe: %v", err)
@@ -201,6 +220,30 @@ func (s Service) handleDelete(_ logger.Logger, w http.ResponseWriter, r *http.Re
 	return nil
 }
 
+// validateSubRecipes ensures that all sub-recipes exist and that the recipe does not contain itself.
+func (s Service) validateSubRecipes(rec recipe.Recipe) error {
+	cached := database.NewCachedUserLookup(rec.User, s.db.LookupRecipe)
+
+	// The recipe being saved replaces the stored one
+	lookup := func(id recipe.ID) (recipe.Recipe, error) {
+		if id == rec.ID {
+			return rec, nil
+		}
+		return cached.Lookup(id)
+	}
+
+	_, err := recipe.Flatten(rec, lookup)
+	if cycle := (recipe.CycleError{}); errors.As(err, &cycle) {
+		return httputils.Errorf(http.StatusBadRequest, "invalid sub-recipes: %v", cycle)
+	} else if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusBadRequest, "invalid sub-recipes: %v", err)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to validate sub-recipes: %v", err)
+	}
+
+	return nil
+}
+
 type ingredient struct {
 	ID        product.ID `json:"id"`
 	Name      string     `json:"name"`
@@ -208,10 +251,41 @@ type ingredient struct {
 	UnitPrice float32    `json:"unit_price"`
 }
 
+type subrecipe struct {
+	ID        recipe.ID `json:"id"`
+	Name      string    `json:"name"`
+	Amount    float32   `json:"amount"`
+	UnitPrice float32   `json:"unit_price"`
+}
+
 type recipeMsg struct {
 	ID          recipe.ID    `json:"id"`
 	Name        string       `json:"name"`
+	ForkedFrom  recipe.ID    `json:"forked_from,omitempty"`
 	Ingredients []ingredient `json:"ingredients"`
+	SubRecipes  []subrecipe  `json:"subrecipes"`
+	Cost        float32      `json:"cost"`
+}
+
+func newRecipeMsg(card recipecard.Card) recipeMsg {
+	msg := recipeMsg{
+		ID:          card.ID,
+		Name:        card.Name,
+		ForkedFrom:  card.ForkedFrom,
+		Ingredients: make([]ingredient, 0, len(card.Ingredients)),
+		SubRecipes:  make([]subrecipe, 0, len(card.SubRecipes)),
+		Cost:        card.Cost,
+	}
+
+	for _, ing := range card.Ingredients {
+		msg.Ingredients = append(msg.Ingredients, ingredient(ing))
+	}
+
+	for _, sub := range card.SubRecipes {
+		msg.SubRecipes = append(msg.SubRecipes, subrecipe(sub))
+	}
+
+	return msg
 }
 
 func parseEndpoint(r *http.Request) (id recipe.ID, err error) {
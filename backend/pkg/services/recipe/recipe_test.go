@@ -0,0 +1,78 @@
+package recipe_test
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers/blank"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/recipe"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	providers.Register(blank.Provider{})
+	m.Run()
+}
+
+func TestRecipeEndpoint(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		method string
+		path   string
+		accept string
+
+		wantCode   int
+		wantBody   string
+		goldenFile string
+	}{
+		"GET":              {method: "GET", path: "/api/recipe/2", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET HTML":         {method: "GET", path: "/api/recipe/2", accept: "text/html", wantCode: http.StatusOK, wantBody: "!golden", goldenFile: "http_response.html"},
+		"GET Markdown":     {method: "GET", path: "/api/recipe/2", accept: "text/markdown", wantCode: http.StatusOK, wantBody: "!golden", goldenFile: "http_response.md"},
+		"GET unacceptable": {method: "GET", path: "/api/recipe/2", accept: "application/xml", wantCode: http.StatusNotAcceptable},
+
+		// Products without a batch size have no price, rather than an infinite one
+		"GET unknown batch size": {method: "GET", path: "/api/recipe/2", wantCode: http.StatusOK, wantBody: "!golden"},
+
+		"POST":   {method: "POST", path: "/api/recipe/2", wantCode: http.StatusAccepted},
+		"DELETE": {method: "DELETE", path: "/api/recipe/2", wantCode: http.StatusNoContent},
+
+		"POST cycle":             {method: "POST", path: "/api/recipe/1", wantCode: http.StatusBadRequest},
+		"POST missing subrecipe": {method: "POST", path: "/api/recipe/2", wantCode: http.StatusBadRequest},
+		"PUT":                    {method: "PUT", path: "/api/recipe/2", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db := testutils.Database(t, testutils.FixturePath(t, "database"))
+
+			sv := recipe.New(recipe.Settings{}.Defaults(), db, testutils.MockAuthGetter())
+			require.True(t, sv.Enabled())
+
+			fixture := testutils.FixturePath(t, "message", "body.json")
+			out, err := os.ReadFile(fixture)
+			if err != nil {
+				require.ErrorIs(t, err, os.ErrNotExist)
+				out = nil
+				t.Logf("No golden file found at %s", fixture)
+			}
+
+			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
+				ServePath:  sv.Path(),
+				ReqPath:    tc.path,
+				Endpoint:   sv.Handle,
+				Method:     tc.method,
+				Body:       string(out),
+				Accept:     tc.accept,
+				WantCode:   tc.wantCode,
+				WantBody:   tc.wantBody,
+				GoldenFile: tc.goldenFile,
+			})
+		})
+	}
+}
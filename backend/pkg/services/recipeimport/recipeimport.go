@@ -0,0 +1,201 @@
+package recipeimport
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipeimport"
+)
+
+// maxDocumentSize is the largest document accepted. Recipe pages are heavy, but not this heavy.
+const maxDocumentSize = 8 << 20
+
+type Service struct {
+	settings Settings
+	db       database.DB
+	auth     auth.Getter
+}
+
+type Settings struct {
+	Enable bool
+}
+
+func (s Settings) Defaults() Settings {
+	return Settings{
+		Enable: true,
+	}
+}
+
+func New(s Settings, db database.DB, auth auth.Getter) Service {
+	return Service{
+		settings: s,
+		db:       db,
+		auth:     auth,
+	}
+}
+
+func (s Service) Name() string {
+	return "recipe-import"
+}
+
+func (s Service) Path() string {
+	return "/api/recipe-import"
+}
+
+func (s Service) Enabled() bool {
+	return s.settings.Enable
+}
+
+func (s Service) Handle(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodPost:
+		return s.handlePost(log, w, r)
+	default:
+		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+func (s Service) handlePost(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	out, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxDocumentSize))
+	if err != nil {
+		return httputils.Errorf(http.StatusBadRequest, "failed to read request: %v", err)
+	}
+	r.Body.Close()
+
+	format, err := detectFormat(r, out)
+	if err != nil {
+		return err
+	}
+
+	products, err := s.db.Products()
+	if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get products: %v", err)
+	}
+
+	draft, err := recipeimport.Import(out, format, products)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnprocessableEntity, "could not import recipe: %v", err)
+	}
+
+	draft.Recipe.User = user
+	log.Debugf("Imported recipe %q with %d ingredients", draft.Name, len(draft.Ingredients))
+
+	if err := json.NewEncoder(w).Encode(newDraftMsg(draft)); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to write response: %v", err)
+	}
+
+	return nil
+}
+
+// detectFormat uses the Content-Type header to tell HTML and JSON-LD apart. If
+// there is no such header, the document is sniffed instead.
+func detectFormat(r *http.Request, doc []byte) (recipeimport.Format, error) {
+	if r.Header.Get("Content-Type") == "" {
+		if trim := bytes.TrimSpace(doc); len(trim) > 0 && (trim[0] == '{' || trim[0] == '[') {
+			return recipeimport.JSONLD, nil
+		}
+		return recipeimport.HTML, nil
+	}
+
+	mt, err := httputils.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return 0, httputils.Errorf(http.StatusUnsupportedMediaType, "invalid Content-Type header: %v", err)
+	}
+
+	switch {
+	case mt.Match(httputils.MediaTypeHTML):
+		return recipeimport.HTML, nil
+	case mt.Match(httputils.MediaTypeJSONLD, httputils.MediaTypeJSON):
+		return recipeimport.JSONLD, nil
+	}
+
+	return 0, httputils.Errorf(http.StatusUnsupportedMediaType, "unsupported Content-Type %s. Only %s, %s, and %s are accepted",
+		mt, httputils.MediaTypeHTML, httputils.MediaTypeJSONLD, httputils.MediaTypeJSON)
+}
+
+type draftMsg struct {
+	Name        string        `json:"name"`
+	Yield       float32       `json:"yield"`
+	Steps       []string      `json:"steps"`
+	Ingredients []lineMsg     `json:"ingredients"`
+	Recipe      recipe.Recipe `json:"recipe"`
+}
+
+type lineMsg struct {
+	Text       string         `json:"text"`
+	Quantity   float32        `json:"quantity"`
+	Unit       string         `json:"unit"`
+	Name       string         `json:"name"`
+	Amount     float32        `json:"amount"`
+	Candidates []candidateMsg `json:"candidates"`
+}
+
+type candidateMsg struct {
+	ProductID product.ID `json:"product_id"`
+	Name      string     `json:"name"`
+	Score     float64    `json:"score"`
+}
+
+func newDraftMsg(d recipeimport.Draft) draftMsg {
+	msg := draftMsg{
+		Name:        d.Name,
+		Yield:       d.Yield,
+		Steps:       d.Steps,
+		Ingredients: make([]lineMsg, 0, len(d.Ingredients)),
+		Recipe:      d.Recipe,
+	}
+
+	if msg.Steps == nil {
+		msg.Steps = []string{}
+	}
+
+	if msg.Recipe.Ingredients == nil {
+		msg.Recipe.Ingredients = []recipe.Ingredient{}
+	}
+
+	for _, l := range d.Ingredients {
+		line := lineMsg{
+			Text:       l.Text,
+			Quantity:   l.Quantity.Amount,
+			Unit:       l.Quantity.Unit,
+			Name:       l.Quantity.Name,
+			Amount:     l.Amount,
+			Candidates: make([]candidateMsg, 0, len(l.Candidates)),
+		}
+
+		for _, c := range l.Candidates {
+			line.Candidates = append(line.Candidates, candidateMsg{
+				ProductID: c.Product.ID,
+				Name:      c.Product.Name,
+				Score:     roundScore(c.Score),
+			})
+		}
+
+		msg.Ingredients = append(msg.Ingredients, line)
+	}
+
+	return msg
+}
+
+// roundScore keeps the response readable: nobody cares about the tenth decimal of a score.
+func roundScore(f float64) float64 {
+	return math.Round(f*1000) / 1000
+}
@@ -0,0 +1,64 @@
+package recipeimport_test
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers/blank"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/recipeimport"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	providers.Register(blank.Provider{})
+	m.Run()
+}
+
+func TestRecipeImportEndpoint(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		method string
+		body   string
+
+		wantCode int
+		wantBody string
+	}{
+		"POST html":      {method: "POST", body: "body.html", wantCode: http.StatusOK, wantBody: "!golden"},
+		"POST jsonld":    {method: "POST", body: "body.json", wantCode: http.StatusOK, wantBody: "!golden"},
+		"POST no recipe": {method: "POST", body: "body.html", wantCode: http.StatusUnprocessableEntity},
+
+		"GET": {method: "GET", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db := testutils.Database(t, testutils.FixturePath(t, "database"))
+
+			sv := recipeimport.New(recipeimport.Settings{}.Defaults(), db, testutils.MockAuthGetter())
+			require.True(t, sv.Enabled())
+
+			var body []byte
+			if tc.body != "" {
+				var err error
+				body, err = os.ReadFile(testutils.FixturePath(t, "message", tc.body))
+				require.NoError(t, err, "Setup: could not read request body")
+			}
+
+			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
+				ServePath: sv.Path(),
+				ReqPath:   "/api/recipe-import",
+				Endpoint:  sv.Handle,
+				Method:    tc.method,
+				Body:      string(body),
+				WantCode:  tc.wantCode,
+				WantBody:  tc.wantBody,
+			})
+		})
+	}
+}
@@ -0,0 +1,326 @@
+package recipelibrary
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/utils"
+)
+
+// Service exposes the recipes other users have shared with the user, either
+// directly or by publishing them, and allows forking them into the user's own collection.
+type Service struct {
+	settings Settings
+	db       database.DB
+	auth     auth.Getter
+}
+
+type Settings struct {
+	Enable bool
+}
+
+func (s Settings) Defaults() Settings {
+	return Settings{
+		Enable: true,
+	}
+}
+
+func New(s Settings, db database.DB, auth auth.Getter) Service {
+	return Service{
+		settings: s,
+		db:       db,
+		auth:     auth,
+	}
+}
+
+func (s Service) Name() string {
+	return "recipe-library"
+}
+
+func (s Service) Path() string {
+	return "/api/recipe-library/{id}"
+}
+
+func (s Service) Enabled() bool {
+	return s.settings.Enable
+}
+
+func (s Service) Handle(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return s.handleGet(log, w, r)
+	case http.MethodPost:
+		return s.handlePost(log, w, r)
+	default:
+		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+type listItem struct {
+	ID    recipe.ID `json:"id"`
+	Name  string    `json:"name"`
+	Owner string    `json:"owner"`
+}
+
+type ingredient struct {
+	ID        product.ID `json:"id"`
+	Name      string     `json:"name"`
+	Amount    float32    `json:"amount"`
+	UnitPrice float32    `json:"unit_price"`
+}
+
+type subrecipe struct {
+	ID     recipe.ID `json:"id"`
+	Name   string    `json:"name"`
+	Amount float32   `json:"amount"`
+}
+
+type recipeMsg struct {
+	ID          recipe.ID    `json:"id"`
+	Name        string       `json:"name"`
+	Owner       string       `json:"owner"`
+	Ingredients []ingredient `json:"ingredients"`
+	SubRecipes  []subrecipe  `json:"subrecipes"`
+}
+
+func (s Service) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	if r.PathValue("id") == "*" {
+		recs, err := s.db.SharedRecipes(user)
+		if err != nil {
+			return httputils.Errorf(http.StatusInternalServerError, "could not get shared recipes: %v", err)
+		}
+
+		items := make([]listItem, 0, len(recs))
+		for _, rec := range recs {
+			items = append(items, listItem{
+				ID:    rec.ID,
+				Name:  rec.Name,
+				Owner: rec.User,
+			})
+		}
+
+		if err := json.NewEncoder(w).Encode(items); err != nil {
+			return httputils.Errorf(http.StatusInternalServerError, "could not encode recipes: %v", err)
+		}
+
+		log.Debugf("Responded with %d items", len(items))
+		return nil
+	}
+
+	id, err := parseEndpoint(r)
+	if err != nil {
+		return err
+	}
+
+	rec, err := s.db.LookupSharedRecipe(user, id)
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "recipe %d not found", id)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to lookup recipe: %v", err)
+	}
+
+	body := recipeMsg{
+		ID:          rec.ID,
+		Name:        rec.Name,
+		Owner:       rec.User,
+		Ingredients: make([]ingredient, 0, len(rec.Ingredients)),
+		SubRecipes:  make([]subrecipe, 0, len(rec.SubRecipes)),
+	}
+
+	for _, ing := range rec.Ingredients {
+		prod, err := s.db.LookupProduct(ing.ProductID)
+		if err != nil {
+			log.Warningf("Product %d not found: %v", ing.ProductID, err)
+			continue
+		}
+
+		body.Ingredients = append(body.Ingredients, ingredient{
+			ID:        prod.ID,
+			Name:      prod.Name,
+			Amount:    ing.Amount,
+			UnitPrice: prod.UnitPrice(),
+		})
+	}
+
+	for _, sub := range rec.SubRecipes {
+		// Sub-recipes are visible because they are part of a shared recipe
+		child, err := s.db.LookupRecipe(rec.User, sub.RecipeID)
+		if err != nil {
+			log.Warningf("Sub-recipe %d not found: %v", sub.RecipeID, err)
+			continue
+		}
+
+		body.SubRecipes = append(body.SubRecipes, subrecipe{
+			ID:     child.ID,
+			Name:   child.Name,
+			Amount: sub.Amount,
+		})
+	}
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to write response: %v", err)
+	}
+
+	return nil
+}
+
+// handlePost forks a shared recipe into the user's collection.
+func (s Service) handlePost(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	id, err := parseEndpoint(r)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	rec, err := s.db.LookupSharedRecipe(user, id)
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "recipe %d not found", id)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to lookup recipe: %v", err)
+	}
+
+	own, err := s.db.Recipes(user)
+	if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get recipes: %v", err)
+	}
+
+	f := forker{
+		db:     s.db,
+		user:   user,
+		owner:  rec.User,
+		forked: make(map[recipe.ID]bool),
+		names:  make(map[string]bool),
+	}
+
+	for _, r := range own {
+		f.names[r.Name] = true
+	}
+
+	if err := f.fork(rec); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to fork recipe: %v", err)
+	}
+
+	// All copies are saved together so that a failure does not leave a partial fork behind
+	ids, err := s.db.ForkRecipes(f.copies)
+	if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to save fork: %v", err)
+	}
+
+	// The recipe is copied after all of its sub-recipes
+	newID := ids[len(ids)-1]
+
+	log.Debugf("Forked recipe %d into %d (%d recipes copied)", id, newID, len(ids))
+
+	w.Header().Set("Location", path.Join("/api/recipe/", fmt.Sprint(newID)))
+	w.WriteHeader(http.StatusCreated)
+
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"id": newID}); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to write response: %v", err)
+	}
+
+	return nil
+}
+
+// forker prepares copies of a recipe and its sub-recipes for another user's collection.
+type forker struct {
+	db    database.DB
+	user  string
+	owner string
+
+	// forked contains the IDs of the original recipes already copied, so that
+	// sub-recipes used more than once are only copied once.
+	forked map[recipe.ID]bool
+
+	// copies are the copies to save, with every sub-recipe before the recipes that use it.
+	// Their sub-recipes point to the originals, which the database replaces with the copies.
+	copies []recipe.Recipe
+
+	// names contains the names of the user's recipes, which must be unique.
+	names map[string]bool
+}
+
+func (f *forker) fork(rec recipe.Recipe) error {
+	if f.forked[rec.ID] {
+		return nil
+	}
+	f.forked[rec.ID] = true
+
+	cp := recipe.Recipe{
+		User:        f.user,
+		Name:        f.uniqueName(rec.Name),
+		Ingredients: rec.Ingredients,
+		ForkedFrom:  rec.ID,
+	}
+
+	for _, sub := range rec.SubRecipes {
+		child, err := f.db.LookupRecipe(f.owner, sub.RecipeID)
+		if err != nil {
+			return fmt.Errorf("could not find sub-recipe %d: %w", sub.RecipeID, err)
+		}
+
+		if err := f.fork(child); err != nil {
+			return err
+		}
+
+		cp.SubRecipes = append(cp.SubRecipes, recipe.SubRecipe{
+			RecipeID: child.ID,
+			Amount:   sub.Amount,
+		})
+	}
+
+	f.copies = append(f.copies, cp)
+	return nil
+}
+
+// uniqueName returns the name, followed by a number if the user already has a recipe with that name.
+func (f *forker) uniqueName(name string) string {
+	candidate := name
+	for i := 2; f.names[candidate]; i++ {
+		candidate = fmt.Sprintf("%s (%d)", name, i)
+	}
+
+	f.names[candidate] = true
+	return candidate
+}
+
+func parseEndpoint(r *http.Request) (id recipe.ID, err error) {
+	sid := r.PathValue("id")
+	if sid == "" {
+		return 0, httputils.Error(http.StatusBadRequest, "missing id")
+	}
+
+	idURL, err := strconv.ParseUint(sid, 10, recipe.IDSize)
+	if err != nil {
+		return 0, httputils.Errorf(http.StatusBadRequest, "invalid id: %v", err)
+	}
+
+	return utils.SafeIntConvert[recipe.ID](idURL)
+}
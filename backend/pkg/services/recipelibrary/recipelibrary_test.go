@@ -0,0 +1,93 @@
+package recipelibrary_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers/blank"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/recipelibrary"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	providers.Register(blank.Provider{})
+	m.Run()
+}
+
+func TestRecipeLibraryEndpoint(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		method string
+		path   string
+
+		wantCode int
+		wantBody string
+	}{
+		"GET all":         {method: "GET", path: "/api/recipe-library/*", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET one":         {method: "GET", path: "/api/recipe-library/2", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET not shared":  {method: "GET", path: "/api/recipe-library/3", wantCode: http.StatusNotFound},
+		"POST":            {method: "POST", path: "/api/recipe-library/2", wantCode: http.StatusCreated},
+		"POST not shared": {method: "POST", path: "/api/recipe-library/3", wantCode: http.StatusNotFound},
+
+		"DELETE": {method: "DELETE", path: "/api/recipe-library/2", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db := testutils.Database(t, testutils.FixturePath(t, "database"))
+
+			sv := recipelibrary.New(recipelibrary.Settings{}.Defaults(), db, testutils.MockAuthGetter())
+			require.True(t, sv.Enabled())
+
+			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
+				ServePath: sv.Path(),
+				ReqPath:   tc.path,
+				Endpoint:  sv.Handle,
+				Method:    tc.method,
+				WantCode:  tc.wantCode,
+				WantBody:  tc.wantBody,
+			})
+		})
+	}
+}
+
+func TestFork(t *testing.T) {
+	t.Parallel()
+
+	db := testutils.Database(t, testutils.FixturePath(t, "database"))
+	sv := recipelibrary.New(recipelibrary.Settings{}.Defaults(), db, testutils.MockAuthGetter())
+
+	testutils.TestEndpoint(t, testutils.ResponseTestOptions{
+		ServePath: sv.Path(),
+		ReqPath:   "/api/recipe-library/2",
+		Endpoint:  sv.Handle,
+		Method:    http.MethodPost,
+		WantCode:  http.StatusCreated,
+	})
+
+	recs, err := db.Recipes("test-user-123")
+	require.NoError(t, err)
+	require.Len(t, recs, 3, "The recipe and its sub-recipe should have been forked")
+
+	byName := make(map[string]recipe.Recipe)
+	for _, r := range recs {
+		byName[r.Name] = r
+	}
+
+	require.Contains(t, byName, "Pizza", "Existing recipe should be kept")
+	require.Contains(t, byName, "Pizza (2)", "Forked recipe should be renamed to avoid name clashes")
+	require.Contains(t, byName, "Pizza dough", "Sub-recipe should have been forked")
+
+	pizza := byName["Pizza (2)"]
+	dough := byName["Pizza dough"]
+
+	require.Equal(t, recipe.ID(2), pizza.ForkedFrom, "Fork should link to the original")
+	require.Equal(t, recipe.ID(1), dough.ForkedFrom, "Forked sub-recipe should link to the original")
+	require.Equal(t, []recipe.SubRecipe{{RecipeID: dough.ID, Amount: 1}}, pizza.SubRecipes, "Fork should use the forked sub-recipe")
+}
@@ -0,0 +1,392 @@
+package reciperevisions
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/utils"
+)
+
+// Service exposes the revision history of a recipe.
+//
+// Menus reference recipes by ID, so they always use the latest revision. Restoring
+// an old revision creates a new one with its contents, so menus pick it up as well.
+type Service struct {
+	settings Settings
+	db       database.DB
+	auth     auth.Getter
+}
+
+type Settings struct {
+	Enable bool
+}
+
+func (s Settings) Defaults() Settings {
+	return Settings{
+		Enable: true,
+	}
+}
+
+func New(s Settings, db database.DB, auth auth.Getter) Service {
+	return Service{
+		settings: s,
+		db:       db,
+		auth:     auth,
+	}
+}
+
+func (s Service) Name() string {
+	return "recipe-revisions"
+}
+
+func (s Service) Path() string {
+	return "/api/recipe/{id}/revisions/{revision}"
+}
+
+func (s Service) Enabled() bool {
+	return s.settings.Enable
+}
+
+func (s Service) Handle(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return s.handleGet(log, w, r)
+	case http.MethodPost:
+		return s.handlePost(log, w, r)
+	default:
+		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+type revisionItem struct {
+	Revision  int       `json:"revision"`
+	Author    string    `json:"author"`
+	Timestamp time.Time `json:"timestamp"`
+	Name      string    `json:"name"`
+}
+
+type item struct {
+	ID     uint32  `json:"id"`
+	Name   string  `json:"name"`
+	Amount float32 `json:"amount"`
+}
+
+type revisionMsg struct {
+	Revision    int       `json:"revision"`
+	Author      string    `json:"author"`
+	Timestamp   time.Time `json:"timestamp"`
+	Name        string    `json:"name"`
+	Ingredients []item    `json:"ingredients"`
+	SubRecipes  []item    `json:"subrecipes"`
+}
+
+type change struct {
+	ID        uint32  `json:"id"`
+	Name      string  `json:"name"`
+	Change    string  `json:"change"`
+	OldAmount float32 `json:"old_amount"`
+	NewAmount float32 `json:"new_amount"`
+}
+
+type diffMsg struct {
+	From        int      `json:"from"`
+	To          int      `json:"to"`
+	OldName     string   `json:"old_name,omitempty"`
+	NewName     string   `json:"new_name,omitempty"`
+	Ingredients []change `json:"ingredients"`
+	SubRecipes  []change `json:"subrecipes"`
+}
+
+func (s Service) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	id, err := parseID(r)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	var body any
+	if r.PathValue("revision") == "*" {
+		body, err = s.list(user, id)
+	} else {
+		body, err = s.getRevision(log, r, user, id)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to write response: %v", err)
+	}
+
+	return nil
+}
+
+// getRevision shows a revision, or its differences with another one if the diff parameter is set.
+func (s Service) getRevision(log logger.Logger, r *http.Request, user string, id recipe.ID) (any, error) {
+	rev, err := parseRevision(r.PathValue("revision"))
+	if err != nil {
+		return nil, err
+	}
+
+	if other := r.URL.Query().Get("diff"); other != "" {
+		return s.diff(log, user, id, other, rev)
+	}
+
+	return s.show(log, user, id, rev)
+}
+
+func (s Service) list(user string, id recipe.ID) ([]revisionItem, error) {
+	revs, err := s.db.RecipeRevisions(user, id)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, httputils.Errorf(http.StatusNotFound, "recipe %d not found", id)
+	} else if err != nil {
+		return nil, httputils.Errorf(http.StatusInternalServerError, "failed to get revisions: %v", err)
+	}
+
+	items := make([]revisionItem, 0, len(revs))
+	for _, rev := range revs {
+		items = append(items, revisionItem{
+			Revision:  rev.Revision,
+			Author:    rev.Author,
+			Timestamp: rev.Timestamp,
+			Name:      rev.Recipe.Name,
+		})
+	}
+
+	return items, nil
+}
+
+func (s Service) show(log logger.Logger, user string, id recipe.ID, revision int) (revisionMsg, error) {
+	rev, err := s.lookup(user, id, revision)
+	if err != nil {
+		return revisionMsg{}, err
+	}
+
+	msg := revisionMsg{
+		Revision:    rev.Revision,
+		Author:      rev.Author,
+		Timestamp:   rev.Timestamp,
+		Name:        rev.Recipe.Name,
+		Ingredients: make([]item, 0, len(rev.Recipe.Ingredients)),
+		SubRecipes:  make([]item, 0, len(rev.Recipe.SubRecipes)),
+	}
+
+	for _, ing := range rev.Recipe.Ingredients {
+		msg.Ingredients = append(msg.Ingredients, item{
+			ID:     uint32(ing.ProductID),
+			Name:   s.productName(log, ing.ProductID),
+			Amount: ing.Amount,
+		})
+	}
+
+	for _, sub := range rev.Recipe.SubRecipes {
+		msg.SubRecipes = append(msg.SubRecipes, item{
+			ID:     uint32(sub.RecipeID),
+			Name:   s.recipeName(log, user, sub.RecipeID),
+			Amount: sub.Amount,
+		})
+	}
+
+	return msg, nil
+}
+
+func (s Service) diff(log logger.Logger, user string, id recipe.ID, fromRaw string, to int) (diffMsg, error) {
+	from, err := parseRevision(fromRaw)
+	if err != nil {
+		return diffMsg{}, err
+	}
+
+	older, err := s.lookup(user, id, from)
+	if err != nil {
+		return diffMsg{}, err
+	}
+
+	newer, err := s.lookup(user, id, to)
+	if err != nil {
+		return diffMsg{}, err
+	}
+
+	d := recipe.Compare(older.Recipe, newer.Recipe)
+
+	msg := diffMsg{
+		From:        from,
+		To:          to,
+		OldName:     d.OldName,
+		NewName:     d.NewName,
+		Ingredients: make([]change, 0, len(d.Ingredients)),
+		SubRecipes:  make([]change, 0, len(d.SubRecipes)),
+	}
+
+	for _, c := range d.Ingredients {
+		msg.Ingredients = append(msg.Ingredients, change{
+			ID:        uint32(c.ProductID),
+			Name:      s.productName(log, c.ProductID),
+			Change:    changeKind(c.OldAmount, c.NewAmount),
+			OldAmount: c.OldAmount,
+			NewAmount: c.NewAmount,
+		})
+	}
+
+	for _, c := range d.SubRecipes {
+		msg.SubRecipes = append(msg.SubRecipes, change{
+			ID:        uint32(c.RecipeID),
+			Name:      s.recipeName(log, user, c.RecipeID),
+			Change:    changeKind(c.OldAmount, c.NewAmount),
+			OldAmount: c.OldAmount,
+			NewAmount: c.NewAmount,
+		})
+	}
+
+	return msg, nil
+}
+
+// handlePost restores a revision by saving its contents as a new revision.
+func (s Service) handlePost(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	id, err := parseID(r)
+	if err != nil {
+		return err
+	}
+
+	revision, err := parseRevision(r.PathValue("revision"))
+	if err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	rev, err := s.lookup(user, id, revision)
+	if err != nil {
+		return err
+	}
+
+	current, err := s.db.LookupRecipe(user, id)
+	if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to lookup recipe: %v", err)
+	}
+
+	restored := rev.Recipe
+	restored.ID = id
+	restored.User = user
+	restored.ForkedFrom = current.ForkedFrom
+
+	// Sub-recipes may have changed since the revision was saved
+	cached := database.NewCachedUserLookup(user, s.db.LookupRecipe)
+	lookup := func(id recipe.ID) (recipe.Recipe, error) {
+		if id == restored.ID {
+			return restored, nil
+		}
+		return cached.Lookup(id)
+	}
+
+	if _, err := recipe.Flatten(restored, lookup); err != nil {
+		return httputils.Errorf(http.StatusConflict, "cannot restore revision %d: %v", revision, err)
+	}
+
+	if _, err := s.db.SetRecipe(restored); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to save recipe: %v", err)
+	}
+
+	revs, err := s.db.RecipeRevisions(user, id)
+	if err != nil || len(revs) == 0 {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to get revisions: %v", err)
+	}
+
+	latest := revs[len(revs)-1].Revision
+	log.Debugf("Restored revision %d of recipe %d as revision %d", revision, id, latest)
+
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "revision": latest}); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to write response: %v", err)
+	}
+
+	return nil
+}
+
+func (s Service) lookup(user string, id recipe.ID, revision int) (dbtypes.RecipeRevision, error) {
+	rev, err := s.db.LookupRecipeRevision(user, id, revision)
+	if errors.Is(err, fs.ErrNotExist) {
+		return rev, httputils.Errorf(http.StatusNotFound, "revision %d of recipe %d not found", revision, id)
+	} else if err != nil {
+		return rev, httputils.Errorf(http.StatusInternalServerError, "failed to lookup revision: %v", err)
+	}
+
+	return rev, nil
+}
+
+func (s Service) productName(log logger.Logger, id product.ID) string {
+	p, err := s.db.LookupProduct(id)
+	if err != nil {
+		log.Warningf("Product %d not found: %v", id, err)
+		return ""
+	}
+	return p.Name
+}
+
+func (s Service) recipeName(log logger.Logger, user string, id recipe.ID) string {
+	r, err := s.db.LookupRecipe(user, id)
+	if err != nil {
+		log.Warningf("Recipe %d not found: %v", id, err)
+		return ""
+	}
+	return r.Name
+}
+
+func changeKind(older, newer float32) string {
+	switch {
+	case older == 0:
+		return "added"
+	case newer == 0:
+		return "removed"
+	default:
+		return "changed"
+	}
+}
+
+func parseID(r *http.Request) (id recipe.ID, err error) {
+	sid := r.PathValue("id")
+	if sid == "" {
+		return 0, httputils.Error(http.StatusBadRequest, "missing id")
+	}
+
+	idURL, err := strconv.ParseUint(sid, 10, recipe.IDSize)
+	if err != nil {
+		return 0, httputils.Errorf(http.StatusBadRequest, "invalid id: %v", err)
+	}
+
+	return utils.SafeIntConvert[recipe.ID](idURL)
+}
+
+func parseRevision(s string) (int, error) {
+	rev, err := strconv.Atoi(s)
+	if err != nil || rev <= 0 {
+		return 0, httputils.Errorf(http.StatusBadRequest, "invalid revision %q", s)
+	}
+	return rev, nil
+}
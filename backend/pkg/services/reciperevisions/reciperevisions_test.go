@@ -0,0 +1,72 @@
+package reciperevisions_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers/blank"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/reciperevisions"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	providers.Register(blank.Provider{})
+	m.Run()
+}
+
+func TestRecipeRevisionsEndpoint(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		method string
+		path   string
+
+		wantCode int
+		wantBody string
+	}{
+		"GET all":      {method: "GET", path: "/api/recipe/1/revisions/*", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET one":      {method: "GET", path: "/api/recipe/1/revisions/1", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET diff":     {method: "GET", path: "/api/recipe/1/revisions/2?diff=1", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET missing":  {method: "GET", path: "/api/recipe/1/revisions/9", wantCode: http.StatusNotFound},
+		"POST":         {method: "POST", path: "/api/recipe/1/revisions/1", wantCode: http.StatusAccepted},
+		"POST missing": {method: "POST", path: "/api/recipe/1/revisions/9", wantCode: http.StatusNotFound},
+
+		"PUT": {method: "PUT", path: "/api/recipe/1/revisions/1", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db := testutils.Database(t, testutils.FixturePath(t, "database"))
+
+			sv := reciperevisions.New(reciperevisions.Settings{}.Defaults(), db, testutils.MockAuthGetter())
+			require.True(t, sv.Enabled())
+
+			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
+				ServePath: sv.Path(),
+				ReqPath:   tc.path,
+				Endpoint:  sv.Handle,
+				Method:    tc.method,
+				WantCode:  tc.wantCode,
+				WantBody:  tc.wantBody,
+			})
+
+			if tc.method != http.MethodPost || tc.wantCode != http.StatusAccepted {
+				return
+			}
+
+			// Restoring creates a new revision with the old contents
+			revs, err := db.RecipeRevisions("test-user-123", 1)
+			require.NoError(t, err)
+			require.Len(t, revs, 3)
+
+			r, err := db.LookupRecipe("test-user-123", 1)
+			require.NoError(t, err)
+			require.Equal(t, "Dough", r.Name, "Recipe should have been restored")
+			require.Equal(t, revs[0].Recipe, revs[2].Recipe, "Restored revision should match the original")
+		})
+	}
+}
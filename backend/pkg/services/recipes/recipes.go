@@ -1,14 +1,19 @@
 package recipes
 
 import (
+	"archive/zip"
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipecard"
 )
 
 type Service struct {
@@ -56,7 +61,8 @@ func (s *Service) Handle(log logger.Logger, w http.ResponseWriter, r *http.Reque
 		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
 	}
 
-	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+	mediaType, err := httputils.Negotiate(r, httputils.MediaTypeJSON, httputils.MediaTypeZip)
+	if err != nil {
 		return err
 	}
 
@@ -70,6 +76,10 @@ func (s *Service) Handle(log logger.Logger, w http.ResponseWriter, r *http.Reque
 		return httputils.Errorf(http.StatusInternalServerError, "could not get recipes: %v", err)
 	}
 
+	if mediaType == httputils.MediaTypeZip {
+		return s.export(log, w, recs)
+	}
+
 	type item struct {
 		ID   recipe.ID `json:"id"`
 		Name string    `json:"name"`
@@ -91,3 +101,64 @@ func (s *Service) Handle(log logger.Logger, w http.ResponseWriter, r *http.Reque
 
 	return nil
 }
+
+// export writes a zip archive with the recipes as Markdown cards, one file per recipe.
+func (s *Service) export(log logger.Logger, w http.ResponseWriter, recs []recipe.Recipe) error {
+	// The archive is built in memory so that errors can still be reported
+	var buff bytes.Buffer
+	archive := zip.NewWriter(&buff)
+
+	names := make(map[string]bool)
+	for _, rec := range recs {
+		card, err := recipecard.New(log, s.db, rec)
+		if err != nil {
+			return httputils.Errorf(http.StatusInternalServerError, "could not export recipe %d: %v", rec.ID, err)
+		}
+
+		f, err := archive.Create(fileName(rec.Name, names))
+		if err != nil {
+			return httputils.Errorf(http.StatusInternalServerError, "could not create archive: %v", err)
+		}
+
+		if err := card.WriteMarkdown(f); err != nil {
+			return httputils.Errorf(http.StatusInternalServerError, "could not export recipe %d: %v", rec.ID, err)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not create archive: %v", err)
+	}
+
+	w.Header().Set("Content-Type", httputils.MediaTypeZip.String())
+	w.Header().Set("Content-Disposition", `attachment; filename="recipes.zip"`)
+
+	if _, err := buff.WriteTo(w); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not write archive: %v", err)
+	}
+
+	log.Debugf("Exported %d recipes", len(recs))
+	return nil
+}
+
+// fileName returns a file name for the recipe that is safe to use in an archive
+// and is not in use yet.
+func fileName(name string, used map[string]bool) string {
+	base := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+
+	if base == "" {
+		base = "recipe"
+	}
+
+	candidate := base + ".md"
+	for i := 2; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s (%d).md", base, i)
+	}
+
+	used[candidate] = true
+	return candidate
+}
@@ -1,7 +1,12 @@
 package recipes_test
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers"
@@ -76,3 +81,52 @@ func TestRecipes(t *testing.T) {
 		})
 	}
 }
+
+func TestRecipesExport(t *testing.T) {
+	t.Parallel()
+
+	db := testutils.Database(t, testutils.FixturePath(t, "database"))
+
+	sv := recipes.New(recipes.Settings{}.Defaults(), db, testutils.MockAuthGetter())
+	require.True(t, sv.Enabled())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr, stop := testutils.HTTPServer(ctx, t, sv.Path(), sv.Handle)
+	defer stop()
+
+	req := testutils.NewRequest(t, http.MethodGet, "http://"+addr+"/api/recipes", "")
+	req.Header.Set("Accept", "application/zip")
+
+	resp, err := (&http.Client{}).Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/zip", resp.Header.Get("Content-Type"))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err, "Response should be a valid zip archive")
+
+	var files []string
+	var contents strings.Builder
+	for _, f := range archive.File {
+		files = append(files, f.Name)
+
+		r, err := f.Open()
+		require.NoError(t, err)
+
+		_, err = io.Copy(&contents, r)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+	}
+
+	require.ElementsMatch(t, []string{"Pizza dough.md", "Pizza margherita.md", "Salt_pepper mix.md"}, files,
+		"Archive should contain one file per recipe of the user")
+
+	testutils.CompareToGolden(t, contents.String(), "contents.md")
+}
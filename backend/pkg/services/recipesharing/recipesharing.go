@@ -0,0 +1,180 @@
+package recipesharing
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"strconv"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/utils"
+)
+
+type Service struct {
+	settings Settings
+	db       database.DB
+	auth     auth.Getter
+}
+
+type Settings struct {
+	Enable bool
+}
+
+func (s Settings) Defaults() Settings {
+	return Settings{
+		Enable: true,
+	}
+}
+
+func New(s Settings, db database.DB, auth auth.Getter) Service {
+	return Service{
+		settings: s,
+		db:       db,
+		auth:     auth,
+	}
+}
+
+func (s Service) Name() string {
+	return "recipe-sharing"
+}
+
+func (s Service) Path() string {
+	return "/api/recipe/{id}/sharing"
+}
+
+func (s Service) Enabled() bool {
+	return s.settings.Enable
+}
+
+func (s Service) Handle(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return s.handleGet(log, w, r)
+	case http.MethodPut:
+		return s.handlePut(log, w, r)
+	default:
+		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+type sharingMsg struct {
+	Public bool     `json:"public"`
+	Users  []string `json:"users"`
+}
+
+func (s Service) handleGet(_ logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	id, err := parseEndpoint(r)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	sh, err := s.db.LookupRecipeSharing(user, id)
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "recipe %d not found", id)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to lookup recipe sharing: %v", err)
+	}
+
+	body := sharingMsg{
+		Public: sh.Public,
+		Users:  sh.Users,
+	}
+
+	if body.Users == nil {
+		body.Users = []string{}
+	}
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to write response: %v", err)
+	}
+
+	return nil
+}
+
+func (s Service) handlePut(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateContentType(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	id, err := parseEndpoint(r)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	var body sharingMsg
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return httputils.Errorf(http.StatusBadRequest, "failed to read request: %v", err)
+	}
+
+	if len(body.Users) > 100 {
+		return httputils.Error(http.StatusBadRequest, "recipe cannot be shared with more than 100 users")
+	}
+
+	sh := dbtypes.RecipeSharing{
+		Recipe: id,
+		Owner:  user,
+		Public: body.Public,
+	}
+
+	for _, u := range body.Users {
+		if u == user {
+			// Sharing with oneself is a no-op
+			continue
+		}
+
+		exists, err := s.db.LookupUser(u)
+		if err != nil {
+			return httputils.Errorf(http.StatusInternalServerError, "failed to lookup user: %v", err)
+		} else if !exists {
+			return httputils.Errorf(http.StatusBadRequest, "user %q does not exist", u)
+		}
+
+		sh.Users = append(sh.Users, u)
+	}
+
+	err = s.db.SetRecipeSharing(sh)
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "recipe %d not found", id)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to save recipe sharing: %v", err)
+	}
+
+	log.Debugf("Recipe %d shared with %d users (public: %t)", id, len(sh.Users), sh.Public)
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func parseEndpoint(r *http.Request) (id recipe.ID, err error) {
+	sid := r.PathValue("id")
+	if sid == "" {
+		return 0, httputils.Error(http.StatusBadRequest, "missing id")
+	}
+
+	idURL, err := strconv.ParseUint(sid, 10, recipe.IDSize)
+	if err != nil {
+		return 0, httputils.Errorf(http.StatusBadRequest, "invalid id: %v", err)
+	}
+
+	return utils.SafeIntConvert[recipe.ID](idURL)
+}
@@ -0,0 +1,67 @@
+package recipesharing_test
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers/blank"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/recipesharing"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	providers.Register(blank.Provider{})
+	m.Run()
+}
+
+func TestRecipeSharingEndpoint(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		method string
+		path   string
+
+		wantCode int
+		wantBody string
+	}{
+		"GET":              {method: "GET", path: "/api/recipe/1/sharing", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET not owned":    {method: "GET", path: "/api/recipe/2/sharing", wantCode: http.StatusNotFound},
+		"PUT":              {method: "PUT", path: "/api/recipe/1/sharing", wantCode: http.StatusNoContent},
+		"PUT unknown user": {method: "PUT", path: "/api/recipe/1/sharing", wantCode: http.StatusBadRequest},
+		"PUT not owned":    {method: "PUT", path: "/api/recipe/2/sharing", wantCode: http.StatusNotFound},
+
+		"DELETE": {method: "DELETE", path: "/api/recipe/1/sharing", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db := testutils.Database(t, testutils.FixturePath(t, "database"))
+
+			sv := recipesharing.New(recipesharing.Settings{}.Defaults(), db, testutils.MockAuthGetter())
+			require.True(t, sv.Enabled())
+
+			fixture := testutils.FixturePath(t, "message", "body.json")
+			out, err := os.ReadFile(fixture)
+			if err != nil {
+				require.ErrorIs(t, err, os.ErrNotExist)
+				out = nil
+				t.Logf("No golden file found at %s", fixture)
+			}
+
+			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
+				ServePath: sv.Path(),
+				ReqPath:   tc.path,
+				Endpoint:  sv.Handle,
+				Method:    tc.method,
+				Body:      string(out),
+				WantCode:  tc.wantCode,
+				WantBody:  tc.wantBody,
+			})
+		})
+	}
+}
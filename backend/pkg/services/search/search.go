@@ -0,0 +1,150 @@
+package search
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+	index "github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/search"
+)
+
+// Service searches the recipes, menus and products visible to the user.
+type Service struct {
+	settings Settings
+	db       *index.IndexedDB
+	auth     auth.Getter
+}
+
+type Settings struct {
+	Enable bool
+
+	// DefaultLimit is the number of results returned when the request does not specify a limit.
+	DefaultLimit int
+}
+
+func (s Settings) Defaults() Settings {
+	return Settings{
+		Enable:       true,
+		DefaultLimit: 20,
+	}
+}
+
+func New(s Settings, db *index.IndexedDB, auth auth.Getter) Service {
+	return Service{
+		settings: s,
+		db:       db,
+		auth:     auth,
+	}
+}
+
+func (s Service) Name() string {
+	return "search"
+}
+
+func (s Service) Path() string {
+	return "/api/search"
+}
+
+func (s Service) Enabled() bool {
+	return s.settings.Enable
+}
+
+func (s Service) Handle(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return s.handleGet(log, w, r)
+	default:
+		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+type result struct {
+	Kind      index.Kind `json:"kind"`
+	Name      string     `json:"name"`
+	Context   string     `json:"context,omitempty"`
+	ProductID product.ID `json:"product_id,omitempty"`
+	RecipeID  recipe.ID  `json:"recipe_id,omitempty"`
+	Menu      string     `json:"menu,omitempty"`
+	Score     float64    `json:"score"`
+}
+
+func (s Service) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	q := r.URL.Query()
+
+	query := strings.TrimSpace(q.Get("q"))
+	if query == "" {
+		return httputils.Error(http.StatusBadRequest, "missing query parameter q")
+	}
+
+	kinds, err := parseKinds(q.Get("kind"))
+	if err != nil {
+		return err
+	}
+
+	limit := s.settings.DefaultLimit
+	if l := q.Get("limit"); l != "" {
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit <= 0 {
+			return httputils.Errorf(http.StatusBadRequest, "invalid limit %q", l)
+		}
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	results, err := s.db.Search(user, query, kinds, limit)
+	if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to search: %v", err)
+	}
+
+	body := make([]result, 0, len(results))
+	for _, res := range results {
+		body = append(body, result{
+			Kind:      res.Kind,
+			Name:      res.Name,
+			Context:   res.Context,
+			ProductID: res.ProductID,
+			RecipeID:  res.RecipeID,
+			Menu:      res.Menu,
+			Score:     res.Score,
+		})
+	}
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to write response: %v", err)
+	}
+
+	log.Debugf("Search for %q returned %d results", query, len(body))
+	return nil
+}
+
+// parseKinds parses a comma-separated list of kinds.
+func parseKinds(s string) ([]index.Kind, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var kinds []index.Kind
+	for _, k := range strings.Split(s, ",") {
+		switch kind := index.Kind(strings.TrimSpace(k)); kind {
+		case index.KindProduct, index.KindRecipe, index.KindMenu, index.KindDish:
+			kinds = append(kinds, kind)
+		default:
+			return nil, httputils.Errorf(http.StatusBadRequest, "unknown kind %q", k)
+		}
+	}
+
+	return kinds, nil
+}
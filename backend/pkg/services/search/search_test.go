@@ -0,0 +1,89 @@
+package search_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers/blank"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+	index "github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/search"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/search"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	providers.Register(blank.Provider{})
+	m.Run()
+}
+
+func TestSearchEndpoint(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		method string
+		path   string
+
+		wantCode int
+		wantBody string
+	}{
+		"GET accents":       {method: "GET", path: "/api/search?q=tomaq", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET ingredient":    {method: "GET", path: "/api/search?q=FARINA", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET kind":          {method: "GET", path: "/api/search?q=pizza&kind=recipe,menu", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET limit":         {method: "GET", path: "/api/search?q=piz&limit=2", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET missing query": {method: "GET", path: "/api/search", wantCode: http.StatusBadRequest},
+		"GET unknown kind":  {method: "GET", path: "/api/search?q=pizza&kind=pantry", wantCode: http.StatusBadRequest},
+
+		"POST": {method: "POST", path: "/api/search?q=pizza", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db := index.NewIndexedDB(testutils.Database(t, testutils.FixturePath(t, "database")))
+
+			sv := search.New(search.Settings{}.Defaults(), db, testutils.MockAuthGetter())
+			require.True(t, sv.Enabled())
+
+			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
+				ServePath: sv.Path(),
+				ReqPath:   tc.path,
+				Endpoint:  sv.Handle,
+				Method:    tc.method,
+				WantCode:  tc.wantCode,
+				WantBody:  tc.wantBody,
+			})
+		})
+	}
+}
+
+func TestSearchIndexSync(t *testing.T) {
+	t.Parallel()
+
+	const user = "test-user-123"
+	db := index.NewIndexedDB(testutils.Database(t, testutils.FixturePath(t, "database")))
+
+	res, err := db.Search(user, "lasanya", nil, 0)
+	require.NoError(t, err)
+	require.Empty(t, res, "Search should not find a recipe that does not exist yet")
+
+	id, err := db.SetRecipe(recipe.Recipe{User: user, Name: "Lasanya de verdures"})
+	require.NoError(t, err)
+
+	res, err = db.Search(user, "lasanya", nil, 0)
+	require.NoError(t, err)
+	require.Len(t, res, 1, "Search should find the new recipe")
+	require.Equal(t, id, res[0].RecipeID)
+
+	res, err = db.Search("friend-user-456", "lasanya", nil, 0)
+	require.NoError(t, err)
+	require.Empty(t, res, "Search should not find recipes of other users")
+
+	require.NoError(t, db.DeleteRecipe(user, id))
+
+	res, err = db.Search(user, "lasanya", nil, 0)
+	require.NoError(t, err)
+	require.Empty(t, res, "Search should not find the deleted recipe")
+}
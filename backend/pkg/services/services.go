@@ -13,19 +13,33 @@ import (
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers/blank"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers/bonpreu"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers/mercadona"
+	index "github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/search"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/batchcooking"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/calendar"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/cooking"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/frontend"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/helloworld"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/ingredientuse"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/menu"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/menucheck"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/menucost"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/menus"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/pantry"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/planner"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/pricing"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/products"
 	providersservice "github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/providers"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/recipe"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/recipeimport"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/recipelibrary"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/reciperevisions"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/recipes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/recipesharing"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/search"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/session"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/shoppinglist"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/shoppingneeds"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/substitutions"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/version"
 )
 
@@ -42,46 +56,94 @@ type Manager struct {
 }
 
 type Settings struct {
-	Database      database.Settings
-	Auth          auth.Settings
-	FrontEnd      frontend.Settings
-	AuthLogin     session.Settings
-	AuthLogout    session.Settings
-	AuthRefresh   session.Settings
-	HelloWorld    helloworld.Settings
-	IngredientUse ingredientuse.Settings
-	Menu          menu.Settings
-	Pantry        pantry.Settings
-	Pricing       pricing.Settings
-	Products      products.Settings
-	Providers     providersservice.Settings
-	Recipe        recipe.Settings
-	Recipes       recipes.Settings
-	ShoppingList  shoppinglist.Settings
-	ShoppingNeeds shoppingneeds.Settings
-	Version       version.Settings
+	Database             database.Settings
+	Auth                 auth.Settings
+	FrontEnd             frontend.Settings
+	AuthLogin            session.Settings
+	AuthLogout           session.Settings
+	AuthRefresh          session.Settings
+	BatchCooking         batchcooking.Settings
+	Cooking              cooking.Settings
+	Calendar             calendar.Settings
+	CalendarFeed         calendar.Settings
+	CalendarSubscription calendar.Settings
+	HelloWorld           helloworld.Settings
+	IngredientUse        ingredientuse.Settings
+	Menu                 menu.Settings
+	MenuCalendar         calendar.Settings
+	MenuCheck            menucheck.Settings
+	MenuCost             menucost.Settings
+	MenuSubstitutions    substitutions.Settings
+	Menus                menus.Settings
+	Pantry               pantry.Settings
+	PantryExpiring       pantry.Settings
+	PantryLedger         pantry.Settings
+	PantryItem           pantry.Settings
+	PantryStaples        pantry.Settings
+	PantryGroup          pantry.Settings
+	PantryCookable       pantry.Settings
+	Planner              planner.Settings
+	Pricing              pricing.Settings
+	Products             products.Settings
+	Providers            providersservice.Settings
+	Recipe               recipe.Settings
+	RecipeImport         recipeimport.Settings
+	RecipeLibrary        recipelibrary.Settings
+	RecipeRevisions      reciperevisions.Settings
+	RecipeSharing        recipesharing.Settings
+	RecipeSubstitutions  substitutions.Settings
+	Recipes              recipes.Settings
+	Search               search.Settings
+	ShoppingList         shoppinglist.Settings
+	ShoppingCheckout     shoppinglist.Settings
+	ShoppingNeeds        shoppingneeds.Settings
+	Version              version.Settings
 }
 
 func (Settings) Defaults() Settings {
 	return Settings{
-		Auth:          auth.Settings{}.Defaults(),
-		Database:      database.Settings{}.Defaults(),
-		FrontEnd:      frontend.Settings{}.Defaults(),
-		AuthLogin:     session.Settings{}.Defaults(),
-		AuthLogout:    session.Settings{}.Defaults(),
-		AuthRefresh:   session.Settings{}.Defaults(),
-		HelloWorld:    helloworld.Settings{}.Defaults(),
-		IngredientUse: ingredientuse.Settings{}.Defaults(),
-		Menu:          menu.Settings{}.Defaults(),
-		Pantry:        pantry.Settings{}.Defaults(),
-		Pricing:       pricing.Settings{}.Defaults(),
-		Products:      products.Settings{}.Defaults(),
-		Providers:     providersservice.Settings{}.Defaults(),
-		Recipe:        recipe.Settings{}.Defaults(),
-		Recipes:       recipes.Settings{}.Defaults(),
-		ShoppingList:  shoppinglist.Settings{}.Defaults(),
-		ShoppingNeeds: shoppingneeds.Settings{}.Defaults(),
-		Version:       version.Settings{}.Defaults(),
+		Auth:                 auth.Settings{}.Defaults(),
+		Database:             database.Settings{}.Defaults(),
+		FrontEnd:             frontend.Settings{}.Defaults(),
+		AuthLogin:            session.Settings{}.Defaults(),
+		AuthLogout:           session.Settings{}.Defaults(),
+		AuthRefresh:          session.Settings{}.Defaults(),
+		BatchCooking:         batchcooking.Settings{}.Defaults(),
+		Cooking:              cooking.Settings{}.Defaults(),
+		Calendar:             calendar.Settings{}.Defaults(),
+		CalendarFeed:         calendar.Settings{}.Defaults(),
+		CalendarSubscription: calendar.Settings{}.Defaults(),
+		HelloWorld:           helloworld.Settings{}.Defaults(),
+		IngredientUse:        ingredientuse.Settings{}.Defaults(),
+		Menu:                 menu.Settings{}.Defaults(),
+		MenuCalendar:         calendar.Settings{}.Defaults(),
+		MenuCheck:            menucheck.Settings{}.Defaults(),
+		MenuCost:             menucost.Settings{}.Defaults(),
+		MenuSubstitutions:    substitutions.Settings{}.Defaults(),
+		Menus:                menus.Settings{}.Defaults(),
+		Pantry:               pantry.Settings{}.Defaults(),
+		PantryExpiring:       pantry.Settings{}.Defaults(),
+		PantryLedger:         pantry.Settings{}.Defaults(),
+		PantryItem:           pantry.Settings{}.Defaults(),
+		PantryStaples:        pantry.Settings{}.Defaults(),
+		PantryGroup:          pantry.Settings{}.Defaults(),
+		PantryCookable:       pantry.Settings{}.Defaults(),
+		Planner:              planner.Settings{}.Defaults(),
+		Pricing:              pricing.Settings{}.Defaults(),
+		Products:             products.Settings{}.Defaults(),
+		Providers:            providersservice.Settings{}.Defaults(),
+		Recipe:               recipe.Settings{}.Defaults(),
+		RecipeImport:         recipeimport.Settings{}.Defaults(),
+		RecipeLibrary:        recipelibrary.Settings{}.Defaults(),
+		RecipeRevisions:      reciperevisions.Settings{}.Defaults(),
+		RecipeSharing:        recipesharing.Settings{}.Defaults(),
+		RecipeSubstitutions:  substitutions.Settings{}.Defaults(),
+		Recipes:              recipes.Settings{}.Defaults(),
+		Search:               search.Settings{}.Defaults(),
+		ShoppingList:         shoppinglist.Settings{}.Defaults(),
+		ShoppingCheckout:     shoppinglist.Settings{}.Defaults(),
+		ShoppingNeeds:        shoppingneeds.Settings{}.Defaults(),
+		Version:              version.Settings{}.Defaults(),
 	}
 }
 
@@ -99,12 +161,15 @@ func New(ctx context.Context, logger logger.Logger, settings Settings) (*Manager
 	providers.Register(bonpreu.New(logger))
 	providers.Register(mercadona.New(logger))
 
-	db, err := database.New(ctx, logger, settings.Database)
+	rawDB, err := database.New(ctx, logger, settings.Database)
 	if err != nil {
 		cancel()
 		return nil, fmt.Errorf("could not load database: %v", err)
 	}
 
+	// All writes go through the indexed database to keep the search index up to date
+	db := index.NewIndexedDB(rawDB)
+
 	auth, err := auth.NewManager(ctx, settings.Auth, logger, db)
 	if err != nil {
 		cancel()
@@ -124,19 +189,46 @@ func New(ctx context.Context, logger logger.Logger, settings Settings) (*Manager
 		frontEnd:     frontend.New(settings.FrontEnd),
 	}
 
+	// Changes to shopping lists, including checkouts, are pushed to everyone following them
+	shoppingLists := shoppinglist.NewHub()
+
 	for _, s := range []HTTPService{
 		session.NewLogin(settings.AuthLogin, auth),
 		session.NewRefresh(settings.AuthRefresh, auth),
 		session.NewLogout(settings.AuthLogout, auth),
+		calendar.New(settings.Calendar, db, auth),
+		calendar.NewFeed(settings.CalendarFeed, db, auth),
+		calendar.NewSubscription(settings.CalendarSubscription, db),
 		helloworld.New(settings.HelloWorld),
 		ingredientuse.New(settings.IngredientUse, db, auth),
 		menu.New(settings.Menu, db, auth),
+		calendar.NewMenuExport(settings.MenuCalendar, db, auth),
+		menucheck.New(settings.MenuCheck, db, auth),
+		menucost.New(settings.MenuCost, db, auth),
+		batchcooking.New(settings.BatchCooking, db, auth),
+		cooking.New(settings.Cooking, db, auth),
+		substitutions.NewMenu(settings.MenuSubstitutions, db, auth),
+		menus.New(settings.Menus, db, auth),
 		pantry.New(settings.Pantry, db, auth),
+		pantry.NewExpiring(settings.PantryExpiring, db, auth),
+		pantry.NewLedger(settings.PantryLedger, db, auth),
+		pantry.NewItem(settings.PantryItem, db, auth),
+		pantry.NewStaples(settings.PantryStaples, db, auth),
+		pantry.NewGroup(settings.PantryGroup, db, auth),
+		pantry.NewCookable(settings.PantryCookable, db, auth),
+		planner.New(settings.Planner, db, auth),
 		products.New(settings.Products, db),
 		providersservice.New(settings.Providers),
 		recipe.New(settings.Recipe, db, auth),
+		recipeimport.New(settings.RecipeImport, db, auth),
+		recipelibrary.New(settings.RecipeLibrary, db, auth),
+		reciperevisions.New(settings.RecipeRevisions, db, auth),
+		recipesharing.New(settings.RecipeSharing, db, auth),
+		substitutions.NewRecipe(settings.RecipeSubstitutions, db, auth),
 		recipes.New(settings.Recipes, db, auth),
-		shoppinglist.New(settings.ShoppingList, db, auth),
+		search.New(settings.Search, db, auth),
+		shoppinglist.New(settings.ShoppingList, db, auth, shoppingLists),
+		shoppinglist.NewCheckout(settings.ShoppingCheckout, db, auth, shoppingLists),
 		shoppingneeds.New(settings.ShoppingNeeds, db, auth),
 		version.New(settings.Version),
 	} {
@@ -0,0 +1,271 @@
+package shoppinglist
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"time"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+)
+
+// CheckoutService takes the products bought from a shopping list into the pantry.
+//
+// Every checkout is recorded with the packs bought and the price paid for them, which
+// can optionally replace the price of the products.
+type CheckoutService struct {
+	settings Settings
+	db       database.DB
+	auth     auth.Getter
+	hub      *Hub
+	now      func() time.Time
+}
+
+func NewCheckout(settings Settings, db database.DB, auth auth.Getter, hub *Hub) *CheckoutService {
+	if !settings.Enable {
+		return nil
+	}
+
+	return &CheckoutService{
+		settings: settings,
+		db:       db,
+		auth:     auth,
+		hub:      hub,
+		now:      time.Now,
+	}
+}
+
+func (s CheckoutService) Name() string {
+	return "shopping-checkout"
+}
+
+func (s CheckoutService) Path() string {
+	return "/api/shopping-list/{menu}/{pantry}/checkout"
+}
+
+func (s CheckoutService) Enabled() bool {
+	return s.settings.Enable
+}
+
+func (s *CheckoutService) Handle(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return s.handleGet(log, w, r)
+	case http.MethodPost:
+		return s.handlePost(log, w, r)
+	default:
+		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+type purchasedMsg struct {
+	ProductID product.ID `json:"product_id"`
+	Name      string     `json:"name"`
+	Packs     int        `json:"packs"`
+	Units     float32    `json:"units"`
+	Price     float32    `json:"price"`
+	Cost      float32    `json:"cost"`
+}
+
+type purchaseMsg struct {
+	Date  time.Time      `json:"date"`
+	Items []purchasedMsg `json:"items"`
+	Spent float32        `json:"spent"`
+}
+
+func (s *CheckoutService) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "failed to get user ID: %v", err)
+	}
+
+	menu := r.PathValue("menu")
+	pantry := r.PathValue("pantry")
+
+	purchases, err := s.db.Purchases(user, menu, pantry)
+	if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to lookup purchases: %v", err)
+	}
+
+	var spent float32
+	out := make([]purchaseMsg, 0, len(purchases))
+	for _, p := range purchases {
+		msg := purchaseMsg{
+			Date:  p.Date,
+			Items: make([]purchasedMsg, 0, len(p.Items)),
+			Spent: p.Spent(),
+		}
+
+		for _, i := range p.Items {
+			prod, ok := getProduct(log, s.db, i.ProductID)
+			if !ok {
+				prod.ID = i.ProductID
+			}
+			msg.Items = append(msg.Items, newPurchasedMsg(prod, i))
+		}
+
+		spent += msg.Spent
+		out = append(out, msg)
+	}
+
+	if err := json.NewEncoder(w).Encode(map[string]any{
+		"menu":      menu,
+		"pantry":    pantry,
+		"purchases": out,
+		"spent":     spent,
+	}); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not encode response: %v", err)
+	}
+
+	return nil
+}
+
+type checkoutMsg struct {
+	Items []struct {
+		ProductID  product.ID    `json:"product_id"`
+		Packs      int           `json:"packs"`
+		Price      float32       `json:"price"`
+		BestBefore *dbtypes.Date `json:"best_before"`
+	} `json:"items"`
+
+	// UpdatePrices makes the prices paid the new prices of the products.
+	UpdatePrices bool `json:"update_prices"`
+}
+
+func (s *CheckoutService) handlePost(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateContentType(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "failed to get user ID: %v", err)
+	}
+
+	menu := r.PathValue("menu")
+
+	if _, err = s.db.LookupMenu(user, menu); errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "menu not found")
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to lookup menu: %v", err)
+	}
+
+	pantry, err := s.db.LookupPantry(user, r.PathValue("pantry"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "pantry not found")
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to lookup pantry: %v", err)
+	}
+
+	out, err := io.ReadAll(r.Body)
+	if err != nil {
+		return httputils.Error(http.StatusBadRequest, "failed to read request")
+	}
+	r.Body.Close()
+
+	var body checkoutMsg
+	if err := json.Unmarshal(out, &body); err != nil {
+		return httputils.Errorf(http.StatusBadRequest, "failed to unmarshal request: %v", err)
+	}
+
+	if len(body.Items) == 0 {
+		return httputils.Error(http.StatusBadRequest, "nothing was bought")
+	}
+
+	now := s.now()
+	today := dbtypes.NewDate(now)
+
+	purchase := dbtypes.Purchase{
+		User:   user,
+		Menu:   menu,
+		Pantry: pantry.Name,
+		Date:   now,
+		Items:  make([]dbtypes.PurchaseItem, 0, len(body.Items)),
+	}
+
+	lots := make([]dbtypes.PantryLot, 0, len(body.Items))
+	items := make([]purchasedMsg, 0, len(body.Items))
+	var estimated float32
+
+	for _, i := range body.Items {
+		if i.Packs <= 0 {
+			return httputils.Errorf(http.StatusBadRequest, "product %d has %d packs", i.ProductID, i.Packs)
+		} else if i.Price < 0 {
+			return httputils.Errorf(http.StatusBadRequest, "product %d has a price of %g", i.ProductID, i.Price)
+		} else if i.BestBefore != nil && i.BestBefore.Compare(today) < 0 {
+			return httputils.Errorf(http.StatusBadRequest, "product %d was bought past its best-before date", i.ProductID)
+		}
+
+		prod, err := s.db.LookupProduct(i.ProductID)
+		if errors.Is(err, fs.ErrNotExist) {
+			return httputils.Errorf(http.StatusBadRequest, "product %d does not exist", i.ProductID)
+		} else if err != nil {
+			return httputils.Errorf(http.StatusInternalServerError, "failed to lookup product %d: %v", i.ProductID, err)
+		}
+
+		item := dbtypes.PurchaseItem{ProductID: prod.ID, Packs: i.Packs, Price: i.Price}
+		purchase.Items = append(purchase.Items, item)
+		items = append(items, newPurchasedMsg(prod, item))
+		estimated += float32(i.Packs) * prod.Price
+
+		lots = append(lots, dbtypes.PantryLot{
+			ProductID:  prod.ID,
+			Amount:     float32(i.Packs) * prod.BatchSize,
+			Purchased:  &today,
+			BestBefore: i.BestBefore,
+		})
+	}
+
+	// The pantry is restocked from its latest contents, so that concurrent changes to it are kept
+	list, err := s.db.AddPurchase(purchase, lots, body.UpdatePrices)
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "pantry not found")
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to store purchase: %v", err)
+	}
+
+	s.hub.publish(user, menu, pantry.Name, list.Contents)
+
+	log.Debugf("Checked out %d items into pantry %s", len(purchase.Items), pantry.Name)
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(map[string]any{
+		"menu":           menu,
+		"pantry":         pantry.Name,
+		"date":           purchase.Date,
+		"items":          items,
+		"spent":          purchase.Spent(),
+		"estimated":      estimated,
+		"prices_updated": body.UpdatePrices,
+	}); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not encode response: %v", err)
+	}
+
+	return nil
+}
+
+func newPurchasedMsg(prod product.Product, item dbtypes.PurchaseItem) purchasedMsg {
+	return purchasedMsg{
+		ProductID: item.ProductID,
+		Name:      prod.Name,
+		Packs:     item.Packs,
+		Units:     float32(item.Packs) * prod.BatchSize,
+		Price:     item.Price,
+		Cost:      float32(item.Packs) * item.Price,
+	}
+}
@@ -0,0 +1,111 @@
+package shoppinglist_test
+
+import (
+	"io/fs"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/shoppinglist"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckoutEndpoint(t *testing.T) {
+	t.Parallel()
+
+	unchanged := []recipe.Ingredient{{ProductID: 3, Amount: 5}, {ProductID: 2, Amount: 3}}
+	restocked := []recipe.Ingredient{{ProductID: 3, Amount: 5}, {ProductID: 2, Amount: 10}, {ProductID: 1, Amount: 3}}
+
+	testCases := map[string]struct {
+		method string
+		path   string
+
+		wantCode int
+		wantBody string
+		check    func(*testing.T, database.DB)
+	}{
+		"GET":                {method: "GET", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET nothing bought": {method: "GET", wantCode: http.StatusOK, wantBody: "!golden"},
+
+		"POST":                  {method: "POST", wantCode: http.StatusCreated, wantBody: "!golden", check: wantRestock(3, restocked, 2.99)},
+		"POST update prices":    {method: "POST", wantCode: http.StatusCreated, wantBody: "!golden", check: wantRestock(1, restocked, 2.5)},
+		"POST nothing bought":   {method: "POST", wantCode: http.StatusBadRequest, check: wantRestock(0, unchanged, 2.99)},
+		"POST invalid packs":    {method: "POST", wantCode: http.StatusBadRequest, check: wantRestock(0, unchanged, 2.99)},
+		"POST unknown product":  {method: "POST", wantCode: http.StatusBadRequest, check: wantRestock(0, unchanged, 2.99)},
+		"POST expired":          {method: "POST", wantCode: http.StatusBadRequest, check: wantRestock(0, unchanged, 2.99)},
+		"POST menu not found":   {method: "POST", path: "/api/shopping-list/nonexistent/testpantry1/checkout", wantCode: http.StatusNotFound},
+		"POST pantry not found": {method: "POST", path: "/api/shopping-list/testmenu1/nonexistent/checkout", wantCode: http.StatusNotFound},
+
+		"PUT": {method: "PUT", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db := testutils.Database(t, testutils.FixturePath(t, "database"))
+
+			sv := shoppinglist.NewCheckout(shoppinglist.Settings{}.Defaults(), db, testutils.MockAuthGetter(), shoppinglist.NewHub())
+			require.True(t, sv.Enabled())
+			sv.SetClock(time.Date(2024, time.April, 1, 12, 0, 0, 0, time.UTC))
+
+			fixture := testutils.FixturePath(t, "message", "body.json")
+			out, err := os.ReadFile(fixture)
+			if err != nil {
+				require.ErrorIs(t, err, os.ErrNotExist)
+				out = nil
+				t.Logf("No golden file found at %s", fixture)
+			}
+
+			if tc.path == "" {
+				tc.path = "/api/shopping-list/testmenu1/testpantry1/checkout"
+			}
+
+			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
+				ServePath: sv.Path(),
+				ReqPath:   tc.path,
+				Endpoint:  sv.Handle,
+				Method:    tc.method,
+				Body:      string(out),
+				WantCode:  tc.wantCode,
+				WantBody:  tc.wantBody,
+			})
+
+			if tc.check != nil {
+				tc.check(t, db)
+			}
+		})
+	}
+}
+
+// wantRestock returns a check that the menu and pantry have the given number of purchases,
+// that the pantry has the given contents, and that bananas have the given price.
+// The shopping list is expected to be cleared if anything was bought.
+func wantRestock(purchases int, contents []recipe.Ingredient, bananaPrice float32) func(*testing.T, database.DB) {
+	return func(t *testing.T, db database.DB) {
+		t.Helper()
+
+		got, err := db.Purchases("test-user-123", "testmenu1", "testpantry1")
+		require.NoError(t, err)
+		require.Len(t, got, purchases, "Purchases do not match")
+
+		p, err := db.LookupPantry("test-user-123", "testpantry1")
+		require.NoError(t, err)
+		require.ElementsMatch(t, contents, p.Contents, "Pantry contents do not match")
+
+		_, err = db.LookupShoppingList("test-user-123", "testmenu1", "testpantry1")
+		if purchases > 0 {
+			require.ErrorIs(t, err, fs.ErrNotExist, "Shopping list should have been cleared")
+		} else {
+			require.NoError(t, err, "Shopping list should not have been cleared")
+		}
+
+		banana, err := db.LookupProduct(2)
+		require.NoError(t, err)
+		require.InDelta(t, bananaPrice, banana.Price, 1e-6, "Price of bananas does not match")
+	}
+}
@@ -0,0 +1,163 @@
+package shoppinglist
+
+import (
+	"encoding/csv"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+)
+
+// export is a shopping list ready to be rendered for people rather than for the frontend.
+// Products that need no packs are left out, since there is nothing to buy.
+type export struct {
+	Menu     string
+	Pantries []string
+	Items    []shoppingListItem
+
+	// Total is the cost of every item, and Pending the cost of the ones not done yet.
+	Total   float32
+	Pending float32
+}
+
+func newExport(menu string, pantries []string, items []shoppingListItem) export {
+	e := export{
+		Menu:     menu,
+		Pantries: pantries,
+		Items:    make([]shoppingListItem, 0, len(items)),
+	}
+
+	for _, it := range items {
+		if it.Packs <= 0 {
+			continue
+		}
+
+		e.Items = append(e.Items, it)
+		e.Total += it.Cost
+		if !it.Done {
+			e.Pending += it.Cost
+		}
+	}
+
+	return e
+}
+
+var funcs = map[string]any{
+	"price": func(x float32) string { return fmt.Sprintf("%.2f €", x) },
+	"join":  strings.Join,
+	"packs": func(n int) string {
+		if n == 1 {
+			return "1 pack"
+		}
+		return fmt.Sprintf("%d packs", n)
+	},
+	"restock": func(reason string) bool { return reason == reasonRestock },
+}
+
+var text = texttemplate.Must(texttemplate.New("text").Funcs(funcs).Parse(`Shopping list for {{ .Menu }} ({{ join .Pantries ", " }})
+
+{{ range .Items -}}
+[{{ if .Done }}x{{ else }} {{ end }}] {{ .Name }}: {{ packs .Packs }}, {{ price .Cost }}{{ if restock .Reason }} (restock){{ end }}
+{{ end }}
+Total: {{ price .Total }} ({{ price .Pending }} left to buy)
+`))
+
+var markdown = texttemplate.Must(texttemplate.New("markdown").Funcs(funcs).Parse(`# Shopping list for {{ .Menu }}
+
+Pantries: {{ join .Pantries ", " }}
+
+{{ range .Items -}}
+- [{{ if .Done }}x{{ else }} {{ end }}] **{{ .Name }}**: {{ packs .Packs }}, {{ price .Cost }}{{ if restock .Reason }} _(restock)_{{ end }}
+{{ end }}
+**Total:** {{ price .Total }} ({{ price .Pending }} left to buy)
+`))
+
+var html = htmltemplate.Must(htmltemplate.New("html").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Shopping list for {{ .Menu }}</title>
+<style>
+body { font-family: sans-serif; max-width: 40em; margin: 2em auto; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 1em; }
+th, td { border-bottom: 1px solid #ccc; padding: 0.3em; text-align: left; }
+td.number, th.number { text-align: right; }
+tr.done td { text-decoration: line-through; color: #888; }
+@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h1>Shopping list for {{ .Menu }}</h1>
+<p>Pantries: {{ join .Pantries ", " }}</p>
+<table>
+<tr><th></th><th>Product</th><th class="number">Packs</th><th class="number">Cost</th></tr>
+{{- range .Items }}
+<tr{{ if .Done }} class="done"{{ end }}><td><input type="checkbox"{{ if .Done }} checked{{ end }}></td><td>{{ .Name }}{{ if restock .Reason }} <em>(restock)</em>{{ end }}</td><td class="number">{{ .Packs }}</td><td class="number">{{ price .Cost }}</td></tr>
+{{- end }}
+</table>
+<p><strong>Total:</strong> {{ price .Total }} ({{ price .Pending }} left to buy)</p>
+</body>
+</html>
+`))
+
+// WriteText renders the list as plain text, to paste into a chat.
+func (e export) WriteText(w io.Writer) error {
+	return text.Execute(w, e)
+}
+
+// WriteMarkdown renders the list as a Markdown task list.
+func (e export) WriteMarkdown(w io.Writer) error {
+	return markdown.Execute(w, e)
+}
+
+// WriteHTML renders the list as a standalone HTML page, ready to print.
+func (e export) WriteHTML(w io.Writer) error {
+	return html.Execute(w, e)
+}
+
+// formulaPrefixes are the characters that make spreadsheets evaluate a cell as a formula.
+const formulaPrefixes = "=+-@"
+
+// csvCell quotes cells that a spreadsheet would evaluate as a formula, so that product
+// names cannot run code when the list is opened.
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune(formulaPrefixes, rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// WriteCSV renders the list as a spreadsheet, with one row per product and a final row with the totals.
+func (e export) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	price := func(x float32) string { return strconv.FormatFloat(float64(x), 'f', 2, 32) }
+
+	rows := [][]string{{"done", "product_id", "name", "units", "packs", "cost", "reason"}}
+	for _, it := range e.Items {
+		rows = append(rows, []string{
+			strconv.FormatBool(it.Done),
+			fmt.Sprint(it.ProductID),
+			it.Name,
+			strconv.FormatFloat(float64(it.Units), 'f', -1, 32),
+			strconv.Itoa(it.Packs),
+			price(it.Cost),
+			it.Reason,
+		})
+	}
+	rows = append(rows, []string{"", "", "Total", "", "", price(e.Total), ""})
+
+	for _, row := range rows {
+		for i := range row {
+			row[i] = csvCell(row[i])
+		}
+	}
+
+	if err := cw.WriteAll(rows); err != nil {
+		return fmt.Errorf("could not write CSV: %v", err)
+	}
+
+	return nil
+}
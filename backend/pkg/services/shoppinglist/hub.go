@@ -0,0 +1,70 @@
+package shoppinglist
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+)
+
+// Hub forwards the changes to shopping lists to the clients following them, so that
+// people shopping together see what the others have already picked up.
+//
+// Every update carries the whole list of products that are done, hence a client that
+// falls behind only needs the latest one and older updates are dropped.
+type Hub struct {
+	mu   sync.Mutex
+	subs map[listKey]map[chan []product.ID]struct{}
+}
+
+type listKey struct {
+	user, menu, pantry string
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subs: make(map[listKey]map[chan []product.ID]struct{}),
+	}
+}
+
+// subscribe returns a channel with the updates to a shopping list, and a function to stop receiving them.
+func (h *Hub) subscribe(user, menu, pantry string) (<-chan []product.ID, func()) {
+	k := listKey{user: user, menu: menu, pantry: pantry}
+	ch := make(chan []product.ID, 1)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.subs[k] == nil {
+		h.subs[k] = make(map[chan []product.ID]struct{})
+	}
+	h.subs[k][ch] = struct{}{}
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		delete(h.subs[k], ch)
+		if len(h.subs[k]) == 0 {
+			delete(h.subs, k)
+		}
+	}
+}
+
+// publish sends the products that are done in a shopping list to everyone following it. It never blocks.
+func (h *Hub) publish(user, menu, pantry string, done []product.ID) {
+	done = slices.Clone(done)
+	slices.Sort(done)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subs[listKey{user: user, menu: menu, pantry: pantry}] {
+		// Replace the pending update, if any, with this one
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- done
+	}
+}
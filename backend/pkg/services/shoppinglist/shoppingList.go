@@ -9,40 +9,56 @@ import (
 	"math"
 	"net/http"
 	"slices"
+	"time"
 
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/menucheck"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/menuneeds"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/utils"
 )
 
+// Service computes the shopping list of a menu, and keeps track of the products that are done.
+//
+// Clients that ask for text/event-stream receive the products that are done every time
+// someone changes them, so that several people can shop from the same list.
 type Service struct {
 	settings Settings
 
 	db   database.DB
 	auth auth.Getter
+	hub  *Hub
 }
 
 type Settings struct {
 	Enable bool
+
+	// MaxPriceAge is how old a price can be before it is reported as stale.
+	MaxPriceAge time.Duration
+
+	// KeepAlive is how often an event stream sends a comment, to keep idle connections open.
+	KeepAlive time.Duration
 }
 
 func (Settings) Defaults() Settings {
 	return Settings{
-		Enable: true,
+		Enable:      true,
+		MaxPriceAge: menucheck.DefaultMaxPriceAge,
+		KeepAlive:   30 * time.Second,
 	}
 }
 
-func New(settings Settings, db database.DB, auth auth.Getter) *Service {
+func New(settings Settings, db database.DB, auth auth.Getter, hub *Hub) *Service {
 	return &Service{
 		settings: settings,
 		db:       db,
 		auth:     auth,
+		hub:      hub,
 	}
 }
 
@@ -64,6 +80,8 @@ func (s *Service) Handle(log logger.Logger, w http.ResponseWriter, r *http.Reque
 		return s.handleGet(log, w, r)
 	case http.MethodPut:
 		return s.handlePut(log, w, r)
+	case http.MethodPatch:
+		return s.handlePatch(log, w, r)
 	case http.MethodDelete:
 		return s.handleDelete(log, w, r)
 	default:
@@ -72,7 +90,9 @@ func (s *Service) Handle(log logger.Logger, w http.ResponseWriter, r *http.Reque
 }
 
 func (s *Service) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
-	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+	mediaType, err := httputils.Negotiate(r, httputils.MediaTypeJSON, httputils.MediaTypeText,
+		httputils.MediaTypeMarkdown, httputils.MediaTypeCSV, httputils.MediaTypeHTML, httputils.MediaTypeEventStream)
+	if err != nil {
 		return err
 	}
 
@@ -91,13 +111,20 @@ func (s *Service) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Re
 		return httputils.Errorf(http.StatusInternalServerError, "failed to lookup menu: %v", err)
 	}
 
-	p, err := s.db.LookupPantry(user, pantry)
+	// The pantry in the path can be combined with more pantries, or a group of them.
+	// Its shopping list is the one that keeps track of the products that are done.
+	names := append([]string{pantry}, r.URL.Query()["pantry"]...)
+	pantries, err := menuneeds.LookupPantries(s.db, user, names, r.URL.Query().Get("group"))
 	if errors.Is(err, fs.ErrNotExist) {
-		return httputils.Errorf(http.StatusNotFound, "pantry not found")
+		return httputils.Errorf(http.StatusNotFound, "%v", err)
 	} else if err != nil {
 		return httputils.Errorf(http.StatusInternalServerError, "failed to lookup pantry: %v", err)
 	}
 
+	if mediaType == httputils.MediaTypeEventStream {
+		return s.stream(log, w, r, user, menu, pantry)
+	}
+
 	var done []product.ID
 	if D, err := s.db.LookupShoppingList(user, menu, pantry); errors.Is(err, fs.ErrNotExist) {
 		done = make([]product.ID, 0)
@@ -107,15 +134,59 @@ func (s *Service) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Re
 		done = D.Contents
 	}
 
-	sl := s.computeShoppingList(log, m, p, done)
+	// Every pantry keeps its own minimum stock of its staples
+	var staples []dbtypes.Staple
+	for _, p := range pantries {
+		st, err := s.db.PantryStaples(user, p.Name)
+		if err != nil {
+			return httputils.Errorf(http.StatusInternalServerError, "failed to lookup staples of pantry %s: %v", p.Name, err)
+		}
+
+		for _, add := range st.Staples {
+			if i := slices.IndexFunc(staples, func(x dbtypes.Staple) bool { return x.ProductID == add.ProductID }); i != -1 {
+				staples[i].Minimum += add.Minimum
+			} else {
+				staples = append(staples, add)
+			}
+		}
+	}
+
+	sl := s.computeShoppingList(log, m, pantries, staples, done)
 	log.Debugf("Responding with shopping list with %d items", len(sl))
 
-	if err := json.NewEncoder(w).Encode(map[string]any{
-		"menu":   menu,
-		"pantry": pantry,
-		"items":  sl,
-	}); err != nil {
-		return httputils.Errorf(http.StatusInternalServerError, "could not encode response: %v", err)
+	combined := make([]string, 0, len(pantries))
+	for _, p := range pantries {
+		combined = append(combined, p.Name)
+	}
+
+	w.Header().Set("Content-Type", mediaType.String()+"; charset=utf-8")
+
+	switch mediaType {
+	case httputils.MediaTypeText:
+		err = newExport(menu, combined, sl).WriteText(w)
+	case httputils.MediaTypeMarkdown:
+		err = newExport(menu, combined, sl).WriteMarkdown(w)
+	case httputils.MediaTypeCSV:
+		err = newExport(menu, combined, sl).WriteCSV(w)
+	case httputils.MediaTypeHTML:
+		err = newExport(menu, combined, sl).WriteHTML(w)
+	default:
+		warnings := menucheck.Check(s.db, m, menucheck.Options{
+			MaxPriceAge: s.settings.MaxPriceAge,
+			Now:         time.Now(),
+		})
+
+		err = json.NewEncoder(w).Encode(map[string]any{
+			"menu":     menu,
+			"pantry":   pantry,
+			"pantries": combined,
+			"items":    sl,
+			"warnings": warnings,
+		})
+	}
+
+	if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not write response: %v", err)
 	}
 
 	return nil
@@ -166,10 +237,82 @@ func (s *Service) handlePut(_ logger.Logger, w http.ResponseWriter, r *http.Requ
 		return httputils.Errorf(http.StatusInternalServerError, "failed to store shopping list: %v", err)
 	}
 
+	s.hub.publish(user, menu, pantry, sl.Contents)
+
 	w.WriteHeader(http.StatusCreated)
 	return nil
 }
 
+// handlePatch marks single products as done or not done. Unlike a PUT, it keeps the changes
+// that other people made to the rest of the list in the meantime.
+func (s *Service) handlePatch(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateContentType(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "failed to get user ID: %v", err)
+	}
+
+	menu := r.PathValue("menu")
+	pantry := r.PathValue("pantry")
+
+	if _, err = s.db.LookupMenu(user, menu); errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "menu not found")
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to lookup menu: %v", err)
+	}
+
+	if _, err = s.db.LookupPantry(user, pantry); errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "pantry not found")
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to lookup pantry: %v", err)
+	}
+
+	out, err := io.ReadAll(r.Body)
+	if err != nil {
+		return httputils.Error(http.StatusBadRequest, "failed to read request")
+	}
+	r.Body.Close()
+
+	var body struct {
+		Ops []dbtypes.ShoppingListOp `json:"ops"`
+	}
+
+	if err := json.Unmarshal(out, &body); err != nil {
+		return httputils.Errorf(http.StatusBadRequest, "failed to unmarshal request: %v", err)
+	} else if len(body.Ops) == 0 {
+		return httputils.Error(http.StatusBadRequest, "no operations to apply")
+	}
+
+	for _, o := range body.Ops {
+		if _, err := s.db.LookupProduct(o.ProductID); errors.Is(err, fs.ErrNotExist) {
+			return httputils.Errorf(http.StatusBadRequest, "product %d does not exist", o.ProductID)
+		} else if err != nil {
+			return httputils.Errorf(http.StatusInternalServerError, "could not lookup product %d: %v", o.ProductID, err)
+		}
+	}
+
+	sl, err := s.db.UpdateShoppingList(user, menu, pantry, body.Ops)
+	if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to update shopping list: %v", err)
+	}
+
+	s.hub.publish(user, menu, pantry, sl.Contents)
+	log.Debugf("Applied %d operations to shopping list, %d products are done", len(body.Ops), len(sl.Contents))
+
+	if err := json.NewEncoder(w).Encode(newDoneMsg(menu, pantry, sl.Contents)); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not write response: %v", err)
+	}
+
+	return nil
+}
+
 func (s *Service) handleDelete(_ logger.Logger, w http.ResponseWriter, r *http.Request) error {
 	user, err := s.auth.GetUserID(r)
 	if err != nil {
@@ -183,10 +326,21 @@ func (s *Service) handleDelete(_ logger.Logger, w http.ResponseWriter, r *http.R
 		return httputils.Errorf(http.StatusInternalServerError, "failed to delete shopping list: %v", err)
 	}
 
+	s.hub.publish(user, menu, pantry, nil)
+
 	w.WriteHeader(http.StatusNoContent)
 	return nil
 }
 
+// Why a product is in the shopping list.
+const (
+	// reasonMenu is a product the pantry does not have enough of to cook the menu.
+	reasonMenu = "menu"
+
+	// reasonRestock is a staple that would fall below its minimum stock, but is not short for the menu.
+	reasonRestock = "restock"
+)
+
 type shoppingListItem struct {
 	ProductID product.ID `json:"product_id"`
 	Name      string     `json:"name"`
@@ -194,16 +348,62 @@ type shoppingListItem struct {
 	Units     float32    `json:"units"`
 	Packs     int        `json:"packs"`
 	Cost      float32    `json:"cost"`
+	Reason    string     `json:"reason"`
+
+	// Pantry contains the lots of the pantry used for this product, oldest first.
+	Pantry []dbtypes.PantryLot `json:"pantry,omitempty"`
+
+	// CoveredBy is how much of the product each pantry provides.
+	CoveredBy []dbtypes.PantryCover `json:"covered_by,omitempty"`
 }
 
-func (s *Service) computeShoppingList(log logger.Logger, menu dbtypes.Menu, pantry dbtypes.Pantry, done []product.ID) []shoppingListItem {
+// computeShoppingList lists what to buy to cook the menu from the stock of the pantries combined, plus
+// whatever is needed to keep the staples at their minimum stock once the menu is cooked.
+func (s *Service) computeShoppingList(log logger.Logger, menu dbtypes.Menu, pantries []dbtypes.Pantry, staples []dbtypes.Staple, done []product.ID) []shoppingListItem {
 	need := menuneeds.ComputeNeeds(log, s.db, menu)
 
 	slices.SortFunc(need, func(i, j recipe.Ingredient) int { return cmp.Compare(i.ProductID, j.ProductID) })
-	slices.SortFunc(pantry.Contents, func(i, j recipe.Ingredient) int { return cmp.Compare(i.ProductID, j.ProductID) })
 	slices.Sort(done)
 
-	tmpList := menuneeds.Subtract(need, pantry.Contents)
+	// Lots that will have expired by the time the menu starts cannot be used
+	on := menu.StartDate
+	if on == nil {
+		today := dbtypes.NewDate(time.Now())
+		on = &today
+	}
+
+	// Products short for the menu alone are bought for the menu, the rest only to restock
+	stock := dbtypes.CombinedStock(pantries)
+	menuMissing, used := dbtypes.Consume(need, stock, on)
+
+	reasons := make(map[product.ID]string, len(menuMissing))
+	for _, m := range menuMissing {
+		if m.Amount > 0 {
+			reasons[m.ProductID] = reasonMenu
+		}
+	}
+
+	tmpList, _ := dbtypes.Consume(menuneeds.AddStaples(need, staples), stock, on)
+
+	for _, staple := range staples {
+		if _, ok := reasons[staple.ProductID]; !ok {
+			reasons[staple.ProductID] = reasonRestock
+		}
+	}
+
+	// Staples that are well stocked are not needed for anything
+	tmpList = slices.DeleteFunc(tmpList, func(i recipe.Ingredient) bool {
+		return i.Amount <= 0 && reasons[i.ProductID] == reasonRestock && !slices.ContainsFunc(need, func(n recipe.Ingredient) bool {
+			return n.ProductID == i.ProductID
+		})
+	})
+
+	lots := make(map[product.ID][]dbtypes.PantryLot)
+	for _, l := range used {
+		lots[l.ProductID] = append(lots[l.ProductID], l)
+	}
+
+	covers := dbtypes.CoveredBy(pantries, used)
 
 	list := make([]shoppingListItem, 0, len(tmpList))
 	utils.Zipper(tmpList, done,
@@ -212,14 +412,14 @@ func (s *Service) computeShoppingList(log logger.Logger, menu dbtypes.Menu, pant
 			// This product is needed but not marked done
 			p, ok := getProduct(log, s.db, a.ProductID)
 			if ok {
-				list = append(list, newItem(p, a.Amount, false))
+				list = append(list, newItem(p, a.Amount, false, lots[p.ID], covers[p.ID], reasons[p.ID]))
 			}
 		},
 		func(a recipe.Ingredient, id product.ID) {
 			// This product is needed and marked done in the DB
 			p, ok := getProduct(log, s.db, a.ProductID)
 			if ok {
-				list = append(list, newItem(p, a.Amount, true))
+				list = append(list, newItem(p, a.Amount, true, lots[p.ID], covers[p.ID], reasons[p.ID]))
 			}
 		},
 		func(id product.ID) {
@@ -229,9 +429,13 @@ func (s *Service) computeShoppingList(log logger.Logger, menu dbtypes.Menu, pant
 	return list
 }
 
-func newItem(prod product.Product, units float32, isDone bool) shoppingListItem {
+func newItem(prod product.Product, units float32, isDone bool, lots []dbtypes.PantryLot, covers []dbtypes.PantryCover, reason string) shoppingListItem {
 	packs := int(math.Ceil(float64(units / prod.BatchSize)))
 
+	if reason == "" {
+		reason = reasonMenu
+	}
+
 	return shoppingListItem{
 		ProductID: prod.ID,
 		Name:      prod.Name,
@@ -239,6 +443,9 @@ func newItem(prod product.Product, units float32, isDone bool) shoppingListItem
 		Packs:     packs,
 		Cost:      float32(packs) * prod.Price,
 		Done:      isDone,
+		Reason:    reason,
+		Pantry:    lots,
+		CoveredBy: covers,
 	}
 }
 
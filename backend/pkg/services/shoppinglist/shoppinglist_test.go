@@ -1,10 +1,16 @@
 package shoppinglist_test
 
 import (
+	"bufio"
+	"context"
 	"net/http"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers/blank"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/shoppinglist"
@@ -22,15 +28,34 @@ func TestShoppingEndpoint(t *testing.T) {
 
 	testCases := map[string]struct {
 		method string
+		query  string
+		accept string
 
-		wantCode int
-		wantBody string
+		wantCode   int
+		wantBody   string
+		goldenFile string
+		check      func(*testing.T, database.DB)
 	}{
-		"GET":    {method: "GET", wantCode: http.StatusOK, wantBody: "!golden"},
-		"PUT":    {method: "PUT", wantCode: http.StatusCreated},
-		"DELETE": {method: "DELETE", wantCode: http.StatusNoContent},
-		"PATCH":  {method: "PATCH", wantCode: http.StatusMethodNotAllowed},
-		"POST":   {method: "POST", wantCode: http.StatusMethodNotAllowed},
+		"GET":                   {method: "GET", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET with lots":         {method: "GET", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET with staples":      {method: "GET", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET with pantries":     {method: "GET", query: "?pantry=freezer", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET with group":        {method: "GET", query: "?group=home", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET pantry not found":  {method: "GET", query: "?pantry=cellar", wantCode: http.StatusNotFound},
+		"GET group not found":   {method: "GET", query: "?group=cellar", wantCode: http.StatusNotFound},
+		"GET text":              {method: "GET", accept: "text/plain", wantCode: http.StatusOK, wantBody: "!golden", goldenFile: "http_response.txt"},
+		"GET Markdown":          {method: "GET", accept: "text/markdown", wantCode: http.StatusOK, wantBody: "!golden", goldenFile: "http_response.md"},
+		"GET CSV":               {method: "GET", accept: "text/csv", wantCode: http.StatusOK, wantBody: "!golden", goldenFile: "http_response.csv"},
+		"GET CSV formulas":      {method: "GET", accept: "text/csv", wantCode: http.StatusOK, wantBody: "!golden", goldenFile: "http_response.csv"},
+		"GET HTML":              {method: "GET", accept: "text/html", wantCode: http.StatusOK, wantBody: "!golden", goldenFile: "http_response.html"},
+		"GET unacceptable":      {method: "GET", accept: "application/xml", wantCode: http.StatusNotAcceptable},
+		"PUT":                   {method: "PUT", wantCode: http.StatusCreated},
+		"DELETE":                {method: "DELETE", wantCode: http.StatusNoContent},
+		"PATCH":                 {method: "PATCH", wantCode: http.StatusOK, wantBody: "!golden", check: wantDone(1)},
+		"PATCH no operations":   {method: "PATCH", wantCode: http.StatusBadRequest, check: wantDone(2)},
+		"PATCH unknown product": {method: "PATCH", wantCode: http.StatusBadRequest, check: wantDone(2)},
+		"PATCH menu not found":  {method: "PATCH", wantCode: http.StatusNotFound},
+		"POST":                  {method: "POST", wantCode: http.StatusMethodNotAllowed},
 	}
 
 	for name, tc := range testCases {
@@ -39,7 +64,7 @@ func TestShoppingEndpoint(t *testing.T) {
 
 			db := testutils.Database(t, testutils.FixturePath(t, "database"))
 
-			sv := shoppinglist.New(shoppinglist.Settings{}.Defaults(), db, testutils.MockAuthGetter())
+			sv := shoppinglist.New(shoppinglist.Settings{}.Defaults(), db, testutils.MockAuthGetter(), shoppinglist.NewHub())
 			require.True(t, sv.Enabled())
 
 			fixture := testutils.FixturePath(t, "message", "body.json")
@@ -51,14 +76,101 @@ func TestShoppingEndpoint(t *testing.T) {
 			}
 
 			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
-				ServePath: sv.Path(),
-				ReqPath:   "/api/shopping-list/testmenu1/testpantry1",
-				Endpoint:  sv.Handle,
-				Method:    tc.method,
-				Body:      string(out),
-				WantCode:  tc.wantCode,
-				WantBody:  tc.wantBody,
+				ServePath:  sv.Path(),
+				ReqPath:    "/api/shopping-list/testmenu1/testpantry1" + tc.query,
+				Endpoint:   sv.Handle,
+				Method:     tc.method,
+				Body:       string(out),
+				Accept:     tc.accept,
+				WantCode:   tc.wantCode,
+				WantBody:   tc.wantBody,
+				GoldenFile: tc.goldenFile,
 			})
+
+			if tc.check != nil {
+				tc.check(t, db)
+			}
 		})
 	}
 }
+
+func TestShoppingStream(t *testing.T) {
+	t.Parallel()
+
+	db := testutils.Database(t, testutils.FixturePath(t, "database"))
+
+	sv := shoppinglist.New(shoppinglist.Settings{}.Defaults(), db, testutils.MockAuthGetter(), shoppinglist.NewHub())
+	require.True(t, sv.Enabled())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr, stop := testutils.HTTPServer(ctx, t, sv.Path(), sv.Handle)
+	defer stop()
+
+	url := "http://" + addr + "/api/shopping-list/testmenu1/testpantry1"
+
+	// The stream must be closed before the server can stop
+	streamCtx, closeStream := context.WithTimeout(ctx, 10*time.Second)
+	defer closeStream()
+
+	req := testutils.NewRequest(t, http.MethodGet, url, "").WithContext(streamCtx)
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := (&http.Client{}).Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := bufio.NewReader(resp.Body)
+	require.JSONEq(t, `{"menu":"testmenu1","pantry":"testpantry1","done":[2]}`, nextEvent(t, events), "Stream should start with the current list")
+
+	patch := testutils.MakeRequest(t, http.MethodPatch, url, `{"ops":[{"product_id":1,"done":true}]}`)
+	patch.Body.Close()
+	require.Equal(t, http.StatusOK, patch.StatusCode)
+	require.JSONEq(t, `{"menu":"testmenu1","pantry":"testpantry1","done":[1,2]}`, nextEvent(t, events), "Stream should receive the toggled product")
+
+	put := testutils.MakeRequest(t, http.MethodPut, url, `[2]`)
+	put.Body.Close()
+	require.Equal(t, http.StatusCreated, put.StatusCode)
+	require.JSONEq(t, `{"menu":"testmenu1","pantry":"testpantry1","done":[2]}`, nextEvent(t, events), "Stream should receive the replaced list")
+
+	del := testutils.MakeRequest(t, http.MethodDelete, url, "")
+	del.Body.Close()
+	require.Equal(t, http.StatusNoContent, del.StatusCode)
+	require.JSONEq(t, `{"menu":"testmenu1","pantry":"testpantry1","done":[]}`, nextEvent(t, events), "Stream should receive the deleted list")
+}
+
+// nextEvent reads the data of the next event in the stream, skipping comments.
+func nextEvent(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+
+	var event, data string
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err, "Could not read event")
+
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && data != "":
+			require.Equal(t, "done", event, "Unexpected event type")
+			return data
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func wantDone(done ...product.ID) func(*testing.T, database.DB) {
+	return func(t *testing.T, db database.DB) {
+		t.Helper()
+
+		sl, err := db.LookupShoppingList("test-user-123", "testmenu1", "testpantry1")
+		require.NoError(t, err)
+		require.ElementsMatch(t, done, sl.Contents, "Products marked done do not match")
+	}
+}
@@ -0,0 +1,111 @@
+package shoppinglist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+)
+
+// doneMsg is the state of a shopping list that is shared between the people using it.
+type doneMsg struct {
+	Menu   string       `json:"menu"`
+	Pantry string       `json:"pantry"`
+	Done   []product.ID `json:"done"`
+}
+
+func newDoneMsg(menu, pantry string, done []product.ID) doneMsg {
+	done = slices.Clone(done)
+	if done == nil {
+		done = make([]product.ID, 0)
+	}
+	slices.Sort(done)
+
+	return doneMsg{
+		Menu:   menu,
+		Pantry: pantry,
+		Done:   done,
+	}
+}
+
+// stream sends the products that are done as server-sent events: first the current ones,
+// and then again every time they change, until the client goes away or the server stops.
+func (s *Service) stream(log logger.Logger, w http.ResponseWriter, r *http.Request, user, menu, pantry string) error {
+	rc := http.NewResponseController(w)
+
+	// The write timeout of the server is meant for ordinary requests, not for streams
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return httputils.Errorf(http.StatusInternalServerError, "could not lift the write deadline: %v", err)
+	}
+
+	// Subscribe before reading the list, so that no change is missed in between
+	updates, unsubscribe := s.hub.subscribe(user, menu, pantry)
+	defer unsubscribe()
+
+	// A nil list means that there is nothing new to send
+	done := make([]product.ID, 0)
+	if sl, err := s.db.LookupShoppingList(user, menu, pantry); err == nil {
+		done = sl.Contents
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to lookup shopping list: %v", err)
+	}
+
+	w.Header().Set("Content-Type", httputils.MediaTypeEventStream.String())
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	keepAlive := time.NewTicker(s.settings.KeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		var err error
+		if done != nil {
+			err = writeEvent(w, newDoneMsg(menu, pantry, done))
+			done = nil
+		}
+
+		if err == nil {
+			err = rc.Flush()
+		}
+
+		if err != nil {
+			// The response has started, so the client cannot be told about the error
+			log.Debugf("Closing shopping list stream: %v", err)
+			return nil
+		}
+
+		select {
+		case <-r.Context().Done():
+			log.Debugf("Shopping list stream closed by the client")
+			return nil
+		case done = <-updates:
+			if done == nil {
+				done = make([]product.ID, 0)
+			}
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		}
+
+		if err != nil {
+			log.Debugf("Closing shopping list stream: %v", err)
+			return nil
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, msg doneMsg) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "event: done\ndata: %s\n\n", data)
+	return err
+}
@@ -7,11 +7,14 @@ import (
 	"io/fs"
 	"net/http"
 	"slices"
+	"time"
 
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/menucheck"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/menuneeds"
 	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
 )
@@ -25,11 +28,15 @@ type Service struct {
 
 type Settings struct {
 	Enable bool
+
+	// MaxPriceAge is how old a price can be before it is reported as stale.
+	MaxPriceAge time.Duration
 }
 
 func (Settings) Defaults() Settings {
 	return Settings{
-		Enable: true,
+		Enable:      true,
+		MaxPriceAge: menucheck.DefaultMaxPriceAge,
 	}
 }
 
@@ -83,15 +90,44 @@ func (s *Service) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Re
 		return httputils.Errorf(http.StatusInternalServerError, "could not get menu: %v", err)
 	}
 
+	// The needs can be compared with the stock of some pantries, or a group of them, combined
+	pantries, err := menuneeds.LookupPantries(s.db, user, r.URL.Query()["pantry"], r.URL.Query().Get("group"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return httputils.Errorf(http.StatusNotFound, "%v", err)
+	} else if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not get pantries: %v", err)
+	}
+
 	// Compute needs for the menu
 	need := menuneeds.ComputeNeeds(log, s.db, m)
 	log.Debugf("Responding menu-needs with %d items", len(need))
 
+	missing := make(map[product.ID]float32)
+	var covers map[product.ID][]dbtypes.PantryCover
+	if len(pantries) > 0 {
+		on := m.StartDate
+		if on == nil {
+			today := dbtypes.NewDate(time.Now())
+			on = &today
+		}
+
+		left, used := dbtypes.Consume(need, dbtypes.CombinedStock(pantries), on)
+		for _, l := range left {
+			missing[l.ProductID] = l.Amount
+		}
+		covers = dbtypes.CoveredBy(pantries, used)
+	}
+
 	// Build response
 	type Item struct {
 		ProductID product.ID `json:"product_id"`
 		Name      string     `json:"name"`
 		Amount    float32    `json:"amount"`
+
+		// These are only set when comparing with pantries
+		Have      *float32              `json:"have,omitempty"`
+		Missing   *float32              `json:"missing,omitempty"`
+		CoveredBy []dbtypes.PantryCover `json:"covered_by,omitempty"`
 	}
 
 	var items []Item
@@ -102,19 +138,45 @@ func (s *Service) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Re
 			continue
 		}
 
-		items = append(items, Item{
+		item := Item{
 			ProductID: i.ProductID,
 			Name:      p.Name,
 			Amount:    i.Amount,
-		})
+		}
+
+		if len(pantries) > 0 {
+			miss := missing[i.ProductID]
+			have := i.Amount - miss
+			item.Have = &have
+			item.Missing = &miss
+			item.CoveredBy = covers[i.ProductID]
+		}
+
+		items = append(items, item)
 	}
 
 	slices.SortFunc(items, func(a, b Item) int { return cmp.Compare(a.ProductID, b.ProductID) })
 
-	if err := json.NewEncoder(w).Encode(map[string]any{
-		"menu":  m.Name,
-		"items": items,
-	}); err != nil {
+	warnings := menucheck.Check(s.db, m, menucheck.Options{
+		MaxPriceAge: s.settings.MaxPriceAge,
+		Now:         time.Now(),
+	})
+
+	resp := map[string]any{
+		"menu":     m.Name,
+		"items":    items,
+		"warnings": warnings,
+	}
+
+	if len(pantries) > 0 {
+		names := make([]string, 0, len(pantries))
+		for _, p := range pantries {
+			names = append(names, p.Name)
+		}
+		resp["pantries"] = names
+	}
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		return httputils.Errorf(http.StatusInternalServerError, "could not encode response: %v", err)
 	}
 
@@ -22,11 +22,16 @@ func TestShoppingNeedsEndpoint(t *testing.T) {
 
 	testCases := map[string]struct {
 		method string
+		query  string
 
 		wantCode int
 		wantBody string
 	}{
-		"GET": {method: "GET", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET":                 {method: "GET", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET with warnings":   {method: "GET", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET with pantries":   {method: "GET", query: "?pantry=testpantry1&pantry=freezer", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET with group":      {method: "GET", query: "?group=home", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET group not found": {method: "GET", query: "?group=cellar", wantCode: http.StatusNotFound},
 
 		"DELETE": {method: "DELETE", wantCode: http.StatusMethodNotAllowed},
 		"PATCH":  {method: "PATCH", wantCode: http.StatusMethodNotAllowed},
@@ -53,7 +58,7 @@ func TestShoppingNeedsEndpoint(t *testing.T) {
 
 			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
 				ServePath: sv.Path(),
-				ReqPath:   "/api/shopping-needs/testmenu1",
+				ReqPath:   "/api/shopping-needs/testmenu1" + tc.query,
 				Endpoint:  sv.Handle,
 				Method:    tc.method,
 				Body:      string(out),
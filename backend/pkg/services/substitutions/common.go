@@ -0,0 +1,185 @@
+package substitutions
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"slices"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/substitutions"
+)
+
+type Settings struct {
+	Enable bool
+}
+
+func (Settings) Defaults() Settings {
+	return Settings{
+		Enable: true,
+	}
+}
+
+type suggestion struct {
+	ProductID   product.ID `json:"product_id"`
+	ProductName string     `json:"product_name"`
+	UnitPrice   float32    `json:"unit_price"`
+
+	SubstituteID        product.ID `json:"substitute_id"`
+	SubstituteName      string     `json:"substitute_name"`
+	SubstituteUnitPrice float32    `json:"substitute_unit_price"`
+	Provider            string     `json:"provider"`
+	SameProvider        bool       `json:"same_provider"`
+
+	Similarity float64 `json:"similarity"`
+	Amount     float32 `json:"amount"`
+	Saving     float32 `json:"saving"`
+}
+
+type suggestionsMsg struct {
+	Suggestions []suggestion `json:"suggestions"`
+
+	// TotalSaving is the saving of applying the best suggestion for every product.
+	TotalSaving float32 `json:"total_saving"`
+}
+
+type applyMsg struct {
+	ProductID    product.ID `json:"product_id"`
+	SubstituteID product.ID `json:"substitute_id"`
+}
+
+// suggest computes the suggestions for the needed products and writes them to the response.
+func suggest(db database.DB, w http.ResponseWriter, need []recipe.Ingredient) (int, error) {
+	catalog, err := db.Products()
+	if err != nil {
+		return 0, httputils.Errorf(http.StatusInternalServerError, "could not get products: %v", err)
+	}
+
+	sugg := substitutions.Suggest(need, catalog)
+
+	body := suggestionsMsg{
+		Suggestions: make([]suggestion, 0, len(sugg)),
+	}
+
+	var prev product.ID
+	for i, s := range sugg {
+		var provider string
+		if s.Substitute.Provider != nil {
+			provider = s.Substitute.Provider.Name()
+		}
+
+		body.Suggestions = append(body.Suggestions, suggestion{
+			ProductID:           s.Original.ID,
+			ProductName:         s.Original.Name,
+			UnitPrice:           s.Original.UnitPrice(),
+			SubstituteID:        s.Substitute.ID,
+			SubstituteName:      s.Substitute.Name,
+			SubstituteUnitPrice: s.Substitute.UnitPrice(),
+			Provider:            provider,
+			SameProvider:        s.SameProvider,
+			Similarity:          s.Similarity,
+			Amount:              s.Amount,
+			Saving:              s.Saving,
+		})
+
+		// Suggestions are sorted by product, with the best one first
+		if i == 0 || s.Original.ID != prev {
+			body.TotalSaving += s.Saving
+		}
+		prev = s.Original.ID
+	}
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		return 0, httputils.Errorf(http.StatusInternalServerError, "failed to write response: %v", err)
+	}
+
+	return len(body.Suggestions), nil
+}
+
+// apply reads a substitution from the request and applies it to the given recipes and
+// their sub-recipes. It returns the IDs of the modified recipes, sorted.
+func apply(db database.DB, r *http.Request, user string, roots []recipe.ID) ([]recipe.ID, error) {
+	var body applyMsg
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, httputils.Errorf(http.StatusBadRequest, "failed to read request: %v", err)
+	}
+
+	if body.ProductID == body.SubstituteID {
+		return nil, httputils.Error(http.StatusBadRequest, "a product cannot be substituted by itself")
+	}
+
+	prods := make([]product.Product, 0, 2)
+	for _, id := range []product.ID{body.ProductID, body.SubstituteID} {
+		p, err := db.LookupProduct(id)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, httputils.Errorf(http.StatusBadRequest, "product %d does not exist", id)
+		} else if err != nil {
+			return nil, httputils.Errorf(http.StatusInternalServerError, "failed to lookup product: %v", err)
+		}
+		prods = append(prods, p)
+	}
+
+	if !substitutions.SameUnit(prods[0], prods[1]) {
+		return nil, httputils.Errorf(http.StatusBadRequest, "products %d and %d are not measured in the same unit",
+			body.ProductID, body.SubstituteID)
+	}
+
+	// Find all recipes involved, including sub-recipes
+	visited := make(map[recipe.ID]recipe.Recipe)
+	queue := slices.Clone(roots)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+
+		if _, ok := visited[id]; ok {
+			continue
+		}
+
+		rec, err := db.LookupRecipe(user, id)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, httputils.Errorf(http.StatusInternalServerError, "failed to lookup recipe: %v", err)
+		}
+
+		visited[id] = rec
+		for _, sub := range rec.SubRecipes {
+			queue = append(queue, sub.RecipeID)
+		}
+	}
+
+	var changed []recipe.Recipe
+	var modified []recipe.ID
+	for id, rec := range visited {
+		if !substitutions.Replace(&rec, body.ProductID, body.SubstituteID) {
+			continue
+		}
+
+		changed = append(changed, rec)
+		modified = append(modified, id)
+	}
+
+	if len(modified) == 0 {
+		return nil, httputils.Errorf(http.StatusNotFound, "product %d is not used", body.ProductID)
+	}
+
+	// All recipes are saved together so that a failure does not leave the substitution half-applied
+	if _, err := db.SetRecipes(changed); err != nil {
+		return nil, httputils.Errorf(http.StatusInternalServerError, "failed to save recipes: %v", err)
+	}
+
+	slices.Sort(modified)
+	return modified, nil
+}
+
+// writeApplied writes the response to a successful substitution.
+func writeApplied(w http.ResponseWriter, modified []recipe.ID) error {
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"recipes": modified}); err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "failed to write response: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package substitutions
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database/dbtypes"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/menuneeds"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+)
+
+// MenuService proposes cheaper substitutes for all the products needed to cook a menu.
+// Amounts and savings are for the whole menu.
+type MenuService struct {
+	settings Settings
+	db       database.DB
+	auth     auth.Getter
+}
+
+func NewMenu(s Settings, db database.DB, auth auth.Getter) MenuService {
+	return MenuService{
+		settings: s,
+		db:       db,
+		auth:     auth,
+	}
+}
+
+func (s MenuService) Name() string {
+	return "menu-substitutions"
+}
+
+func (s MenuService) Path() string {
+	return "/api/menu/{menu}/substitutions"
+}
+
+func (s MenuService) Enabled() bool {
+	return s.settings.Enable
+}
+
+func (s MenuService) Handle(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return s.handleGet(log, w, r)
+	case http.MethodPost:
+		return s.handlePost(log, w, r)
+	default:
+		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+func (s MenuService) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	m, err := s.lookup(r, user)
+	if err != nil {
+		return err
+	}
+
+	n, err := suggest(s.db, w, menuneeds.ComputeNeeds(log, s.db, m))
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("Suggested %d substitutions for menu %s", n, m.Name)
+	return nil
+}
+
+// handlePost applies a substitution to every recipe in the menu.
+func (s MenuService) handlePost(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateContentType(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	m, err := s.lookup(r, user)
+	if err != nil {
+		return err
+	}
+
+	var roots []recipe.ID
+	for _, day := range m.Days {
+		for _, meal := range day.Meals {
+			for _, dish := range meal.Dishes {
+				roots = append(roots, dish.ID)
+			}
+		}
+	}
+
+	modified, err := apply(s.db, r, user, roots)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("Applied substitution to %d recipes", len(modified))
+	return writeApplied(w, modified)
+}
+
+func (s MenuService) lookup(r *http.Request, user string) (dbtypes.Menu, error) {
+	name := r.PathValue("menu")
+	if name == "" {
+		return dbtypes.Menu{}, httputils.Error(http.StatusBadRequest, "missing menu")
+	}
+
+	m, err := s.db.LookupMenu(user, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, httputils.Errorf(http.StatusNotFound, "menu %s not found", name)
+	} else if err != nil {
+		return m, httputils.Errorf(http.StatusInternalServerError, "failed to lookup menu: %v", err)
+	}
+
+	return m, nil
+}
@@ -0,0 +1,146 @@
+package substitutions
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"strconv"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/auth"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/httputils"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/logger"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/utils"
+)
+
+// RecipeService proposes cheaper substitutes for the ingredients of a recipe, including
+// those of its sub-recipes. Amounts and savings are per serving.
+type RecipeService struct {
+	settings Settings
+	db       database.DB
+	auth     auth.Getter
+}
+
+func NewRecipe(s Settings, db database.DB, auth auth.Getter) RecipeService {
+	return RecipeService{
+		settings: s,
+		db:       db,
+		auth:     auth,
+	}
+}
+
+func (s RecipeService) Name() string {
+	return "recipe-substitutions"
+}
+
+func (s RecipeService) Path() string {
+	return "/api/recipe/{id}/substitutions"
+}
+
+func (s RecipeService) Enabled() bool {
+	return s.settings.Enable
+}
+
+func (s RecipeService) Handle(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return s.handleGet(log, w, r)
+	case http.MethodPost:
+		return s.handlePost(log, w, r)
+	default:
+		return httputils.Errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+func (s RecipeService) handleGet(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	id, err := parseID(r)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	rec, err := s.lookup(user, id)
+	if err != nil {
+		return err
+	}
+
+	cached := database.NewCachedUserLookup(user, s.db.LookupRecipe)
+	need, err := recipe.Flatten(rec, cached.Lookup)
+	if err != nil {
+		return httputils.Errorf(http.StatusInternalServerError, "could not expand recipe: %v", err)
+	}
+
+	n, err := suggest(s.db, w, need)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("Suggested %d substitutions for recipe %d", n, id)
+	return nil
+}
+
+// handlePost applies a substitution to the recipe and its sub-recipes.
+func (s RecipeService) handlePost(log logger.Logger, w http.ResponseWriter, r *http.Request) error {
+	if err := httputils.ValidateContentType(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	if err := httputils.ValidateAccepts(r, httputils.MediaTypeJSON); err != nil {
+		return err
+	}
+
+	id, err := parseID(r)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.auth.GetUserID(r)
+	if err != nil {
+		return httputils.Errorf(http.StatusUnauthorized, "could not get user: %v", err)
+	}
+
+	if _, err := s.lookup(user, id); err != nil {
+		return err
+	}
+
+	modified, err := apply(s.db, r, user, []recipe.ID{id})
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("Applied substitution to %d recipes", len(modified))
+	return writeApplied(w, modified)
+}
+
+func (s RecipeService) lookup(user string, id recipe.ID) (recipe.Recipe, error) {
+	rec, err := s.db.LookupRecipe(user, id)
+	if errors.Is(err, fs.ErrNotExist) {
+		return rec, httputils.Errorf(http.StatusNotFound, "recipe %d not found", id)
+	} else if err != nil {
+		return rec, httputils.Errorf(http.StatusInternalServerError, "failed to lookup recipe: %v", err)
+	}
+	return rec, nil
+}
+
+func parseID(r *http.Request) (id recipe.ID, err error) {
+	sid := r.PathValue("id")
+	if sid == "" {
+		return 0, httputils.Error(http.StatusBadRequest, "missing id")
+	}
+
+	idURL, err := strconv.ParseUint(sid, 10, recipe.IDSize)
+	if err != nil {
+		return 0, httputils.Errorf(http.StatusBadRequest, "invalid id: %v", err)
+	}
+
+	return utils.SafeIntConvert[recipe.ID](idURL)
+}
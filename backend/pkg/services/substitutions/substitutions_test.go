@@ -0,0 +1,140 @@
+package substitutions_test
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/database"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/providers/blank"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/services/substitutions"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	providers.Register(blank.Provider{})
+	m.Run()
+}
+
+func TestRecipeSubstitutionsEndpoint(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		method string
+		path   string
+
+		wantCode int
+		wantBody string
+
+		// wantDough is the expected ingredients of recipe 1 after the request
+		wantDough []recipe.Ingredient
+	}{
+		"GET":           {method: "GET", path: "/api/recipe/2/substitutions", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET not found": {method: "GET", path: "/api/recipe/9/substitutions", wantCode: http.StatusNotFound},
+
+		"POST":                    {method: "POST", path: "/api/recipe/2/substitutions", wantCode: http.StatusOK, wantBody: "!golden", wantDough: []recipe.Ingredient{{ProductID: 5, Amount: 0.25}}},
+		"POST unused product":     {method: "POST", path: "/api/recipe/2/substitutions", wantCode: http.StatusNotFound},
+		"POST unknown substitute": {method: "POST", path: "/api/recipe/2/substitutions", wantCode: http.StatusBadRequest},
+		"POST different units":    {method: "POST", path: "/api/recipe/2/substitutions", wantCode: http.StatusBadRequest, wantDough: []recipe.Ingredient{{ProductID: 4, Amount: 0.25}}},
+
+		"DELETE": {method: "DELETE", path: "/api/recipe/2/substitutions", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db := testutils.Database(t, testutils.FixturePath(t, "database"))
+
+			sv := substitutions.NewRecipe(substitutions.Settings{}.Defaults(), db, testutils.MockAuthGetter())
+			require.True(t, sv.Enabled())
+
+			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
+				ServePath: sv.Path(),
+				ReqPath:   tc.path,
+				Endpoint:  sv.Handle,
+				Method:    tc.method,
+				Body:      readBody(t),
+				WantCode:  tc.wantCode,
+				WantBody:  tc.wantBody,
+			})
+
+			checkDough(t, db, tc.wantDough)
+		})
+	}
+}
+
+func TestMenuSubstitutionsEndpoint(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		method string
+		path   string
+
+		wantCode int
+		wantBody string
+
+		// wantDough is the expected ingredients of recipe 1 after the request
+		wantDough []recipe.Ingredient
+	}{
+		"GET":           {method: "GET", path: "/api/menu/testmenu/substitutions", wantCode: http.StatusOK, wantBody: "!golden"},
+		"GET not found": {method: "GET", path: "/api/menu/nonexistent/substitutions", wantCode: http.StatusNotFound},
+
+		"POST": {method: "POST", path: "/api/menu/testmenu/substitutions", wantCode: http.StatusOK, wantBody: "!golden", wantDough: []recipe.Ingredient{{ProductID: 5, Amount: 0.25}}},
+
+		"DELETE": {method: "DELETE", path: "/api/menu/testmenu/substitutions", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db := testutils.Database(t, testutils.FixturePath(t, "database"))
+
+			sv := substitutions.NewMenu(substitutions.Settings{}.Defaults(), db, testutils.MockAuthGetter())
+			require.True(t, sv.Enabled())
+
+			testutils.TestEndpoint(t, testutils.ResponseTestOptions{
+				ServePath: sv.Path(),
+				ReqPath:   tc.path,
+				Endpoint:  sv.Handle,
+				Method:    tc.method,
+				Body:      readBody(t),
+				WantCode:  tc.wantCode,
+				WantBody:  tc.wantBody,
+			})
+
+			checkDough(t, db, tc.wantDough)
+		})
+	}
+}
+
+func readBody(t *testing.T) string {
+	t.Helper()
+
+	fixture := testutils.FixturePath(t, "message", "body.json")
+	out, err := os.ReadFile(fixture)
+	if err != nil {
+		require.ErrorIs(t, err, os.ErrNotExist)
+		t.Logf("No golden file found at %s", fixture)
+		return ""
+	}
+
+	return string(out)
+}
+
+// checkDough verifies the ingredients of the sub-recipe, which substitutions must reach.
+func checkDough(t *testing.T, db database.DB, want []recipe.Ingredient) {
+	t.Helper()
+
+	if want == nil {
+		return
+	}
+
+	rec, err := db.LookupRecipe("test-user-123", 1)
+	require.NoError(t, err)
+	require.Equal(t, want, rec.Ingredients, "Substitution was not applied to the sub-recipe")
+}
@@ -0,0 +1,159 @@
+// Package substitutions proposes cheaper products that can replace the ingredients of a recipe.
+package substitutions
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/fuzzy"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/product"
+	"github.com/EduardGomezEscandell/grocery-price-fetcher/backend/pkg/recipe"
+)
+
+const (
+	// MinSimilarity is the minimum name similarity for a product to be proposed as a substitute.
+	MinSimilarity = 0.7
+
+	// MaxPerProduct is the maximum number of substitutes proposed for each product.
+	MaxPerProduct = 3
+)
+
+// Suggestion proposes replacing a product with a cheaper one.
+type Suggestion struct {
+	Original   product.Product
+	Substitute product.Product
+
+	// Similarity is how similar the names of both products are, between 0 and 1.
+	Similarity float64
+
+	// SameProvider is true if both products are sold by the same provider.
+	SameProvider bool
+
+	// Amount is the amount of the original product needed.
+	Amount float32
+
+	// Saving is the cost reduction of buying the amount needed from the substitute instead.
+	Saving float32
+}
+
+// Suggest proposes cheaper substitutes for each of the needed products.
+//
+// Substitutes must have a similar name, the same unit and a lower unit price. Products sold by the same
+// provider are proposed first, so that the shopping can still be done in a single store;
+// ties are broken by the largest saving. Suggestions are sorted by product ID.
+func Suggest(need []recipe.Ingredient, catalog []product.Product) []Suggestion {
+	byID := make(map[product.ID]product.Product, len(catalog))
+	for _, p := range catalog {
+		byID[p.ID] = p
+	}
+
+	var out []Suggestion
+	for _, n := range need {
+		orig, ok := byID[n.ProductID]
+		if !ok || n.Amount <= 0 {
+			continue
+		}
+
+		out = append(out, suggest(orig, n.Amount, catalog)...)
+	}
+
+	slices.SortStableFunc(out, func(a, b Suggestion) int {
+		return cmp.Compare(a.Original.ID, b.Original.ID)
+	})
+
+	return out
+}
+
+func suggest(orig product.Product, amount float32, catalog []product.Product) []Suggestion {
+	price := orig.UnitPrice()
+	if price <= 0 {
+		return nil
+	}
+
+	var out []Suggestion
+	for _, p := range catalog {
+		if p.ID == orig.ID || !SameUnit(orig, p) {
+			continue
+		}
+
+		subPrice := p.UnitPrice()
+		if subPrice <= 0 || subPrice >= price {
+			continue
+		}
+
+		sim := fuzzy.Similarity(orig.Name, p.Name)
+		if sim < MinSimilarity {
+			continue
+		}
+
+		out = append(out, Suggestion{
+			Original:     orig,
+			Substitute:   p,
+			Similarity:   sim,
+			SameProvider: sameProvider(orig, p),
+			Amount:       amount,
+			Saving:       amount * (price - subPrice),
+		})
+	}
+
+	slices.SortFunc(out, func(a, b Suggestion) int {
+		if a.SameProvider != b.SameProvider {
+			if a.SameProvider {
+				return -1
+			}
+			return 1
+		}
+		if c := cmp.Compare(b.Saving, a.Saving); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Substitute.ID, b.Substitute.ID)
+	})
+
+	if len(out) > MaxPerProduct {
+		out = out[:MaxPerProduct]
+	}
+
+	return out
+}
+
+func sameProvider(a, b product.Product) bool {
+	if a.Provider == nil || b.Provider == nil {
+		return false
+	}
+	return a.Provider.Name() == b.Provider.Name()
+}
+
+// SameUnit returns true if both products are measured in the same unit, so that one can
+// replace the other without changing the amounts.
+func SameUnit(a, b product.Product) bool {
+	return strings.EqualFold(a.Unit, b.Unit)
+}
+
+// Replace replaces every use of a product in a recipe with another one, keeping the amounts.
+// It returns false if the recipe does not use the product directly.
+func Replace(r *recipe.Recipe, from, to product.ID) bool {
+	var replaced bool
+	var amount float32
+
+	ingredients := make([]recipe.Ingredient, 0, len(r.Ingredients))
+	for _, ing := range r.Ingredients {
+		if ing.ProductID == from {
+			replaced = true
+			amount += ing.Amount
+			continue
+		}
+		if ing.ProductID == to {
+			amount += ing.Amount
+			continue
+		}
+		ingredients = append(ingredients, ing)
+	}
+
+	if !replaced {
+		return false
+	}
+
+	r.Ingredients = append(ingredients, recipe.Ingredient{ProductID: to, Amount: amount})
+	return true
+}
@@ -34,8 +34,15 @@ type ResponseTestOptions struct {
 	Method string
 	Body   string
 
+	// Accept is the Accept header of the request. Empty means no header.
+	Accept string
+
 	WantCode int
 	WantBody string
+
+	// GoldenFile is the name of the golden file used when WantBody is "!golden".
+	// Defaults to http_response.json.
+	GoldenFile string
 }
 
 func TestEndpoint(t *testing.T, opt ResponseTestOptions) {
@@ -50,7 +57,13 @@ func TestEndpoint(t *testing.T, opt ResponseTestOptions) {
 	t.Logf("Server started serving %s with endpoint %s", addr, opt.ServePath)
 
 	url := "http://" + path.Join(addr, opt.ReqPath)
-	resp := MakeRequest(t, opt.Method, url, opt.Body)
+	req := NewRequest(t, opt.Method, url, opt.Body)
+	if opt.Accept != "" {
+		req.Header.Set("Accept", opt.Accept)
+	}
+
+	resp, err := (&http.Client{}).Do(req)
+	require.NoError(t, err)
 	defer resp.Body.Close()
 
 	t.Logf("Request %s to %s returned %d", opt.Method, url, resp.StatusCode)
@@ -64,7 +77,11 @@ func TestEndpoint(t *testing.T, opt ResponseTestOptions) {
 	}
 
 	if opt.WantBody == "!golden" {
-		CompareToGolden(t, string(out), "http_response.json")
+		golden := opt.GoldenFile
+		if golden == "" {
+			golden = "http_response.json"
+		}
+		CompareToGolden(t, string(out), golden)
 	} else {
 		require.Equal(t, opt.WantBody, string(out))
 	}
@@ -73,16 +90,22 @@ func TestEndpoint(t *testing.T, opt ResponseTestOptions) {
 func MakeRequest(t *testing.T, method, url string, body string) *http.Response {
 	t.Helper()
 
+	resp, err := (&http.Client{}).Do(NewRequest(t, method, url, body))
+	require.NoError(t, err)
+
+	return resp
+}
+
+func NewRequest(t *testing.T, method, url string, body string) *http.Request {
+	t.Helper()
+
 	var buff bytes.Buffer
 	fmt.Fprint(&buff, body)
 
 	req, err := http.NewRequest(method, url, &buff)
 	require.NoError(t, err)
 
-	resp, err := (&http.Client{}).Do(req)
-	require.NoError(t, err)
-
-	return resp
+	return req
 }
 
 const PingEndpoint = "/test_utils_api/ping"